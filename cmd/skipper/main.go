@@ -23,6 +23,7 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/zalando/skipper"
+	"github.com/zalando/skipper/predicates/jsonbody"
 	"github.com/zalando/skipper/proxy"
 )
 
@@ -48,6 +49,10 @@ const (
 	oauthCredentialsDirUsage       = "directory where oauth credentials are stored: client.json and user.json"
 	oauthScopeUsage                = "the whitespace separated list of oauth scopes"
 	routesFileUsage                = "file containing static route definitions"
+	watchRoutesFileUsage           = "flag indicating to watch the routes file for changes, and to update the routes without restart"
+	trustForwardedForUsage         = "flag indicating to take the source address of the requests from the X-Forwarded-For header in the Source predicate"
+	trustForwardedProtoUsage       = "flag indicating to take the scheme of the requests from the X-Forwarded-Proto header in the Scheme predicate"
+	jsonBodyPredicateMaxSizeUsage  = "maximum number of bytes read from the request body by the JSONBodyKV predicate"
 	sourcePollTimeoutUsage         = "polling timeout of the routing data sources, in milliseconds"
 	insecureUsage                  = "flag indicating to ignore the verification of the TLS certificates of the backend services"
 	proxyPreserveHostUsage         = "flag indicating to preserve the incoming request 'Host' header in the outgoing requests"
@@ -67,6 +72,8 @@ const (
 	keyPathTLSUsage                = "the path on the local filesystem to the certificate's private key file"
 	backendFlushIntervalUsage      = "flush interval for upgraded proxy connections"
 	experimentalUpgradeUsage       = "enable experimental feature to handle upgrade protocol requests"
+	ignoreTrailingSlashUsage       = "flag indicating to ignore the trailing slash of the request paths when matching the Path conditions"
+	ignoreHostCaseUsage            = "flag indicating to ignore the case and the trailing dot of the host when matching the Host conditions"
 )
 
 var (
@@ -80,6 +87,10 @@ var (
 	innkeeperUrl              string
 	sourcePollTimeout         int64
 	routesFile                string
+	watchRoutesFile           bool
+	trustForwardedFor         bool
+	trustForwardedProto       bool
+	jsonBodyPredicateMaxSize  int64
 	oauthUrl                  string
 	oauthScope                string
 	oauthCredentialsDir       string
@@ -100,6 +111,8 @@ var (
 	keyPathTLS                string
 	backendFlushInterval      time.Duration
 	experimentalUpgrade       bool
+	ignoreTrailingSlash       bool
+	ignoreHostCase            bool
 )
 
 func init() {
@@ -113,6 +126,10 @@ func init() {
 	flag.StringVar(&innkeeperUrl, "innkeeper-url", "", innkeeperUrlUsage)
 	flag.Int64Var(&sourcePollTimeout, "source-poll-timeout", defaultSourcePollTimeout, sourcePollTimeoutUsage)
 	flag.StringVar(&routesFile, "routes-file", "", routesFileUsage)
+	flag.BoolVar(&watchRoutesFile, "watch-routes-file", false, watchRoutesFileUsage)
+	flag.BoolVar(&trustForwardedFor, "trust-forwarded-for", false, trustForwardedForUsage)
+	flag.BoolVar(&trustForwardedProto, "trust-forwarded-proto", false, trustForwardedProtoUsage)
+	flag.Int64Var(&jsonBodyPredicateMaxSize, "json-body-predicate-max-size", jsonbody.DefaultMaxBodySize, jsonBodyPredicateMaxSizeUsage)
 	flag.StringVar(&oauthUrl, "oauth-url", "", oauthUrlUsage)
 	flag.StringVar(&oauthScope, "oauth-scope", "", oauthScopeUsage)
 	flag.StringVar(&oauthCredentialsDir, "oauth-credentials-dir", "", oauthCredentialsDirUsage)
@@ -133,6 +150,8 @@ func init() {
 	flag.StringVar(&keyPathTLS, "tls-key", "", keyPathTLSUsage)
 	flag.DurationVar(&backendFlushInterval, "backend-flush-interval", defaultBackendFlushInterval, backendFlushIntervalUsage)
 	flag.BoolVar(&experimentalUpgrade, "experimental-upgrade", defaultExperimentalUpgrade, experimentalUpgradeUsage)
+	flag.BoolVar(&ignoreTrailingSlash, "ignore-trailing-slash", false, ignoreTrailingSlashUsage)
+	flag.BoolVar(&ignoreHostCase, "ignore-host-case", false, ignoreHostCaseUsage)
 	flag.Parse()
 }
 
@@ -169,9 +188,14 @@ func main() {
 		InnkeeperUrl:              innkeeperUrl,
 		SourcePollTimeout:         time.Duration(sourcePollTimeout) * time.Millisecond,
 		RoutesFile:                routesFile,
+		WatchRoutesFile:           watchRoutesFile,
+		TrustForwardedFor:         trustForwardedFor,
+		TrustForwardedProto:       trustForwardedProto,
+		JSONBodyPredicateMaxSize:  jsonBodyPredicateMaxSize,
 		IdleConnectionsPerHost:    idleConnsPerHost,
 		CloseIdleConnsPeriod:      time.Duration(clsic) * time.Second,
-		IgnoreTrailingSlash:       false,
+		IgnoreTrailingSlash:       ignoreTrailingSlash,
+		IgnoreHostCase:            ignoreHostCase,
 		OAuthUrl:                  oauthUrl,
 		OAuthScope:                oauthScope,
 		OAuthCredentialsDir:       oauthCredentialsDir,
@@ -174,9 +174,10 @@ https://github.com/zalando/innkeeper.
 (https://github.com/coreos/etcd). See the 'etcd' package.
 
 - static file: package eskipfile implements a simple data client, which
-can load route definitions from a static file in eskip format.
-Currently, it loads the routes on startup. It doesn't support runtime
-updates.
+can load route definitions from a static file in eskip format. By
+default, it loads the routes on startup. With the -watch-routes-file
+flag, the file is checked for changes, and the routes are updated
+without restart.
 
 Skipper can use additional data sources, provided by extensions. Sources
 must implement the DataClient interface in the routing package.
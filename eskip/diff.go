@@ -0,0 +1,166 @@
+package eskip
+
+import "reflect"
+
+func eqStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func eqStringMaps(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, v := range a {
+		if vb, ok := b[k]; !ok || vb != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func eqStringsMaps(a, b map[string][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, v := range a {
+		if vb, ok := b[k]; !ok || !eqStrings(v, vb) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// compares the args by their type and value, e.g. float64(3) is not
+// equal to int(3)
+func eqArgs(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !reflect.DeepEqual(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func eqPredicates(a, b []*Predicate) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Negated != b[i].Negated || !eqArgs(a[i].Args, b[i].Args) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func eqFilters(a, b []*Filter) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Name != b[i].Name || !eqArgs(a[i].Args, b[i].Args) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func eqLBBackends(a, b []*LBBackend) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if *a[i] != *b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Compares two route definitions. The order of the filters, the
+// predicates and their arguments matters, and the arguments are compared
+// by their type, too.
+func (r *Route) Equal(b *Route) bool {
+	return r.Id == b.Id &&
+		r.Path == b.Path &&
+		r.PathSubtree == b.PathSubtree &&
+		eqStrings(r.HostRegexps, b.HostRegexps) &&
+		eqStrings(r.PathRegexps, b.PathRegexps) &&
+		r.Method == b.Method &&
+		eqStringMaps(r.Headers, b.Headers) &&
+		eqStringsMaps(r.HeaderRegexps, b.HeaderRegexps) &&
+		eqPredicates(r.Predicates, b.Predicates) &&
+		eqStringMaps(r.Annotations, b.Annotations) &&
+		eqFilters(r.Filters, b.Filters) &&
+		r.Shunt == b.Shunt &&
+		r.Backend == b.Backend &&
+		r.LBAlgorithm == b.LBAlgorithm &&
+		eqLBBackends(r.LBBackends, b.LBBackends)
+}
+
+// Compares two sets of routes by their ids, without taking the order of
+// the routes into account. See Route.Equal.
+func RoutesEqual(a, b []*Route) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	added, updated, deleted := Diff(a, b)
+	return len(added) == 0 && len(updated) == 0 && len(deleted) == 0
+}
+
+// Compares two sets of routes by their ids. It returns the routes from b
+// that are not in a, the routes from b that are different in a, and the
+// routes from a that are not in b. See Route.Equal.
+func Diff(a, b []*Route) (added, updated, deleted []*Route) {
+	byId := make(map[string]*Route)
+	for _, r := range a {
+		byId[r.Id] = r
+	}
+
+	for _, r := range b {
+		if ra, ok := byId[r.Id]; !ok {
+			added = append(added, r)
+		} else if !ra.Equal(r) {
+			updated = append(updated, r)
+		}
+	}
+
+	bIds := make(map[string]bool)
+	for _, r := range b {
+		bIds[r.Id] = true
+	}
+
+	for _, r := range a {
+		if !bIds[r.Id] {
+			deleted = append(deleted, r)
+		}
+	}
+
+	return
+}
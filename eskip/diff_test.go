@@ -0,0 +1,131 @@
+package eskip
+
+import "testing"
+
+func TestRouteEqual(t *testing.T) {
+	for _, ti := range []struct {
+		msg   string
+		a, b  *Route
+		equal bool
+	}{{
+		"empty",
+		&Route{},
+		&Route{},
+		true,
+	}, {
+		"same routes",
+		&Route{
+			Id:          "route1",
+			Path:        "/some/path",
+			HostRegexps: []string{"[.]example[.]org$"},
+			Headers:     map[string]string{"X-Foo": "bar"},
+			Predicates:  []*Predicate{{Name: "Test", Args: []interface{}{3.14, "hello"}}},
+			Filters:     []*Filter{{"filter0", []interface{}{float64(42)}}, {"filter1", nil}},
+			Backend:     "https://www.example.org"},
+		&Route{
+			Id:          "route1",
+			Path:        "/some/path",
+			HostRegexps: []string{"[.]example[.]org$"},
+			Headers:     map[string]string{"X-Foo": "bar"},
+			Predicates:  []*Predicate{{Name: "Test", Args: []interface{}{3.14, "hello"}}},
+			Filters:     []*Filter{{"filter0", []interface{}{float64(42)}}, {"filter1", nil}},
+			Backend:     "https://www.example.org"},
+		true,
+	}, {
+		"different ids",
+		&Route{Id: "route1"},
+		&Route{Id: "route2"},
+		false,
+	}, {
+		"reordered filters",
+		&Route{Filters: []*Filter{{"filter0", nil}, {"filter1", nil}}},
+		&Route{Filters: []*Filter{{"filter1", nil}, {"filter0", nil}}},
+		false,
+	}, {
+		"reordered args",
+		&Route{Filters: []*Filter{{"filter0", []interface{}{"a", "b"}}}},
+		&Route{Filters: []*Filter{{"filter0", []interface{}{"b", "a"}}}},
+		false,
+	}, {
+		"float and int args",
+		&Route{Filters: []*Filter{{"filter0", []interface{}{float64(3)}}}},
+		&Route{Filters: []*Filter{{"filter0", []interface{}{3}}}},
+		false,
+	}, {
+		"float and string args",
+		&Route{Predicates: []*Predicate{{Name: "Test", Args: []interface{}{3.14}}}},
+		&Route{Predicates: []*Predicate{{Name: "Test", Args: []interface{}{"3.14"}}}},
+		false,
+	}, {
+		"different predicate args",
+		&Route{Predicates: []*Predicate{{Name: "Test", Args: []interface{}{3.14}}}},
+		&Route{Predicates: []*Predicate{{Name: "Test", Args: []interface{}{3.1415}}}},
+		false,
+	}, {
+		"negated predicate",
+		&Route{Predicates: []*Predicate{{Name: "Test", Args: []interface{}{3.14}}}},
+		&Route{Predicates: []*Predicate{{Name: "Test", Args: []interface{}{3.14}, Negated: true}}},
+		false,
+	}, {
+		"different headers",
+		&Route{Headers: map[string]string{"X-Foo": "bar"}},
+		&Route{Headers: map[string]string{"X-Foo": "baz"}},
+		false,
+	}, {
+		"shunt and backend",
+		&Route{Shunt: true},
+		&Route{Backend: "https://www.example.org"},
+		false,
+	}, {
+		"different lb weights",
+		&Route{LBAlgorithm: LBRandom, LBBackends: []*LBBackend{{"https://a.example.org", 1}}},
+		&Route{LBAlgorithm: LBRandom, LBBackends: []*LBBackend{{"https://a.example.org", 2}}},
+		false,
+	}} {
+		if ti.a.Equal(ti.b) != ti.equal || ti.b.Equal(ti.a) != ti.equal {
+			t.Error(ti.msg, "failed to compare routes")
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a, err := Parse(`
+		route1: Path("/one") -> filter0(3.14) -> "https://one.example.org";
+		route2: Path("/two") -> "https://two.example.org";
+		route3: Path("/three") -> filter0() -> filter1() -> <shunt>`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	b, err := Parse(`
+		route4: Path("/four") -> "https://four.example.org";
+		route3: Path("/three") -> filter1() -> filter0() -> <shunt>;
+		route1: Path("/one") -> filter0(3.14) -> "https://one.example.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	added, updated, deleted := Diff(a, b)
+	if len(added) != 1 || added[0].Id != "route4" {
+		t.Error("invalid added routes", added)
+	}
+
+	if len(updated) != 1 || updated[0].Id != "route3" {
+		t.Error("invalid updated routes", updated)
+	}
+
+	if len(deleted) != 1 || deleted[0].Id != "route2" {
+		t.Error("invalid deleted routes", deleted)
+	}
+
+	if RoutesEqual(a, b) {
+		t.Error("failed to detect the difference")
+	}
+
+	reordered := []*Route{a[2], a[0], a[1]}
+	if !RoutesEqual(a, reordered) {
+		t.Error("failed to ignore the order of the routes")
+	}
+}
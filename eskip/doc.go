@@ -56,6 +56,15 @@ supports the glob standard, e.g. "/some/path/**" will work as expected.
 The arguments are available to the filters while processing the matched
 requests.
 
+    PathSubtree("/some/path")
+
+The path subtree condition accepts a single argument, a path prefix,
+and it matches the prefix itself and any path under it. Routes with a
+deeper subtree take precedence over routes with a shallower one, while
+a route with the same exact path takes precedence over a subtree route.
+A route can contain either a Path or a PathSubtree condition, but not
+both.
+
     PathRegexp(/regular-expression/)
 
 The regexp path condition accepts a regular expression as a single
@@ -82,6 +91,15 @@ header value to match.
 The header regexp condition works similar to the header expression, but
 the value to be matched is a regular expression.
 
+    Annotation("owner", "team-a")
+
+The annotation condition doesn't affect the matching. It attaches
+metadata to the route, like the owner team, that can be used by the
+filters or for monitoring. It accepts two arguments, the name and the
+value of the annotation. The annotation named "deprecated" marks the
+route deprecated, and the routing logs its value as a warning when the
+route matches a request.
+
     *
 
 Catch all condition.
@@ -106,6 +124,20 @@ responsibility of the implementation to validate them.
 (See the documentation of the routing package.)
 
 
+Negated Predicates
+
+The Method, Header and HeaderRegexp conditions, and the custom
+predicates, can be negated with a leading exclamation mark. The route
+matches only those requests that the negated predicate doesn't match:
+
+    !Method("OPTIONS") && !Header("X-Forwarded-Proto", "http")
+
+The Path, PathSubtree, Host, PathRegexp, Annotation and catch all
+conditions cannot be negated, and the parser rejects such route
+definitions. The negated predicates are stored in the Predicates field
+of the route with the Negated flag set.
+
+
 Filters
 
 Filters are used to augment the incoming requests and the outgoing
@@ -158,7 +190,8 @@ root skipper package.
 
 Backend
 
-There are two types of backends: a network endpoint address or a shunt.
+There are three types of backends: a network endpoint address, a group of
+load balanced network endpoint addresses, or a shunt.
 
 A network endpoint address example:
 
@@ -168,6 +201,17 @@ An endpoint address backend is surrounded by '"'. It contains the scheme
 and the hostname of the endpoint, and optionally the port number that is
 inferred from the scheme if not specified.
 
+A load balanced backend example:
+
+    <random, "http://a.example.org", 90, "http://b.example.org", 10>
+
+A load balanced backend is surrounded by '<' and '>'. It starts with the
+name of the load balancing algorithm, currently only 'random' is
+supported, followed by the endpoint addresses. Every address can be
+followed by its weight, either all of them or none. The requests are
+forwarded to a randomly picked endpoint, proportionally to the weights.
+When no weights are set, the endpoints get the same share.
+
 A shunt backend:
 
     <shunt>
@@ -191,6 +235,21 @@ Example with comments:
     route2: * -> <shunt> // everything else 404
 
 
+Includes
+
+A routing document can include other documents with the include
+directive, in place of a route definition:
+
+    include "common.eskip";
+    route1: Path("/api") -> "https://api.example.org";
+
+The included routes take the place of the directive. The include
+directives are resolved only by the eskip.ParseWithIncludes function,
+with a function that loads the included documents, e.g. the eskipfile
+package resolves them as file paths. The eskip.Parse function rejects
+the documents containing include directives.
+
+
 Regular expressions
 
 The matching conditions and the built-in filters that use regular
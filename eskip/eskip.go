@@ -14,25 +14,44 @@
 
 package eskip
 
-//go:generate go tool yacc -o parser.go -p eskip parser.y
+//go:generate goyacc -o parser.go -p eskip parser.y
 
 import (
 	"errors"
 	"fmt"
 	"github.com/zalando/skipper/filters/flowid"
+	"hash/fnv"
+	"io"
 	"regexp"
 	"strings"
 )
 
-const duplicateHeaderPredicateErrorFmt = "duplicate header predicate: %s"
+const (
+	duplicateHeaderPredicateErrorFmt = "duplicate header predicate: %s"
+	duplicateAnnotationErrorFmt      = "duplicate annotation: %s"
+	invalidNegationErrorFmt          = "predicate cannot be negated: %s"
+	invalidDirectiveErrorFmt         = "invalid directive: %s"
+
+	includeKeyword = "include"
+)
 
 var (
 	invalidPredicateArgError        = errors.New("invalid predicate arg")
 	invalidPredicateArgCountError   = errors.New("invalid predicate count arg")
 	duplicatePathTreePredicateError = errors.New("duplicate path tree predicate")
 	duplicateMethodPredicateError   = errors.New("duplicate method predicate")
+	invalidLBAlgorithmError         = errors.New("invalid load balancing algorithm")
+	invalidLBBackendsError          = errors.New("invalid load balanced backends")
 )
 
+// ErrIncludeNotSupported is returned when parsing a document with include
+// directives, without an include function. See ParseWithIncludes.
+var ErrIncludeNotSupported = errors.New("include directive not supported")
+
+// The only supported load balancing algorithm, picking the backends
+// randomly, proportionally to their weight.
+const LBRandom = "random"
+
 // Represents a matcher condition for incoming requests.
 type matcher struct {
 
@@ -41,6 +60,9 @@ type matcher struct {
 
 	// The args of the matcher, e.g. the path to be matched.
 	args []interface{}
+
+	// Set when the matcher is negated, e.g. !Method("OPTIONS")
+	negated bool
 }
 
 // Route definition used during the parser processes the raw routing
@@ -51,6 +73,14 @@ type parsedRoute struct {
 	filters  []*Filter
 	shunt    bool
 	backend  string
+
+	// load balanced backends, e.g. <random, "https://a", 90, "https://b", 10>
+	lbAlgorithm string
+	lbArgs      []interface{}
+
+	// set for the include directives, e.g. include "other.eskip"
+	isInclude bool
+	include   string
 }
 
 // A Predicate object represents a parsed, in-memory, route matching predicate
@@ -66,6 +96,11 @@ type Predicate struct {
 	// float64 or string (string for both strings and
 	// regular expressions).
 	Args []interface{}
+
+	// Set when the predicate is negated, and the route matches
+	// only the requests that the predicate doesn't match.
+	// E.g. !Method("OPTIONS")
+	Negated bool
 }
 
 // A Filter object represents a parsed, in-memory filter expression.
@@ -78,6 +113,19 @@ type Filter struct {
 	Args []interface{}
 }
 
+// A LBBackend object represents one of the backends of a load balanced
+// route.
+type LBBackend struct {
+
+	// The address of the backend.
+	// E.g. "https://a.example.org"
+	Address string
+
+	// The weight of the backend, relative to the sum of the weights of
+	// all the backends in the route.
+	Weight float64
+}
+
 // A Route object represents a parsed, in-memory route definition.
 type Route struct {
 
@@ -89,6 +137,11 @@ type Route struct {
 	// E.g. Path("/some/path")
 	Path string
 
+	// Path prefix to be matched, where the route matches
+	// the prefix itself and any path under it.
+	// E.g. PathSubtree("/api/v1")
+	PathSubtree string
+
 	// Host regular expressions to match.
 	// E.g. Host(/[.]example[.]org/)
 	HostRegexps []string
@@ -113,6 +166,10 @@ type Route struct {
 	// E.g. Traffic(.3)
 	Predicates []*Predicate
 
+	// Metadata of the route, that doesn't affect the matching.
+	// E.g. Annotation("owner", "team-a")
+	Annotations map[string]string
+
 	// Set of filters in a particular route.
 	// E.g. redirect(302, "https://www.example.org/hello")
 	Filters []*Filter
@@ -124,6 +181,15 @@ type Route struct {
 	// The address of a backend for a parsed route.
 	// E.g. "https://www.example.org"
 	Backend string
+
+	// The algorithm used to pick one of the load balanced backends
+	// for every request. Currently only "random" is supported.
+	LBAlgorithm string
+
+	// The load balanced backends, when the route doesn't have a single
+	// backend.
+	// E.g. <random, "https://a.example.org", 90, "https://b.example.org", 10>
+	LBBackends []*LBBackend
 }
 
 type RoutePredicate func(*Route) bool
@@ -157,6 +223,14 @@ func getStringArgs(n int, args []interface{}) ([]string, error) {
 	return sargs, nil
 }
 
+// StringArgs checks that the predicate arguments are n strings, and
+// returns them. It validates the arguments of the built-in predicates,
+// e.g. Method or Header, when they are created from route definitions
+// that were not parsed from eskip.
+func StringArgs(n int, args []interface{}) ([]string, error) {
+	return getStringArgs(n, args)
+}
+
 // Checks and sets the different predicates taken from the yacc result.
 // As the syntax is getting stabilized, this logic soon should be defined as
 // yacc rules. (https://github.com/zalando/skipper/issues/89)
@@ -173,6 +247,11 @@ func applyPredicates(route *Route, proute *parsedRoute) error {
 			return err
 		}
 
+		if m.negated {
+			err = applyNegated(route, m)
+			continue
+		}
+
 		switch m.name {
 		case "Path":
 			if pathSet {
@@ -183,6 +262,15 @@ func applyPredicates(route *Route, proute *parsedRoute) error {
 				route.Path = args[0]
 				pathSet = true
 			}
+		case "PathSubtree":
+			if pathSet {
+				return duplicatePathTreePredicateError
+			}
+
+			if args, err = getStringArgs(1, m.args); err == nil {
+				route.PathSubtree = args[0]
+				pathSet = true
+			}
 		case "Host":
 			if args, err = getStringArgs(1, m.args); err == nil {
 				route.HostRegexps = append(route.HostRegexps, args[0])
@@ -220,18 +308,103 @@ func applyPredicates(route *Route, proute *parsedRoute) error {
 
 				route.Headers[args[0]] = args[1]
 			}
+		case "Annotation":
+			if args, err = getStringArgs(2, m.args); err == nil {
+				if route.Annotations == nil {
+					route.Annotations = make(map[string]string)
+				}
+
+				if _, ok := route.Annotations[args[0]]; ok {
+					return fmt.Errorf(duplicateAnnotationErrorFmt, args[0])
+				}
+
+				route.Annotations[args[0]] = args[1]
+			}
 		case "*", "Any":
 			// void
 		default:
 			route.Predicates = append(
 				route.Predicates,
-				&Predicate{m.name, m.args})
+				&Predicate{Name: m.name, Args: m.args})
 		}
 	}
 
 	return err
 }
 
+// Checks and sets a negated predicate. Only the predicates that are
+// evaluated on the request alone can be negated, the ones that
+// decide the lookup in the routing tree (Path, PathSubtree) or depend
+// on the matching options (Host, PathRegexp), and the annotations,
+// cannot. The negated predicates are stored with the custom
+// predicates.
+func applyNegated(route *Route, m *matcher) error {
+	switch m.name {
+	case "Method":
+		if _, err := getStringArgs(1, m.args); err != nil {
+			return err
+		}
+	case "Header", "HeaderRegexp":
+		if _, err := getStringArgs(2, m.args); err != nil {
+			return err
+		}
+	case "Path", "PathSubtree", "Host", "PathRegexp", "Annotation", "*", "Any":
+		return fmt.Errorf(invalidNegationErrorFmt, m.name)
+	}
+
+	route.Predicates = append(
+		route.Predicates,
+		&Predicate{Name: m.name, Args: m.args, Negated: true})
+	return nil
+}
+
+// Checks and sets the load balanced backends. The backend addresses can be
+// followed by their weight, either all of them or none. When no weight is
+// set, the backends have the same weight.
+func applyLBBackends(route *Route, algorithm string, args []interface{}) error {
+	if algorithm != LBRandom {
+		return invalidLBAlgorithmError
+	}
+
+	var (
+		backends []*LBBackend
+		weighted int
+		total    float64
+	)
+
+	for _, a := range args {
+		switch v := a.(type) {
+		case string:
+			backends = append(backends, &LBBackend{Address: v, Weight: 1})
+		case float64:
+			if len(backends) == 0 || len(backends) == weighted {
+				return invalidLBBackendsError
+			}
+
+			backends[len(backends)-1].Weight = v
+			weighted++
+		default:
+			return invalidLBBackendsError
+		}
+	}
+
+	if len(backends) == 0 || weighted > 0 && weighted != len(backends) {
+		return invalidLBBackendsError
+	}
+
+	for _, b := range backends {
+		total += b.Weight
+	}
+
+	if total <= 0 {
+		return invalidLBBackendsError
+	}
+
+	route.LBAlgorithm = algorithm
+	route.LBBackends = backends
+	return nil
+}
+
 // Converts a parsing route objects to the exported route definition with
 // pre-processed but not validated matchers.
 func newRouteDefinition(r *parsedRoute) (*Route, error) {
@@ -242,6 +415,12 @@ func newRouteDefinition(r *parsedRoute) (*Route, error) {
 	rd.Shunt = r.shunt
 	rd.Backend = r.backend
 
+	if r.lbAlgorithm != "" {
+		if err := applyLBBackends(rd, r.lbAlgorithm, r.lbArgs); err != nil {
+			return nil, err
+		}
+	}
+
 	err := applyPredicates(rd, r)
 
 	return rd, err
@@ -265,20 +444,54 @@ func filtersToRoute(f string) string {
 }
 
 // Parses a route expression or a routing document to a set of route definitions.
+// It fails when the document contains include directives, see
+// ParseWithIncludes.
 func Parse(code string) ([]*Route, error) {
+	return ParseWithIncludes(code, nil)
+}
+
+// ParseWithIncludes parses a routing document like Parse, and resolves
+// its include directives by calling the include function with the
+// included path. The routes returned by the include function take the
+// place of the directive in the result. Example document:
+//
+// 	include "common.eskip";
+// 	route1: Path("/foo") -> "https://www.example.org";
+//
+// The interpretation of the path is left to the include function. When
+// it is nil, the include directives fail with ErrIncludeNotSupported.
+func ParseWithIncludes(code string, include func(path string) ([]*Route, error)) ([]*Route, error) {
 	parsedRoutes, err := parse(code)
 	if err != nil {
 		return nil, err
 	}
 
-	routeDefinitions := make([]*Route, len(parsedRoutes))
-	for i, r := range parsedRoutes {
+	routeDefinitions := make([]*Route, 0, len(parsedRoutes))
+	for _, r := range parsedRoutes {
+		if r.isInclude {
+			if r.id != includeKeyword {
+				return nil, fmt.Errorf(invalidDirectiveErrorFmt, r.id)
+			}
+
+			if include == nil {
+				return nil, ErrIncludeNotSupported
+			}
+
+			included, err := include(r.include)
+			if err != nil {
+				return nil, err
+			}
+
+			routeDefinitions = append(routeDefinitions, included...)
+			continue
+		}
+
 		rd, err := newRouteDefinition(r)
 		if err != nil {
 			return nil, err
 		}
 
-		routeDefinitions[i] = rd
+		routeDefinitions = append(routeDefinitions, rd)
 	}
 
 	return routeDefinitions, nil
@@ -320,3 +533,15 @@ func GenerateIfNeeded(existingId string) string {
 	id = routeIdRx.ReplaceAllString(id, "x")
 	return "route" + id
 }
+
+// ContentId returns a synthetic id for a route, derived from the hash of
+// its canonical representation, without its own id. Unlike the random
+// ids of GenerateIfNeeded, it is the same every time the same route is
+// parsed, so it can identify the anonymous routes when merging or
+// comparing route definitions. The routes with the same meaning, see
+// Canonical, get the same id.
+func ContentId(r *Route) string {
+	h := fnv.New64a()
+	io.WriteString(h, Canonical(r).String())
+	return fmt.Sprintf("route%016x", h.Sum64())
+}
@@ -14,7 +14,10 @@
 
 package eskip
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func checkItems(t *testing.T, message string, l, lenExpected int, checkItem func(int) bool) bool {
 	if l != lenExpected {
@@ -58,6 +61,11 @@ func TestParseRouteExpression(t *testing.T) {
 		`Path("/some/path") -> "https://www.example.org"`,
 		&Route{Path: "/some/path", Backend: "https://www.example.org"},
 		false,
+	}, {
+		"path subtree predicate",
+		`PathSubtree("/some/path") -> "https://www.example.org"`,
+		&Route{PathSubtree: "/some/path", Backend: "https://www.example.org"},
+		false,
 	}, {
 		"path regexp",
 		`PathRegexp("^/some") && PathRegexp(/\/\w+Id$/) -> "https://www.example.org"`,
@@ -117,8 +125,8 @@ func TestParseRouteExpression(t *testing.T) {
 		`Custom1(3.14, "test value") && Custom2() -> "https://www.example.org"`,
 		&Route{
 			Predicates: []*Predicate{
-				&Predicate{"Custom1", []interface{}{float64(3.14), "test value"}},
-				&Predicate{"Custom2", nil}},
+				&Predicate{Name: "Custom1", Args: []interface{}{float64(3.14), "test value"}},
+				&Predicate{Name: "Custom2"}},
 			Backend: "https://www.example.org"},
 		false,
 	}, {
@@ -126,11 +134,65 @@ func TestParseRouteExpression(t *testing.T) {
 		`Path("/one") && Path("/two") -> "https://www.example.org"`,
 		nil,
 		true,
+	}, {
+		"annotations",
+		`Path("/") && Annotation("owner", "team-a") && Annotation("tier", "1") -> "https://www.example.org"`,
+		&Route{
+			Path:        "/",
+			Annotations: map[string]string{"owner": "team-a", "tier": "1"},
+			Backend:     "https://www.example.org"},
+		false,
+	}, {
+		"duplicate annotations",
+		`Annotation("owner", "team-a") && Annotation("owner", "team-b") -> "https://www.example.org"`,
+		nil,
+		true,
+	}, {
+		"invalid annotation",
+		`Annotation("owner") -> "https://www.example.org"`,
+		nil,
+		true,
+	}, {
+		"path and path subtree predicates",
+		`Path("/one") && PathSubtree("/two") -> "https://www.example.org"`,
+		nil,
+		true,
 	}, {
 		"double method predicates",
 		`Method("HEAD") && Method("GET") -> "https://www.example.org"`,
 		nil,
 		true,
+	}, {
+		"negated predicates",
+		`Path("/some/path") && !Header("X-Test", "foo") && !Method("OPTIONS") && !Custom("bar") -> "https://www.example.org"`,
+		&Route{
+			Path: "/some/path",
+			Predicates: []*Predicate{
+				{Name: "Header", Args: []interface{}{"X-Test", "foo"}, Negated: true},
+				{Name: "Method", Args: []interface{}{"OPTIONS"}, Negated: true},
+				{Name: "Custom", Args: []interface{}{"bar"}, Negated: true}},
+			Backend: "https://www.example.org"},
+		false,
+	}, {
+		"negated header predicate with invalid args",
+		`!Header("X-Test") -> "https://www.example.org"`,
+		nil,
+		true,
+	}, {
+		"negated path predicate",
+		`!Path("/some/path") -> "https://www.example.org"`,
+		nil,
+		true,
+	}, {
+		"negated host predicate",
+		`!Host(/www[.]example[.]org/) -> "https://www.example.org"`,
+		nil,
+		true,
+	}, {
+		"negated catch all",
+		`!* -> "https://www.example.org"`,
+		nil,
+		true,
 	}} {
 		stringMapKeys := func(m map[string]string) []string {
 			keys := make([]string, 0, len(m))
@@ -198,6 +260,11 @@ func TestParseRouteExpression(t *testing.T) {
 			return
 		}
 
+		if r.PathSubtree != ti.check.PathSubtree {
+			t.Error(ti.msg, "path subtree", r.PathSubtree, ti.check.PathSubtree)
+			return
+		}
+
 		if !checkStrings("host", r.HostRegexps, ti.check.HostRegexps) {
 			return
 		}
@@ -219,11 +286,16 @@ func TestParseRouteExpression(t *testing.T) {
 			return
 		}
 
+		if !checkStringMap("annotations", r.Annotations, ti.check.Annotations) {
+			return
+		}
+
 		if !checkItemsT("custom predicates",
 			len(r.Predicates),
 			len(ti.check.Predicates),
 			func(i int) bool {
 				return r.Predicates[i].Name == ti.check.Predicates[i].Name &&
+					r.Predicates[i].Negated == ti.check.Predicates[i].Negated &&
 					checkItemsT("custom predicate args",
 						len(r.Predicates[i].Args),
 						len(ti.check.Predicates[i].Args),
@@ -279,3 +351,222 @@ func TestParseFilters(t *testing.T) {
 		checkFilters(t, ti.msg, fs, ti.check)
 	}
 }
+
+func TestParseLBBackends(t *testing.T) {
+	for _, ti := range []struct {
+		msg        string
+		expression string
+		check      []*LBBackend
+		err        bool
+	}{{
+		"weighted backends",
+		`* -> <random, "https://a.example.org", 90, "https://b.example.org", 10>`,
+		[]*LBBackend{{"https://a.example.org", 90}, {"https://b.example.org", 10}},
+		false,
+	}, {
+		"backends without weights",
+		`* -> filter() -> <random, "https://a.example.org", "https://b.example.org">`,
+		[]*LBBackend{{"https://a.example.org", 1}, {"https://b.example.org", 1}},
+		false,
+	}, {
+		"shunt is not a load balanced backend",
+		`* -> <shunt>`,
+		nil,
+		false,
+	}, {
+		"unknown algorithm",
+		`* -> <roundRobin, "https://a.example.org", "https://b.example.org">`,
+		nil,
+		true,
+	}, {
+		"no backends",
+		`* -> <random, >`,
+		nil,
+		true,
+	}, {
+		"weight without backend",
+		`* -> <random, 90, "https://a.example.org">`,
+		nil,
+		true,
+	}, {
+		"double weight",
+		`* -> <random, "https://a.example.org", 90, 10>`,
+		nil,
+		true,
+	}, {
+		"partial weights",
+		`* -> <random, "https://a.example.org", 90, "https://b.example.org">`,
+		nil,
+		true,
+	}, {
+		"zero total weight",
+		`* -> <random, "https://a.example.org", 0, "https://b.example.org", 0>`,
+		nil,
+		true,
+	}, {
+		"negative weight",
+		`* -> <random, "https://a.example.org", -90, "https://b.example.org", 10>`,
+		nil,
+		true,
+	}, {
+		"single backend",
+		`* -> <random, "https://a.example.org">`,
+		[]*LBBackend{{"https://a.example.org", 1}},
+		false,
+	}} {
+		routes, err := Parse(ti.expression)
+		if err == nil && ti.err || err != nil && !ti.err {
+			t.Error(ti.msg, "failure case", err, ti.err)
+			continue
+		}
+
+		if ti.err {
+			continue
+		}
+
+		r := routes[0]
+		if len(r.LBBackends) != len(ti.check) {
+			t.Error(ti.msg, "invalid backends", r.LBBackends)
+			continue
+		}
+
+		if len(ti.check) > 0 && r.LBAlgorithm != LBRandom {
+			t.Error(ti.msg, "invalid algorithm", r.LBAlgorithm)
+		}
+
+		for i, b := range r.LBBackends {
+			if *b != *ti.check[i] {
+				t.Error(ti.msg, "invalid backend", b, ti.check[i])
+			}
+		}
+	}
+}
+
+func TestParseWithIncludes(t *testing.T) {
+	included := map[string][]*Route{
+		"a.eskip": {{Id: "a", Backend: "https://a.example.org"}},
+		"b.eskip": {{Id: "b", Backend: "https://b.example.org"}}}
+
+	include := func(path string) ([]*Route, error) {
+		if r, ok := included[path]; ok {
+			return r, nil
+		}
+
+		return nil, errors.New("not found")
+	}
+
+	for _, ti := range []struct {
+		msg  string
+		doc  string
+		ids  []string
+		fail bool
+	}{{
+		msg: "no includes",
+		doc: `route1: * -> <shunt>`,
+		ids: []string{"route1"},
+	}, {
+		msg: "includes in order",
+		doc: `include "a.eskip"; route1: * -> <shunt>; include "b.eskip";`,
+		ids: []string{"a", "route1", "b"},
+	}, {
+		msg: "only include",
+		doc: `include "a.eskip"`,
+		ids: []string{"a"},
+	}, {
+		msg: "route id include",
+		doc: `include: * -> <shunt>`,
+		ids: []string{"include"},
+	}, {
+		msg:  "failed include",
+		doc:  `include "missing.eskip"; route1: * -> <shunt>`,
+		fail: true,
+	}, {
+		msg:  "invalid directive",
+		doc:  `import "a.eskip"`,
+		fail: true,
+	}, {
+		msg:  "missing path",
+		doc:  `include; route1: * -> <shunt>`,
+		fail: true,
+	}} {
+		r, err := ParseWithIncludes(ti.doc, include)
+		if ti.fail {
+			if err == nil {
+				t.Error(ti.msg, "failed to fail")
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		if len(r) != len(ti.ids) {
+			t.Error(ti.msg, "invalid number of routes", len(r))
+			continue
+		}
+
+		for i, id := range ti.ids {
+			if r[i].Id != id {
+				t.Error(ti.msg, "invalid route", i, r[i].Id, id)
+			}
+		}
+	}
+
+	if _, err := Parse(`include "a.eskip"`); err != ErrIncludeNotSupported {
+		t.Error("failed to fail without include function", err)
+	}
+}
+
+func TestContentId(t *testing.T) {
+	docs := []string{
+		`Path("/foo") -> "https://foo.example.org"`,
+		`Path("/bar") && Method("POST") -> setPath("/baz") -> "https://bar.example.org"`,
+		`Header("X-Foo", "bar") && Header("X-Baz", "qux") -> <shunt>`,
+		`* -> "https://www.example.org"`,
+	}
+
+	ids := make(map[string]bool)
+	for _, doc := range docs {
+		first, err := Parse(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		second, err := Parse(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if first[0].Id != "" {
+			t.Error("unexpected id", first[0].Id)
+		}
+
+		id := ContentId(first[0])
+		if id != ContentId(second[0]) {
+			t.Error("failed to get the same id", doc)
+		}
+
+		if _, err := Parse(id + ": " + doc); err != nil {
+			t.Error("failed to use the id", id, err)
+		}
+
+		ids[id] = true
+	}
+
+	if len(ids) != len(docs) {
+		t.Error("failed to get different ids for different routes")
+	}
+
+	// the id and the order of the conditions don't change the content
+	r, err := Parse(`route1: Header("X-Baz", "qux") && Header("X-Foo", "bar") -> <shunt>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ContentId(r[0]) != ContentId(&Route{Headers: map[string]string{"X-Foo": "bar", "X-Baz": "qux"}, Shunt: true}) {
+		t.Error("failed to get the same id for the same content")
+	}
+}
@@ -43,6 +43,12 @@ type eskipLex struct {
 	initialLength int
 	routes        []*parsedRoute
 	filters       []*Filter
+
+	// the offset of the token being scanned, and the offset and
+	// the message of the parse error
+	tokenOffset int
+	errOffset   int
+	errMessage  string
 }
 
 type fixedScanner string
@@ -66,9 +72,12 @@ var fixedTokens = map[fixedScanner]int{
 	"&&":      and,
 	"*":       any,
 	"->":      arrow,
+	">":       closeangle,
 	")":       closeparen,
 	":":       colon,
 	",":       comma,
+	"!":       not,
+	"<":       openangle,
 	"(":       openparen,
 	";":       semicolon,
 	"<shunt>": shunt}
@@ -232,14 +241,21 @@ func scanSymbol(code string) (t token, rest string, err error) {
 	return
 }
 
+// selects the longest fixed token matching the code, e.g. <shunt>
+// instead of <
 func selectFixed(code string) scanner {
+	var longest fixedScanner
 	for fixed, _ := range fixedTokens {
-		if len(code) >= len(fixed) && strings.HasPrefix(code, string(fixed)) {
-			return fixed
+		if len(fixed) > len(longest) && strings.HasPrefix(code, string(fixed)) {
+			longest = fixed
 		}
 	}
 
-	return nil
+	if longest == "" {
+		return nil
+	}
+
+	return longest
 }
 
 func selectVaryingScanner(code string) scanner {
@@ -278,6 +294,7 @@ func selectScanner(code string) scanner {
 
 func (l *eskipLex) next() (t token, err error) {
 	l.code = scanWhitespace(l.code)
+	l.tokenOffset = l.initialLength - len(l.code)
 	if len(l.code) == 0 {
 		err = eof
 		return
@@ -317,6 +334,8 @@ func (l *eskipLex) Lex(lval *eskipSymType) int {
 }
 
 func (l *eskipLex) Error(err string) {
+	l.errOffset = l.tokenOffset
+	l.errMessage = err
 	l.err = errors.New(fmt.Sprintf(
 		"parse failed after token %v, position %d: %s",
 		l.lastToken, l.initialLength-len(l.code), err))
@@ -0,0 +1,144 @@
+package eskip
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var missingRouteIdError = errors.New("missing route id")
+
+// SyntaxError is returned by ParseAll for the invalid route definitions,
+// with the position of the error in the document.
+type SyntaxError struct {
+
+	// The line and the column of the error, starting from 1. The
+	// column counts bytes.
+	Line, Column int
+
+	// The position of the error, in bytes from the start of the
+	// document.
+	Offset int
+
+	// The description of the error.
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+func newSyntaxError(code string, offset int, message string) *SyntaxError {
+	line := strings.Count(code[:offset], "\n") + 1
+	column := offset - strings.LastIndex(code[:offset], "\n")
+	return &SyntaxError{
+		Line:    line,
+		Column:  column,
+		Offset:  offset,
+		Message: message}
+}
+
+// returns the offsets following the semicolons that separate the route
+// definitions in a document. The invalid characters are skipped, they
+// are reported when parsing the route definitions.
+func splitRouteOffsets(code string) []int {
+	var offsets []int
+	l := newLexer(code)
+	for {
+		t, err := l.next()
+		if err == eof {
+			return offsets
+		}
+
+		if err != nil {
+			l.code = code[l.tokenOffset+1:]
+			continue
+		}
+
+		if t.id == semicolon {
+			offsets = append(offsets, l.initialLength-len(l.code))
+		}
+	}
+}
+
+// returns the offset of the first token in a route definition
+func firstTokenOffset(code string) int {
+	l := newLexer(code)
+	l.next()
+	return l.tokenOffset
+}
+
+// a route definition or a syntax error in a document
+type parsedSegment struct {
+	offset int
+	route  *parsedRoute
+	err    *SyntaxError
+}
+
+// ParseAll parses a routing document like Parse, but instead of stopping
+// at the first error, it skips the invalid route definition, continues
+// with the next one following the semicolon, and returns all the errors.
+// The returned errors are of type *SyntaxError, containing the position
+// of the error in the document. The returned routes are the valid ones.
+// It is meant for editors and validation tools, to report all the
+// errors of a document at once. The include directives are not
+// supported.
+func ParseAll(code string) ([]*Route, []error) {
+	if routes, err := Parse(code); err == nil {
+		return routes, nil
+	}
+
+	var segments []parsedSegment
+
+	start := 0
+	for _, end := range append(splitRouteOffsets(code), len(code)) {
+		segment := code[start:end]
+		offset := start
+		start = end
+
+		l := newLexer(segment)
+		eskipParse(l)
+		if l.err != nil {
+			segments = append(segments, parsedSegment{
+				err: newSyntaxError(code, offset+l.errOffset, l.errMessage)})
+			continue
+		}
+
+		for _, r := range l.routes {
+			segments = append(segments, parsedSegment{
+				offset: offset + firstTokenOffset(segment),
+				route:  r})
+		}
+	}
+
+	var (
+		routes []*Route
+		errs   []error
+	)
+
+	for _, s := range segments {
+		if s.err != nil {
+			errs = append(errs, s.err)
+			continue
+		}
+
+		var err error
+		switch {
+		case s.route.isInclude:
+			err = ErrIncludeNotSupported
+		case s.route.id == "" && len(segments) > 1:
+			err = missingRouteIdError
+		default:
+			var r *Route
+			if r, err = newRouteDefinition(s.route); err == nil {
+				routes = append(routes, r)
+			}
+		}
+
+		if err != nil {
+			errs = append(errs, newSyntaxError(code, s.offset, err.Error()))
+		}
+	}
+
+	return routes, errs
+}
@@ -0,0 +1,90 @@
+package eskip
+
+import "testing"
+
+func TestParseAll(t *testing.T) {
+	for _, ti := range []struct {
+		msg       string
+		doc       string
+		routes    []string
+		positions [][2]int
+	}{{
+		msg:    "valid",
+		doc:    `route1: Path("/foo") -> "https://foo.example.org"; route2: * -> <shunt>`,
+		routes: []string{"route1", "route2"},
+	}, {
+		msg:    "valid, single route without id",
+		doc:    `Path("/foo") -> "https://foo.example.org"`,
+		routes: []string{""},
+	}, {
+		msg: "three syntax errors",
+		doc: `route1: Path("/foo") -> "https://foo.example.org";
+			route2: Path("/bar") -> -> "https://bar.example.org";
+			route3: Path("/baz") -> "https://baz.example.org";
+			// comment; with a semicolon
+			route4: Path("/qux") && -> "https://qux.example.org";
+			route5: Path("/quux") -> "https://quux.example.org";
+			route6 Path("/corge") -> "https://corge.example.org";
+			route7: * -> <shunt>`,
+		routes:    []string{"route1", "route3", "route5", "route7"},
+		positions: [][2]int{{2, 28}, {5, 28}, {7, 11}},
+	}, {
+		msg: "invalid character and unterminated string",
+		doc: `route1: Path("/foo") -> "https://foo.example.org";
+			route2: Path("/bar") -> % "https://bar.example.org";
+			route3: Path("/baz") -> "https://baz.example.org`,
+		routes:    []string{"route1"},
+		positions: [][2]int{{2, 28}, {3, 28}},
+	}, {
+		msg: "invalid route definition",
+		doc: `route1: Path("/foo") && Path("/bar") -> <shunt>;
+			route2: * -> <shunt>`,
+		routes:    []string{"route2"},
+		positions: [][2]int{{1, 1}},
+	}, {
+		msg: "missing route id",
+		doc: `route1: * -> <shunt>;
+			  Path("/foo") -> <shunt>;
+			route3: Path("/bar") -> -> <shunt>`,
+		routes:    []string{"route1"},
+		positions: [][2]int{{2, 29}, {3, 28}},
+	}, {
+		msg:       "missing route id in the last route",
+		doc:       `route1: * -> <shunt>; Path("/foo") -> <shunt>`,
+		routes:    []string{"route1"},
+		positions: [][2]int{{1, 23}},
+	}, {
+		msg:       "include directive",
+		doc:       `include "common.eskip"; route1: * -> <shunt>`,
+		routes:    []string{"route1"},
+		positions: [][2]int{{1, 1}},
+	}} {
+		routes, errs := ParseAll(ti.doc)
+		if len(routes) != len(ti.routes) {
+			t.Error(ti.msg, "invalid number of routes", len(routes))
+		} else {
+			for i, r := range routes {
+				if r.Id != ti.routes[i] {
+					t.Error(ti.msg, "invalid route", i, r.Id)
+				}
+			}
+		}
+
+		if len(errs) != len(ti.positions) {
+			t.Error(ti.msg, "invalid number of errors", len(errs), errs)
+			continue
+		}
+
+		for i, err := range errs {
+			se, ok := err.(*SyntaxError)
+			if !ok {
+				t.Error(ti.msg, "invalid error type", err)
+				continue
+			}
+
+			if se.Line != ti.positions[i][0] || se.Column != ti.positions[i][1] {
+				t.Error(ti.msg, "invalid position", i, se)
+			}
+		}
+	}
+}
@@ -1,9 +1,12 @@
+// Code generated by goyacc -o parser.go -p eskip parser.y. DO NOT EDIT.
+
 //line parser.y:16
 package eskip
 
 import __yyfmt__ "fmt"
 
 //line parser.y:16
+
 import "strconv"
 
 // conversion error ignored, tokenizer expression already checked format
@@ -14,36 +17,41 @@ func convertNumber(s string) float64 {
 
 //line parser.y:28
 type eskipSymType struct {
-	yys       int
-	token     string
-	route     *parsedRoute
-	routes    []*parsedRoute
-	matchers  []*matcher
-	matcher   *matcher
-	filter    *Filter
-	filters   []*Filter
-	args      []interface{}
-	arg       interface{}
-	backend   string
-	shunt     bool
-	numval    float64
-	stringval string
-	regexpval string
+	yys         int
+	token       string
+	route       *parsedRoute
+	routes      []*parsedRoute
+	matchers    []*matcher
+	matcher     *matcher
+	filter      *Filter
+	filters     []*Filter
+	args        []interface{}
+	arg         interface{}
+	backend     string
+	shunt       bool
+	lbAlgorithm string
+	lbArgs      []interface{}
+	numval      float64
+	stringval   string
+	regexpval   string
 }
 
 const and = 57346
 const any = 57347
 const arrow = 57348
-const closeparen = 57349
-const colon = 57350
-const comma = 57351
-const number = 57352
-const openparen = 57353
-const regexpliteral = 57354
-const semicolon = 57355
-const shunt = 57356
-const stringliteral = 57357
-const symbol = 57358
+const closeangle = 57349
+const closeparen = 57350
+const colon = 57351
+const comma = 57352
+const number = 57353
+const openangle = 57354
+const not = 57355
+const openparen = 57356
+const regexpliteral = 57357
+const semicolon = 57358
+const shunt = 57359
+const stringliteral = 57360
+const symbol = 57361
 
 var eskipToknames = [...]string{
 	"$end",
@@ -52,10 +60,13 @@ var eskipToknames = [...]string{
 	"and",
 	"any",
 	"arrow",
+	"closeangle",
 	"closeparen",
 	"colon",
 	"comma",
 	"number",
+	"openangle",
+	"not",
 	"openparen",
 	"regexpliteral",
 	"semicolon",
@@ -63,88 +74,92 @@ var eskipToknames = [...]string{
 	"stringliteral",
 	"symbol",
 }
+
 var eskipStatenames = [...]string{}
 
 const eskipEofCode = 1
 const eskipErrCode = 2
-const eskipMaxDepth = 200
+const eskipInitialStackSize = 16
 
-//line parser.y:203
+//line parser.y:248
 
 //line yacctab:1
-var eskipExca = [...]int{
+var eskipExca = [...]int8{
 	-1, 1,
 	1, -1,
 	-2, 0,
 }
 
-const eskipNprod = 29
 const eskipPrivate = 57344
 
-var eskipTokenNames []string
-var eskipStates []string
-
-const eskipLast = 46
+const eskipLast = 64
 
-var eskipAct = [...]int{
-
-	28, 30, 21, 27, 16, 17, 20, 22, 23, 32,
-	9, 33, 9, 19, 22, 3, 10, 7, 13, 14,
-	35, 25, 4, 8, 42, 36, 37, 37, 34, 26,
-	24, 31, 12, 15, 11, 29, 19, 39, 41, 40,
-	38, 18, 5, 6, 2, 1,
+var eskipAct = [...]int8{
+	33, 34, 26, 36, 27, 10, 22, 25, 28, 29,
+	10, 42, 21, 11, 18, 16, 17, 24, 11, 31,
+	16, 38, 12, 3, 8, 39, 17, 9, 28, 43,
+	40, 55, 49, 54, 45, 45, 5, 15, 4, 32,
+	41, 46, 30, 37, 50, 24, 48, 51, 47, 20,
+	53, 19, 52, 44, 45, 45, 14, 35, 13, 23,
+	6, 7, 2, 1,
 }
-var eskipPact = [...]int{
 
-	7, -1000, 3, -1000, -1000, 28, 10, -1000, 8, -1000,
-	-12, -8, 5, 5, -1, -1000, -1000, -1000, 22, -1000,
-	-1000, -1000, -1000, 9, -1000, 8, -1000, 18, -1000, -1000,
-	-1000, -1000, -1000, -1000, -8, -1, -1000, -1, -1000, -1000,
-	17, -1000, -1000,
+var eskipPact = [...]int16{
+	5, -1000, 6, -1000, -1000, -1000, 52, 28, 2, -1000,
+	-1000, -5, -7, -10, 0, 0, -1000, 10, 16, -1000,
+	-1000, -3, -1000, 34, -1000, -1000, -8, -1000, -1000, 15,
+	-1000, 12, -1000, 45, -1000, -1000, -1000, -1000, -1000, -1000,
+	10, -10, 22, 10, -1000, 10, 44, -1000, -1000, 10,
+	25, -1000, -1000, 24, -1000, -1000,
 }
-var eskipPgo = [...]int{
 
-	0, 45, 44, 15, 22, 43, 42, 5, 41, 17,
-	3, 2, 0, 35, 1, 31,
+var eskipPgo = [...]int8{
+	0, 63, 62, 23, 38, 36, 61, 60, 6, 59,
+	27, 0, 4, 1, 57, 3, 43,
 }
-var eskipR1 = [...]int{
 
-	0, 1, 1, 2, 2, 2, 2, 4, 5, 3,
-	3, 6, 6, 9, 9, 8, 8, 11, 10, 10,
-	10, 12, 12, 12, 7, 7, 13, 14, 15,
+var eskipR1 = [...]int8{
+	0, 1, 1, 2, 2, 2, 2, 2, 2, 4,
+	5, 6, 3, 3, 7, 7, 10, 10, 10, 9,
+	9, 12, 11, 11, 11, 13, 13, 13, 8, 8,
+	8, 14, 15, 16,
 }
-var eskipR2 = [...]int{
 
-	0, 1, 1, 0, 1, 3, 2, 3, 1, 3,
-	5, 1, 3, 1, 4, 1, 3, 4, 0, 1,
-	3, 1, 1, 1, 1, 1, 1, 1, 1,
+var eskipR2 = [...]int8{
+	0, 1, 1, 0, 1, 1, 3, 3, 2, 3,
+	2, 1, 3, 5, 1, 3, 1, 4, 5, 1,
+	3, 4, 0, 1, 3, 1, 1, 1, 1, 1,
+	5, 1, 1, 1,
 }
-var eskipChk = [...]int{
 
-	-1000, -1, -2, -3, -4, -6, -5, -9, 16, 5,
-	13, 6, 4, 8, 11, -4, 16, -7, -8, -14,
-	14, -11, 15, 16, -9, 16, -3, -10, -12, -13,
-	-14, -15, 10, 12, 6, 11, 7, 9, -7, -11,
-	-10, -12, 7,
+var eskipChk = [...]int16{
+	-1000, -1, -2, -3, -4, -5, -7, -6, 19, -10,
+	5, 13, 16, 6, 4, 9, 18, 14, 19, -4,
+	-5, 19, -8, -9, -15, 17, 12, -12, 18, 19,
+	-10, 19, -3, -11, -13, -14, -15, -16, 11, 15,
+	14, 6, 19, 14, 8, 10, -11, -8, -12, 10,
+	-11, -13, 8, -11, 8, 7,
 }
-var eskipDef = [...]int{
 
-	3, -2, 1, 2, 4, 0, 0, 11, 8, 13,
-	6, 0, 0, 0, 18, 5, 8, 9, 0, 24,
-	25, 15, 27, 0, 12, 0, 7, 0, 19, 21,
-	22, 23, 26, 28, 0, 18, 14, 0, 10, 16,
-	0, 20, 17,
+var eskipDef = [...]int8{
+	3, -2, 1, 2, 4, 5, 0, 0, 11, 14,
+	16, 0, 8, 0, 0, 0, 10, 22, 0, 6,
+	7, 11, 12, 0, 28, 29, 0, 19, 32, 0,
+	15, 0, 9, 0, 23, 25, 26, 27, 31, 33,
+	22, 0, 0, 22, 17, 0, 0, 13, 20, 22,
+	0, 24, 18, 0, 21, 30,
 }
-var eskipTok1 = [...]int{
 
+var eskipTok1 = [...]int8{
 	1,
 }
-var eskipTok2 = [...]int{
 
+var eskipTok2 = [...]int8{
 	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
-	12, 13, 14, 15, 16,
+	12, 13, 14, 15, 16, 17, 18, 19,
 }
-var eskipTok3 = [...]int{
+
+var eskipTok3 = [...]int8{
 	0,
 }
 
@@ -174,18 +189,17 @@ type eskipParser interface {
 }
 
 type eskipParserImpl struct {
-	lookahead func() int
+	lval  eskipSymType
+	stack [eskipInitialStackSize]eskipSymType
+	char  int
 }
 
 func (p *eskipParserImpl) Lookahead() int {
-	return p.lookahead()
+	return p.char
 }
 
 func eskipNewParser() eskipParser {
-	p := &eskipParserImpl{
-		lookahead: func() int { return -1 },
-	}
-	return p
+	return &eskipParserImpl{}
 }
 
 const eskipFlag = -1000
@@ -227,9 +241,9 @@ func eskipErrorMessage(state, lookAhead int) string {
 	expected := make([]int, 0, 4)
 
 	// Look for shiftable tokens.
-	base := eskipPact[state]
+	base := int(eskipPact[state])
 	for tok := TOKSTART; tok-1 < len(eskipToknames); tok++ {
-		if n := base + tok; n >= 0 && n < eskipLast && eskipChk[eskipAct[n]] == tok {
+		if n := base + tok; n >= 0 && n < eskipLast && int(eskipChk[int(eskipAct[n])]) == tok {
 			if len(expected) == cap(expected) {
 				return res
 			}
@@ -239,13 +253,13 @@ func eskipErrorMessage(state, lookAhead int) string {
 
 	if eskipDef[state] == -2 {
 		i := 0
-		for eskipExca[i] != -1 || eskipExca[i+1] != state {
+		for eskipExca[i] != -1 || int(eskipExca[i+1]) != state {
 			i += 2
 		}
 
 		// Look for tokens that we accept or reduce.
 		for i += 2; eskipExca[i] >= 0; i += 2 {
-			tok := eskipExca[i]
+			tok := int(eskipExca[i])
 			if tok < TOKSTART || eskipExca[i+1] == 0 {
 				continue
 			}
@@ -276,30 +290,30 @@ func eskiplex1(lex eskipLexer, lval *eskipSymType) (char, token int) {
 	token = 0
 	char = lex.Lex(lval)
 	if char <= 0 {
-		token = eskipTok1[0]
+		token = int(eskipTok1[0])
 		goto out
 	}
 	if char < len(eskipTok1) {
-		token = eskipTok1[char]
+		token = int(eskipTok1[char])
 		goto out
 	}
 	if char >= eskipPrivate {
 		if char < eskipPrivate+len(eskipTok2) {
-			token = eskipTok2[char-eskipPrivate]
+			token = int(eskipTok2[char-eskipPrivate])
 			goto out
 		}
 	}
 	for i := 0; i < len(eskipTok3); i += 2 {
-		token = eskipTok3[i+0]
+		token = int(eskipTok3[i+0])
 		if token == char {
-			token = eskipTok3[i+1]
+			token = int(eskipTok3[i+1])
 			goto out
 		}
 	}
 
 out:
 	if token == 0 {
-		token = eskipTok2[1] /* unknown char */
+		token = int(eskipTok2[1]) /* unknown char */
 	}
 	if eskipDebug >= 3 {
 		__yyfmt__.Printf("lex %s(%d)\n", eskipTokname(token), uint(char))
@@ -313,22 +327,20 @@ func eskipParse(eskiplex eskipLexer) int {
 
 func (eskiprcvr *eskipParserImpl) Parse(eskiplex eskipLexer) int {
 	var eskipn int
-	var eskiplval eskipSymType
 	var eskipVAL eskipSymType
 	var eskipDollar []eskipSymType
 	_ = eskipDollar // silence set and not used
-	eskipS := make([]eskipSymType, eskipMaxDepth)
+	eskipS := eskiprcvr.stack[:]
 
 	Nerrs := 0   /* number of errors */
 	Errflag := 0 /* error recovery flag */
 	eskipstate := 0
-	eskipchar := -1
-	eskiptoken := -1 // eskipchar translated into internal numbering
-	eskiprcvr.lookahead = func() int { return eskipchar }
+	eskiprcvr.char = -1
+	eskiptoken := -1 // eskiprcvr.char translated into internal numbering
 	defer func() {
 		// Make sure we report no lookahead when not parsing.
 		eskipstate = -1
-		eskipchar = -1
+		eskiprcvr.char = -1
 		eskiptoken = -1
 	}()
 	eskipp := -1
@@ -356,22 +368,22 @@ eskipstack:
 	eskipS[eskipp].yys = eskipstate
 
 eskipnewstate:
-	eskipn = eskipPact[eskipstate]
+	eskipn = int(eskipPact[eskipstate])
 	if eskipn <= eskipFlag {
 		goto eskipdefault /* simple state */
 	}
-	if eskipchar < 0 {
-		eskipchar, eskiptoken = eskiplex1(eskiplex, &eskiplval)
+	if eskiprcvr.char < 0 {
+		eskiprcvr.char, eskiptoken = eskiplex1(eskiplex, &eskiprcvr.lval)
 	}
 	eskipn += eskiptoken
 	if eskipn < 0 || eskipn >= eskipLast {
 		goto eskipdefault
 	}
-	eskipn = eskipAct[eskipn]
-	if eskipChk[eskipn] == eskiptoken { /* valid shift */
-		eskipchar = -1
+	eskipn = int(eskipAct[eskipn])
+	if int(eskipChk[eskipn]) == eskiptoken { /* valid shift */
+		eskiprcvr.char = -1
 		eskiptoken = -1
-		eskipVAL = eskiplval
+		eskipVAL = eskiprcvr.lval
 		eskipstate = eskipn
 		if Errflag > 0 {
 			Errflag--
@@ -381,27 +393,27 @@ eskipnewstate:
 
 eskipdefault:
 	/* default state action */
-	eskipn = eskipDef[eskipstate]
+	eskipn = int(eskipDef[eskipstate])
 	if eskipn == -2 {
-		if eskipchar < 0 {
-			eskipchar, eskiptoken = eskiplex1(eskiplex, &eskiplval)
+		if eskiprcvr.char < 0 {
+			eskiprcvr.char, eskiptoken = eskiplex1(eskiplex, &eskiprcvr.lval)
 		}
 
 		/* look through exception table */
 		xi := 0
 		for {
-			if eskipExca[xi+0] == -1 && eskipExca[xi+1] == eskipstate {
+			if eskipExca[xi+0] == -1 && int(eskipExca[xi+1]) == eskipstate {
 				break
 			}
 			xi += 2
 		}
 		for xi += 2; ; xi += 2 {
-			eskipn = eskipExca[xi+0]
+			eskipn = int(eskipExca[xi+0])
 			if eskipn < 0 || eskipn == eskiptoken {
 				break
 			}
 		}
-		eskipn = eskipExca[xi+1]
+		eskipn = int(eskipExca[xi+1])
 		if eskipn < 0 {
 			goto ret0
 		}
@@ -423,10 +435,10 @@ eskipdefault:
 
 			/* find a state where "error" is a legal shift action */
 			for eskipp >= 0 {
-				eskipn = eskipPact[eskipS[eskipp].yys] + eskipErrCode
+				eskipn = int(eskipPact[eskipS[eskipp].yys]) + eskipErrCode
 				if eskipn >= 0 && eskipn < eskipLast {
-					eskipstate = eskipAct[eskipn] /* simulate a shift of "error" */
-					if eskipChk[eskipstate] == eskipErrCode {
+					eskipstate = int(eskipAct[eskipn]) /* simulate a shift of "error" */
+					if int(eskipChk[eskipstate]) == eskipErrCode {
 						goto eskipstack
 					}
 				}
@@ -447,7 +459,7 @@ eskipdefault:
 			if eskiptoken == eskipEofCode {
 				goto ret1
 			}
-			eskipchar = -1
+			eskiprcvr.char = -1
 			eskiptoken = -1
 			goto eskipnewstate /* try again in the same state */
 		}
@@ -462,7 +474,7 @@ eskipdefault:
 	eskippt := eskipp
 	_ = eskippt // guard against "declared and not used"
 
-	eskipp -= eskipR2[eskipn]
+	eskipp -= int(eskipR2[eskipn])
 	// eskipp is now the index of $0. Perform the default action. Iff the
 	// reduced production is ε, $1 is possibly out of range.
 	if eskipp+1 >= len(eskipS) {
@@ -473,16 +485,16 @@ eskipdefault:
 	eskipVAL = eskipS[eskipp+1]
 
 	/* consult goto table to find next state */
-	eskipn = eskipR1[eskipn]
-	eskipg := eskipPgo[eskipn]
+	eskipn = int(eskipR1[eskipn])
+	eskipg := int(eskipPgo[eskipn])
 	eskipj := eskipg + eskipS[eskipp].yys + 1
 
 	if eskipj >= eskipLast {
-		eskipstate = eskipAct[eskipg]
+		eskipstate = int(eskipAct[eskipg])
 	} else {
-		eskipstate = eskipAct[eskipj]
-		if eskipChk[eskipstate] != -eskipn {
-			eskipstate = eskipAct[eskipg]
+		eskipstate = int(eskipAct[eskipj])
+		if int(eskipChk[eskipstate]) != -eskipn {
+			eskipstate = int(eskipAct[eskipg])
 		}
 	}
 	// dummy call; replaced with literal code
@@ -490,178 +502,227 @@ eskipdefault:
 
 	case 1:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-		//line parser.y:62
+//line parser.y:67
 		{
 			eskipVAL.routes = eskipDollar[1].routes
 			eskiplex.(*eskipLex).routes = eskipVAL.routes
 		}
 	case 2:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-		//line parser.y:67
+//line parser.y:72
 		{
 			eskipVAL.routes = []*parsedRoute{eskipDollar[1].route}
 			eskiplex.(*eskipLex).routes = eskipVAL.routes
 		}
 	case 4:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-		//line parser.y:74
+//line parser.y:79
 		{
 			eskipVAL.routes = []*parsedRoute{eskipDollar[1].route}
 		}
 	case 5:
+		eskipDollar = eskipS[eskippt-1 : eskippt+1]
+//line parser.y:83
+		{
+			eskipVAL.routes = []*parsedRoute{eskipDollar[1].route}
+		}
+	case 6:
 		eskipDollar = eskipS[eskippt-3 : eskippt+1]
-		//line parser.y:78
+//line parser.y:87
 		{
 			eskipVAL.routes = eskipDollar[1].routes
 			eskipVAL.routes = append(eskipVAL.routes, eskipDollar[3].route)
 		}
-	case 6:
+	case 7:
+		eskipDollar = eskipS[eskippt-3 : eskippt+1]
+//line parser.y:92
+		{
+			eskipVAL.routes = eskipDollar[1].routes
+			eskipVAL.routes = append(eskipVAL.routes, eskipDollar[3].route)
+		}
+	case 8:
 		eskipDollar = eskipS[eskippt-2 : eskippt+1]
-		//line parser.y:83
+//line parser.y:97
 		{
 			eskipVAL.routes = eskipDollar[1].routes
 		}
-	case 7:
+	case 9:
 		eskipDollar = eskipS[eskippt-3 : eskippt+1]
-		//line parser.y:88
+//line parser.y:102
 		{
 			eskipVAL.route = eskipDollar[3].route
 			eskipVAL.route.id = eskipDollar[1].token
 		}
-	case 8:
+	case 10:
+		eskipDollar = eskipS[eskippt-2 : eskippt+1]
+//line parser.y:108
+		{
+			eskipVAL.route = &parsedRoute{
+				id:        eskipDollar[1].token,
+				isInclude: true,
+				include:   eskipDollar[2].token}
+		}
+	case 11:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-		//line parser.y:94
+//line parser.y:116
 		{
 			eskipVAL.token = eskipDollar[1].token
 		}
-	case 9:
+	case 12:
 		eskipDollar = eskipS[eskippt-3 : eskippt+1]
-		//line parser.y:99
+//line parser.y:121
 		{
 			eskipVAL.route = &parsedRoute{
-				matchers: eskipDollar[1].matchers,
-				backend:  eskipDollar[3].backend,
-				shunt:    eskipDollar[3].shunt}
+				matchers:    eskipDollar[1].matchers,
+				backend:     eskipDollar[3].backend,
+				shunt:       eskipDollar[3].shunt,
+				lbAlgorithm: eskipDollar[3].lbAlgorithm,
+				lbArgs:      eskipDollar[3].lbArgs}
+			eskipDollar[3].lbArgs = nil
 		}
-	case 10:
+	case 13:
 		eskipDollar = eskipS[eskippt-5 : eskippt+1]
-		//line parser.y:106
+//line parser.y:131
 		{
 			eskipVAL.route = &parsedRoute{
-				matchers: eskipDollar[1].matchers,
-				filters:  eskipDollar[3].filters,
-				backend:  eskipDollar[5].backend,
-				shunt:    eskipDollar[5].shunt}
+				matchers:    eskipDollar[1].matchers,
+				filters:     eskipDollar[3].filters,
+				backend:     eskipDollar[5].backend,
+				shunt:       eskipDollar[5].shunt,
+				lbAlgorithm: eskipDollar[5].lbAlgorithm,
+				lbArgs:      eskipDollar[5].lbArgs}
 			eskipDollar[1].matchers = nil
 			eskipDollar[3].filters = nil
+			eskipDollar[5].lbArgs = nil
 		}
-	case 11:
+	case 14:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-		//line parser.y:117
+//line parser.y:145
 		{
 			eskipVAL.matchers = []*matcher{eskipDollar[1].matcher}
 		}
-	case 12:
+	case 15:
 		eskipDollar = eskipS[eskippt-3 : eskippt+1]
-		//line parser.y:121
+//line parser.y:149
 		{
 			eskipVAL.matchers = eskipDollar[1].matchers
 			eskipVAL.matchers = append(eskipVAL.matchers, eskipDollar[3].matcher)
 		}
-	case 13:
+	case 16:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-		//line parser.y:127
+//line parser.y:155
 		{
-			eskipVAL.matcher = &matcher{"*", nil}
+			eskipVAL.matcher = &matcher{"*", nil, false}
 		}
-	case 14:
+	case 17:
 		eskipDollar = eskipS[eskippt-4 : eskippt+1]
-		//line parser.y:131
+//line parser.y:159
 		{
-			eskipVAL.matcher = &matcher{eskipDollar[1].token, eskipDollar[3].args}
+			eskipVAL.matcher = &matcher{eskipDollar[1].token, eskipDollar[3].args, false}
 			eskipDollar[3].args = nil
 		}
-	case 15:
+	case 18:
+		eskipDollar = eskipS[eskippt-5 : eskippt+1]
+//line parser.y:164
+		{
+			eskipVAL.matcher = &matcher{eskipDollar[2].token, eskipDollar[4].args, true}
+			eskipDollar[4].args = nil
+		}
+	case 19:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-		//line parser.y:137
+//line parser.y:170
 		{
 			eskipVAL.filters = []*Filter{eskipDollar[1].filter}
 		}
-	case 16:
+	case 20:
 		eskipDollar = eskipS[eskippt-3 : eskippt+1]
-		//line parser.y:141
+//line parser.y:174
 		{
 			eskipVAL.filters = eskipDollar[1].filters
 			eskipVAL.filters = append(eskipVAL.filters, eskipDollar[3].filter)
 		}
-	case 17:
+	case 21:
 		eskipDollar = eskipS[eskippt-4 : eskippt+1]
-		//line parser.y:147
+//line parser.y:180
 		{
 			eskipVAL.filter = &Filter{
 				Name: eskipDollar[1].token,
 				Args: eskipDollar[3].args}
 			eskipDollar[3].args = nil
 		}
-	case 19:
+	case 23:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-		//line parser.y:156
+//line parser.y:189
 		{
 			eskipVAL.args = []interface{}{eskipDollar[1].arg}
 		}
-	case 20:
+	case 24:
 		eskipDollar = eskipS[eskippt-3 : eskippt+1]
-		//line parser.y:160
+//line parser.y:193
 		{
 			eskipVAL.args = eskipDollar[1].args
 			eskipVAL.args = append(eskipVAL.args, eskipDollar[3].arg)
 		}
-	case 21:
+	case 25:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-		//line parser.y:166
+//line parser.y:199
 		{
 			eskipVAL.arg = eskipDollar[1].numval
 		}
-	case 22:
+	case 26:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-		//line parser.y:170
+//line parser.y:203
 		{
 			eskipVAL.arg = eskipDollar[1].stringval
 		}
-	case 23:
+	case 27:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-		//line parser.y:174
+//line parser.y:207
 		{
 			eskipVAL.arg = eskipDollar[1].regexpval
 		}
-	case 24:
+	case 28:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-		//line parser.y:179
+//line parser.y:212
 		{
 			eskipVAL.backend = eskipDollar[1].stringval
 			eskipVAL.shunt = false
+			eskipVAL.lbAlgorithm = ""
+			eskipVAL.lbArgs = nil
 		}
-	case 25:
+	case 29:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-		//line parser.y:184
+//line parser.y:219
 		{
 			eskipVAL.shunt = true
+			eskipVAL.lbAlgorithm = ""
+			eskipVAL.lbArgs = nil
 		}
-	case 26:
+	case 30:
+		eskipDollar = eskipS[eskippt-5 : eskippt+1]
+//line parser.y:225
+		{
+			eskipVAL.backend = ""
+			eskipVAL.shunt = false
+			eskipVAL.lbAlgorithm = eskipDollar[2].token
+			eskipVAL.lbArgs = eskipDollar[4].args
+			eskipDollar[4].args = nil
+		}
+	case 31:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-		//line parser.y:189
+//line parser.y:234
 		{
 			eskipVAL.numval = convertNumber(eskipDollar[1].token)
 		}
-	case 27:
+	case 32:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-		//line parser.y:194
+//line parser.y:239
 		{
 			eskipVAL.stringval = eskipDollar[1].token
 		}
-	case 28:
+	case 33:
 		eskipDollar = eskipS[eskippt-1 : eskippt+1]
-		//line parser.y:199
+//line parser.y:244
 		{
 			eskipVAL.regexpval = eskipDollar[1].token
 		}
@@ -37,6 +37,8 @@ func convertNumber(s string) float64 {
 	arg interface{}
 	backend string
 	shunt bool
+	lbAlgorithm string
+	lbArgs []interface{}
 	numval float64
 	stringval string
 	regexpval string
@@ -45,10 +47,13 @@ func convertNumber(s string) float64 {
 %token and
 %token any
 %token arrow
+%token closeangle
 %token closeparen
 %token colon
 %token comma
 %token number
+%token openangle
+%token not
 %token openparen
 %token regexpliteral
 %token semicolon
@@ -75,11 +80,20 @@ routes:
 		$$.routes = []*parsedRoute{$1.route}
 	}
 	|
+	includedef {
+		$$.routes = []*parsedRoute{$1.route}
+	}
+	|
 	routes semicolon routedef {
 		$$.routes = $1.routes
 		$$.routes = append($$.routes, $3.route)
 	}
 	|
+	routes semicolon includedef {
+		$$.routes = $1.routes
+		$$.routes = append($$.routes, $3.route)
+	}
+	|
 	routes semicolon {
 		$$.routes = $1.routes
 	}
@@ -90,6 +104,14 @@ routedef:
 		$$.route.id = $1.token
 	}
 
+includedef:
+	symbol stringliteral {
+		$$.route = &parsedRoute{
+			id: $1.token,
+			isInclude: true,
+			include: $2.token}
+	}
+
 routeid:
 	symbol {
 		$$.token = $1.token
@@ -100,7 +122,10 @@ route:
 		$$.route = &parsedRoute{
 			matchers: $1.matchers,
 			backend: $3.backend,
-			shunt: $3.shunt}
+			shunt: $3.shunt,
+			lbAlgorithm: $3.lbAlgorithm,
+			lbArgs: $3.lbArgs}
+		$3.lbArgs = nil
 	}
 	|
 	frontend arrow filters arrow backend {
@@ -108,9 +133,12 @@ route:
 			matchers: $1.matchers,
 			filters: $3.filters,
 			backend: $5.backend,
-			shunt: $5.shunt}
+			shunt: $5.shunt,
+			lbAlgorithm: $5.lbAlgorithm,
+			lbArgs: $5.lbArgs}
 		$1.matchers = nil
 		$3.filters = nil
+		$5.lbArgs = nil
 	}
 
 frontend:
@@ -125,13 +153,18 @@ frontend:
 
 matcher:
     any {
-        $$.matcher = &matcher{"*", nil}
+        $$.matcher = &matcher{"*", nil, false}
     }
     |
 	symbol openparen args closeparen {
-        $$.matcher = &matcher{$1.token, $3.args}
+        $$.matcher = &matcher{$1.token, $3.args, false}
 		$3.args = nil
 	}
+	|
+	not symbol openparen args closeparen {
+		$$.matcher = &matcher{$2.token, $4.args, true}
+		$4.args = nil
+	}
 
 filters:
 	filter {
@@ -179,10 +212,22 @@ backend:
 	stringval {
 		$$.backend = $1.stringval
 		$$.shunt = false
+		$$.lbAlgorithm = ""
+		$$.lbArgs = nil
 	}
 	|
 	shunt {
 		$$.shunt = true
+		$$.lbAlgorithm = ""
+		$$.lbArgs = nil
+	}
+	|
+	openangle symbol comma args closeangle {
+		$$.backend = ""
+		$$.shunt = false
+		$$.lbAlgorithm = $2.token
+		$$.lbArgs = $4.args
+		$4.args = nil
 	}
 
 numval:
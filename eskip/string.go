@@ -2,9 +2,38 @@ package eskip
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 )
 
+const defaultIndent = "  "
+
+// PrettyPrintInfo controls the layout of the printed routes.
+type PrettyPrintInfo struct {
+
+	// When set, the filters and the backend are printed in
+	// separate lines.
+	Pretty bool
+
+	// The indentation of the filters and the backend, when printing
+	// in pretty mode. Defaults to two spaces.
+	IndentStr string
+}
+
+func (p PrettyPrintInfo) separator() string {
+	if !p.Pretty {
+		return " -> "
+	}
+
+	indent := p.IndentStr
+	if indent == "" {
+		indent = defaultIndent
+	}
+
+	return "\n" + indent + "-> "
+}
+
 func escape(s string, chars string) string {
 	s = strings.Replace(s, "\\", "\\\\", -1)
 	for i := 0; i < len(chars); i++ {
@@ -28,12 +57,28 @@ func appendFmtEscape(s []string, format string, escapeChars string, args ...inte
 	return appendFmt(s, format, eargs...)
 }
 
+// prints numbers without exponent, with the shortest representation that
+// parses back to the same value, e.g. 3, 3.14 or 1000000
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
 func argsString(args []interface{}) string {
 	var sargs []string
 	for _, a := range args {
 		switch a.(type) {
 		case float64:
-			sargs = appendFmt(sargs, "%g", a)
+			sargs = append(sargs, formatNumber(a.(float64)))
 		case string:
 			sargs = appendFmtEscape(sargs, `"%s"`, `"`, a)
 		}
@@ -49,6 +94,10 @@ func (r *Route) predicateString() string {
 		predicates = appendFmtEscape(predicates, `Path("%s")`, `"`, r.Path)
 	}
 
+	if r.PathSubtree != "" {
+		predicates = appendFmtEscape(predicates, `PathSubtree("%s")`, `"`, r.PathSubtree)
+	}
+
 	for _, h := range r.HostRegexps {
 		predicates = appendFmtEscape(predicates, "Host(/%s/)", "/", h)
 	}
@@ -61,18 +110,31 @@ func (r *Route) predicateString() string {
 		predicates = appendFmtEscape(predicates, `Method("%s")`, `"`, r.Method)
 	}
 
-	for k, v := range r.Headers {
-		predicates = appendFmtEscape(predicates, `Header("%s", "%s")`, `"`, k, v)
+	for _, k := range sortedKeys(r.Headers) {
+		predicates = appendFmtEscape(predicates, `Header("%s", "%s")`, `"`, k, r.Headers[k])
 	}
 
-	for k, rxs := range r.HeaderRegexps {
-		for _, rx := range rxs {
+	var headerRegexpKeys []string
+	for k := range r.HeaderRegexps {
+		headerRegexpKeys = append(headerRegexpKeys, k)
+	}
+
+	sort.Strings(headerRegexpKeys)
+	for _, k := range headerRegexpKeys {
+		for _, rx := range r.HeaderRegexps[k] {
 			predicates = appendFmt(predicates, `HeaderRegexp("%s", /%s/)`, escape(k, `"`), escape(rx, "/"))
 		}
 	}
 
+	for _, k := range sortedKeys(r.Annotations) {
+		predicates = appendFmtEscape(predicates, `Annotation("%s", "%s")`, `"`, k, r.Annotations[k])
+	}
+
 	for _, p := range r.Predicates {
-		if p.Name != "Any" {
+		switch {
+		case p.Negated:
+			predicates = appendFmt(predicates, "!%s(%s)", p.Name, argsString(p.Args))
+		case p.Name != "Any":
 			predicates = appendFmt(predicates, "%s(%s)", p.Name, argsString(p.Args))
 		}
 	}
@@ -84,15 +146,13 @@ func (r *Route) predicateString() string {
 	return strings.Join(predicates, " && ")
 }
 
-func (r *Route) filterString(pretty bool) string {
+func (r *Route) filterString(prettyPrintInfo PrettyPrintInfo) string {
 	var sfilters []string
 	for _, f := range r.Filters {
 		sfilters = appendFmt(sfilters, "%s(%s)", f.Name, argsString(f.Args))
 	}
-	if pretty {
-		return strings.Join(sfilters, "\n  -> ")
-	}
-	return strings.Join(sfilters, " -> ")
+
+	return strings.Join(sfilters, prettyPrintInfo.separator())
 }
 
 func (r *Route) backendString() string {
@@ -100,44 +160,173 @@ func (r *Route) backendString() string {
 		return "<shunt>"
 	}
 
-	return fmt.Sprintf(`"%s"`, r.Backend)
+	if len(r.LBBackends) > 0 {
+		args := []interface{}{}
+		for _, b := range r.LBBackends {
+			args = append(args, b.Address, b.Weight)
+		}
+
+		return fmt.Sprintf("<%s, %s>", r.LBAlgorithm, argsString(args))
+	}
+
+	return fmt.Sprintf(`"%s"`, escape(r.Backend, `"`))
 }
 
 // Serializes a route expression. Omits the route id if any.
 func (r *Route) String() string {
-	return r.Print(false)
+	return r.Print(PrettyPrintInfo{})
 }
 
-func (r *Route) Print(pretty bool) string {
+// Serializes a route expression, with the layout defined by
+// prettyPrintInfo. Omits the route id if any.
+//
+// The built-in predicates are printed in a fixed order, and the header
+// and annotation predicates are ordered by their keys, while the custom
+// predicates and the filters keep the order of the route. To get the
+// same output for routes that differ only in the order of their
+// predicates, use Canonical.
+func (r *Route) Print(prettyPrintInfo PrettyPrintInfo) string {
 	s := []string{r.predicateString()}
 
-	fs := r.filterString(pretty)
+	fs := r.filterString(prettyPrintInfo)
 	if fs != "" {
 		s = append(s, fs)
 	}
 
 	s = append(s, r.backendString())
-	separator := " -> "
-	if pretty {
-		separator = "\n  -> "
-	}
-	return strings.Join(s, separator)
+	return strings.Join(s, prettyPrintInfo.separator())
 }
 
 // Serializes a set of routes.
 func String(routes ...*Route) string {
-	return Print(false, routes...)
+	return Print(PrettyPrintInfo{}, routes...)
 }
 
-func Print(pretty bool, routes ...*Route) string {
+// Serializes a set of routes, with the layout defined by
+// prettyPrintInfo. When there is only a single route without an id,
+// the id is omitted.
+func Print(prettyPrintInfo PrettyPrintInfo, routes ...*Route) string {
 	if len(routes) == 1 && routes[0].Id == "" {
-		return routes[0].Print(pretty)
+		return routes[0].Print(prettyPrintInfo)
 	}
 
 	rs := make([]string, len(routes))
 	for i, r := range routes {
-		rs[i] = fmt.Sprintf("%s: %s", r.Id, r.Print(pretty))
+		rs[i] = fmt.Sprintf("%s: %s", r.Id, r.Print(prettyPrintInfo))
 	}
 
 	return strings.Join(rs, ";\n")
 }
+
+func copyArgs(args []interface{}) []interface{} {
+	if len(args) == 0 {
+		return nil
+	}
+
+	c := make([]interface{}, len(args))
+	copy(c, args)
+	return c
+}
+
+func sortedStrings(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+
+	c := make([]string, len(s))
+	copy(c, s)
+	sort.Strings(c)
+	return c
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+
+	c := make(map[string]string)
+	for k, v := range m {
+		c[k] = v
+	}
+
+	return c
+}
+
+type predicatesByNameAndArgs []*Predicate
+
+func (p predicatesByNameAndArgs) Len() int      { return len(p) }
+func (p predicatesByNameAndArgs) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+
+func (p predicatesByNameAndArgs) Less(i, j int) bool {
+	if p[i].Name != p[j].Name {
+		return p[i].Name < p[j].Name
+	}
+
+	if p[i].Negated != p[j].Negated {
+		return !p[i].Negated
+	}
+
+	return argsString(p[i].Args) < argsString(p[j].Args)
+}
+
+// Canonical returns a normalized copy of a route, that is printed the
+// same way as any other route with the same meaning:
+//
+// - the regular expressions of the Host, PathRegexp and HeaderRegexp
+// predicates are sorted,
+//
+// - the custom and the negated predicates are sorted by their name and
+// arguments, and the Any predicates are removed,
+//
+// - empty lists and maps are set to nil, and the backend address is
+// cleared for shunt routes.
+//
+// The order of the filters and the load balanced backends is preserved.
+func Canonical(r *Route) *Route {
+	c := &Route{
+		Id:          r.Id,
+		Path:        r.Path,
+		PathSubtree: r.PathSubtree,
+		HostRegexps: sortedStrings(r.HostRegexps),
+		PathRegexps: sortedStrings(r.PathRegexps),
+		Method:      r.Method,
+		Headers:     copyStringMap(r.Headers),
+		Annotations: copyStringMap(r.Annotations),
+		Shunt:       r.Shunt,
+		LBAlgorithm: r.LBAlgorithm,
+	}
+
+	if !r.Shunt {
+		c.Backend = r.Backend
+	}
+
+	for k, rxs := range r.HeaderRegexps {
+		if len(rxs) == 0 {
+			continue
+		}
+
+		if c.HeaderRegexps == nil {
+			c.HeaderRegexps = make(map[string][]string)
+		}
+
+		c.HeaderRegexps[k] = sortedStrings(rxs)
+	}
+
+	for _, p := range r.Predicates {
+		if p.Name != "Any" || p.Negated {
+			c.Predicates = append(c.Predicates, &Predicate{p.Name, copyArgs(p.Args), p.Negated})
+		}
+	}
+
+	sort.Stable(predicatesByNameAndArgs(c.Predicates))
+
+	for _, f := range r.Filters {
+		c.Filters = append(c.Filters, &Filter{f.Name, copyArgs(f.Args)})
+	}
+
+	for _, b := range r.LBBackends {
+		c.LBBackends = append(c.LBBackends, &LBBackend{b.Address, b.Weight})
+	}
+
+	return c
+}
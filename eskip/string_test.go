@@ -52,7 +52,7 @@ func TestRouteString(t *testing.T) {
 				`ap"key`: `ap"value`},
 			HeaderRegexps: map[string][]string{
 				`ap"key`: []string{"slash/value0", "slash/value1"}},
-			Predicates: []*Predicate{{"Test", []interface{}{3.14, "hello"}}},
+			Predicates: []*Predicate{{Name: "Test", Args: []interface{}{3.14, "hello"}}},
 			Filters: []*Filter{
 				{"filter0", []interface{}{float64(3.1415), "argvalue"}},
 				{"filter1", []interface{}{float64(-42), `ap"argvalue`}}},
@@ -67,6 +67,23 @@ func TestRouteString(t *testing.T) {
 			`Test(3.14, "hello") -> ` +
 			`filter0(3.1415, "argvalue") -> filter1(-42, "ap\"argvalue") -> ` +
 			`"https://www.example.org"`,
+	}, {
+		&Route{
+			Path:        "/some/path",
+			LBAlgorithm: LBRandom,
+			LBBackends: []*LBBackend{
+				{"https://a.example.org", 90},
+				{"https://b.example.org", 10}}},
+		`Path("/some/path") -> <random, "https://a.example.org", 90, "https://b.example.org", 10>`,
+	}, {
+		&Route{PathSubtree: "/some/path", Backend: "https://www.example.org"},
+		`PathSubtree("/some/path") -> "https://www.example.org"`,
+	}, {
+		&Route{
+			Path:        "/some/path",
+			Annotations: map[string]string{"tier": "1", "owner": "team-\"a\""},
+			Backend:     "https://www.example.org"},
+		`Path("/some/path") && Annotation("owner", "team-\"a\"") && Annotation("tier", "1") -> "https://www.example.org"`,
 	}, {
 		&Route{
 			Method:  "GET",
@@ -157,9 +174,9 @@ func testPrinting(routestr string, expected string, t *testing.T, i int, pretty
 	var printedRoute string
 
 	if multi {
-		printedRoute = Print(pretty, routes...)
+		printedRoute = Print(PrettyPrintInfo{Pretty: pretty}, routes...)
 	} else {
-		printedRoute = routes[0].Print(pretty)
+		printedRoute = routes[0].Print(PrettyPrintInfo{Pretty: pretty})
 	}
 
 	if printedRoute != expected {
@@ -170,8 +187,97 @@ func testPrinting(routestr string, expected string, t *testing.T, i int, pretty
 
 func TestParseAndStringAndParse(t *testing.T) {
 	doc := `route1: Method("GET") -> filter("expression") -> <shunt>;` + "\n" +
-		`route2: Path("/some/path") -> "https://www.example.org"`
+		`route2: Path("/some/path") -> "https://www.example.org";` + "\n" +
+		`route3: Annotation("owner", "team-a") && Annotation("tier", "1") -> "https://www.example.org";` + "\n" +
+		`route4: Path("/lb") -> <random, "https://a.example.org", 0.9, "https://b.example.org", 0.1>`
 	doc = testDoc(t, doc)
 	doc = testDoc(t, doc)
 	doc = testDoc(t, doc)
 }
+
+func TestPrintIndent(t *testing.T) {
+	r := &Route{Id: "route1", Method: "GET", Filters: []*Filter{{"filter", nil}}, Shunt: true}
+	expected := "route1: Method(\"GET\")\n\t-> filter()\n\t-> <shunt>"
+	if s := Print(PrettyPrintInfo{Pretty: true, IndentStr: "\t"}, r); s != expected {
+		t.Error("failed to print with custom indentation", s)
+	}
+}
+
+func TestCanonicalRoundTrip(t *testing.T) {
+	const (
+		doc = `route1: Traffic(0.3) && Header("X-B", "b") && HeaderRegexp("X-R", /z/) &&
+			Host(/b[.]example[.]org/) && Foo("b") && Any() && Method("GET") &&
+			Header("X-A", "a") && Host(/a[.]example[.]org/) && HeaderRegexp("X-R", /a/) &&
+			Foo("a") && Path("/some/path")
+			-> filter2(3.14, 3, 1000000, "q\"uoted") -> filter1() -> "https://www.example.org";
+		route2: PathSubtree("/api") && Annotation("tier", "1") && Annotation("owner", "team-a")
+			-> <random, "https://b.example.org", 1, "https://a.example.org", 0.25>`
+
+		expected = `route1: Path("/some/path") && Host(/a[.]example[.]org/) && Host(/b[.]example[.]org/) && ` +
+			`Method("GET") && Header("X-A", "a") && Header("X-B", "b") && ` +
+			`HeaderRegexp("X-R", /a/) && HeaderRegexp("X-R", /z/) && ` +
+			`Foo("a") && Foo("b") && Traffic(0.3)` + "\n" +
+			`  -> filter2(3.14, 3, 1000000, "q\"uoted")` + "\n" +
+			`  -> filter1()` + "\n" +
+			`  -> "https://www.example.org";` + "\n" +
+			`route2: PathSubtree("/api") && Annotation("owner", "team-a") && Annotation("tier", "1")` + "\n" +
+			`  -> <random, "https://b.example.org", 1, "https://a.example.org", 0.25>`
+	)
+
+	printCanonical := func(doc string) string {
+		routes, err := Parse(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, r := range routes {
+			routes[i] = Canonical(r)
+		}
+
+		return Print(PrettyPrintInfo{Pretty: true}, routes...)
+	}
+
+	printed := printCanonical(doc)
+	if printed != expected {
+		t.Error("failed to print canonical routes")
+		t.Log(printed)
+		t.Log(expected)
+		return
+	}
+
+	// repeating to catch the varying order of map iteration
+	for i := 0; i < 30; i++ {
+		if reprinted := printCanonical(printed); reprinted != expected {
+			t.Error("failed to reprint canonical routes")
+			t.Log(reprinted)
+			t.Log(expected)
+			return
+		}
+	}
+}
+
+func TestCanonicalDoesNotModifyRoute(t *testing.T) {
+	r := &Route{
+		HostRegexps: []string{"b", "a"},
+		Predicates:  []*Predicate{{Name: "Foo", Args: []interface{}{"b"}}, {Name: "Foo", Args: []interface{}{"a"}}},
+		Shunt:       true,
+		Backend:     "https://www.example.org"}
+
+	c := Canonical(r)
+	if r.HostRegexps[0] != "b" || r.Predicates[0].Args[0] != "b" || r.Backend == "" {
+		t.Error("failed to preserve the original route")
+	}
+
+	if c.HostRegexps[0] != "a" || c.Predicates[0].Args[0] != "a" || c.Backend != "" {
+		t.Error("failed to normalize the route", c.String())
+	}
+}
+
+func TestPrintNegatedPredicates(t *testing.T) {
+	testDoc(t, `route1: Path("/some/path") && !Header("X-Test", "foo") && !Method("OPTIONS") -> "https://www.example.org"`)
+
+	r := &Route{Predicates: []*Predicate{{Name: "Foo", Negated: true}, {Name: "Foo"}}, Shunt: true}
+	if s := Canonical(r).String(); s != `Foo() && !Foo() -> <shunt>` {
+		t.Error("failed to print canonical negated predicates", s)
+	}
+}
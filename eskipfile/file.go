@@ -16,33 +16,116 @@
 Package eskipfile implements a DataClient for reading the skipper route
 definitions from an eskip formatted file when opened.
 
+The files can include other eskip files, with the include directive:
+
+	include "common.eskip";
+	route1: Path("/foo") -> "https://www.example.org";
+
+The routes of the included file take the place of the directive, as if
+the files were concatenated, so when multiple routes have the same id,
+the one that comes last is used. Relative paths are resolved from the
+directory of the including file, unless a base directory is set in the
+options. Including a file that is already being included fails.
+
+The Client loads the file once, when opened. The WatchClient reloads
+the file, when it, or one of the included files, changes.
+
 (See the DataClient interface in the skipper/routing package and the eskip
 format in the skipper/eskip package.)
 */
 package eskipfile
 
 import (
-	"github.com/zalando/skipper/eskip"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
 )
 
+// Options for opening eskip files.
+type Options struct {
+
+	// When set, the relative paths of the include directives are
+	// resolved from this directory, instead of the directory of the
+	// including file.
+	BaseDir string
+}
+
 // A Client contains the route definitions from an eskip file.
 type Client struct{ routes []*eskip.Route }
 
+// ErrIncludeCycle is returned when an eskip file includes itself,
+// directly or indirectly.
+var ErrIncludeCycle = errors.New("include cycle")
+
 // Opens an eskip file and parses it, returning a DataClient implementation.
 // If reading or parsing the file fails, returns an error.
 func Open(path string) (*Client, error) {
-	content, err := ioutil.ReadFile(path)
+	return OpenWithOptions(path, Options{})
+}
+
+// OpenWithOptions opens and parses an eskip file like Open, resolving
+// the includes according to the options.
+func OpenWithOptions(path string, o Options) (*Client, error) {
+	routes, err := load(path, o, nil, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	routes, err := eskip.Parse(string(content))
+	return &Client{routes}, nil
+}
+
+// reads and parses a file, and the files it includes. The including
+// stores the files currently being included, to detect cycles. When
+// files is not nil, the state of every file that was read is stored in
+// it.
+func load(path string, o Options, including []string, files map[string]fileState) ([]*eskip.Route, error) {
+	abs, err := filepath.Abs(path)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Client{routes}, nil
+	if files != nil {
+		files[abs] = stat(abs)
+	}
+
+	for _, p := range including {
+		if p == abs {
+			return nil, fmt.Errorf("%w: %s", ErrIncludeCycle, strings.Join(append(including, abs), " -> "))
+		}
+	}
+
+	content, err := ioutil.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	including = append(including, abs)
+	routes, err := eskip.ParseWithIncludes(string(content), func(include string) ([]*eskip.Route, error) {
+		if !filepath.IsAbs(include) {
+			dir := o.BaseDir
+			if dir == "" {
+				dir = filepath.Dir(abs)
+			}
+
+			include = filepath.Join(dir, include)
+		}
+
+		return load(include, o, including[:len(including):len(including)], files)
+	})
+
+	if err != nil && len(including) == 1 {
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return routes, nil
 }
 
 func (c Client) LoadAndParseAll() (routeInfos []*eskip.RouteInfo, err error) {
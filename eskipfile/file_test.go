@@ -0,0 +1,168 @@
+package eskipfile
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "eskipfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func routeBackends(t *testing.T, c *Client) map[string]string {
+	routes, err := c.LoadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backends := make(map[string]string)
+	for _, r := range routes {
+		backends[r.Id] = r.Backend
+	}
+
+	return backends
+}
+
+func TestOpen(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"routes.eskip": `route1: Path("/foo") -> "https://foo.example.org"`})
+	defer os.RemoveAll(dir)
+
+	c, err := Open(filepath.Join(dir, "routes.eskip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := routeBackends(t, c); len(b) != 1 || b["route1"] != "https://foo.example.org" {
+		t.Error("failed to load the routes", b)
+	}
+}
+
+func TestInclude(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"routes.eskip": `
+			include "common/common.eskip";
+			route1: Path("/foo") -> "https://foo.example.org";
+			shared: Path("/shared") -> "https://override.example.org"`,
+		"common/common.eskip": `
+			include "health.eskip";
+			shared: Path("/shared") -> "https://shared.example.org";
+			route1: Path("/foo") -> "https://common.example.org"`,
+		"common/health.eskip": `health: Path("/health") -> <shunt>`})
+	defer os.RemoveAll(dir)
+
+	c, err := Open(filepath.Join(dir, "routes.eskip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := routeBackends(t, c)
+	if len(b) != 3 {
+		t.Fatal("failed to load the included routes", b)
+	}
+
+	// the later definitions override the included ones, as if the
+	// files were concatenated
+	routes, _ := c.LoadAll()
+	if len(routes) != 5 || routes[len(routes)-1].Id != "shared" ||
+		routes[len(routes)-1].Backend != "https://override.example.org" {
+		t.Error("invalid order of the routes", routes)
+	}
+
+	if _, ok := b["health"]; !ok {
+		t.Error("failed to resolve the include relative to the including file")
+	}
+}
+
+func TestIncludeBaseDir(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"config/routes.eskip": `include "common.eskip"; route1: Path("/foo") -> "https://foo.example.org"`,
+		"shared/common.eskip": `common: Path("/common") -> "https://common.example.org"`})
+	defer os.RemoveAll(dir)
+
+	if _, err := Open(filepath.Join(dir, "config/routes.eskip")); err == nil {
+		t.Error("failed to fail without the base dir")
+	}
+
+	c, err := OpenWithOptions(filepath.Join(dir, "config/routes.eskip"), Options{BaseDir: filepath.Join(dir, "shared")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := routeBackends(t, c); len(b) != 2 || b["common"] != "https://common.example.org" {
+		t.Error("failed to resolve the include from the base dir", b)
+	}
+}
+
+func TestIncludeMissingFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"routes.eskip": `include "missing.eskip"; route1: Path("/foo") -> "https://foo.example.org"`})
+	defer os.RemoveAll(dir)
+
+	_, err := Open(filepath.Join(dir, "routes.eskip"))
+	if err == nil || !strings.Contains(err.Error(), "missing.eskip") {
+		t.Error("failed to fail with the missing file", err)
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	for _, ti := range []struct {
+		msg   string
+		files map[string]string
+	}{{
+		"self",
+		map[string]string{"a.eskip": `include "a.eskip"`},
+	}, {
+		"indirect",
+		map[string]string{
+			"a.eskip": `include "b.eskip"`,
+			"b.eskip": `include "c.eskip"; route1: * -> <shunt>`,
+			"c.eskip": `include "a.eskip"`},
+	}} {
+		func() {
+			dir := writeFiles(t, ti.files)
+			defer os.RemoveAll(dir)
+
+			_, err := Open(filepath.Join(dir, "a.eskip"))
+			if err == nil || !errors.Is(err, ErrIncludeCycle) {
+				t.Error(ti.msg, "failed to detect the cycle", err)
+			}
+		}()
+	}
+}
+
+func TestIncludeSameFileTwice(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"routes.eskip": `include "common.eskip"; include "common.eskip"`,
+		"common.eskip": `common: Path("/common") -> "https://common.example.org"`})
+	defer os.RemoveAll(dir)
+
+	c, err := Open(filepath.Join(dir, "routes.eskip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := routeBackends(t, c); len(b) != 1 {
+		t.Error("failed to include the same file twice", b)
+	}
+}
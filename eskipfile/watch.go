@@ -0,0 +1,137 @@
+package eskipfile
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/routing"
+)
+
+// the state of a file used to detect the changes. A missing file has
+// the zero state.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// A WatchClient reads the route definitions from an eskip file, and
+// reloads them when the file, or one of the files that it includes,
+// changes.
+//
+// The client doesn't start its own goroutine. The files are checked
+// for changes every time the routing polls the client for updates, by
+// comparing their modification time and size. When a file changed, the
+// client signals a reset to the routing, and all the routes of the file
+// are replaced.
+//
+// When reloading the files fails, e.g. because of a syntax error, the
+// client keeps serving the routes of the last successful load, and it
+// reports the error as an invalid route with the id set to the path of
+// the file. The error is cleared by the next successful load.
+type WatchClient struct {
+	path    string
+	options Options
+
+	mx      sync.Mutex
+	files   map[string]fileState
+	routes  []*eskip.Route
+	invalid []routing.RouteError
+	loaded  bool
+}
+
+// Watch creates a client that reads the route definitions from an eskip
+// file, and watches it for changes. The file is read only when the
+// routing requests the routes the first time.
+func Watch(path string) *WatchClient {
+	return WatchWithOptions(path, Options{})
+}
+
+// WatchWithOptions creates a watching client like Watch, resolving the
+// includes according to the options.
+func WatchWithOptions(path string, o Options) *WatchClient {
+	return &WatchClient{path: path, options: o}
+}
+
+func stat(path string) fileState {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+
+	return fileState{fi.ModTime(), fi.Size()}
+}
+
+// tells whether any of the files read during the last load changed
+func (c *WatchClient) changed() bool {
+	if c.files == nil {
+		return true
+	}
+
+	for path, s := range c.files {
+		if stat(path) != s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *WatchClient) reload() error {
+	files := make(map[string]fileState)
+	routes, err := load(c.path, c.options, nil, files)
+	c.files = files
+	if err != nil {
+		c.invalid = []routing.RouteError{{Id: c.path, Err: err}}
+		return err
+	}
+
+	c.routes = routes
+	c.invalid = nil
+	c.loaded = true
+	return nil
+}
+
+// LoadAll reads and parses the file, and returns the routes. When it
+// fails, but the file was loaded successfully before, the routes from
+// the last successful load are returned.
+func (c *WatchClient) LoadAll() ([]*eskip.Route, error) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if err := c.reload(); err != nil && !c.loaded {
+		return nil, err
+	}
+
+	return c.routes, nil
+}
+
+// LoadUpdate checks whether the files changed, and when yes, it reloads
+// them and returns routing.ErrReset, signalling the routing to request
+// the new set of routes by calling Reset.
+func (c *WatchClient) LoadUpdate() ([]*eskip.Route, []string, error) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if !c.changed() {
+		return nil, nil, nil
+	}
+
+	c.reload()
+	return nil, nil, routing.ErrReset
+}
+
+// Reset returns the routes from the last successful load.
+func (c *WatchClient) Reset() ([]*eskip.Route, error) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.routes, nil
+}
+
+// InvalidRoutes returns the error of the last load, when it failed.
+func (c *WatchClient) InvalidRoutes() []routing.RouteError {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.invalid
+}
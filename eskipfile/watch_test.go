@@ -0,0 +1,211 @@
+package eskipfile
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters/builtin"
+	"github.com/zalando/skipper/logging/loggingtest"
+	"github.com/zalando/skipper/routing"
+)
+
+const watchPollTimeout = 15 * time.Millisecond
+
+// writes the file, and makes sure that its modification time changes,
+// also when the file system has a low time resolution
+func updateFile(t *testing.T, path, content string) {
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mt := time.Now().Add(time.Duration(time.Now().UnixNano()%1000+1) * time.Second)
+	if err := os.Chtimes(path, mt, mt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func backends(routes []*eskip.Route) map[string]string {
+	b := make(map[string]string)
+	for _, r := range routes {
+		b[r.Id] = r.Backend
+	}
+
+	return b
+}
+
+func TestWatchLoadAll(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"routes.eskip": `route1: Path("/foo") -> "https://foo.example.org"`})
+	defer os.RemoveAll(dir)
+
+	c := Watch(filepath.Join(dir, "routes.eskip"))
+	routes, err := c.LoadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := backends(routes); len(b) != 1 || b["route1"] != "https://foo.example.org" {
+		t.Error("failed to load the routes", b)
+	}
+
+	routes, deleted, err := c.LoadUpdate()
+	if err != nil || len(routes) != 0 || len(deleted) != 0 {
+		t.Error("unexpected update", routes, deleted, err)
+	}
+}
+
+func TestWatchInitialLoadFails(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"routes.eskip": `route1: Path("/foo") -> `})
+	defer os.RemoveAll(dir)
+
+	c := Watch(filepath.Join(dir, "routes.eskip"))
+	if _, err := c.LoadAll(); err == nil {
+		t.Error("failed to fail")
+	}
+
+	if _, err := Watch(filepath.Join(dir, "missing.eskip")).LoadAll(); err == nil {
+		t.Error("failed to fail")
+	}
+}
+
+func TestWatchUpdate(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"routes.eskip": `include "common.eskip"; route1: Path("/foo") -> "https://foo.example.org"`,
+		"common.eskip": `common: Path("/common") -> "https://common.example.org"`})
+	defer os.RemoveAll(dir)
+
+	c := Watch(filepath.Join(dir, "routes.eskip"))
+	if _, err := c.LoadAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	updateFile(t, filepath.Join(dir, "routes.eskip"), `include "common.eskip"; route2: Path("/bar") -> "https://bar.example.org"`)
+	if _, _, err := c.LoadUpdate(); err != routing.ErrReset {
+		t.Fatal("failed to signal the change", err)
+	}
+
+	routes, err := c.Reset()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := backends(routes); len(b) != 2 || b["route2"] != "https://bar.example.org" || b["common"] == "" {
+		t.Error("failed to reload the routes", b)
+	}
+
+	if _, _, err := c.LoadUpdate(); err != nil {
+		t.Error("unexpected change", err)
+	}
+
+	updateFile(t, filepath.Join(dir, "common.eskip"), `common: Path("/common") -> "https://common2.example.org"`)
+	if _, _, err := c.LoadUpdate(); err != routing.ErrReset {
+		t.Fatal("failed to signal the change of the included file", err)
+	}
+
+	routes, err = c.Reset()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := backends(routes); b["common"] != "https://common2.example.org" {
+		t.Error("failed to reload the included routes", b)
+	}
+}
+
+func TestWatchKeepsRoutesOnInvalidUpdate(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"routes.eskip": `route1: Path("/foo") -> "https://foo.example.org"`})
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "routes.eskip")
+	c := Watch(path)
+	if _, err := c.LoadAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	updateFile(t, path, `route1: Path("/foo") -> `)
+	if _, _, err := c.LoadUpdate(); err != routing.ErrReset {
+		t.Fatal("failed to signal the change", err)
+	}
+
+	routes, err := c.Reset()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := backends(routes); len(b) != 1 || b["route1"] != "https://foo.example.org" {
+		t.Error("failed to keep the previous routes", b)
+	}
+
+	if invalid := c.InvalidRoutes(); len(invalid) != 1 || invalid[0].Id != path || invalid[0].Err == nil {
+		t.Error("failed to report the error", invalid)
+	}
+
+	updateFile(t, path, `route1: Path("/foo") -> "https://bar.example.org"`)
+	if _, _, err := c.LoadUpdate(); err != routing.ErrReset {
+		t.Fatal("failed to signal the change", err)
+	}
+
+	if invalid := c.InvalidRoutes(); len(invalid) != 0 {
+		t.Error("failed to clear the error", invalid)
+	}
+}
+
+func TestWatchWithRouting(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"routes.eskip": `route1: Path("/foo") -> "https://foo.example.org"`})
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "routes.eskip")
+	l := loggingtest.New()
+	defer l.Close()
+
+	rt := routing.New(routing.Options{
+		FilterRegistry: builtin.MakeRegistry(),
+		DataClients:    []routing.DataClient{Watch(path)},
+		PollTimeout:    watchPollTimeout,
+		Log:            l})
+	defer rt.Close()
+
+	if err := l.WaitFor("route settings applied", 12*watchPollTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	checkBackend := func(reqPath, backend string) {
+		req, err := http.NewRequest("GET", "https://www.example.org"+reqPath, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, _ := rt.Route(req)
+		if r == nil || r.Backend != backend {
+			t.Error("failed to route", reqPath, backend, r)
+		}
+	}
+
+	checkBackend("/foo", "https://foo.example.org")
+
+	l.Reset()
+	updateFile(t, path, `route1: Path("/bar") -> "https://bar.example.org"`)
+	if err := l.WaitFor("route settings applied", 12*watchPollTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	checkBackend("/bar", "https://bar.example.org")
+
+	l.Reset()
+	updateFile(t, path, `route1: Path("/baz") -> `)
+	if err := l.WaitFor("route settings applied", 12*watchPollTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	checkBackend("/bar", "https://bar.example.org")
+	if invalid := rt.InvalidRoutes(); len(invalid) != 1 || invalid[0].Id != path {
+		t.Error("failed to report the invalid file", invalid)
+	}
+}
@@ -0,0 +1,38 @@
+package filters
+
+import "github.com/zalando/skipper/internal/args"
+
+// Args wraps the arguments of a filter, and provides typed access to
+// them. The returned errors wrap ErrInvalidFilterParameters, and
+// contain the name of the filter and the index of the invalid
+// argument. The predicates use the same type, as predicates.Args.
+//
+// Example, for a filter with a required duration and an optional count
+// between 1 and 10, defaulting to 3:
+//
+// 	a := filters.NewArgs(Name, args)
+// 	if err := a.Count(1, 2); err != nil {
+// 		return nil, err
+// 	}
+//
+// 	window, err := a.Duration(0)
+// 	if err != nil {
+// 		return nil, err
+// 	}
+//
+// 	count, err := a.OptionalInt(1, 3)
+// 	if err != nil {
+// 		return nil, err
+// 	}
+//
+// 	if err := a.InRange(1, float64(count), 1, 10); err != nil {
+// 		return nil, err
+// 	}
+//
+type Args = args.Args
+
+// NewArgs creates an Args object for the arguments of the filter with
+// the given name.
+func NewArgs(name string, a []interface{}) Args {
+	return args.New(ErrInvalidFilterParameters, name, a)
+}
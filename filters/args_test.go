@@ -0,0 +1,129 @@
+package filters
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testArgs struct {
+	window time.Duration
+	count  int
+	ratio  float64
+	label  string
+}
+
+func bindTestArgs(args []interface{}) (testArgs, error) {
+	a := NewArgs("testFilter", args)
+	if err := a.Count(1, 4); err != nil {
+		return testArgs{}, err
+	}
+
+	var (
+		ta  testArgs
+		err error
+	)
+
+	if ta.window, err = a.Duration(0); err != nil {
+		return testArgs{}, err
+	}
+
+	if ta.count, err = a.OptionalInt(1, 3); err != nil {
+		return testArgs{}, err
+	}
+
+	if err := a.InRange(1, float64(ta.count), 1, 10); err != nil {
+		return testArgs{}, err
+	}
+
+	if ta.ratio, err = a.OptionalFloat(2, 0.5); err != nil {
+		return testArgs{}, err
+	}
+
+	if err := a.InRange(2, ta.ratio, 0, 1); err != nil {
+		return testArgs{}, err
+	}
+
+	if ta.label, err = a.OptionalString(3, "default"); err != nil {
+		return testArgs{}, err
+	}
+
+	return ta, nil
+}
+
+func TestArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg      string
+		args     []interface{}
+		expected testArgs
+		err      string
+	}{{
+		msg:      "defaults",
+		args:     []interface{}{"1m"},
+		expected: testArgs{time.Minute, 3, 0.5, "default"},
+	}, {
+		msg:      "partial defaults",
+		args:     []interface{}{"1m", 7.0},
+		expected: testArgs{time.Minute, 7, 0.5, "default"},
+	}, {
+		msg:      "all set",
+		args:     []interface{}{"10s", 1.0, 0.25, "custom"},
+		expected: testArgs{10 * time.Second, 1, 0.25, "custom"},
+	}, {
+		msg:      "created in code",
+		args:     []interface{}{10 * time.Second, 1, 1},
+		expected: testArgs{10 * time.Second, 1, 1, "default"},
+	}, {
+		msg: "missing required",
+		err: "testFilter: expected 1 to 4 arguments, got 0",
+	}, {
+		msg:  "invalid duration",
+		args: []interface{}{"a minute"},
+		err:  `testFilter: argument 0: invalid duration: "a minute"`,
+	}, {
+		msg:  "duration not a string",
+		args: []interface{}{60.0},
+		err:  "testFilter: argument 0: expected duration string, got float64",
+	}, {
+		msg:  "fraction as integer",
+		args: []interface{}{"1m", 3.14},
+		err:  "testFilter: argument 1: expected integer, got 3.14",
+	}, {
+		msg:  "string as integer",
+		args: []interface{}{"1m", "3"},
+		err:  "testFilter: argument 1: expected integer, got string",
+	}, {
+		msg:  "integer out of range",
+		args: []interface{}{"1m", 11.0},
+		err:  "testFilter: argument 1: expected value between 1 and 10, got 11",
+	}, {
+		msg:  "float out of range",
+		args: []interface{}{"1m", 3.0, 1.5},
+		err:  "testFilter: argument 2: expected value between 0 and 1, got 1.5",
+	}, {
+		msg:  "extra args",
+		args: []interface{}{"1m", 3.0, 0.5, "label", "extra"},
+		err:  "testFilter: expected 1 to 4 arguments, got 5",
+	}} {
+		ta, err := bindTestArgs(ti.args)
+		if ti.err != "" {
+			if err == nil ||
+				!errors.Is(err, ErrInvalidFilterParameters) ||
+				!strings.HasSuffix(err.Error(), ti.err) {
+				t.Error(ti.msg, "invalid error", err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		if ta != ti.expected {
+			t.Error(ti.msg, "invalid values", ta, ti.expected)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package builtin
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/zalando/skipper/filters"
+)
+
+const backendTimeoutStateKey = "timeout"
+
+type backendTimeout time.Duration
+
+// the context of the request, cancelled by the filter on timeout. When
+// cancelled by the filter, it reports context.DeadlineExceeded.
+type backendTimeoutContext struct {
+	context.Context
+	timedOut int32
+}
+
+// the state of the filter during a request
+type backendTimeoutState struct {
+	parent context.Context
+	cancel context.CancelFunc
+	timer  *time.Timer
+}
+
+// Returns a filter specification whose instances cancel the request to
+// the backend, when the backend doesn't respond within the configured
+// timeout. The timeout is set as a duration string, e.g. "2s" or
+// "150ms".
+//
+// Example:
+//
+// 	* -> backendTimeout("2s") -> "https://www.example.org"
+//
+// The timeout starts in the request phase of the filter, and it applies
+// until the response headers are received from the backend, the
+// streaming of the response body is not affected. When the request is
+// cancelled, the proxy responds with 504 Gateway Timeout.
+//
+// The request is cancelled through its context, which the proxy passes
+// on to the request sent to the backend. When multiple backendTimeout
+// filters are set on a route, the last one takes effect.
+//
+func NewBackendTimeout() filters.Spec { return backendTimeout(0) }
+
+func (t backendTimeout) Name() string { return BackendTimeoutName }
+
+func (t backendTimeout) CreateFilter(args []interface{}) (filters.Filter, error) {
+	a := filters.NewArgs(BackendTimeoutName, args)
+	if err := a.Count(1, 1); err != nil {
+		return nil, err
+	}
+
+	d, err := a.Duration(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if d <= 0 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	return backendTimeout(d), nil
+}
+
+func (c *backendTimeoutContext) Err() error {
+	if atomic.LoadInt32(&c.timedOut) == 1 {
+		return context.DeadlineExceeded
+	}
+
+	return c.Context.Err()
+}
+
+func (t backendTimeout) Request(ctx filters.FilterContext) {
+	req := ctx.Request()
+	parent := req.Context()
+
+	// a preceding backendTimeout filter is overridden
+	v, _ := filters.StateBagGet(ctx, BackendTimeoutName, backendTimeoutStateKey)
+	if prev, _ := v.(*backendTimeoutState); prev != nil {
+		prev.timer.Stop()
+		prev.cancel()
+		parent = prev.parent
+	}
+
+	cctx, cancel := context.WithCancel(parent)
+	tctx := &backendTimeoutContext{Context: cctx}
+	timer := time.AfterFunc(time.Duration(t), func() {
+		atomic.StoreInt32(&tctx.timedOut, 1)
+		cancel()
+	})
+
+	*req = *req.WithContext(tctx)
+	filters.StateBagSet(ctx, BackendTimeoutName, backendTimeoutStateKey, &backendTimeoutState{
+		parent: parent,
+		cancel: cancel,
+		timer:  timer})
+}
+
+// stops the timer, when the response was received in time. The context
+// is not cancelled, because the response body is still streamed.
+func (t backendTimeout) Response(ctx filters.FilterContext) {
+	v, _ := filters.StateBagGet(ctx, BackendTimeoutName, backendTimeoutStateKey)
+	if st, _ := v.(*backendTimeoutState); st != nil {
+		st.timer.Stop()
+		filters.StateBagSet(ctx, BackendTimeoutName, backendTimeoutStateKey, nil)
+	}
+}
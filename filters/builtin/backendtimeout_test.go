@@ -0,0 +1,129 @@
+package builtin
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+	"github.com/zalando/skipper/proxy/proxytest"
+)
+
+func TestBackendTimeoutArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg   string
+		args  []interface{}
+		valid bool
+	}{
+		{"no args", nil, false},
+		{"too many args", []interface{}{"1s", "2s"}, false},
+		{"not a string", []interface{}{1.0}, false},
+		{"invalid duration", []interface{}{"one second"}, false},
+		{"zero duration", []interface{}{"0s"}, false},
+		{"valid", []interface{}{"150ms"}, true},
+	} {
+		_, err := NewBackendTimeout().CreateFilter(ti.args)
+		if ti.valid && err != nil || !ti.valid && err == nil {
+			t.Error(ti.msg, "failed to validate args", err)
+		}
+	}
+}
+
+func TestBackendTimeoutCancel(t *testing.T) {
+	newFilter := func(timeout string) filters.Filter {
+		f, err := NewBackendTimeout().CreateFilter([]interface{}{timeout})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return f
+	}
+
+	newContext := func() *filtertest.Context {
+		return &filtertest.Context{
+			FRequest:  &http.Request{},
+			FStateBag: make(map[string]interface{})}
+	}
+
+	f := newFilter("10ms")
+	ctx := newContext()
+	f.Request(ctx)
+
+	select {
+	case <-ctx.FRequest.Context().Done():
+		if err := ctx.FRequest.Context().Err(); err != context.DeadlineExceeded {
+			t.Error("invalid context error", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("failed to cancel request")
+	}
+
+	ctx = newContext()
+	f.Request(ctx)
+	f.Response(ctx)
+
+	select {
+	case <-ctx.FRequest.Context().Done():
+		t.Error("unexpected cancel after the response")
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	ctx = newContext()
+	f.Request(ctx)
+	newFilter("1h").Request(ctx)
+
+	select {
+	case <-ctx.FRequest.Context().Done():
+		t.Error("failed to override the preceding timeout")
+	case <-time.After(30 * time.Millisecond):
+	}
+}
+
+func TestBackendTimeoutProxy(t *testing.T) {
+	for _, ti := range []struct {
+		msg    string
+		delay  time.Duration
+		status int
+	}{
+		{"fast backend", 0, http.StatusOK},
+		{"slow backend", 300 * time.Millisecond, http.StatusGatewayTimeout},
+	} {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(ti.delay)
+			w.Write([]byte("Hello, world!"))
+		}))
+		defer backend.Close()
+
+		p := proxytest.New(MakeRegistry(), &eskip.Route{
+			Filters: []*eskip.Filter{{Name: BackendTimeoutName, Args: []interface{}{"60ms"}}},
+			Backend: backend.URL})
+		defer p.Close()
+
+		rsp, err := http.Get(p.URL)
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		b, err := ioutil.ReadAll(rsp.Body)
+		rsp.Body.Close()
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		if rsp.StatusCode != ti.status {
+			t.Error(ti.msg, "invalid status", rsp.StatusCode)
+			continue
+		}
+
+		if ti.status == http.StatusOK && string(b) != "Hello, world!" {
+			t.Error(ti.msg, "invalid content", string(b))
+		}
+	}
+}
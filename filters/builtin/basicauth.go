@@ -0,0 +1,158 @@
+package builtin
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/zalando/skipper/filters"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type basicAuthCredential struct {
+
+	// the SHA-256 sum of the user name, so that the user names are
+	// compared with a fixed length
+	userHash [sha256.Size]byte
+
+	// either a bcrypt hash, or the SHA-256 sum of the password
+	passwordHash []byte
+	bcrypt       bool
+}
+
+type basicAuth struct {
+	realm       string
+	credentials []basicAuthCredential
+}
+
+// Returns a filter specification whose instances require the requests
+// to carry one of the credentials set in the arguments, with HTTP basic
+// authentication. The first argument is the realm, the rest are the
+// credentials, in the form of "user:hash", where the hash is a bcrypt
+// hash of the password, e.g.:
+//
+// 	* -> basicAuth("admin area", "jdoe:$2a$10$XW/2bBvHkbW7ZcRLYMZHpevvn8hDkUfWyMDuwfPWxdX32pZWDl3Rm") -> "https://admin.example.org";
+//
+// The credentials in this form can be generated with:
+//
+// 	htpasswd -nbB jdoe secret
+//
+// For compatibility, the hash can be also the hex encoded SHA-256 sum of
+// the password, generated with:
+//
+// 	printf '%s' 'secret' | sha256sum
+//
+// The requests without valid credentials are answered with 401
+// Unauthorized and the WWW-Authenticate header, without forwarding them
+// to the backend. The user names are compared in constant time, and the
+// password is checked only against the credential of the matching user.
+//
+func NewBasicAuth() filters.Spec { return &basicAuth{} }
+
+func (a *basicAuth) Name() string { return BasicAuthName }
+
+func parseBasicAuthCredential(s string) (basicAuthCredential, bool) {
+	i := strings.LastIndex(s, ":")
+	if i <= 0 {
+		return basicAuthCredential{}, false
+	}
+
+	c := basicAuthCredential{userHash: sha256.Sum256([]byte(s[:i]))}
+	hash := s[i+1:]
+	if strings.HasPrefix(hash, "$2") {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return basicAuthCredential{}, false
+		}
+
+		c.passwordHash, c.bcrypt = []byte(hash), true
+		return c, true
+	}
+
+	var err error
+	if c.passwordHash, err = hex.DecodeString(hash); err != nil || len(c.passwordHash) != sha256.Size {
+		return basicAuthCredential{}, false
+	}
+
+	return c, true
+}
+
+func (a *basicAuth) CreateFilter(args []interface{}) (filters.Filter, error) {
+	fa := filters.NewArgs(BasicAuthName, args)
+	if err := fa.Count(2, -1); err != nil {
+		return nil, err
+	}
+
+	realm, err := fa.String(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if realm == "" || strings.ContainsAny(realm, "\"\\") {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	f := &basicAuth{realm: realm}
+	for i := 1; i < fa.Len(); i++ {
+		s, err := fa.String(i)
+		if err != nil {
+			return nil, err
+		}
+
+		c, ok := parseBasicAuthCredential(s)
+		if !ok {
+			return nil, filters.ErrInvalidFilterParameters
+		}
+
+		f.credentials = append(f.credentials, c)
+	}
+
+	return f, nil
+}
+
+func (c *basicAuthCredential) checkPassword(password string) bool {
+	if c.bcrypt {
+		return bcrypt.CompareHashAndPassword(c.passwordHash, []byte(password)) == nil
+	}
+
+	h := sha256.Sum256([]byte(password))
+	return subtle.ConstantTimeCompare(c.passwordHash, h[:]) == 1
+}
+
+// finds the credential of the user, comparing the hash of the user
+// name with all the configured ones, so that the time taken doesn't
+// depend on the user name. When the user is not found, the password is
+// still checked against the first credential, so that the time taken
+// doesn't tell whether the user exists.
+func (a *basicAuth) valid(user, password string) bool {
+	h := sha256.Sum256([]byte(user))
+
+	match := -1
+	for i := range a.credentials {
+		if subtle.ConstantTimeCompare(a.credentials[i].userHash[:], h[:]) == 1 {
+			match = i
+		}
+	}
+
+	if match < 0 {
+		a.credentials[0].checkPassword(password)
+		return false
+	}
+
+	return a.credentials[match].checkPassword(password)
+}
+
+func (a *basicAuth) Request(ctx filters.FilterContext) {
+	if user, password, ok := ctx.Request().BasicAuth(); ok && a.valid(user, password) {
+		return
+	}
+
+	ctx.Serve(&http.Response{
+		StatusCode: http.StatusUnauthorized,
+		Header: http.Header{
+			"Www-Authenticate": []string{fmt.Sprintf(`Basic realm="%s"`, a.realm)}}})
+}
+
+func (a *basicAuth) Response(filters.FilterContext) {}
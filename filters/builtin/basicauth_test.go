@@ -0,0 +1,154 @@
+package builtin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/proxy/proxytest"
+)
+
+const (
+	// sha256 of "secret"
+	testBasicAuthHash = "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"
+
+	// bcrypt of "secret", with the minimum cost
+	testBasicAuthBcrypt = "$2a$04$5cnuP/ERAahohVp.ER8ruukq0gpst04AqlSpN2QjUOqv99MLvbA0G"
+)
+
+func TestBasicAuthArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg   string
+		args  []interface{}
+		valid bool
+	}{
+		{"no args", nil, false},
+		{"no credentials", []interface{}{"admin"}, false},
+		{"realm not a string", []interface{}{42.0, "jdoe:" + testBasicAuthHash}, false},
+		{"empty realm", []interface{}{"", "jdoe:" + testBasicAuthHash}, false},
+		{"quote in realm", []interface{}{`"admin"`, "jdoe:" + testBasicAuthHash}, false},
+		{"credential not a string", []interface{}{"admin", 42.0}, false},
+		{"missing hash", []interface{}{"admin", "jdoe"}, false},
+		{"missing user", []interface{}{"admin", ":" + testBasicAuthHash}, false},
+		{"not hex", []interface{}{"admin", "jdoe:secret"}, false},
+		{"wrong hash length", []interface{}{"admin", "jdoe:" + testBasicAuthHash[:32]}, false},
+		{"invalid bcrypt hash", []interface{}{"admin", "jdoe:" + testBasicAuthBcrypt[:20]}, false},
+		{"valid", []interface{}{"admin", "jdoe:" + testBasicAuthHash}, true},
+		{"valid, multiple", []interface{}{"admin", "jdoe:" + testBasicAuthHash, "mdoe:" + testBasicAuthHash}, true},
+		{"valid, bcrypt", []interface{}{"admin", "jdoe:" + testBasicAuthBcrypt}, true},
+	} {
+		_, err := NewBasicAuth().CreateFilter(ti.args)
+		if ti.valid && err != nil || !ti.valid && err == nil {
+			t.Error(ti.msg, "failed to validate args", err)
+		}
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	for _, ti := range []struct {
+		msg         string
+		user        string
+		password    string
+		header      string
+		backendCall bool
+		status      int
+	}{{
+		msg:         "valid credentials",
+		user:        "jdoe",
+		password:    "secret",
+		backendCall: true,
+		status:      http.StatusOK,
+	}, {
+		msg:         "valid credentials of another user",
+		user:        "mdoe",
+		password:    "secret",
+		backendCall: true,
+		status:      http.StatusOK,
+	}, {
+		msg:         "valid credentials with bcrypt",
+		user:        "bdoe",
+		password:    "secret",
+		backendCall: true,
+		status:      http.StatusOK,
+	}, {
+		msg:      "invalid password",
+		user:     "jdoe",
+		password: "guess",
+		status:   http.StatusUnauthorized,
+	}, {
+		msg:      "invalid password with bcrypt",
+		user:     "bdoe",
+		password: "guess",
+		status:   http.StatusUnauthorized,
+	}, {
+		msg:      "unknown user",
+		user:     "root",
+		password: "secret",
+		status:   http.StatusUnauthorized,
+	}, {
+		msg:    "missing header",
+		status: http.StatusUnauthorized,
+	}, {
+		msg:    "not basic auth",
+		header: "Bearer foo",
+		status: http.StatusUnauthorized,
+	}} {
+		var backendCalls int32
+		backend := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			atomic.AddInt32(&backendCalls, 1)
+		}))
+
+		p := proxytest.New(MakeRegistry(), &eskip.Route{
+			Filters: []*eskip.Filter{{
+				Name: BasicAuthName,
+				Args: []interface{}{
+					"admin area",
+					"jdoe:" + testBasicAuthHash,
+					"mdoe:" + testBasicAuthHash,
+					"bdoe:" + testBasicAuthBcrypt}}},
+			Backend: backend.URL})
+
+		func() {
+			defer backend.Close()
+			defer p.Close()
+
+			req, err := http.NewRequest("GET", p.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ti.user != "" {
+				req.SetBasicAuth(ti.user, ti.password)
+			}
+
+			if ti.header != "" {
+				req.Header.Set("Authorization", ti.header)
+			}
+
+			rsp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Error(ti.msg, err)
+				return
+			}
+
+			defer rsp.Body.Close()
+
+			if rsp.StatusCode != ti.status {
+				t.Error(ti.msg, "invalid status", rsp.StatusCode, ti.status)
+			}
+
+			if called := atomic.LoadInt32(&backendCalls) > 0; called != ti.backendCall {
+				t.Error(ti.msg, "invalid backend call", called, ti.backendCall)
+			}
+
+			challenge := rsp.Header.Get("WWW-Authenticate")
+			if ti.status == http.StatusUnauthorized && challenge != `Basic realm="admin area"` {
+				t.Error(ti.msg, "invalid challenge", challenge)
+			} else if ti.status != http.StatusUnauthorized && challenge != "" {
+				t.Error(ti.msg, "unexpected challenge", challenge)
+			}
+		}()
+	}
+}
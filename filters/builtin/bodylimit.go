@@ -0,0 +1,158 @@
+package builtin
+
+import (
+	"errors"
+	"io"
+	"math"
+	"net/http"
+
+	"github.com/zalando/skipper/filters"
+)
+
+type bodyLimitType int
+
+const (
+	requestBodyLimit bodyLimitType = iota
+	responseBodyLimit
+)
+
+// ErrBodyTooLarge is returned when reading a request or response body
+// that exceeds the limit set by the maxRequestBodyBytes or the
+// maxResponseBodyBytes filters.
+var ErrBodyTooLarge = errors.New("body too large")
+
+type bodyLimit struct {
+	typ   bodyLimitType
+	limit int64
+}
+
+// wraps a body, and fails reading it when the limit is exceeded. It
+// doesn't buffer the content.
+type limitedBody struct {
+	body     io.ReadCloser
+	left     int64
+	status   int
+	exceeded bool
+}
+
+// Returns a filter specification whose instances reject requests with
+// a body larger than the configured limit in bytes, responding with
+// 413 Request Entity Too Large.
+//
+// Example:
+//
+// 	* -> maxRequestBodyBytes(1048576) -> "https://www.example.org"
+//
+// When the request declares its Content-Length, the filter rejects it
+// without reading the body. Otherwise, e.g. for chunked uploads, the
+// body is streamed to the backend unbuffered, and when the limit is
+// exceeded, the request to the backend is aborted, and the proxy
+// responds with 413.
+//
+func NewMaxRequestBodyBytes() filters.Spec { return &bodyLimit{typ: requestBodyLimit} }
+
+// Returns a filter specification whose instances prevent responses with
+// a body larger than the configured limit in bytes from being returned
+// to the client.
+//
+// Example:
+//
+// 	* -> maxResponseBodyBytes(1048576) -> "https://www.example.org"
+//
+// When the backend declares the Content-Length of the response, and it
+// exceeds the limit, the filter replaces the response with 502 Bad
+// Gateway. Otherwise the response body is streamed to the client, and
+// when the limit is exceeded, the response is terminated early.
+//
+func NewMaxResponseBodyBytes() filters.Spec { return &bodyLimit{typ: responseBodyLimit} }
+
+func (b *bodyLimit) Name() string {
+	if b.typ == requestBodyLimit {
+		return MaxRequestBodyBytesName
+	}
+
+	return MaxResponseBodyBytesName
+}
+
+func (b *bodyLimit) CreateFilter(args []interface{}) (filters.Filter, error) {
+	a := filters.NewArgs(b.Name(), args)
+	if err := a.Count(1, 1); err != nil {
+		return nil, err
+	}
+
+	limit, err := a.Int(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.InRange(0, float64(limit), 0, math.MaxInt64); err != nil {
+		return nil, err
+	}
+
+	return &bodyLimit{typ: b.typ, limit: int64(limit)}, nil
+}
+
+func (b *bodyLimit) Request(ctx filters.FilterContext) {
+	if b.typ != requestBodyLimit {
+		return
+	}
+
+	r := ctx.Request()
+	if r.ContentLength > b.limit {
+		ctx.Serve(&http.Response{StatusCode: http.StatusRequestEntityTooLarge})
+		return
+	}
+
+	// with a known Content-Length, the body cannot be longer
+	if r.ContentLength < 0 && r.Body != nil {
+		r.Body = &limitedBody{body: r.Body, left: b.limit, status: http.StatusRequestEntityTooLarge}
+	}
+}
+
+func (b *bodyLimit) Response(ctx filters.FilterContext) {
+	if b.typ != responseBodyLimit {
+		return
+	}
+
+	rsp := ctx.Response()
+	if rsp.ContentLength > b.limit {
+		if rsp.Body != nil {
+			rsp.Body.Close()
+		}
+
+		ctx.Serve(&http.Response{StatusCode: http.StatusBadGateway})
+		return
+	}
+
+	if rsp.ContentLength < 0 && rsp.Body != nil {
+		rsp.Body = &limitedBody{body: rsp.Body, left: b.limit, status: http.StatusBadGateway}
+	}
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.exceeded {
+		return 0, ErrBodyTooLarge
+	}
+
+	// reading one more byte than the limit, to detect when it's exceeded
+	if int64(len(p)) > l.left+1 {
+		p = p[:l.left+1]
+	}
+
+	n, err := l.body.Read(p)
+	if int64(n) > l.left {
+		l.exceeded = true
+		n = int(l.left)
+		l.left = 0
+		return n, ErrBodyTooLarge
+	}
+
+	l.left -= int64(n)
+	return n, err
+}
+
+func (l *limitedBody) Close() error { return l.body.Close() }
+
+// Status tells the status code that the proxy should respond with, when
+// forwarding the body failed because it exceeded the limit.
+func (l *limitedBody) Status() (int, bool) { return l.status, l.exceeded }
@@ -0,0 +1,195 @@
+package builtin
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/proxy/proxytest"
+)
+
+// hides the length of the body from the http client, to make it send
+// the request chunked
+type unknownLength struct{ r *bytes.Reader }
+
+func (u unknownLength) Read(p []byte) (int, error) { return u.r.Read(p) }
+
+func TestBodyLimitArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg   string
+		args  []interface{}
+		valid bool
+	}{
+		{"no args", nil, false},
+		{"too many args", []interface{}{1024.0, 2048.0}, false},
+		{"not a number", []interface{}{"1024"}, false},
+		{"fraction", []interface{}{1.5}, false},
+		{"negative", []interface{}{-1.0}, false},
+		{"valid", []interface{}{1024.0}, true},
+	} {
+		_, err := NewMaxRequestBodyBytes().CreateFilter(ti.args)
+		if ti.valid && err != nil || !ti.valid && err == nil {
+			t.Error(ti.msg, "failed to validate request limit args", err)
+		}
+
+		_, err = NewMaxResponseBodyBytes().CreateFilter(ti.args)
+		if ti.valid && err != nil || !ti.valid && err == nil {
+			t.Error(ti.msg, "failed to validate response limit args", err)
+		}
+	}
+}
+
+func TestMaxRequestBodyBytes(t *testing.T) {
+	for _, ti := range []struct {
+		msg           string
+		size          int
+		chunked       bool
+		status        int
+		backendCalled bool
+	}{
+		{"content length under limit", 1000, false, http.StatusOK, true},
+		{"content length at limit", 1024, false, http.StatusOK, true},
+		{"content length over limit", 1025, false, http.StatusRequestEntityTooLarge, false},
+		{"chunked under limit", 1000, true, http.StatusOK, true},
+		{"chunked at limit", 1024, true, http.StatusOK, true},
+		{"chunked over limit", 1 << 20, true, http.StatusRequestEntityTooLarge, true},
+	} {
+		var (
+			backendCalled bool
+			received      int
+			contentLength int64
+		)
+
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			backendCalled = true
+			contentLength = r.ContentLength
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				return
+			}
+
+			received = len(b)
+		}))
+
+		p := proxytest.New(MakeRegistry(), &eskip.Route{
+			Filters: []*eskip.Filter{{Name: MaxRequestBodyBytesName, Args: []interface{}{1024.0}}},
+			Backend: backend.URL})
+
+		func() {
+			defer backend.Close()
+			defer p.Close()
+
+			body := bytes.NewReader(make([]byte, ti.size))
+			req, err := http.NewRequest("POST", p.URL, body)
+			if err != nil {
+				t.Error(ti.msg, err)
+				return
+			}
+
+			if ti.chunked {
+				req.Body = ioutil.NopCloser(unknownLength{body})
+				req.ContentLength = 0
+			}
+
+			rsp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Error(ti.msg, err)
+				return
+			}
+
+			rsp.Body.Close()
+			if rsp.StatusCode != ti.status {
+				t.Error(ti.msg, "invalid status", rsp.StatusCode)
+				return
+			}
+
+			if !ti.backendCalled && backendCalled {
+				t.Error(ti.msg, "unexpected call to the backend")
+				return
+			}
+
+			if ti.status != http.StatusOK {
+				return
+			}
+
+			if received != ti.size {
+				t.Error(ti.msg, "invalid body size at the backend", received)
+			}
+
+			if !ti.chunked && contentLength >= 0 && contentLength != int64(ti.size) {
+				t.Error(ti.msg, "invalid content length at the backend", contentLength)
+			}
+		}()
+	}
+}
+
+func TestMaxResponseBodyBytes(t *testing.T) {
+	for _, ti := range []struct {
+		msg       string
+		size      int
+		chunked   bool
+		status    int
+		truncated bool
+	}{
+		{"content length under limit", 1000, false, http.StatusOK, false},
+		{"content length at limit", 1024, false, http.StatusOK, false},
+		{"content length over limit", 1025, false, http.StatusBadGateway, false},
+		{"chunked under limit", 1000, true, http.StatusOK, false},
+		{"chunked over limit", 1 << 20, true, http.StatusOK, true},
+	} {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !ti.chunked {
+				w.Header().Set("Content-Length", strconv.Itoa(ti.size))
+			}
+
+			w.Write(make([]byte, ti.size))
+		}))
+
+		p := proxytest.New(MakeRegistry(), &eskip.Route{
+			Filters: []*eskip.Filter{{Name: MaxResponseBodyBytesName, Args: []interface{}{1024.0}}},
+			Backend: backend.URL})
+
+		func() {
+			defer backend.Close()
+			defer p.Close()
+
+			rsp, err := http.Get(p.URL)
+			if err != nil {
+				t.Error(ti.msg, err)
+				return
+			}
+
+			defer rsp.Body.Close()
+			if rsp.StatusCode != ti.status {
+				t.Error(ti.msg, "invalid status", rsp.StatusCode)
+				return
+			}
+
+			if ti.status != http.StatusOK {
+				return
+			}
+
+			b, err := ioutil.ReadAll(rsp.Body)
+			if ti.truncated {
+				if err == nil && len(b) > 1024 {
+					t.Error(ti.msg, "failed to limit the response body", len(b))
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Error(ti.msg, err)
+				return
+			}
+
+			if len(b) != ti.size {
+				t.Error(ti.msg, "invalid body size", len(b))
+			}
+		}()
+	}
+}
@@ -26,14 +26,28 @@ const (
 	DropRequestHeaderName    = "dropRequestHeader"
 	DropResponseHeaderName   = "dropResponseHeader"
 
-	HealthCheckName  = "healthcheck"
-	ModPathName      = "modPath"
-	RedirectToName   = "redirectTo"
-	StaticName       = "static"
-	StripQueryName   = "stripQuery"
-	PreserveHostName = "preserveHost"
-	StatusName       = "status"
-	CompressName     = "compress"
+	HealthCheckName    = "healthcheck"
+	ModPathName        = "modPath"
+	RedirectToName     = "redirectTo"
+	StaticName         = "static"
+	StripQueryName     = "stripQuery"
+	PreserveHostName   = "preserveHost"
+	StatusName         = "status"
+	CompressName       = "compress"
+	TeeName            = "teeRequest"
+	BackendTimeoutName = "backendTimeout"
+
+	MaxRequestBodyBytesName  = "maxRequestBodyBytes"
+	MaxResponseBodyBytesName = "maxResponseBodyBytes"
+	RequestIdName            = "requestId"
+	RetryName                = "retry"
+	ConcurrencyLimitName     = "concurrencyLimit"
+	RespondName              = "respond"
+	SetHostName              = "setHost"
+	TrafficSampleName        = "trafficSample"
+	CorsOriginName           = "corsOrigin"
+	CircuitBreakerName       = "circuitBreaker"
+	BasicAuthName            = "basicAuth"
 )
 
 // Returns a Registry object initialized with the default set of filter
@@ -60,6 +74,19 @@ func MakeRegistry() filters.Registry {
 		PreserveHost(),
 		NewStatus(),
 		NewCompress(),
+		NewTee(),
+		NewBackendTimeout(),
+		NewMaxRequestBodyBytes(),
+		NewMaxResponseBodyBytes(),
+		NewRequestId(),
+		NewRetry(),
+		NewConcurrencyLimit(),
+		NewRespond(),
+		NewSetHost(),
+		NewTrafficSample(),
+		NewCorsOrigin(),
+		NewCircuitBreaker(),
+		NewBasicAuth(),
 		diag.NewRandom(),
 		diag.NewLatency(),
 		diag.NewBandwidth(),
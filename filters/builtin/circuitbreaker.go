@@ -0,0 +1,295 @@
+package builtin
+
+import (
+	"math"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/zalando/skipper/filters"
+)
+
+const (
+	// DefaultCircuitBreakerFailures is the number of failures opening
+	// the circuit breaker, when not set in the arguments.
+	DefaultCircuitBreakerFailures = 5
+
+	// DefaultCircuitBreakerWindow is the window counting the failures,
+	// when not set in the arguments.
+	DefaultCircuitBreakerWindow = 10 * time.Second
+
+	// DefaultCircuitBreakerHalfOpenAfter is the time after which an
+	// open circuit breaker lets a probe request through, when not set
+	// in the arguments.
+	DefaultCircuitBreakerHalfOpenAfter = 30 * time.Second
+
+	circuitBreakerStateKey = "breaker"
+
+	// the interval of removing the idle circuit breakers
+	circuitBreakerSweepInterval = time.Minute
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+type breakerSettings struct {
+	failures      int
+	window        time.Duration
+	halfOpenAfter time.Duration
+}
+
+// the state of the circuit breaker of a backend host
+type breaker struct {
+	now func() time.Time
+
+	// the last use of the breaker, and the longest window or half
+	// open duration of the routes using it, guarded by the mutex of
+	// the spec
+	lastUsed    time.Time
+	idleTimeout time.Duration
+
+	mx       sync.Mutex
+	state    breakerState
+	failures []time.Time
+	openedAt time.Time
+	probing  bool
+}
+
+type circuitBreakerSpec struct {
+	now func() time.Time
+
+	mx        sync.Mutex
+	breakers  map[string]*breaker
+	lastSweep time.Time
+}
+
+type circuitBreaker struct {
+	spec     *circuitBreakerSpec
+	settings breakerSettings
+}
+
+// Returns a filter specification whose instances protect the backends
+// of the routes with a circuit breaker. After the number of failures
+// set in the first argument, counted within the window set in the
+// second argument, the circuit breaker opens, and the requests fail
+// fast with 503 Service Unavailable, without being forwarded to the
+// backend. After the duration set in the third argument, a single
+// probe request is forwarded. When it succeeds, the circuit breaker
+// closes, otherwise it stays open for the same duration again.
+//
+// All the arguments are optional, the defaults are 5 failures, "10s"
+// window and "30s" before the probe:
+//
+// 	* -> circuitBreaker() -> "https://www.example.org";
+// 	* -> circuitBreaker(3, "1m", "15s") -> "https://www.example.org";
+//
+// The failures are the requests to the backend failing with an error,
+// and the responses with a 5xx status code.
+//
+// The circuit breakers are kept by the backend host, and they are
+// shared by the routes with the same backend host, e.g. when one of
+// them opens the circuit breaker, the requests of the others fail
+// fast, too. The failures and the durations are counted by the
+// arguments of the route handling the request. The circuit breakers
+// keep their state when the routes are updated, and they are removed
+// when they were not used for longer than the longest window and half
+// open duration of the routes using them.
+// Since the failed requests are detected only when the request was
+// handled, the filter should be the last one in the filter chain of
+// the route, so that the other filters don't change the outcome.
+//
+func NewCircuitBreaker() filters.Spec {
+	return &circuitBreakerSpec{
+		now:      time.Now,
+		breakers: make(map[string]*breaker)}
+}
+
+func (s *circuitBreakerSpec) Name() string { return CircuitBreakerName }
+
+func (s *circuitBreakerSpec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	a := filters.NewArgs(CircuitBreakerName, args)
+	if err := a.Count(0, 3); err != nil {
+		return nil, err
+	}
+
+	var (
+		bs  breakerSettings
+		err error
+	)
+
+	if bs.failures, err = a.OptionalInt(0, DefaultCircuitBreakerFailures); err != nil {
+		return nil, err
+	}
+
+	if err := a.InRange(0, float64(bs.failures), 1, math.MaxInt32); err != nil {
+		return nil, err
+	}
+
+	if bs.window, err = a.OptionalDuration(1, DefaultCircuitBreakerWindow); err != nil {
+		return nil, err
+	}
+
+	if bs.halfOpenAfter, err = a.OptionalDuration(2, DefaultCircuitBreakerHalfOpenAfter); err != nil {
+		return nil, err
+	}
+
+	if bs.window <= 0 || bs.halfOpenAfter <= 0 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	return &circuitBreaker{spec: s, settings: bs}, nil
+}
+
+// removes the circuit breakers that were not used for longer than
+// the longest window and half open duration of the routes using them.
+// Their state doesn't matter anymore: the counted failures are out of
+// the window, and an open circuit breaker would let the next request
+// through.
+func (s *circuitBreakerSpec) sweep(now time.Time) {
+	s.lastSweep = now
+	for host, b := range s.breakers {
+		if now.Sub(b.lastUsed) > b.idleTimeout {
+			delete(s.breakers, host)
+		}
+	}
+}
+
+// returns the circuit breaker of a backend host
+func (s *circuitBreakerSpec) get(host string, bs breakerSettings) *breaker {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= circuitBreakerSweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.breakers[host]
+	if !ok {
+		b = &breaker{now: s.now}
+		s.breakers[host] = b
+	}
+
+	b.lastUsed = now
+	if bs.window > b.idleTimeout {
+		b.idleTimeout = bs.window
+	}
+
+	if bs.halfOpenAfter > b.idleTimeout {
+		b.idleTimeout = bs.halfOpenAfter
+	}
+
+	return b
+}
+
+// tells whether a request can be forwarded to the backend
+func (b *breaker) allow(bs breakerSettings) bool {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < bs.halfOpenAfter {
+			return false
+		}
+
+		b.state = breakerHalfOpen
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// records the outcome of a forwarded request
+func (b *breaker) done(bs breakerSettings, failed bool) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	now := b.now()
+	switch b.state {
+	case breakerHalfOpen:
+		b.probing = false
+		if failed {
+			b.state = breakerOpen
+			b.openedAt = now
+			return
+		}
+
+		b.state = breakerClosed
+		b.failures = nil
+	case breakerClosed:
+		if !failed {
+			return
+		}
+
+		from := now.Add(-bs.window)
+		i := 0
+		for i < len(b.failures) && !b.failures[i].After(from) {
+			i++
+		}
+
+		b.failures = append(b.failures[i:], now)
+		if len(b.failures) >= bs.failures {
+			b.state = breakerOpen
+			b.openedAt = now
+			b.failures = nil
+		}
+	}
+}
+
+func (cb *circuitBreaker) Request(ctx filters.FilterContext) {
+	u, err := url.Parse(ctx.BackendUrl())
+	if err != nil || u.Host == "" {
+		return
+	}
+
+	b := cb.spec.get(u.Host, cb.settings)
+	if !b.allow(cb.settings) {
+		ctx.Serve(&http.Response{StatusCode: http.StatusServiceUnavailable})
+		return
+	}
+
+	filters.StateBagSet(ctx, CircuitBreakerName, circuitBreakerStateKey, b)
+}
+
+// returns the circuit breaker waiting for the outcome of the request,
+// and removes it from the state bag, so that the outcome is recorded
+// only once
+func takeBreaker(ctx filters.FilterContext) *breaker {
+	v, _ := filters.StateBagGet(ctx, CircuitBreakerName, circuitBreakerStateKey)
+	b, _ := v.(*breaker)
+	if b != nil {
+		filters.StateBagSet(ctx, CircuitBreakerName, circuitBreakerStateKey, nil)
+	}
+
+	return b
+}
+
+func (cb *circuitBreaker) Response(ctx filters.FilterContext) {
+	if b := takeBreaker(ctx); b != nil {
+		b.done(cb.settings, ctx.Response().StatusCode >= http.StatusInternalServerError)
+	}
+}
+
+// Cleanup is called by the proxy when the request was handled. When the
+// response filters were skipped, because the request to the backend
+// failed, it records the failure.
+func (cb *circuitBreaker) Cleanup(ctx filters.FilterContext) {
+	if b := takeBreaker(ctx); b != nil {
+		b.done(cb.settings, true)
+	}
+}
@@ -0,0 +1,240 @@
+package builtin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+	"github.com/zalando/skipper/proxy/proxytest"
+)
+
+func TestCircuitBreakerArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg   string
+		args  []interface{}
+		valid bool
+	}{
+		{"defaults", nil, true},
+		{"failures only", []interface{}{3.0}, true},
+		{"all set", []interface{}{3.0, "1m", "15s"}, true},
+		{"zero failures", []interface{}{0.0}, false},
+		{"invalid window", []interface{}{3.0, "a minute"}, false},
+		{"zero window", []interface{}{3.0, "0s"}, false},
+		{"negative half open", []interface{}{3.0, "1m", "-15s"}, false},
+		{"too many args", []interface{}{3.0, "1m", "15s", "1s"}, false},
+	} {
+		_, err := NewCircuitBreaker().CreateFilter(ti.args)
+		if ti.valid && err != nil || !ti.valid && err == nil {
+			t.Error(ti.msg, "failed to validate args", err)
+		}
+	}
+}
+
+type breakerTest struct {
+	t     *testing.T
+	clock time.Time
+	spec  *circuitBreakerSpec
+}
+
+func newBreakerTest(t *testing.T) *breakerTest {
+	bt := &breakerTest{t: t, clock: time.Now()}
+	bt.spec = NewCircuitBreaker().(*circuitBreakerSpec)
+	bt.spec.now = func() time.Time { return bt.clock }
+	return bt
+}
+
+func (bt *breakerTest) filter(args ...interface{}) filters.Filter {
+	f, err := bt.spec.CreateFilter(args)
+	if err != nil {
+		bt.t.Fatal(err)
+	}
+
+	return f
+}
+
+// executes a request through the filter, and tells whether it was
+// forwarded. When forwarded, the backend responds with the status, or
+// fails with an error when the status is zero.
+func (bt *breakerTest) request(f filters.Filter, backend string, status int) bool {
+	ctx := &filtertest.Context{
+		FRequest:    &http.Request{},
+		FStateBag:   make(map[string]interface{}),
+		FBackendUrl: backend}
+
+	f.Request(ctx)
+	if ctx.FServed {
+		if ctx.FResponse.StatusCode != http.StatusServiceUnavailable {
+			bt.t.Error("invalid status", ctx.FResponse.StatusCode)
+		}
+
+		f.Response(ctx)
+		f.(*circuitBreaker).Cleanup(ctx)
+		return false
+	}
+
+	if status != 0 {
+		ctx.FResponse = &http.Response{StatusCode: status}
+		f.Response(ctx)
+	}
+
+	f.(*circuitBreaker).Cleanup(ctx)
+	return true
+}
+
+func TestCircuitBreakerTrips(t *testing.T) {
+	bt := newBreakerTest(t)
+	f := bt.filter(3.0, "10s", "30s")
+	const backend = "https://www.example.org"
+
+	// failures spread over a longer period than the window
+	for i := 0; i < 4; i++ {
+		if !bt.request(f, backend, http.StatusInternalServerError) {
+			t.Fatal("failed to forward the request")
+		}
+
+		bt.clock = bt.clock.Add(6 * time.Second)
+	}
+
+	bt.clock = bt.clock.Add(10 * time.Second)
+	if !bt.request(f, backend, http.StatusOK) {
+		t.Fatal("failed to forward the request")
+	}
+
+	// failures within the window, including a backend error
+	for _, status := range []int{http.StatusBadGateway, 0, http.StatusServiceUnavailable} {
+		if !bt.request(f, backend, status) {
+			t.Fatal("failed to forward the request before tripping")
+		}
+	}
+
+	if bt.request(f, backend, http.StatusOK) {
+		t.Error("failed to open the circuit breaker")
+	}
+
+	if !bt.request(f, "https://other.example.org", http.StatusOK) {
+		t.Error("failed to forward the request to the other host")
+	}
+
+	// the routes with the same backend share the circuit breaker
+	if bt.request(bt.filter(3.0, "10s", "30s"), backend, http.StatusOK) {
+		t.Error("failed to share the circuit breaker")
+	}
+
+	// also when they use different settings
+	if bt.request(bt.filter(5.0), backend, http.StatusOK) {
+		t.Error("failed to share the circuit breaker with different settings")
+	}
+}
+
+func TestCircuitBreakerRemovesIdle(t *testing.T) {
+	bt := newBreakerTest(t)
+	f := bt.filter(1.0, "10s", "30s")
+
+	bt.request(f, "https://idle.example.org", http.StatusInternalServerError)
+	bt.request(bt.filter(1.0, "10s", "5m"), "https://long.example.org", http.StatusInternalServerError)
+
+	bt.clock = bt.clock.Add(2 * time.Minute)
+	bt.request(f, "https://www.example.org", http.StatusOK)
+
+	bt.spec.mx.Lock()
+	_, idle := bt.spec.breakers["idle.example.org"]
+	_, long := bt.spec.breakers["long.example.org"]
+	bt.spec.mx.Unlock()
+
+	if idle {
+		t.Error("failed to remove the idle circuit breaker")
+	}
+
+	if !long {
+		t.Error("removed the circuit breaker before its half open duration")
+	}
+
+	if !bt.request(f, "https://idle.example.org", http.StatusOK) {
+		t.Error("failed to forward the request with a new circuit breaker")
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	bt := newBreakerTest(t)
+	f := bt.filter(1.0, "10s", "30s")
+	const backend = "https://www.example.org"
+
+	bt.request(f, backend, http.StatusInternalServerError)
+	bt.clock = bt.clock.Add(29 * time.Second)
+	if bt.request(f, backend, http.StatusOK) {
+		t.Fatal("failed to fast-fail while open")
+	}
+
+	// a failing probe opens the circuit breaker again
+	bt.clock = bt.clock.Add(time.Second)
+	if !bt.request(f, backend, http.StatusInternalServerError) {
+		t.Fatal("failed to forward the probe")
+	}
+
+	if bt.request(f, backend, http.StatusOK) {
+		t.Fatal("failed to open again after the failed probe")
+	}
+
+	// only one probe is forwarded while half-open
+	bt.clock = bt.clock.Add(30 * time.Second)
+	probe := &filtertest.Context{
+		FRequest:    &http.Request{},
+		FStateBag:   make(map[string]interface{}),
+		FBackendUrl: backend}
+	f.Request(probe)
+	if probe.FServed {
+		t.Fatal("failed to forward the probe")
+	}
+
+	if bt.request(f, backend, http.StatusOK) {
+		t.Error("failed to fast-fail during the probe")
+	}
+
+	probe.FResponse = &http.Response{StatusCode: http.StatusOK}
+	f.Response(probe)
+	f.(*circuitBreaker).Cleanup(probe)
+
+	for i := 0; i < 3; i++ {
+		if !bt.request(f, backend, http.StatusOK) {
+			t.Error("failed to close after the successful probe")
+		}
+	}
+}
+
+func TestCircuitBreakerProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer backend.Close()
+
+	p := proxytest.New(MakeRegistry(), &eskip.Route{
+		Filters: []*eskip.Filter{{Name: CircuitBreakerName, Args: []interface{}{2.0, "1m", "1h"}}},
+		Backend: backend.URL})
+	defer p.Close()
+
+	for _, ti := range []struct {
+		path   string
+		status int
+	}{
+		{"/ok", http.StatusOK},
+		{"/fail", http.StatusInternalServerError},
+		{"/fail", http.StatusInternalServerError},
+		{"/ok", http.StatusServiceUnavailable},
+	} {
+		rsp, err := http.Get(p.URL + ti.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rsp.Body.Close()
+		if rsp.StatusCode != ti.status {
+			t.Error("invalid status", ti.path, rsp.StatusCode, ti.status)
+		}
+	}
+}
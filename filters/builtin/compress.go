@@ -13,6 +13,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/andybalholm/brotli"
 	"github.com/zalando/skipper/filters"
 )
 
@@ -26,8 +27,9 @@ type encoding struct {
 type encodings []*encoding
 
 type compress struct {
-	mime  []string
-	level int
+	mime      []string
+	encodings []string
+	level     int
 }
 
 type encoder interface {
@@ -37,7 +39,7 @@ type encoder interface {
 }
 
 var (
-	supportedEncodings  = []string{"gzip", "deflate"}
+	supportedEncodings  = []string{"gzip", "deflate", "br"}
 	unsupportedEncoding = errors.New("unsupported encoding")
 )
 
@@ -56,6 +58,7 @@ var defaultCompressMIME = []string{
 var (
 	gzipPool    = &sync.Pool{}
 	deflatePool = &sync.Pool{}
+	brPool      = &sync.Pool{}
 )
 
 func init() {
@@ -76,6 +79,13 @@ func init() {
 		}
 
 		deflatePool.Put(fe)
+
+		be, err := newEncoder("br", flate.BestSpeed)
+		if err != nil {
+			panic(err)
+		}
+
+		brPool.Put(be)
 	}
 }
 
@@ -114,16 +124,29 @@ func (e encodings) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
 // It is possible to control the compression level, by setting it as the first
 // filter argument, in front of the MIME types. The default compression level is
 // best-speed. The possible values are integers between 0 and 9 (inclusive), where
-// 0 means no-compression, 1 means best-speed and 9 means best-compression.
+// 0 means no-compression, 1 means best-speed and 9 means best-compression. For
+// br, the level is used as the brotli quality, where 0 is the fastest, and 9 is
+// below the maximum quality of brotli, 11.
 // Example:
 //
 // 	* -> compress(9, "image/tiff") -> "https://www.example.org"
 //
 // The filter also checks the incoming request, if it accepts the supported
 // encodings, explicitly stated in the Accept-Encoding header. The filter currently
-// supports gzip and deflate. It does not assume that the client accepts any
+// supports gzip, deflate and br. It does not assume that the client accepts any
 // encoding if the Accept-Encoding header is not set. It ignores * in the
-// Accept-Encoding header.
+// Accept-Encoding header, and the encodings with q=0. When the client accepts
+// multiple encodings with the same preference, the first one in the header is
+// selected.
+//
+// The used encodings can be restricted by listing them after the compression
+// level, if any, and in front of the MIME types. The responses to the clients
+// that don't accept any of the listed encodings are not compressed. Example:
+//
+// 	* -> compress("gzip") -> "https://www.example.org"
+// 	* -> compress(9, "gzip", "...", "image/tiff") -> "https://www.example.org"
+//
+// The encodings that are not supported by the filter are rejected.
 //
 // When compressing the response, it updates the response header. It deletes the
 // the Content-Length value triggering the proxy to always return the response
@@ -140,8 +163,9 @@ func (c *compress) Name() string {
 
 func (c *compress) CreateFilter(args []interface{}) (filters.Filter, error) {
 	f := &compress{
-		mime:  defaultCompressMIME,
-		level: flate.BestSpeed}
+		mime:      defaultCompressMIME,
+		encodings: supportedEncodings,
+		level:     flate.BestSpeed}
 
 	if len(args) == 0 {
 		return f, nil
@@ -162,6 +186,27 @@ func (c *compress) CreateFilter(args []interface{}) (filters.Filter, error) {
 		args = args[1:]
 	}
 
+	// the encodings are distinguished from the MIME types by the
+	// missing slash
+	var encs []string
+	for len(args) > 0 {
+		s, ok := args[0].(string)
+		if !ok || s == "..." || strings.Contains(s, "/") {
+			break
+		}
+
+		if !stringsContain(supportedEncodings, s) {
+			return nil, filters.ErrInvalidFilterParameters
+		}
+
+		encs = append(encs, s)
+		args = args[1:]
+	}
+
+	if len(encs) > 0 {
+		f.encodings = encs
+	}
+
 	if len(args) == 0 {
 		return f, nil
 	}
@@ -221,7 +266,7 @@ func canEncodeEntity(r *http.Response, mime []string) bool {
 	return true
 }
 
-func acceptedEncoding(r *http.Request) string {
+func acceptedEncoding(r *http.Request, supported []string) string {
 	var encs encodings
 	for _, s := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
 		sp := strings.Split(s, ";")
@@ -230,7 +275,7 @@ func acceptedEncoding(r *http.Request) string {
 		}
 
 		name := strings.ToLower(strings.TrimSpace(sp[0]))
-		if !stringsContain(supportedEncodings, name) {
+		if !stringsContain(supported, name) {
 			continue
 		}
 
@@ -251,13 +296,17 @@ func acceptedEncoding(r *http.Request) string {
 			enc.q = float32(q)
 			break
 		}
+
+		if enc.q <= 0 {
+			encs = encs[:len(encs)-1]
+		}
 	}
 
 	if len(encs) == 0 {
 		return ""
 	}
 
-	sort.Sort(encs)
+	sort.Stable(encs)
 	return encs[0].name
 }
 
@@ -283,6 +332,8 @@ func newEncoder(enc string, level int) (encoder, error) {
 		return gzip.NewWriterLevel(nil, level)
 	case "deflate":
 		return flate.NewWriter(nil, level)
+	case "br":
+		return brotli.NewWriterLevel(nil, level), nil
 	default:
 		unsupported()
 		return nil, nil
@@ -295,6 +346,8 @@ func encoderPool(enc string) *sync.Pool {
 		return gzipPool
 	case "deflate":
 		return deflatePool
+	case "br":
+		return brPool
 	default:
 		unsupported()
 		return nil
@@ -374,7 +427,7 @@ func (c *compress) Response(ctx filters.FilterContext) {
 		return
 	}
 
-	enc := acceptedEncoding(ctx.Request())
+	enc := acceptedEncoding(ctx.Request(), c.encodings)
 	if enc == "" {
 		return
 	}
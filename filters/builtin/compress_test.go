@@ -10,10 +10,12 @@ import (
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strconv"
 	"testing"
 	"time"
 
+	"github.com/andybalholm/brotli"
 	"github.com/zalando/skipper/eskip"
 	"github.com/zalando/skipper/filters"
 	"github.com/zalando/skipper/filters/filtertest"
@@ -77,6 +79,8 @@ func decoder(enc string, r io.Reader) io.Reader {
 		return rr
 	case "deflate":
 		return flate.NewReader(r)
+	case "br":
+		return brotli.NewReader(r)
 	default:
 		panic(unsupportedEncoding)
 	}
@@ -221,6 +225,64 @@ func TestCompressArgs(t *testing.T) {
 	}
 }
 
+func TestCompressEncodingArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg               string
+		args              []interface{}
+		err               error
+		expectedEncodings []string
+		expectedMime      []string
+	}{{
+		"default encodings",
+		nil,
+		nil,
+		supportedEncodings,
+		defaultCompressMIME,
+	}, {
+		"restricted encodings",
+		[]interface{}{"gzip"},
+		nil,
+		[]string{"gzip"},
+		defaultCompressMIME,
+	}, {
+		"restricted encodings, level and mime types",
+		[]interface{}{float64(6), "deflate", "gzip", "x/custom"},
+		nil,
+		[]string{"deflate", "gzip"},
+		[]string{"x/custom"},
+	}, {
+		"unsupported encoding",
+		[]interface{}{"gzip", "x-custom"},
+		filters.ErrInvalidFilterParameters,
+		nil,
+		nil,
+	}, {
+		"encoding after mime types",
+		[]interface{}{"x/custom", "gzip"},
+		nil,
+		supportedEncodings,
+		[]string{"x/custom", "gzip"},
+	}} {
+		f, err := NewCompress().CreateFilter(ti.args)
+		if err != ti.err {
+			t.Error(ti.msg, "unexpected error value", ti.err, err)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		c := f.(*compress)
+		if !reflect.DeepEqual(c.encodings, ti.expectedEncodings) {
+			t.Error(ti.msg, "invalid encodings", ti.expectedEncodings, c.encodings)
+		}
+
+		if !reflect.DeepEqual(c.mime, ti.expectedMime) {
+			t.Error(ti.msg, "invalid mime types", ti.expectedMime, c.mime)
+		}
+	}
+}
+
 func TestCompress(t *testing.T) {
 	for _, ti := range []struct {
 		msg            string
@@ -391,6 +453,75 @@ func TestCompress(t *testing.T) {
 		http.Header{
 			"Content-Encoding": []string{"deflate"},
 			"Vary":             []string{"Accept-Encoding"}},
+	}, {
+		"refused with zero weight",
+		http.Header{},
+		3 * 8192,
+		nil,
+		"gzip; q=0, deflate; q=0.2",
+		http.Header{
+			"Content-Encoding": []string{"deflate"},
+			"Vary":             []string{"Accept-Encoding"}},
+	}, {
+		"same weight, first in the header",
+		http.Header{},
+		3 * 8192,
+		nil,
+		"deflate, gzip",
+		http.Header{
+			"Content-Encoding": []string{"deflate"},
+			"Vary":             []string{"Accept-Encoding"}},
+	}, {
+		"unsupported encoding",
+		http.Header{},
+		3 * 8192,
+		nil,
+		"x-custom",
+		http.Header{},
+	}, {
+		"brotli",
+		http.Header{},
+		3 * 8192,
+		nil,
+		"br",
+		http.Header{
+			"Content-Encoding": []string{"br"},
+			"Vary":             []string{"Accept-Encoding"}},
+	}, {
+		"brotli with level",
+		http.Header{},
+		3 * 8192,
+		[]interface{}{float64(flate.BestCompression), "br"},
+		"gzip, br",
+		http.Header{
+			"Content-Encoding": []string{"br"},
+			"Vary":             []string{"Accept-Encoding"}},
+	}, {
+		"restricted encodings",
+		http.Header{},
+		3 * 8192,
+		[]interface{}{"gzip"},
+		"deflate, gzip; q=0.5",
+		http.Header{
+			"Content-Encoding": []string{"gzip"},
+			"Vary":             []string{"Accept-Encoding"}},
+	}, {
+		"restricted encodings, not accepted",
+		http.Header{},
+		3 * 8192,
+		[]interface{}{"gzip"},
+		"br, deflate",
+		http.Header{},
+	}, {
+		"restricted encodings with level and mime types",
+		http.Header{"Content-Type": []string{"x/custom"}},
+		3 * 8192,
+		[]interface{}{float64(flate.BestCompression), "deflate", "...", "x/custom"},
+		"gzip, deflate; q=0.5",
+		http.Header{
+			"Content-Type":     []string{"x/custom"},
+			"Content-Encoding": []string{"deflate"},
+			"Vary":             []string{"Accept-Encoding"}},
 	}, {
 		"drops content length",
 		http.Header{"Content-Length": []string{strconv.Itoa(3 * 8192)}},
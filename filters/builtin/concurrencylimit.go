@@ -0,0 +1,93 @@
+package builtin
+
+import (
+	"math"
+	"net/http"
+
+	"github.com/zalando/skipper/filters"
+)
+
+const concurrencyLimitStateKey = "acquired"
+
+type concurrencyLimit struct {
+	slots  chan struct{}
+	status int
+}
+
+// Returns a filter specification whose instances limit the number of
+// the concurrent requests handled by a route. When the limit is
+// reached, the requests are rejected with 503 Service Unavailable, or
+// with the status code set in the second, optional argument.
+//
+// Example:
+//
+// 	* -> concurrencyLimit(100) -> "https://www.example.org"
+// 	* -> concurrencyLimit(100, 429) -> "https://www.example.org"
+//
+// A request holds its slot until it was handled completely, including
+// streaming the response body to the client, also when the request to
+// the backend failed. The limit applies to every route separately, and
+// the counting starts again when the route is updated.
+//
+func NewConcurrencyLimit() filters.Spec { return &concurrencyLimit{} }
+
+func (l *concurrencyLimit) Name() string { return ConcurrencyLimitName }
+
+func (l *concurrencyLimit) CreateFilter(args []interface{}) (filters.Filter, error) {
+	a := filters.NewArgs(ConcurrencyLimitName, args)
+	if err := a.Count(1, 2); err != nil {
+		return nil, err
+	}
+
+	max, err := a.Int(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.InRange(0, float64(max), 1, math.MaxInt32); err != nil {
+		return nil, err
+	}
+
+	status, err := a.OptionalInt(1, http.StatusServiceUnavailable)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.InRange(1, float64(status), 100, 599); err != nil {
+		return nil, err
+	}
+
+	return &concurrencyLimit{slots: make(chan struct{}, max), status: status}, nil
+}
+
+// the filter instances of the route that acquired a slot for the
+// request
+func acquiredLimits(ctx filters.FilterContext) []*concurrencyLimit {
+	v, _ := filters.StateBagGet(ctx, ConcurrencyLimitName, concurrencyLimitStateKey)
+	acquired, _ := v.([]*concurrencyLimit)
+	return acquired
+}
+
+func (l *concurrencyLimit) Request(ctx filters.FilterContext) {
+	select {
+	case l.slots <- struct{}{}:
+		filters.StateBagSet(ctx, ConcurrencyLimitName, concurrencyLimitStateKey, append(acquiredLimits(ctx), l))
+	default:
+		ctx.Serve(&http.Response{StatusCode: l.status})
+	}
+}
+
+func (l *concurrencyLimit) Response(filters.FilterContext) {}
+
+// Cleanup is called by the proxy when the request was handled, and it
+// releases the slot of the request, if it acquired one.
+func (l *concurrencyLimit) Cleanup(ctx filters.FilterContext) {
+	acquired := acquiredLimits(ctx)
+	for i, a := range acquired {
+		if a == l {
+			filters.StateBagSet(ctx, ConcurrencyLimitName, concurrencyLimitStateKey, append(acquired[:i], acquired[i+1:]...))
+			<-l.slots
+			return
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package builtin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+	"github.com/zalando/skipper/proxy/proxytest"
+)
+
+func TestConcurrencyLimitArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg   string
+		args  []interface{}
+		valid bool
+	}{
+		{"no args", nil, false},
+		{"not a number", []interface{}{"100"}, false},
+		{"zero", []interface{}{0.0}, false},
+		{"invalid status", []interface{}{100.0, 999.0}, false},
+		{"too many args", []interface{}{100.0, 503.0, 1.0}, false},
+		{"valid", []interface{}{100.0}, true},
+		{"valid with status", []interface{}{100.0, 429.0}, true},
+	} {
+		_, err := NewConcurrencyLimit().CreateFilter(ti.args)
+		if ti.valid && err != nil || !ti.valid && err == nil {
+			t.Error(ti.msg, "failed to validate args", err)
+		}
+	}
+}
+
+func TestConcurrencyLimitSlots(t *testing.T) {
+	f, err := NewConcurrencyLimit().CreateFilter([]interface{}{2.0, 429.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newContext := func() *filtertest.Context {
+		return &filtertest.Context{
+			FRequest:  &http.Request{},
+			FStateBag: make(map[string]interface{})}
+	}
+
+	cleanup := f.(interface {
+		Cleanup(filters.FilterContext)
+	}).Cleanup
+
+	ctx1, ctx2, ctx3 := newContext(), newContext(), newContext()
+	f.Request(ctx1)
+	f.Request(ctx2)
+	if ctx1.FServed || ctx2.FServed {
+		t.Fatal("unexpected rejection")
+	}
+
+	f.Request(ctx3)
+	if !ctx3.FServed || ctx3.FResponse == nil || ctx3.FResponse.StatusCode != 429 {
+		t.Fatal("failed to reject the request")
+	}
+
+	// the rejected request doesn't release a slot
+	cleanup(ctx3)
+	ctx4 := newContext()
+	f.Request(ctx4)
+	if !ctx4.FServed {
+		t.Fatal("failed to reject the request")
+	}
+
+	cleanup(ctx1)
+
+	// releasing only once
+	cleanup(ctx1)
+
+	ctx5 := newContext()
+	f.Request(ctx5)
+	if ctx5.FServed {
+		t.Fatal("failed to release the slot")
+	}
+
+	ctx6 := newContext()
+	f.Request(ctx6)
+	if !ctx6.FServed {
+		t.Fatal("failed to reject the request")
+	}
+}
+
+func TestConcurrencyLimitProxy(t *testing.T) {
+	const limit = 3
+
+	var (
+		arrived = make(chan struct{})
+		release = make(chan struct{})
+	)
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/block" {
+			arrived <- struct{}{}
+			<-release
+		}
+	}))
+	defer backend.Close()
+
+	p := proxytest.New(MakeRegistry(), &eskip.Route{
+		Filters: []*eskip.Filter{{Name: ConcurrencyLimitName, Args: []interface{}{float64(limit)}}},
+		Backend: backend.URL})
+	defer p.Close()
+
+	get := func(path string) int {
+		rsp, err := http.Get(p.URL + path)
+		if err != nil {
+			t.Error(err)
+			return 0
+		}
+
+		rsp.Body.Close()
+		return rsp.StatusCode
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < limit; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s := get("/block"); s != http.StatusOK {
+				t.Error("unexpected status", s)
+			}
+		}()
+
+		<-arrived
+	}
+
+	for i := 0; i < 3; i++ {
+		if s := get("/"); s != http.StatusServiceUnavailable {
+			t.Error("failed to reject the request", s)
+		}
+	}
+
+	close(release)
+	wg.Wait()
+
+	for i := 0; i < 2*limit; i++ {
+		if s := get("/"); s != http.StatusOK {
+			t.Error("failed to release the slots", s)
+		}
+	}
+}
+
+func TestConcurrencyLimitReleasesOnBackendError(t *testing.T) {
+	// reserving an address, then closing the server, to get connection
+	// errors
+	s := httptest.NewServer(http.NotFoundHandler())
+	url := s.URL
+	s.Close()
+
+	p := proxytest.New(MakeRegistry(), &eskip.Route{
+		Filters: []*eskip.Filter{{Name: ConcurrencyLimitName, Args: []interface{}{1.0}}},
+		Backend: url})
+	defer p.Close()
+
+	for i := 0; i < 3; i++ {
+		rsp, err := http.Get(p.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rsp.Body.Close()
+		if rsp.StatusCode != http.StatusInternalServerError {
+			t.Error("failed to release the slot after the backend error", rsp.StatusCode)
+		}
+	}
+}
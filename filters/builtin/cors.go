@@ -0,0 +1,138 @@
+package builtin
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/zalando/skipper/filters"
+)
+
+const corsWildcard = "*"
+
+type corsOrigin struct {
+	origins  map[string]bool
+	wildcard bool
+}
+
+// Returns a filter specification whose instances allow cross origin
+// requests from the origins listed in the arguments, e.g.:
+//
+// 	* -> corsOrigin("https://app.example.org", "https://admin.example.org") -> "https://api.example.org";
+//
+// When the Origin header of the request matches one of the arguments,
+// the response gets the Access-Control-Allow-Origin header with the
+// same origin. The responses to the requests from other origins don't
+// get the header, so the browsers reject them. Unless in wildcard mode,
+// all the responses get the Vary: Origin header.
+//
+// The preflight requests, i.e. the OPTIONS requests with the
+// Access-Control-Request-Method header, from the allowed origins are
+// answered by the filter, without forwarding them to the backend. The
+// response allows the requested method and headers.
+//
+// The wildcard mode, allowing any origin, needs to be set explicitly,
+// with "*" as the only argument:
+//
+// 	* -> corsOrigin("*") -> "https://api.example.org";
+//
+func NewCorsOrigin() filters.Spec { return &corsOrigin{} }
+
+func (c *corsOrigin) Name() string { return CorsOriginName }
+
+func (c *corsOrigin) CreateFilter(args []interface{}) (filters.Filter, error) {
+	a := filters.NewArgs(CorsOriginName, args)
+	if err := a.Count(1, -1); err != nil {
+		return nil, err
+	}
+
+	f := &corsOrigin{origins: make(map[string]bool)}
+	for i := 0; i < a.Len(); i++ {
+		o, err := a.String(i)
+		if err != nil {
+			return nil, err
+		}
+
+		switch o {
+		case "":
+			return nil, filters.ErrInvalidFilterParameters
+		case corsWildcard:
+			f.wildcard = true
+		default:
+			f.origins[strings.TrimSuffix(o, "/")] = true
+		}
+	}
+
+	if f.wildcard && len(f.origins) > 0 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	return f, nil
+}
+
+// returns the value of the Access-Control-Allow-Origin header for the
+// origin, or empty string, when the origin is not allowed
+func (c *corsOrigin) allowOrigin(origin string) string {
+	switch {
+	case origin == "":
+		return ""
+	case c.wildcard:
+		return corsWildcard
+	case c.origins[origin]:
+		return origin
+	default:
+		return ""
+	}
+}
+
+// marks the response as varying by origin, unless in wildcard mode. It
+// is set for every response, including the ones to the requests from
+// the disallowed origins or without an origin, so that the caches don't
+// serve them to the allowed origins. The preflight responses served by
+// the filter are passed to the response filters, too, so it doesn't add
+// the Vary header twice.
+func (c *corsOrigin) setVary(h http.Header) {
+	if c.wildcard {
+		return
+	}
+
+	for _, v := range h["Vary"] {
+		if v == "Origin" {
+			return
+		}
+	}
+
+	h.Add("Vary", "Origin")
+}
+
+func (c *corsOrigin) Request(ctx filters.FilterContext) {
+	r := ctx.Request()
+	method := r.Header.Get("Access-Control-Request-Method")
+	if r.Method != "OPTIONS" || method == "" {
+		return
+	}
+
+	allow := c.allowOrigin(r.Header.Get("Origin"))
+	if allow == "" {
+		return
+	}
+
+	rsp := &http.Response{
+		StatusCode: http.StatusNoContent,
+		Header:     make(http.Header)}
+	rsp.Header.Set("Access-Control-Allow-Origin", allow)
+	c.setVary(rsp.Header)
+	rsp.Header.Set("Access-Control-Allow-Methods", method)
+	if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+		rsp.Header.Set("Access-Control-Allow-Headers", headers)
+	}
+
+	ctx.Serve(rsp)
+}
+
+func (c *corsOrigin) Response(ctx filters.FilterContext) {
+	h := ctx.Response().Header
+	c.setVary(h)
+	if allow := c.allowOrigin(ctx.Request().Header.Get("Origin")); allow != "" {
+		h.Set("Access-Control-Allow-Origin", allow)
+	}
+}
@@ -0,0 +1,176 @@
+package builtin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/proxy/proxytest"
+)
+
+func TestCorsOriginArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg   string
+		args  []interface{}
+		valid bool
+	}{
+		{"no args", nil, false},
+		{"not a string", []interface{}{42.0}, false},
+		{"empty", []interface{}{""}, false},
+		{"wildcard mixed with origins", []interface{}{"*", "https://app.example.org"}, false},
+		{"valid", []interface{}{"https://app.example.org"}, true},
+		{"valid, multiple", []interface{}{"https://app.example.org", "https://admin.example.org"}, true},
+		{"valid, wildcard", []interface{}{"*"}, true},
+	} {
+		_, err := NewCorsOrigin().CreateFilter(ti.args)
+		if ti.valid && err != nil || !ti.valid && err == nil {
+			t.Error(ti.msg, "failed to validate args", err)
+		}
+	}
+}
+
+func TestCorsOrigin(t *testing.T) {
+	for _, ti := range []struct {
+		msg          string
+		args         []interface{}
+		method       string
+		header       http.Header
+		backendCall  bool
+		status       int
+		allowOrigin  string
+		allowMethods string
+		allowHeaders string
+		vary         []string
+	}{{
+		msg:    "preflight",
+		args:   []interface{}{"https://app.example.org", "https://admin.example.org"},
+		method: "OPTIONS",
+		header: http.Header{
+			"Origin":                         []string{"https://admin.example.org"},
+			"Access-Control-Request-Method":  []string{"PUT"},
+			"Access-Control-Request-Headers": []string{"Content-Type, X-Foo"}},
+		status:       http.StatusNoContent,
+		allowOrigin:  "https://admin.example.org",
+		allowMethods: "PUT",
+		allowHeaders: "Content-Type, X-Foo",
+		vary:         []string{"Origin"},
+	}, {
+		msg:    "preflight from a disallowed origin",
+		args:   []interface{}{"https://app.example.org"},
+		method: "OPTIONS",
+		header: http.Header{
+			"Origin":                        []string{"https://evil.example.org"},
+			"Access-Control-Request-Method": []string{"PUT"}},
+		backendCall: true,
+		status:      http.StatusOK,
+		vary:        []string{"Origin"},
+	}, {
+		msg:         "options request without preflight",
+		args:        []interface{}{"https://app.example.org"},
+		method:      "OPTIONS",
+		header:      http.Header{"Origin": []string{"https://app.example.org"}},
+		backendCall: true,
+		status:      http.StatusOK,
+		allowOrigin: "https://app.example.org",
+		vary:        []string{"Origin"},
+	}, {
+		msg:         "allowed request",
+		args:        []interface{}{"https://app.example.org"},
+		method:      "GET",
+		header:      http.Header{"Origin": []string{"https://app.example.org"}},
+		backendCall: true,
+		status:      http.StatusOK,
+		allowOrigin: "https://app.example.org",
+		vary:        []string{"Origin"},
+	}, {
+		msg:         "disallowed origin",
+		args:        []interface{}{"https://app.example.org"},
+		method:      "GET",
+		header:      http.Header{"Origin": []string{"https://evil.example.org"}},
+		backendCall: true,
+		status:      http.StatusOK,
+		vary:        []string{"Origin"},
+	}, {
+		msg:         "no origin",
+		args:        []interface{}{"https://app.example.org"},
+		method:      "GET",
+		backendCall: true,
+		status:      http.StatusOK,
+		vary:        []string{"Origin"},
+	}, {
+		msg:         "wildcard",
+		args:        []interface{}{"*"},
+		method:      "GET",
+		header:      http.Header{"Origin": []string{"https://any.example.org"}},
+		backendCall: true,
+		status:      http.StatusOK,
+		allowOrigin: "*",
+	}, {
+		msg:    "wildcard preflight",
+		args:   []interface{}{"*"},
+		method: "OPTIONS",
+		header: http.Header{
+			"Origin":                        []string{"https://any.example.org"},
+			"Access-Control-Request-Method": []string{"DELETE"}},
+		status:       http.StatusNoContent,
+		allowOrigin:  "*",
+		allowMethods: "DELETE",
+	}} {
+		var backendCalls int32
+		backend := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			atomic.AddInt32(&backendCalls, 1)
+		}))
+
+		p := proxytest.New(MakeRegistry(), &eskip.Route{
+			Filters: []*eskip.Filter{{Name: CorsOriginName, Args: ti.args}},
+			Backend: backend.URL})
+
+		func() {
+			defer backend.Close()
+			defer p.Close()
+
+			req, err := http.NewRequest(ti.method, p.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for k, v := range ti.header {
+				req.Header[k] = v
+			}
+
+			rsp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Error(ti.msg, err)
+				return
+			}
+
+			rsp.Body.Close()
+
+			if rsp.StatusCode != ti.status {
+				t.Error(ti.msg, "invalid status", rsp.StatusCode)
+			}
+
+			if (atomic.LoadInt32(&backendCalls) > 0) != ti.backendCall {
+				t.Error(ti.msg, "unexpected backend calls", backendCalls)
+			}
+
+			if h := rsp.Header.Get("Access-Control-Allow-Origin"); h != ti.allowOrigin {
+				t.Error(ti.msg, "invalid allow origin header", h)
+			}
+
+			if h := rsp.Header.Get("Access-Control-Allow-Methods"); h != ti.allowMethods {
+				t.Error(ti.msg, "invalid allow methods header", h)
+			}
+
+			if h := rsp.Header.Get("Access-Control-Allow-Headers"); h != ti.allowHeaders {
+				t.Error(ti.msg, "invalid allow headers header", h)
+			}
+
+			if h := rsp.Header["Vary"]; len(h) != len(ti.vary) || len(h) > 0 && h[0] != ti.vary[0] {
+				t.Error(ti.msg, "invalid vary header", h)
+			}
+		}()
+	}
+}
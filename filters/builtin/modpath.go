@@ -17,6 +17,7 @@ package builtin
 import (
 	"fmt"
 	"github.com/zalando/skipper/filters"
+	"net/url"
 	"regexp"
 )
 
@@ -63,10 +64,37 @@ func (spec *modPath) CreateFilter(config []interface{}) (filters.Filter, error)
 	return f, nil
 }
 
-// Modifies the path with regexp.ReplaceAll.
+// Modifies the path with regexp.ReplaceAll. When the request path
+// contains escaped characters that are preserved in RawPath, the
+// replacement is executed on the escaped form, and both Path and
+// RawPath are set from the result, so that the path sent to the
+// backend keeps the original encoding.
 func (f *modPath) Request(ctx filters.FilterContext) {
-	req := ctx.Request()
-	req.URL.Path = string(f.rx.ReplaceAll([]byte(req.URL.Path), f.replacement))
+	u := ctx.Request().URL
+	if u.RawPath != "" {
+		if f.modifyRawPath(u) {
+			return
+		}
+
+		u.RawPath = ""
+	}
+
+	u.Path = string(f.rx.ReplaceAll([]byte(u.Path), f.replacement))
+}
+
+// executes the replacement on the escaped path. Returns false, when the
+// result is not a valid escaped path.
+func (f *modPath) modifyRawPath(u *url.URL) bool {
+	raw := string(f.rx.ReplaceAll([]byte(u.RawPath), f.replacement))
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Scheme != "" || parsed.Host != "" ||
+		parsed.RawQuery != "" || parsed.Fragment != "" || parsed.Opaque != "" {
+		return false
+	}
+
+	u.Path = parsed.Path
+	u.RawPath = parsed.RawPath
+	return true
 }
 
 // Noop.
@@ -15,8 +15,12 @@
 package builtin
 
 import (
+	"github.com/zalando/skipper/eskip"
 	"github.com/zalando/skipper/filters/filtertest"
+	"github.com/zalando/skipper/proxy/proxytest"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -54,3 +58,91 @@ func TestModifyPath(t *testing.T) {
 		t.Error("failed to replace path")
 	}
 }
+
+func TestModifyPathCases(t *testing.T) {
+	for _, ti := range []struct {
+		msg             string
+		expression      string
+		replacement     string
+		url             string
+		expectedPath    string
+		expectedRawPath string
+	}{{
+		msg:          "match",
+		expression:   "^/old/",
+		replacement:  "/new/",
+		url:          "https://www.example.org/old/some/path",
+		expectedPath: "/new/some/path",
+	}, {
+		msg:          "no match",
+		expression:   "^/old/",
+		replacement:  "/new/",
+		url:          "https://www.example.org/some/old/path",
+		expectedPath: "/some/old/path",
+	}, {
+		msg:          "capture groups",
+		expression:   "^/api/v([0-9]+)/(.*)$",
+		replacement:  "/$2/version-$1",
+		url:          "https://www.example.org/api/v2/users/42",
+		expectedPath: "/users/42/version-2",
+	}, {
+		msg:             "escaped path",
+		expression:      "^/old/",
+		replacement:     "/new/",
+		url:             "https://www.example.org/old/a%2Fb",
+		expectedPath:    "/new/a/b",
+		expectedRawPath: "/new/a%2Fb",
+	}, {
+		msg:          "escaped path, rewritten to an invalid escaped path",
+		expression:   "%2F",
+		replacement:  "%",
+		url:          "https://www.example.org/some/a%2Fb",
+		expectedPath: "/some/a/b",
+	}} {
+		f, err := NewModPath().CreateFilter([]interface{}{ti.expression, ti.replacement})
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		req, err := http.NewRequest("GET", ti.url, nil)
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		f.Request(&filtertest.Context{FRequest: req})
+		if req.URL.Path != ti.expectedPath || req.URL.RawPath != ti.expectedRawPath {
+			t.Error(ti.msg, "failed to modify path", req.URL.Path, req.URL.RawPath)
+		}
+	}
+}
+
+func TestModifyPathProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RequestURI))
+	}))
+	defer backend.Close()
+
+	p := proxytest.New(MakeRegistry(), &eskip.Route{
+		Filters: []*eskip.Filter{{Name: ModPathName, Args: []interface{}{"^/old/", "/new/"}}},
+		Backend: backend.URL})
+	defer p.Close()
+
+	rsp, err := http.Get(p.URL + "/old/a%2Fb?foo=bar")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer rsp.Body.Close()
+	b, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if string(b) != "/new/a%2Fb?foo=bar" {
+		t.Error("invalid request uri at the backend", string(b))
+	}
+}
@@ -0,0 +1,124 @@
+package builtin
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/zalando/skipper/filters"
+)
+
+const (
+	// DefaultRequestIdHeader is the header used by the requestId filter,
+	// when not set in the arguments.
+	DefaultRequestIdHeader = "X-Request-Id"
+
+	requestIdStateKey = "id"
+
+	// buffering the random bytes for 256 ids, to avoid reading the
+	// system source for every request
+	uuidRandomBufferSize = 16 * 256
+)
+
+type requestIdSpec struct{}
+
+type requestId struct {
+	header string
+}
+
+// generates version 4 (random) UUIDs from a cryptographically secure
+// source, shared between the requests
+type uuidGenerator struct {
+	mx     sync.Mutex
+	random *bufio.Reader
+}
+
+var defaultUUIDGenerator = &uuidGenerator{
+	random: bufio.NewReaderSize(rand.Reader, uuidRandomBufferSize)}
+
+func (g *uuidGenerator) next() (string, error) {
+	var u [16]byte
+
+	g.mx.Lock()
+	_, err := io.ReadFull(g.random, u[:])
+	g.mx.Unlock()
+
+	if err != nil {
+		return "", err
+	}
+
+	u[6] = u[6]&0x0f | 0x40 // version 4
+	u[8] = u[8]&0x3f | 0x80 // variant RFC 4122
+
+	var s [36]byte
+	hex.Encode(s[0:8], u[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], u[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], u[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], u[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], u[10:])
+	return string(s[:]), nil
+}
+
+// Returns a filter specification whose instances make sure that every
+// request has an id in a header, and that the response carries the
+// same id. When the request doesn't have the header, the filter
+// generates a random UUID (version 4), otherwise it keeps the one sent
+// by the client. The header name is an optional argument, and it
+// defaults to X-Request-Id.
+//
+// Example:
+//
+// 	* -> requestId() -> "https://www.example.org"
+// 	* -> requestId("X-Correlation-Id") -> "https://www.example.org"
+//
+func NewRequestId() filters.Spec { return requestIdSpec{} }
+
+func (s requestIdSpec) Name() string { return RequestIdName }
+
+func (s requestIdSpec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	a := filters.NewArgs(RequestIdName, args)
+	if err := a.Count(0, 1); err != nil {
+		return nil, err
+	}
+
+	header, err := a.OptionalString(0, DefaultRequestIdHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	if header == "" {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	return requestId{header}, nil
+}
+
+func (f requestId) Request(ctx filters.FilterContext) {
+	r := ctx.Request()
+	id := r.Header.Get(f.header)
+	if id == "" {
+		var err error
+		if id, err = defaultUUIDGenerator.next(); err != nil {
+			log.Error(err)
+			return
+		}
+
+		r.Header.Set(f.header, id)
+	}
+
+	filters.StateBagSet(ctx, RequestIdName, requestIdStateKey, id)
+}
+
+// sets the id of the request on the response
+func (f requestId) Response(ctx filters.FilterContext) {
+	if id, ok := filters.StateBagGetString(ctx, RequestIdName, requestIdStateKey); ok {
+		ctx.Response().Header.Set(f.header, id)
+	}
+}
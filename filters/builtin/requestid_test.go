@@ -0,0 +1,101 @@
+package builtin
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/zalando/skipper/filters/filtertest"
+)
+
+var uuidRx = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestRequestIdArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg   string
+		args  []interface{}
+		valid bool
+	}{
+		{"no args", nil, true},
+		{"header", []interface{}{"X-Correlation-Id"}, true},
+		{"empty header", []interface{}{""}, false},
+		{"not a string", []interface{}{42.0}, false},
+		{"too many args", []interface{}{"X-Request-Id", "X-Other"}, false},
+	} {
+		_, err := NewRequestId().CreateFilter(ti.args)
+		if ti.valid && err != nil || !ti.valid && err == nil {
+			t.Error(ti.msg, "failed to validate args", err)
+		}
+	}
+}
+
+func TestRequestId(t *testing.T) {
+	for _, ti := range []struct {
+		msg      string
+		args     []interface{}
+		header   string
+		clientId string
+	}{
+		{"generate when absent", nil, DefaultRequestIdHeader, ""},
+		{"preserve when present", nil, DefaultRequestIdHeader, "client-provided-id"},
+		{"custom header", []interface{}{"X-Correlation-Id"}, "X-Correlation-Id", ""},
+		{"custom header, preserved", []interface{}{"X-Correlation-Id"}, "X-Correlation-Id", "client-provided-id"},
+	} {
+		f, err := NewRequestId().CreateFilter(ti.args)
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		req := &http.Request{Header: make(http.Header)}
+		if ti.clientId != "" {
+			req.Header.Set(ti.header, ti.clientId)
+		}
+
+		ctx := &filtertest.Context{
+			FRequest:  req,
+			FResponse: &http.Response{Header: make(http.Header)},
+			FStateBag: make(map[string]interface{})}
+
+		f.Request(ctx)
+		id := req.Header.Get(ti.header)
+		if ti.clientId != "" && id != ti.clientId {
+			t.Error(ti.msg, "failed to preserve the id", id)
+			continue
+		}
+
+		if ti.clientId == "" && !uuidRx.MatchString(id) {
+			t.Error(ti.msg, "failed to generate a uuid", id)
+			continue
+		}
+
+		f.Response(ctx)
+		if rid := ctx.FResponse.Header.Get(ti.header); rid != id {
+			t.Error(ti.msg, "failed to set the id on the response", rid, id)
+		}
+	}
+}
+
+func TestRequestIdUnique(t *testing.T) {
+	ids := make(map[string]bool)
+	for i := 0; i < 10000; i++ {
+		id, err := defaultUUIDGenerator.next()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if ids[id] {
+			t.Error("duplicate id", id)
+			return
+		}
+
+		ids[id] = true
+	}
+}
+
+func BenchmarkRequestId(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		defaultUUIDGenerator.next()
+	}
+}
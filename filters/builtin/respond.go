@@ -0,0 +1,78 @@
+package builtin
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/zalando/skipper/filters"
+)
+
+const defaultRespondContentType = "text/plain; charset=utf-8"
+
+type respond struct {
+	status      int
+	body        string
+	contentType string
+}
+
+// Returns a filter specification whose instances serve a static
+// response in the request phase, without forwarding the request to the
+// backend. The first argument is the status code, the second one is the
+// body of the response, and the third, optional one is the content
+// type, defaulting to text/plain.
+//
+// Example:
+//
+// 	maintenance: * -> respond(503, "Service Unavailable") -> "https://www.example.org";
+// 	teapot: Path("/tea") -> respond(418, "{\"tea\": true}", "application/json") -> <shunt>;
+//
+// The filters preceding the respond filter in the route still process
+// the response, while the filters following it are not executed.
+//
+func NewRespond() filters.Spec { return &respond{} }
+
+func (r *respond) Name() string { return RespondName }
+
+func (r *respond) CreateFilter(args []interface{}) (filters.Filter, error) {
+	a := filters.NewArgs(RespondName, args)
+	if err := a.Count(2, 3); err != nil {
+		return nil, err
+	}
+
+	var (
+		f   respond
+		err error
+	)
+
+	if f.status, err = a.Int(0); err != nil {
+		return nil, err
+	}
+
+	if err := a.InRange(0, float64(f.status), 100, 599); err != nil {
+		return nil, err
+	}
+
+	if f.body, err = a.String(1); err != nil {
+		return nil, err
+	}
+
+	if f.contentType, err = a.OptionalString(2, defaultRespondContentType); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+func (r *respond) Request(ctx filters.FilterContext) {
+	ctx.Serve(&http.Response{
+		StatusCode: r.status,
+		Header: http.Header{
+			"Content-Type":   []string{r.contentType},
+			"Content-Length": []string{strconv.Itoa(len(r.body))}},
+		ContentLength: int64(len(r.body)),
+		Body:          ioutil.NopCloser(strings.NewReader(r.body))})
+}
+
+func (r *respond) Response(filters.FilterContext) {}
@@ -0,0 +1,110 @@
+package builtin
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/proxy/proxytest"
+)
+
+func TestRespondArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg   string
+		args  []interface{}
+		valid bool
+	}{
+		{"no args", nil, false},
+		{"missing body", []interface{}{503.0}, false},
+		{"status not a number", []interface{}{"503", "Service Unavailable"}, false},
+		{"invalid status", []interface{}{999.0, "Service Unavailable"}, false},
+		{"body not a string", []interface{}{503.0, 42.0}, false},
+		{"too many args", []interface{}{503.0, "Service Unavailable", "text/plain", "foo"}, false},
+		{"valid", []interface{}{503.0, "Service Unavailable"}, true},
+		{"valid with empty body", []interface{}{204.0, ""}, true},
+		{"valid with content type", []interface{}{200.0, "{}", "application/json"}, true},
+	} {
+		_, err := NewRespond().CreateFilter(ti.args)
+		if ti.valid && err != nil || !ti.valid && err == nil {
+			t.Error(ti.msg, "failed to validate args", err)
+		}
+	}
+}
+
+func TestRespond(t *testing.T) {
+	for _, ti := range []struct {
+		msg         string
+		args        []interface{}
+		status      int
+		body        string
+		contentType string
+	}{{
+		msg:         "default content type",
+		args:        []interface{}{503.0, "Service Unavailable"},
+		status:      http.StatusServiceUnavailable,
+		body:        "Service Unavailable",
+		contentType: "text/plain; charset=utf-8",
+	}, {
+		msg:         "custom content type",
+		args:        []interface{}{418.0, `{"tea": true}`, "application/json"},
+		status:      http.StatusTeapot,
+		body:        `{"tea": true}`,
+		contentType: "application/json",
+	}} {
+		var backendCalls int32
+		backend := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			atomic.AddInt32(&backendCalls, 1)
+		}))
+
+		p := proxytest.New(MakeRegistry(), &eskip.Route{
+			Filters: []*eskip.Filter{
+				{Name: SetResponseHeaderName, Args: []interface{}{"X-Test", "foo"}},
+				{Name: RespondName, Args: ti.args},
+				{Name: SetRequestHeaderName, Args: []interface{}{"X-Test", "bar"}}},
+			Backend: backend.URL})
+
+		func() {
+			defer backend.Close()
+			defer p.Close()
+
+			// requesting twice, to check that the body is served every time
+			for i := 0; i < 2; i++ {
+				rsp, err := http.Get(p.URL)
+				if err != nil {
+					t.Error(ti.msg, err)
+					return
+				}
+
+				b, err := ioutil.ReadAll(rsp.Body)
+				rsp.Body.Close()
+				if err != nil {
+					t.Error(ti.msg, err)
+					return
+				}
+
+				if rsp.StatusCode != ti.status {
+					t.Error(ti.msg, "invalid status", rsp.StatusCode)
+				}
+
+				if string(b) != ti.body {
+					t.Error(ti.msg, "invalid body", string(b))
+				}
+
+				if ct := rsp.Header.Get("Content-Type"); ct != ti.contentType {
+					t.Error(ti.msg, "invalid content type", ct)
+				}
+
+				if h := rsp.Header.Get("X-Test"); h != "foo" {
+					t.Error(ti.msg, "failed to execute the preceding response filter", h)
+				}
+			}
+
+			if n := atomic.LoadInt32(&backendCalls); n != 0 {
+				t.Error(ti.msg, "unexpected backend call", n)
+			}
+		}()
+	}
+}
@@ -0,0 +1,189 @@
+package builtin
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/zalando/skipper/filters"
+)
+
+const (
+	// DefaultRetryMaxBodyBytes is the size limit of the request body that
+	// the retry filter buffers for replaying, when not set in the
+	// arguments.
+	DefaultRetryMaxBodyBytes = 1 << 16
+
+	retryBodyStateKey = "body"
+)
+
+// the methods that are safe to repeat by default
+var defaultRetryMethods = []string{"GET", "HEAD", "PUT", "DELETE"}
+
+type retry struct {
+	times        int
+	delay        time.Duration
+	maxBodyBytes int64
+	methods      map[string]bool
+}
+
+// wraps the already buffered part of a body that was too large for
+// replaying, and the rest of the original body
+type partialBody struct {
+	io.Reader
+	body io.Closer
+}
+
+// Returns a filter specification whose instances repeat the request to
+// the backend, when it fails with a connection error or the backend
+// responds with a 5xx status code. The first argument is the maximum
+// number of the retries, the second one is the delay between them, as
+// a duration string.
+//
+// Example:
+//
+// 	* -> retry(3, "500ms") -> "https://www.example.org"
+//
+// By default, only the requests with the idempotent methods GET, HEAD,
+// PUT and DELETE are retried. To enable retrying other methods, too,
+// they can be set in the fourth, optional argument, as a comma
+// separated list, which replaces the default ones:
+//
+// 	* -> retry(3, "500ms", 65536, "GET,POST") -> "https://www.example.org"
+//
+// To be able to replay the request, the filter buffers the request body.
+// The size of the buffer is limited by the third, optional argument, in
+// bytes, defaulting to DefaultRetryMaxBodyBytes. Requests with a larger
+// body are forwarded once, without retrying. When the request times out,
+// e.g. due to the backendTimeout filter, it is not retried.
+//
+// When all the retries fail, the response of the last attempt is
+// returned to the client.
+//
+func NewRetry() filters.Spec { return &retry{} }
+
+func (r *retry) Name() string { return RetryName }
+
+func (r *retry) CreateFilter(args []interface{}) (filters.Filter, error) {
+	a := filters.NewArgs(RetryName, args)
+	if err := a.Count(2, 4); err != nil {
+		return nil, err
+	}
+
+	times, err := a.Int(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.InRange(0, float64(times), 1, math.MaxInt32); err != nil {
+		return nil, err
+	}
+
+	delay, err := a.Duration(1)
+	if err != nil {
+		return nil, err
+	}
+
+	maxBodyBytes, err := a.OptionalInt(2, DefaultRetryMaxBodyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.InRange(2, float64(maxBodyBytes), 0, math.MaxInt32); err != nil {
+		return nil, err
+	}
+
+	methods, err := a.OptionalString(3, strings.Join(defaultRetryMethods, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	if delay < 0 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	f := &retry{
+		times:        times,
+		delay:        delay,
+		maxBodyBytes: int64(maxBodyBytes),
+		methods:      make(map[string]bool)}
+	for _, m := range strings.Split(methods, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			f.methods[strings.ToUpper(m)] = true
+		}
+	}
+
+	if len(f.methods) == 0 {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	return f, nil
+}
+
+// buffers the request body, when the request can be retried. When the
+// state is not set, the proxy doesn't retry the request.
+func (r *retry) Request(ctx filters.FilterContext) {
+	req := ctx.Request()
+	if !r.methods[req.Method] || req.ContentLength > r.maxBodyBytes {
+		return
+	}
+
+	var b []byte
+	if req.Body != nil {
+		var err error
+		b, err = ioutil.ReadAll(io.LimitReader(req.Body, r.maxBodyBytes+1))
+		if err != nil {
+			ctx.Serve(&http.Response{StatusCode: http.StatusBadRequest})
+			return
+		}
+
+		if int64(len(b)) > r.maxBodyBytes {
+			req.Body = &partialBody{
+				Reader: io.MultiReader(bytes.NewReader(b), req.Body),
+				body:   req.Body}
+			return
+		}
+
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+
+	filters.StateBagSet(ctx, RetryName, retryBodyStateKey, b)
+}
+
+func (r *retry) Response(filters.FilterContext) {}
+
+// RetryBackend is called by the proxy after every attempt to send the
+// request to the backend. It tells whether the request should be sent
+// again, and when yes, it waits for the configured delay, and resets the
+// request body. The attempt argument is the number of the retries made
+// before.
+func (r *retry) RetryBackend(ctx filters.FilterContext, attempt int, rsp *http.Response, err error) bool {
+	v, ok := filters.StateBagGet(ctx, RetryName, retryBodyStateKey)
+	if !ok || attempt >= r.times {
+		return false
+	}
+
+	if err == nil && rsp.StatusCode < http.StatusInternalServerError {
+		return false
+	}
+
+	req := ctx.Request()
+	select {
+	case <-time.After(r.delay):
+	case <-req.Context().Done():
+		return false
+	}
+
+	if b, _ := v.([]byte); b != nil {
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+
+	return true
+}
+
+func (b *partialBody) Close() error { return b.body.Close() }
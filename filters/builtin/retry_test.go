@@ -0,0 +1,235 @@
+package builtin
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/proxy/proxytest"
+)
+
+type retryBackend struct {
+	mx       sync.Mutex
+	statuses []int
+	bodies   []string
+}
+
+func (b *retryBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	b.bodies = append(b.bodies, string(body))
+	status := http.StatusOK
+	if len(b.bodies) <= len(b.statuses) {
+		status = b.statuses[len(b.bodies)-1]
+	}
+
+	w.WriteHeader(status)
+}
+
+func (b *retryBackend) requests() []string {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return b.bodies
+}
+
+func TestRetryArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg   string
+		args  []interface{}
+		valid bool
+	}{
+		{"no args", nil, false},
+		{"missing delay", []interface{}{3.0}, false},
+		{"zero retries", []interface{}{0.0, "10ms"}, false},
+		{"invalid delay", []interface{}{3.0, "soon"}, false},
+		{"negative delay", []interface{}{3.0, "-1s"}, false},
+		{"negative body size", []interface{}{3.0, "10ms", -1.0}, false},
+		{"no methods", []interface{}{3.0, "10ms", 1024.0, " , "}, false},
+		{"too many args", []interface{}{3.0, "10ms", 1024.0, "GET", "POST"}, false},
+		{"valid", []interface{}{3.0, "500ms"}, true},
+		{"valid with body size", []interface{}{3.0, "0s", 1024.0}, true},
+		{"valid with methods", []interface{}{3.0, "10ms", 1024.0, "get, POST"}, true},
+	} {
+		_, err := NewRetry().CreateFilter(ti.args)
+		if ti.valid && err != nil || !ti.valid && err == nil {
+			t.Error(ti.msg, "failed to validate args", err)
+		}
+	}
+}
+
+func TestRetryProxy(t *testing.T) {
+	for _, ti := range []struct {
+		msg      string
+		args     []interface{}
+		method   string
+		body     string
+		statuses []int
+		expected int
+		attempts int
+	}{{
+		msg:      "no retry on success",
+		args:     []interface{}{3.0, "1ms"},
+		method:   "GET",
+		expected: http.StatusOK,
+		attempts: 1,
+	}, {
+		msg:      "no retry on client error",
+		args:     []interface{}{3.0, "1ms"},
+		method:   "GET",
+		statuses: []int{http.StatusNotFound},
+		expected: http.StatusNotFound,
+		attempts: 1,
+	}, {
+		msg:      "retry on 503",
+		args:     []interface{}{3.0, "1ms"},
+		method:   "GET",
+		statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+		expected: http.StatusOK,
+		attempts: 3,
+	}, {
+		msg:      "retries exhausted",
+		args:     []interface{}{2.0, "1ms"},
+		method:   "GET",
+		statuses: []int{500, 502, 503, 504},
+		expected: http.StatusServiceUnavailable,
+		attempts: 3,
+	}, {
+		msg:      "no retry on POST by default",
+		args:     []interface{}{3.0, "1ms"},
+		method:   "POST",
+		body:     "foo",
+		statuses: []int{http.StatusServiceUnavailable},
+		expected: http.StatusServiceUnavailable,
+		attempts: 1,
+	}, {
+		msg:      "retry on POST when enabled",
+		args:     []interface{}{3.0, "1ms", 1024.0, "POST"},
+		method:   "POST",
+		body:     "foo",
+		statuses: []int{http.StatusServiceUnavailable},
+		expected: http.StatusOK,
+		attempts: 2,
+	}, {
+		msg:      "body replayed",
+		args:     []interface{}{3.0, "1ms"},
+		method:   "PUT",
+		body:     "foo bar baz",
+		statuses: []int{http.StatusInternalServerError, http.StatusBadGateway},
+		expected: http.StatusOK,
+		attempts: 3,
+	}, {
+		msg:      "no retry when the body is too large",
+		args:     []interface{}{3.0, "1ms", 4.0},
+		method:   "PUT",
+		body:     "foo bar baz",
+		statuses: []int{http.StatusInternalServerError},
+		expected: http.StatusInternalServerError,
+		attempts: 1,
+	}} {
+		backend := &retryBackend{statuses: ti.statuses}
+		s := httptest.NewServer(backend)
+
+		p := proxytest.New(MakeRegistry(), &eskip.Route{
+			Filters: []*eskip.Filter{{Name: RetryName, Args: ti.args}},
+			Backend: s.URL})
+
+		func() {
+			defer s.Close()
+			defer p.Close()
+
+			// using a reader of unknown length, to test the body sent
+			// with chunked encoding
+			req, err := http.NewRequest(ti.method, p.URL, ioutil.NopCloser(strings.NewReader(ti.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rsp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Error(ti.msg, err)
+				return
+			}
+
+			defer rsp.Body.Close()
+
+			if rsp.StatusCode != ti.expected {
+				t.Error(ti.msg, "invalid status code", rsp.StatusCode, ti.expected)
+			}
+
+			requests := backend.requests()
+			if len(requests) != ti.attempts {
+				t.Error(ti.msg, "invalid number of attempts", len(requests), ti.attempts)
+			}
+
+			for i, b := range requests {
+				if b != ti.body {
+					t.Error(ti.msg, "invalid body received in attempt", i, b, ti.body)
+				}
+			}
+		}()
+	}
+}
+
+func TestRetryConnectionError(t *testing.T) {
+	// reserving an address, then closing the server, to get connection
+	// errors
+	s := httptest.NewServer(http.NotFoundHandler())
+	url := s.URL
+	s.Close()
+
+	p := proxytest.New(MakeRegistry(), &eskip.Route{
+		Filters: []*eskip.Filter{{Name: RetryName, Args: []interface{}{2.0, "30ms"}}},
+		Backend: url})
+	defer p.Close()
+
+	start := time.Now()
+	rsp, err := http.Post(p.URL, "text/plain", bytes.NewBufferString("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusInternalServerError {
+		t.Error("invalid status code", rsp.StatusCode)
+	}
+
+	// POST is not retried by default
+	if time.Since(start) >= 30*time.Millisecond {
+		t.Error("unexpected retry")
+	}
+
+	req, err := http.NewRequest("GET", p.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start = time.Now()
+	rsp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusInternalServerError {
+		t.Error("invalid status code", rsp.StatusCode)
+	}
+
+	if time.Since(start) < 60*time.Millisecond {
+		t.Error("failed to retry with delay")
+	}
+}
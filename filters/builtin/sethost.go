@@ -0,0 +1,52 @@
+package builtin
+
+import (
+	"strings"
+
+	"github.com/zalando/skipper/filters"
+)
+
+type setHost string
+
+// Returns a filter specification whose instances set the Host header of
+// the outgoing request to the backend, e.g. for backends serving
+// multiple virtual hosts. It expects a single argument, the host name,
+// optionally with a port.
+//
+// Example:
+//
+// 	* -> setHost("internal.svc") -> "https://10.0.0.1:8443"
+//
+// The filter changes only the Host header received by the backend: the
+// connection, including the TLS server name, is still made to the host
+// of the backend address. It overrides the PreserveHost proxy flag, and
+// the preserveHost filter doesn't change the host set by it. It has the
+// same effect as setRequestHeader("Host", "internal.svc"), but it
+// validates the argument, and it doesn't change the Host header of the
+// request seen by the subsequent filters.
+//
+func NewSetHost() filters.Spec { return setHost("") }
+
+func (s setHost) Name() string { return SetHostName }
+
+func (s setHost) CreateFilter(args []interface{}) (filters.Filter, error) {
+	a := filters.NewArgs(SetHostName, args)
+	if err := a.Count(1, 1); err != nil {
+		return nil, err
+	}
+
+	host, err := a.String(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if host == "" || strings.ContainsAny(host, "/?#@ \t\r\n") {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	return setHost(host), nil
+}
+
+func (s setHost) Request(ctx filters.FilterContext) { ctx.SetOutgoingHost(string(s)) }
+
+func (s setHost) Response(filters.FilterContext) {}
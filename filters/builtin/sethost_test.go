@@ -0,0 +1,112 @@
+package builtin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters/filtertest"
+	"github.com/zalando/skipper/proxy"
+	"github.com/zalando/skipper/proxy/proxytest"
+)
+
+func TestSetHostArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg   string
+		args  []interface{}
+		valid bool
+	}{
+		{"no args", nil, false},
+		{"not a string", []interface{}{42.0}, false},
+		{"empty", []interface{}{""}, false},
+		{"url", []interface{}{"https://internal.svc"}, false},
+		{"with path", []interface{}{"internal.svc/foo"}, false},
+		{"too many args", []interface{}{"internal.svc", "foo"}, false},
+		{"valid", []interface{}{"internal.svc"}, true},
+		{"valid with port", []interface{}{"internal.svc:8080"}, true},
+	} {
+		_, err := NewSetHost().CreateFilter(ti.args)
+		if ti.valid && err != nil || !ti.valid && err == nil {
+			t.Error(ti.msg, "failed to validate args", err)
+		}
+	}
+}
+
+func TestSetHostRequest(t *testing.T) {
+	f, err := NewSetHost().CreateFilter([]interface{}{"internal.svc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := &filtertest.Context{
+		FRequest:      &http.Request{Host: "www.example.org"},
+		FOutgoingHost: "backend.example.org"}
+	f.Request(ctx)
+	if ctx.FOutgoingHost != "internal.svc" {
+		t.Error("failed to set the outgoing host", ctx.FOutgoingHost)
+	}
+
+	if ctx.FRequest.Host != "www.example.org" {
+		t.Error("unexpected change of the incoming host", ctx.FRequest.Host)
+	}
+}
+
+func TestSetHostProxy(t *testing.T) {
+	received := make(chan string, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Host
+	}))
+	defer backend.Close()
+
+	for _, ti := range []struct {
+		msg     string
+		flags   proxy.Flags
+		filters []*eskip.Filter
+	}{{
+		msg:     "set host",
+		filters: []*eskip.Filter{{Name: SetHostName, Args: []interface{}{"internal.svc"}}},
+	}, {
+		msg:     "overrides preserving the host",
+		flags:   proxy.PreserveHost,
+		filters: []*eskip.Filter{{Name: SetHostName, Args: []interface{}{"internal.svc"}}},
+	}, {
+		msg:   "not changed by the preserveHost filter",
+		flags: proxy.PreserveHost,
+		filters: []*eskip.Filter{
+			{Name: SetHostName, Args: []interface{}{"internal.svc"}},
+			{Name: PreserveHostName, Args: []interface{}{"false"}}},
+	}} {
+		p := proxytest.WithParams(MakeRegistry(), proxy.Params{
+			Flags:                ti.flags,
+			CloseIdleConnsPeriod: -time.Second,
+		}, &eskip.Route{Filters: ti.filters, Backend: backend.URL})
+
+		func() {
+			defer p.Close()
+
+			req, err := http.NewRequest("GET", p.URL, nil)
+			if err != nil {
+				t.Fatal(ti.msg, err)
+			}
+
+			req.Host = "www.example.org"
+			rsp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Error(ti.msg, err)
+				return
+			}
+
+			rsp.Body.Close()
+			if rsp.StatusCode != http.StatusOK {
+				t.Error(ti.msg, "unexpected status", rsp.StatusCode)
+				return
+			}
+
+			if h := <-received; h != "internal.svc" {
+				t.Error(ti.msg, "invalid host received by the backend", h)
+			}
+		}()
+	}
+}
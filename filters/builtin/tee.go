@@ -0,0 +1,166 @@
+package builtin
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/zalando/skipper/filters"
+)
+
+const (
+	defaultTeeMaxBufferSize = 1 << 20
+	teeTimeout              = 30 * time.Second
+)
+
+type teeSpec struct {
+	client *http.Client
+}
+
+type tee struct {
+	client        *http.Client
+	scheme        string
+	host          string
+	maxBufferSize int64
+}
+
+type teeBody struct {
+	io.Reader
+	io.Closer
+}
+
+// Returns a filter specification whose instances send a copy of the
+// incoming requests to a shadow backend, while the response from the
+// primary backend is returned to the client unchanged.
+//
+// Example:
+//
+// 	* -> teeRequest("https://shadow.example.org") -> "https://www.example.org"
+//
+// The copy keeps the method, the path, the query and the headers of the
+// request, while the scheme and the host are taken from the shadow address.
+// It is sent in a separate goroutine, and its response, or the error when
+// sending it, is discarded. The shadow backend has no effect on the
+// response to the client.
+//
+// To send the same content to both backends, the filter buffers the request
+// body. The size of the buffer is limited by the optional second argument,
+// in bytes, which defaults to 1MB. When the body is larger than the limit,
+// the request is only forwarded to the primary backend:
+//
+// 	* -> teeRequest("https://shadow.example.org", 65536) -> "https://www.example.org"
+//
+func NewTee() filters.Spec {
+	return &teeSpec{client: &http.Client{Timeout: teeTimeout}}
+}
+
+func (s *teeSpec) Name() string { return TeeName }
+
+func (s *teeSpec) CreateFilter(args []interface{}) (filters.Filter, error) {
+	a := filters.NewArgs(TeeName, args)
+	if err := a.Count(1, 2); err != nil {
+		return nil, err
+	}
+
+	address, err := a.String(0)
+	if err != nil {
+		return nil, err
+	}
+
+	maxBufferSize, err := a.OptionalInt(1, defaultTeeMaxBufferSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.InRange(1, float64(maxBufferSize), 0, math.MaxInt32); err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(address)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, filters.ErrInvalidFilterParameters
+	}
+
+	return &tee{
+		client:        s.client,
+		scheme:        u.Scheme,
+		host:          u.Host,
+		maxBufferSize: int64(maxBufferSize)}, nil
+}
+
+// reads the request body up to the buffer limit. When the body fits, it
+// returns its content, otherwise the body of the request is restored
+// without consuming it.
+func (t *tee) bufferBody(r *http.Request) ([]byte, bool) {
+	if r.Body == nil {
+		return nil, true
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, t.maxBufferSize+1))
+	if err != nil || int64(len(b)) > t.maxBufferSize {
+		r.Body = teeBody{io.MultiReader(bytes.NewReader(b), r.Body), r.Body}
+		return nil, false
+	}
+
+	r.Body = teeBody{bytes.NewReader(b), r.Body}
+	return b, true
+}
+
+func (t *tee) shadowRequest(r *http.Request, body []byte) (*http.Request, error) {
+	u := *r.URL
+	u.Scheme = t.scheme
+	u.Host = t.host
+
+	var br io.Reader
+	if body != nil {
+		br = bytes.NewReader(body)
+	}
+
+	sr, err := http.NewRequest(r.Method, u.String(), br)
+	if err != nil {
+		return nil, err
+	}
+
+	sr.Header = make(http.Header)
+	for k, v := range r.Header {
+		sr.Header[k] = append([]string(nil), v...)
+	}
+
+	sr.Host = t.host
+	return sr, nil
+}
+
+func (t *tee) send(r *http.Request) {
+	rsp, err := t.client.Do(r)
+	if err != nil {
+		log.Debug("failed to send request to shadow backend", err)
+		return
+	}
+
+	io.Copy(ioutil.Discard, rsp.Body)
+	rsp.Body.Close()
+}
+
+func (t *tee) Request(ctx filters.FilterContext) {
+	r := ctx.Request()
+	body, ok := t.bufferBody(r)
+	if !ok {
+		log.Debug("request body exceeds the buffer limit, not sent to shadow backend")
+		return
+	}
+
+	sr, err := t.shadowRequest(r, body)
+	if err != nil {
+		log.Error("failed to create shadow request", err)
+		return
+	}
+
+	go t.send(sr)
+}
+
+func (t *tee) Response(filters.FilterContext) {}
@@ -0,0 +1,172 @@
+package builtin
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/proxy/proxytest"
+)
+
+type shadowRequest struct {
+	method string
+	uri    string
+	host   string
+	header string
+	body   string
+}
+
+func TestTeeArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg   string
+		args  []interface{}
+		valid bool
+	}{{
+		"no args",
+		nil,
+		false,
+	}, {
+		"not a string",
+		[]interface{}{42.0},
+		false,
+	}, {
+		"no host",
+		[]interface{}{"/shadow"},
+		false,
+	}, {
+		"invalid buffer size",
+		[]interface{}{"https://shadow.example.org", "64"},
+		false,
+	}, {
+		"negative buffer size",
+		[]interface{}{"https://shadow.example.org", -1.0},
+		false,
+	}, {
+		"too many args",
+		[]interface{}{"https://shadow.example.org", 64.0, 64.0},
+		false,
+	}, {
+		"address only",
+		[]interface{}{"https://shadow.example.org"},
+		true,
+	}, {
+		"address and buffer size",
+		[]interface{}{"https://shadow.example.org", 64.0},
+		true,
+	}} {
+		_, err := NewTee().CreateFilter(ti.args)
+		if ti.valid && err != nil || !ti.valid && err == nil {
+			t.Error(ti.msg, "failed to validate args", err)
+		}
+	}
+}
+
+func TestTee(t *testing.T) {
+	for _, ti := range []struct {
+		msg        string
+		body       string
+		maxBuffer  float64
+		shadowDown bool
+		mirrored   bool
+	}{{
+		"mirrors request without body",
+		"",
+		64,
+		false,
+		true,
+	}, {
+		"mirrors request with body",
+		"Hello, world!",
+		64,
+		false,
+		true,
+	}, {
+		"does not mirror request with body over the limit",
+		"Hello, world!",
+		4,
+		false,
+		false,
+	}, {
+		"shadow error does not affect the response",
+		"Hello, world!",
+		64,
+		true,
+		false,
+	}} {
+		shadowRequests := make(chan shadowRequest, 1)
+		shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			shadowRequests <- shadowRequest{r.Method, r.RequestURI, r.Host, r.Header.Get("X-Test"), string(b)}
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("shadow"))
+		}))
+
+		shadowAddress := shadow.URL
+		if ti.shadowDown {
+			shadow.Close()
+		} else {
+			defer shadow.Close()
+		}
+
+		primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			w.Write(append([]byte("primary: "), b...))
+		}))
+		defer primary.Close()
+
+		p := proxytest.New(MakeRegistry(), &eskip.Route{
+			Filters: []*eskip.Filter{{Name: TeeName, Args: []interface{}{shadowAddress, ti.maxBuffer}}},
+			Backend: primary.URL})
+		defer p.Close()
+
+		req, err := http.NewRequest("POST", p.URL+"/foo?bar=baz", bytes.NewBufferString(ti.body))
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		req.Header.Set("X-Test", "test-value")
+
+		rsp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		b, err := ioutil.ReadAll(rsp.Body)
+		rsp.Body.Close()
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		if rsp.StatusCode != http.StatusOK || string(b) != "primary: "+ti.body {
+			t.Error(ti.msg, "invalid response", rsp.StatusCode, string(b))
+			continue
+		}
+
+		if !ti.mirrored {
+			select {
+			case <-shadowRequests:
+				t.Error(ti.msg, "unexpected shadow request")
+			case <-time.After(30 * time.Millisecond):
+			}
+
+			continue
+		}
+
+		select {
+		case sr := <-shadowRequests:
+			expected := shadowRequest{"POST", "/foo?bar=baz", shadow.Listener.Addr().String(), "test-value", ti.body}
+			if sr != expected {
+				t.Error(ti.msg, "invalid shadow request", sr, expected)
+			}
+		case <-time.After(3 * time.Second):
+			t.Error(ti.msg, "timeout waiting for the shadow request")
+		}
+	}
+}
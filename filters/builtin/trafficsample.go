@@ -0,0 +1,80 @@
+package builtin
+
+import (
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/internal/sample"
+)
+
+const trafficSampleStateKey = "sampled"
+
+type trafficSample struct {
+	fraction float64
+	header   string
+}
+
+// Returns a filter specification whose instances mark a fraction of the
+// requests as sampled, storing the decision in the state bag, where the
+// filters executed later can read it with IsSampled. It makes it
+// possible for multiple filters, e.g. tracing and metrics, to act on the
+// same sample of the requests.
+//
+// The first argument is the sampled fraction of the requests, between 0
+// and 1. The second, optional argument is the name of a header, e.g.
+// X-Request-Id, whose value makes the decision sticky, the same way as
+// the header argument of the Traffic predicate.
+//
+// Example:
+//
+// 	* -> trafficSample(0.05) -> "https://www.example.org";
+// 	* -> requestId() -> trafficSample(0.05, "X-Request-Id") -> "https://www.example.org";
+//
+func NewTrafficSample() filters.Spec { return &trafficSample{} }
+
+func (s *trafficSample) Name() string { return TrafficSampleName }
+
+func (s *trafficSample) CreateFilter(args []interface{}) (filters.Filter, error) {
+	a := filters.NewArgs(TrafficSampleName, args)
+	if err := a.Count(1, 2); err != nil {
+		return nil, err
+	}
+
+	fraction, err := a.Float(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.InRange(0, fraction, 0, 1); err != nil {
+		return nil, err
+	}
+
+	header, err := a.OptionalString(1, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &trafficSample{fraction: fraction, header: header}, nil
+}
+
+func (s *trafficSample) sample(ctx filters.FilterContext) bool {
+	var key string
+	if s.header != "" {
+		key = ctx.Request().Header.Get(s.header)
+	}
+
+	return sample.Selected(key, s.fraction)
+}
+
+func (s *trafficSample) Request(ctx filters.FilterContext) {
+	filters.StateBagSet(ctx, TrafficSampleName, trafficSampleStateKey, s.sample(ctx))
+}
+
+func (s *trafficSample) Response(filters.FilterContext) {}
+
+// IsSampled tells whether the request was marked as sampled by the
+// trafficSample filter. It returns false, when the filter was not
+// executed for the request.
+func IsSampled(ctx filters.FilterContext) bool {
+	v, _ := filters.StateBagGet(ctx, TrafficSampleName, trafficSampleStateKey)
+	sampled, _ := v.(bool)
+	return sampled
+}
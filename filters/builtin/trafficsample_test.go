@@ -0,0 +1,118 @@
+package builtin
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+)
+
+func TestTrafficSampleArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg   string
+		args  []interface{}
+		valid bool
+	}{
+		{"no args", nil, false},
+		{"not a number", []interface{}{"0.05"}, false},
+		{"negative", []interface{}{-0.05}, false},
+		{"greater than one", []interface{}{1.5}, false},
+		{"header not a string", []interface{}{0.05, 42.0}, false},
+		{"too many args", []interface{}{0.05, "X-Request-Id", "foo"}, false},
+		{"valid", []interface{}{0.05}, true},
+		{"valid with header", []interface{}{0.05, "X-Request-Id"}, true},
+		{"valid, none", []interface{}{0.0}, true},
+		{"valid, all", []interface{}{1.0}, true},
+	} {
+		_, err := NewTrafficSample().CreateFilter(ti.args)
+		if ti.valid && err != nil || !ti.valid && err == nil {
+			t.Error(ti.msg, "failed to validate args", err)
+		}
+	}
+}
+
+func sampleRequest(t *testing.T, f filters.Filter, id string) bool {
+	req, err := http.NewRequest("GET", "https://www.example.org", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id != "" {
+		req.Header.Set("X-Request-Id", id)
+	}
+
+	ctx := &filtertest.Context{FRequest: req, FStateBag: make(map[string]interface{})}
+	f.Request(ctx)
+	return IsSampled(ctx)
+}
+
+func TestTrafficSampleFraction(t *testing.T) {
+	const n = 20000
+	for _, ti := range []struct {
+		msg      string
+		fraction float64
+		header   bool
+	}{
+		{"random, none", 0, false},
+		{"random", 0.05, false},
+		{"random, half", 0.5, false},
+		{"random, all", 1, false},
+		{"by header, none", 0, true},
+		{"by header", 0.05, true},
+		{"by header, half", 0.5, true},
+		{"by header, all", 1, true},
+	} {
+		args := []interface{}{ti.fraction}
+		if ti.header {
+			args = append(args, "X-Request-Id")
+		}
+
+		f, err := NewTrafficSample().CreateFilter(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var sampled int
+		for i := 0; i < n; i++ {
+			var id string
+			if ti.header {
+				id = fmt.Sprintf("request-%d", i)
+			}
+
+			if sampleRequest(t, f, id) {
+				sampled++
+			}
+		}
+
+		if d := math.Abs(float64(sampled)/n - ti.fraction); d > 0.02 {
+			t.Error(ti.msg, "failed to sample the expected fraction", float64(sampled)/n)
+		}
+	}
+}
+
+func TestTrafficSampleDeterministic(t *testing.T) {
+	f, err := NewTrafficSample().CreateFilter([]interface{}{0.5, "X-Request-Id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("request-%d", i)
+		first := sampleRequest(t, f, id)
+		for j := 0; j < 10; j++ {
+			if sampleRequest(t, f, id) != first {
+				t.Error("failed to sample deterministically", id)
+				break
+			}
+		}
+	}
+}
+
+func TestNotSampledWithoutFilter(t *testing.T) {
+	if IsSampled(&filtertest.Context{FStateBag: make(map[string]interface{})}) {
+		t.Error("failed to report not sampled")
+	}
+}
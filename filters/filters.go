@@ -46,12 +46,21 @@ type FilterContext interface {
 	// from continuing
 	Serve(*http.Response)
 
+	// Rejects the matched route. FilterContext implementations should stop the filter chain, and
+	// continue processing the request with the next route matching it, if any.
+	RejectRoute()
+
 	// Provides the wildcard parameter values from the request path by their
 	// name as the key.
 	PathParam(string) string
 
 	// Provides a read-write state bag, unique to a request and shared by all
-	// the filters in the route.
+	// the filters in the route. The state bag is created empty for every
+	// incoming request, and it is discarded when the response was sent, so
+	// it can be used to pass data from a filter to the subsequent ones, e.g.
+	// from the request phase of a filter to its response phase, but not
+	// between requests. To avoid collisions between the keys used by
+	// unrelated filters, see StateBagSet and StateBagGet.
 	StateBag() map[string]interface{}
 
 	// Gives filters access to the backend url specified in the route or an empty
@@ -97,7 +106,8 @@ type Spec interface {
 	Name() string
 
 	// Creates a Filter instance. Called with the parameters in the route
-	// definition while initializing a route.
+	// definition while initializing a route. When it returns neither a
+	// filter nor an error, the route is rejected.
 	CreateFilter(config []interface{}) (Filter, error)
 }
 
@@ -23,6 +23,7 @@ import (
 	"github.com/zalando/skipper/routing"
 	"github.com/zalando/skipper/routing/testdataclient"
 	"log"
+	"net/http/httptest"
 )
 
 type customFilter struct{}
@@ -69,3 +70,46 @@ func ExampleContext() {
 	// Output:
 	// true
 }
+
+func ExampleRecorder() {
+	// create recording filters sharing the same recorder:
+	recorder := &filtertest.Recorder{}
+	fr := make(filters.Registry)
+	fr.Register(recorder.Spec("first"))
+	fr.Register(recorder.Spec("second"))
+	fr.Register(recorder.Spec("third"))
+
+	// create a route using the filters:
+	dc, err := testdataclient.NewDoc(`* -> first() -> second() -> third() -> <shunt>`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// create routing object, and wait for the route:
+	updates := make(chan routing.RouteUpdate, 1)
+	rt := routing.New(routing.Options{
+		DataClients:       []routing.DataClient{dc},
+		FilterRegistry:    fr,
+		SignalRouteUpdate: updates})
+	defer rt.Close()
+	<-updates
+
+	// make a request through the proxy:
+	p := proxy.New(rt, proxy.OptionsNone)
+	defer p.Close()
+	p.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "https://www.example.org", nil))
+
+	// the request filters run in the order of their declaration, the
+	// response filters in reverse order:
+	for _, c := range recorder.Calls() {
+		fmt.Println(c.Filter, c.Phase)
+	}
+
+	// Output:
+	// first request
+	// second request
+	// third request
+	// third response
+	// second response
+	// first response
+}
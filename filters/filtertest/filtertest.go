@@ -21,6 +21,7 @@ package filtertest
 import (
 	"github.com/zalando/skipper/filters"
 	"net/http"
+	"sync"
 )
 
 // Noop filter, used to verify the filter name and the args in the route.
@@ -37,6 +38,7 @@ type Context struct {
 	FResponse           *http.Response
 	FServed             bool
 	FServedWithResponse bool
+	FRejected           bool
 	FParams             map[string]string
 	FStateBag           map[string]interface{}
 	FBackendUrl         string
@@ -59,6 +61,7 @@ func (fc *Context) OriginalResponse() *http.Response    { return nil }
 func (fc *Context) BackendUrl() string                  { return fc.FBackendUrl }
 func (fc *Context) OutgoingHost() string                { return fc.FOutgoingHost }
 func (fc *Context) SetOutgoingHost(h string)            { fc.FOutgoingHost = h }
+func (fc *Context) RejectRoute()                        { fc.FRejected = true }
 func (fc *Context) Serve(resp *http.Response) {
 	fc.FServedWithResponse = true
 	fc.FResponse = resp
@@ -68,3 +71,70 @@ func (fc *Context) Serve(resp *http.Response) {
 func (spec *Filter) CreateFilter(config []interface{}) (filters.Filter, error) {
 	return &Filter{spec.FilterName, config}, nil
 }
+
+// Phase of a filter call.
+type Phase string
+
+const (
+	RequestPhase  Phase = "request"
+	ResponsePhase Phase = "response"
+)
+
+// Call is a filter call observed by a Recorder.
+type Call struct {
+	Filter string
+	Phase  Phase
+}
+
+// Recorder records the calls of the filters created by its specs, in the
+// order they happened. It can be shared by multiple filter specs, to
+// verify the order of the calls of the filters in a route.
+type Recorder struct {
+	mx    sync.Mutex
+	calls []Call
+}
+
+type recordingFilter struct {
+	name     string
+	recorder *Recorder
+}
+
+// Creates a filter spec with the provided name, whose filter instances
+// record their calls.
+func (r *Recorder) Spec(name string) filters.Spec {
+	return &recordingFilter{name, r}
+}
+
+func (r *Recorder) record(name string, phase Phase) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.calls = append(r.calls, Call{name, phase})
+}
+
+// Returns the calls recorded so far.
+func (r *Recorder) Calls() []Call {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// Clears the recorded calls.
+func (r *Recorder) Reset() {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.calls = nil
+}
+
+func (f *recordingFilter) Name() string { return f.name }
+
+func (f *recordingFilter) CreateFilter(config []interface{}) (filters.Filter, error) {
+	return f, nil
+}
+
+func (f *recordingFilter) Request(ctx filters.FilterContext) {
+	f.recorder.record(f.name, RequestPhase)
+}
+
+func (f *recordingFilter) Response(ctx filters.FilterContext) {
+	f.recorder.record(f.name, ResponsePhase)
+}
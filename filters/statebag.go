@@ -0,0 +1,35 @@
+package filters
+
+// StateBagKey returns the key of a value in the state bag, namespaced by
+// the name of the filter that owns it, e.g. "filter::jwt::claims".
+func StateBagKey(filterName, key string) string {
+	return "filter::" + filterName + "::" + key
+}
+
+// StateBagSet stores a value in the state bag of the request, under the
+// key namespaced by the filter name. Other filters can read the value
+// with StateBagGet, using the same filter name and key.
+func StateBagSet(ctx FilterContext, filterName, key string, value interface{}) {
+	ctx.StateBag()[StateBagKey(filterName, key)] = value
+}
+
+// StateBagGet returns a value from the state bag of the request, stored
+// by StateBagSet with the same filter name and key. The second return
+// value is false, when the value was not set.
+func StateBagGet(ctx FilterContext, filterName, key string) (interface{}, bool) {
+	v, ok := ctx.StateBag()[StateBagKey(filterName, key)]
+	return v, ok
+}
+
+// StateBagGetString returns a string value from the state bag of the
+// request, stored by StateBagSet. The second return value is false,
+// when the value was not set, or it is not a string.
+func StateBagGetString(ctx FilterContext, filterName, key string) (string, bool) {
+	v, ok := StateBagGet(ctx, filterName, key)
+	if !ok {
+		return "", false
+	}
+
+	s, ok := v.(string)
+	return s, ok
+}
@@ -0,0 +1,140 @@
+package filters_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+	"github.com/zalando/skipper/proxy/proxytest"
+)
+
+const (
+	writerName = "stateWriter"
+	readerName = "stateReader"
+)
+
+type stateWriter struct{ value string }
+
+type stateReader struct{}
+
+func (w stateWriter) Request(ctx filters.FilterContext) {
+	filters.StateBagSet(ctx, writerName, "value", w.value)
+}
+
+func (w stateWriter) Response(filters.FilterContext) {}
+
+func (r stateReader) Request(ctx filters.FilterContext) {
+	if v, ok := filters.StateBagGetString(ctx, writerName, "value"); ok {
+		ctx.Request().Header.Set("X-State", v)
+	}
+}
+
+func (r stateReader) Response(filters.FilterContext) {}
+
+func TestStateBagSharedBetweenFilters(t *testing.T) {
+	ctx := &filtertest.Context{
+		FRequest:  &http.Request{Header: make(http.Header)},
+		FStateBag: make(map[string]interface{})}
+
+	stateWriter{"foo"}.Request(ctx)
+	stateReader{}.Request(ctx)
+
+	if v := ctx.FRequest.Header.Get("X-State"); v != "foo" {
+		t.Error("failed to read the value set by the other filter", v)
+	}
+}
+
+func TestStateBagNamespaces(t *testing.T) {
+	ctx := &filtertest.Context{FStateBag: make(map[string]interface{})}
+
+	filters.StateBagSet(ctx, writerName, "value", "foo")
+	filters.StateBagSet(ctx, readerName, "value", 42)
+
+	if v, ok := filters.StateBagGetString(ctx, writerName, "value"); !ok || v != "foo" {
+		t.Error("failed to get the value", v, ok)
+	}
+
+	if v, ok := filters.StateBagGet(ctx, readerName, "value"); !ok || v != 42 {
+		t.Error("failed to get the value of the other namespace", v, ok)
+	}
+
+	if _, ok := filters.StateBagGetString(ctx, readerName, "value"); ok {
+		t.Error("failed to detect the type mismatch")
+	}
+
+	if _, ok := filters.StateBagGet(ctx, writerName, "missing"); ok {
+		t.Error("unexpected value")
+	}
+}
+
+type stateSpec struct {
+	name   string
+	filter filters.Filter
+}
+
+func (s stateSpec) Name() string { return s.name }
+
+func (s stateSpec) CreateFilter([]interface{}) (filters.Filter, error) { return s.filter, nil }
+
+// writes the state only when the request has the X-Write header
+type conditionalWriter struct{}
+
+func (w conditionalWriter) Request(ctx filters.FilterContext) {
+	if v := ctx.Request().Header.Get("X-Write"); v != "" {
+		filters.StateBagSet(ctx, writerName, "value", v)
+	}
+}
+
+func (w conditionalWriter) Response(filters.FilterContext) {}
+
+func TestStateBagClearedPerRequest(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-State")))
+	}))
+	defer backend.Close()
+
+	fr := make(filters.Registry)
+	fr.Register(stateSpec{writerName, conditionalWriter{}})
+	fr.Register(stateSpec{readerName, stateReader{}})
+
+	p := proxytest.New(fr, &eskip.Route{
+		Filters: []*eskip.Filter{{Name: writerName}, {Name: readerName}},
+		Backend: backend.URL})
+	defer p.Close()
+
+	get := func(write string) string {
+		req, err := http.NewRequest("GET", p.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if write != "" {
+			req.Header.Set("X-Write", write)
+		}
+
+		rsp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer rsp.Body.Close()
+		b, err := ioutil.ReadAll(rsp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return string(b)
+	}
+
+	if s := get("foo"); s != "foo" {
+		t.Error("failed to pass the state", s)
+	}
+
+	if s := get(""); s != "" {
+		t.Error("failed to clear the state", s)
+	}
+}
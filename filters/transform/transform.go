@@ -0,0 +1,75 @@
+/*
+Package transform provides a helper for filters that modify the body of
+the responses while it is streamed to the client.
+*/
+package transform
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/zalando/skipper/filters"
+)
+
+// Func transforms the body of a response, by reading the original body
+// from the reader, and writing the transformed body to the writer. It
+// should write the output as soon as possible, to preserve streaming.
+// When it returns a non-nil error, the transformed body is terminated
+// with that error, and the client receives a truncated response.
+type Func func(w io.Writer, r io.Reader) error
+
+// Replaces the body of the response with the output of a transform
+// function.
+//
+// The transform function is executed in a separate goroutine, and its
+// output is piped to the new response body, without buffering. The
+// original body is closed when the transform function returns.
+//
+// Since the length of the transformed body is unknown, the
+// Content-Length header is removed, and the response is sent with
+// chunked transfer encoding. The Content-Encoding header is not
+// changed: the transform function receives the body as it was returned
+// by the backend, e.g. gzip compressed, and decoding and encoding it is
+// the responsibility of the transform function. Filters that only
+// handle plain bodies should skip the encoded responses.
+//
+// Example, a filter replacing a string in the response body:
+//
+// 	func (f *replace) Response(ctx filters.FilterContext) {
+// 		if ctx.Response().Header.Get("Content-Encoding") != "" {
+// 			return
+// 		}
+//
+// 		transform.ResponseBody(ctx, f.replaceStream)
+// 	}
+//
+func ResponseBody(ctx filters.FilterContext, t Func) {
+	Response(ctx.Response(), t)
+}
+
+// Replaces the body of a response with the output of a transform
+// function. See ResponseBody.
+func Response(rsp *http.Response, t Func) {
+	in := rsp.Body
+	if in == nil {
+		in = ioutil.NopCloser(strings.NewReader(""))
+	}
+
+	r, w := io.Pipe()
+
+	rsp.Body = r
+	rsp.ContentLength = -1
+	rsp.Header.Del("Content-Length")
+
+	go func() {
+		err := t(w, in)
+		in.Close()
+		if err == nil {
+			err = io.EOF
+		}
+
+		w.CloseWithError(err)
+	}()
+}
@@ -0,0 +1,186 @@
+package transform
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/filters/filtertest"
+)
+
+const testTimeout = 120 * time.Millisecond
+
+// replaces a string in the stream, keeping only the bytes that can
+// still be the beginning of a match
+func replace(old, new string) Func {
+	return func(w io.Writer, r io.Reader) error {
+		var pending []byte
+		b := make([]byte, 1024)
+		for {
+			n, err := r.Read(b)
+			pending = append(pending, b[:n]...)
+			pending = bytes.Replace(pending, []byte(old), []byte(new), -1)
+
+			keep := len(old) - 1
+			if err != nil || keep > len(pending) {
+				keep = 0
+				if err == nil {
+					keep = len(pending)
+				}
+			}
+
+			if _, werr := w.Write(pending[:len(pending)-keep]); werr != nil {
+				return werr
+			}
+
+			pending = append([]byte(nil), pending[len(pending)-keep:]...)
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestReplace(t *testing.T) {
+	for _, ti := range []struct {
+		msg      string
+		chunks   []string
+		expected string
+	}{{
+		msg:      "empty",
+		expected: "",
+	}, {
+		msg:      "no match",
+		chunks:   []string{"<html><head>", "</head></html>"},
+		expected: "<html><head></head></html>",
+	}, {
+		msg:      "match in a chunk",
+		chunks:   []string{"<html><body>", "foo</body>", "</html>"},
+		expected: "<html><body>foo<script></script></body></html>",
+	}, {
+		msg:      "match across chunks",
+		chunks:   []string{"<html><body>foo</bo", "dy></html>"},
+		expected: "<html><body>foo<script></script></body></html>",
+	}, {
+		msg:      "multiple matches",
+		chunks:   []string{"</body>", "</", "body>"},
+		expected: "<script></script></body><script></script></body>",
+	}} {
+		r, w := io.Pipe()
+		go func() {
+			for _, c := range ti.chunks {
+				w.Write([]byte(c))
+			}
+
+			w.Close()
+		}()
+
+		body := &closeRecorder{Reader: r}
+		rsp := &http.Response{
+			Header:        http.Header{"Content-Length": []string{"42"}},
+			ContentLength: 42,
+			Body:          body}
+		ResponseBody(&filtertest.Context{FResponse: rsp}, replace("</body>", "<script></script></body>"))
+
+		if rsp.ContentLength != -1 || rsp.Header.Get("Content-Length") != "" {
+			t.Error(ti.msg, "failed to remove the content length")
+		}
+
+		b, err := ioutil.ReadAll(rsp.Body)
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		if string(b) != ti.expected {
+			t.Error(ti.msg, "invalid body", string(b))
+		}
+
+		if !body.closed {
+			t.Error(ti.msg, "failed to close the original body")
+		}
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+
+	rsp := &http.Response{Header: make(http.Header), Body: r}
+	Response(rsp, replace("foo", "bar"))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Write([]byte("foo baz"))
+	}()
+
+	// reading the transformed first chunk, while the original body
+	// is still open
+	read := make(chan string)
+	go func() {
+		b := make([]byte, 64)
+		n, _ := rsp.Body.Read(b)
+		read <- string(b[:n])
+	}()
+
+	select {
+	case s := <-read:
+		if s != "bar b" && s != "bar baz" {
+			t.Error("invalid chunk", s)
+		}
+	case <-time.After(testTimeout):
+		t.Error("failed to stream the body")
+	}
+
+	<-done
+}
+
+func TestTransformError(t *testing.T) {
+	testErr := errors.New("test error")
+	rsp := &http.Response{
+		Header: make(http.Header),
+		Body:   ioutil.NopCloser(bytes.NewBufferString("foo"))}
+	Response(rsp, func(w io.Writer, r io.Reader) error {
+		w.Write([]byte("f"))
+		return testErr
+	})
+
+	b, err := ioutil.ReadAll(rsp.Body)
+	if err != testErr {
+		t.Error("failed to pass on the error", err)
+	}
+
+	if string(b) != "f" {
+		t.Error("invalid body", string(b))
+	}
+}
+
+func TestNoBody(t *testing.T) {
+	rsp := &http.Response{Header: make(http.Header)}
+	Response(rsp, func(w io.Writer, r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+
+	b, err := ioutil.ReadAll(rsp.Body)
+	if err != nil || len(b) != 0 {
+		t.Error("failed to handle the missing body", err, string(b))
+	}
+}
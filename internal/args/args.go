@@ -0,0 +1,190 @@
+/*
+Package args implements the typed access to the arguments of the
+filters and the predicates. It is used through filters.Args and
+predicates.Args, which set the error that the returned errors wrap.
+*/
+package args
+
+import (
+	"fmt"
+	"time"
+)
+
+// Args wraps the arguments of a filter or a predicate, and provides
+// typed access to them. The returned errors wrap the error passed to
+// New, and contain the name of the filter or predicate and the index
+// of the invalid argument.
+type Args struct {
+	err  error
+	name string
+	args []interface{}
+}
+
+// New creates an Args object for the arguments of the filter or
+// predicate with the given name. The returned errors wrap err, so that
+// the callers can check them with errors.Is.
+func New(err error, name string, args []interface{}) Args {
+	return Args{err: err, name: name, args: args}
+}
+
+func (a Args) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s: %s", a.err, a.name, fmt.Sprintf(format, args...))
+}
+
+func (a Args) argErrorf(i int, format string, args ...interface{}) error {
+	return a.errorf("argument %d: %s", i, fmt.Sprintf(format, args...))
+}
+
+// Len returns the number of arguments.
+func (a Args) Len() int { return len(a.args) }
+
+// Count returns an error when the number of arguments is less than
+// min or more than max. A negative max means no upper limit.
+func (a Args) Count(min, max int) error {
+	n := len(a.args)
+	switch {
+	case n >= min && (max < 0 || n <= max):
+		return nil
+	case min == max:
+		return a.errorf("expected %d arguments, got %d", min, n)
+	case max < 0:
+		return a.errorf("expected at least %d arguments, got %d", min, n)
+	default:
+		return a.errorf("expected %d to %d arguments, got %d", min, max, n)
+	}
+}
+
+func (a Args) get(i int) (interface{}, error) {
+	if i < 0 || i >= len(a.args) {
+		return nil, a.argErrorf(i, "missing")
+	}
+
+	return a.args[i], nil
+}
+
+// String returns the argument at index i when it is a string.
+func (a Args) String(i int) (string, error) {
+	v, err := a.get(i)
+	if err != nil {
+		return "", err
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", a.argErrorf(i, "expected string, got %T", v)
+	}
+
+	return s, nil
+}
+
+// Int returns the argument at index i when it is a whole number.
+func (a Args) Int(i int) (int, error) {
+	v, err := a.get(i)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case float64:
+		if n != float64(int(n)) {
+			return 0, a.argErrorf(i, "expected integer, got %v", n)
+		}
+
+		return int(n), nil
+	default:
+		return 0, a.argErrorf(i, "expected integer, got %T", v)
+	}
+}
+
+// Float returns the argument at index i when it is a number.
+func (a Args) Float(i int) (float64, error) {
+	v, err := a.get(i)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n := v.(type) {
+	case int:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	default:
+		return 0, a.argErrorf(i, "expected number, got %T", v)
+	}
+}
+
+// Duration returns the argument at index i when it is a string in
+// the format accepted by time.ParseDuration, e.g. "1m30s", or, for the
+// routes created in code, a time.Duration value.
+func (a Args) Duration(i int) (time.Duration, error) {
+	v, err := a.get(i)
+	if err != nil {
+		return 0, err
+	}
+
+	switch d := v.(type) {
+	case time.Duration:
+		return d, nil
+	case string:
+		pd, err := time.ParseDuration(d)
+		if err != nil {
+			return 0, a.argErrorf(i, "invalid duration: %q", d)
+		}
+
+		return pd, nil
+	default:
+		return 0, a.argErrorf(i, "expected duration string, got %T", v)
+	}
+}
+
+// OptionalString returns def when there is no argument at index i,
+// otherwise the same as String.
+func (a Args) OptionalString(i int, def string) (string, error) {
+	if i >= len(a.args) {
+		return def, nil
+	}
+
+	return a.String(i)
+}
+
+// OptionalInt returns def when there is no argument at index i,
+// otherwise the same as Int.
+func (a Args) OptionalInt(i int, def int) (int, error) {
+	if i >= len(a.args) {
+		return def, nil
+	}
+
+	return a.Int(i)
+}
+
+// OptionalFloat returns def when there is no argument at index i,
+// otherwise the same as Float.
+func (a Args) OptionalFloat(i int, def float64) (float64, error) {
+	if i >= len(a.args) {
+		return def, nil
+	}
+
+	return a.Float(i)
+}
+
+// OptionalDuration returns def when there is no argument at index i,
+// otherwise the same as Duration.
+func (a Args) OptionalDuration(i int, def time.Duration) (time.Duration, error) {
+	if i >= len(a.args) {
+		return def, nil
+	}
+
+	return a.Duration(i)
+}
+
+// InRange returns an error for the argument at index i when its value,
+// v, is not between min and max (inclusive).
+func (a Args) InRange(i int, v, min, max float64) error {
+	if v < min || v > max {
+		return a.argErrorf(i, "expected value between %v and %v, got %v", min, max, v)
+	}
+
+	return nil
+}
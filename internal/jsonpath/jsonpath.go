@@ -0,0 +1,55 @@
+/*
+Package jsonpath implements the dot separated paths used by the
+predicates to reference a field in a decoded JSON document, e.g.
+"realm.role".
+*/
+package jsonpath
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Path references a field, through the nested JSON objects.
+type Path []string
+
+// Parse parses a dot separated path. It returns false when the path
+// contains an empty segment.
+func Parse(s string) (Path, bool) {
+	p := Path(strings.Split(s, "."))
+	for _, pi := range p {
+		if pi == "" {
+			return nil, false
+		}
+	}
+
+	return p, true
+}
+
+// Lookup returns the field referenced by the path in v, as decoded by
+// encoding/json, as a string. Strings are returned as they are, while
+// numbers and booleans in their JSON representation. It returns false
+// when the field doesn't exist, or it is an object, an array or null.
+func (p Path) Lookup(v interface{}) (string, bool) {
+	for _, pi := range p {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+
+		if v, ok = m[pi]; !ok {
+			return "", false
+		}
+	}
+
+	switch vt := v.(type) {
+	case string:
+		return vt, true
+	case float64:
+		return strconv.FormatFloat(vt, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(vt), true
+	default:
+		return "", false
+	}
+}
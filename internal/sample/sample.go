@@ -0,0 +1,43 @@
+/*
+Package sample implements the selection of a share of the requests,
+used by the Traffic predicate and by the trafficSample filter.
+
+When a request has a key, e.g. the value of a user id header, it is
+selected by the hash of the key, so the requests with the same key are
+either all selected or all not selected. The requests without a key are
+selected randomly.
+*/
+package sample
+
+import (
+	"hash/fnv"
+	"math/rand"
+)
+
+// maps the key to a number in [0, 1), evenly distributed. The FNV hash
+// of similar keys, like sequential ids, differs mostly in the low bits,
+// so the result is mixed with the finalizer of MurmurHash3.
+func hashFraction(key string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+
+	return float64(x>>11) / (1 << 53)
+}
+
+// Selected tells whether a request with the given key falls into the
+// share of the requests, between 0 and 1. When the key is empty, the
+// request is selected randomly.
+func Selected(key string, share float64) bool {
+	if key != "" {
+		return hashFraction(key) < share
+	}
+
+	return rand.Float64() < share
+}
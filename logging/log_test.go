@@ -51,3 +51,35 @@ func TestDisableAccessLog(t *testing.T) {
 		t.Error("failed to disable access log")
 	}
 }
+
+func TestNoopLogger(t *testing.T) {
+	var l Logger = NoopLogger{}
+	if !Discards(l) || !Discards(&NoopLogger{}) {
+		t.Error("failed to detect the noop logger")
+	}
+
+	if Discards(&DefaultLog{}) {
+		t.Error("unexpected noop logger")
+	}
+
+	var buf bytes.Buffer
+	Init(Options{ApplicationLogOutput: &buf})
+	allocs := testing.AllocsPerRun(100, func() {
+		l.Error("Hello, world!")
+		l.Errorf("Hello, %s!", "world")
+		l.Warn("Hello, world!")
+		l.Warnf("Hello, %s!", "world")
+		l.Info("Hello, world!")
+		l.Infof("Hello, %s!", "world")
+		l.Debug("Hello, world!")
+		l.Debugf("Hello, %s!", "world")
+	})
+
+	if allocs != 0 {
+		t.Error("unexpected allocations", allocs)
+	}
+
+	if buf.Len() != 0 {
+		t.Error("failed to discard the entries", buf.String())
+	}
+}
@@ -33,6 +33,26 @@ type Logger interface {
 	Debugf(string, ...interface{})
 }
 
+// Fields are key/value pairs attached to the structured log entries.
+type Fields map[string]interface{}
+
+// FieldLogger is an optional extension of the Logger interface, for
+// loggers supporting structured log entries. Where available, the
+// packages of skipper use it to attach details to the log messages,
+// e.g. the routing attaches the counts of the changed routes to the
+// message about an applied routing table.
+type FieldLogger interface {
+	Logger
+
+	// Returns a logger that attaches the fields to every entry logged
+	// with it.
+	WithFields(Fields) Logger
+}
+
+// WithFields returns a logger that attaches the fields to every entry,
+// as logrus fields.
+func (dl *DefaultLog) WithFields(f Fields) Logger { return logrus.WithFields(logrus.Fields(f)) }
+
 func (dl *DefaultLog) Error(a ...interface{})            { logrus.Error(a...) }
 func (dl *DefaultLog) Errorf(f string, a ...interface{}) { logrus.Errorf(f, a...) }
 func (dl *DefaultLog) Warn(a ...interface{})             { logrus.Warn(a...) }
@@ -41,3 +61,29 @@ func (dl *DefaultLog) Info(a ...interface{})             { logrus.Info(a...) }
 func (dl *DefaultLog) Infof(f string, a ...interface{})  { logrus.Infof(f, a...) }
 func (dl *DefaultLog) Debug(a ...interface{})            { logrus.Debug(a...) }
 func (dl *DefaultLog) Debugf(f string, a ...interface{}) { logrus.Debugf(f, a...) }
+
+// NoopLogger is an implementation of the Logger interface that discards
+// every entry, e.g. for benchmarks. Skipper packages receiving a
+// NoopLogger, like the routing, skip preparing the arguments of their
+// log entries.
+type NoopLogger struct{}
+
+func (NoopLogger) Error(...interface{})          {}
+func (NoopLogger) Errorf(string, ...interface{}) {}
+func (NoopLogger) Warn(...interface{})           {}
+func (NoopLogger) Warnf(string, ...interface{})  {}
+func (NoopLogger) Info(...interface{})           {}
+func (NoopLogger) Infof(string, ...interface{})  {}
+func (NoopLogger) Debug(...interface{})          {}
+func (NoopLogger) Debugf(string, ...interface{}) {}
+
+// Discards tells whether the logger is a NoopLogger, so that the
+// callers can skip formatting the log entries.
+func Discards(l Logger) bool {
+	switch l.(type) {
+	case NoopLogger, *NoopLogger:
+		return true
+	default:
+		return false
+	}
+}
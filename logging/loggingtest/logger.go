@@ -4,8 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"time"
+
+	"github.com/zalando/skipper/logging"
 )
 
 type logSubscription struct {
@@ -28,6 +31,13 @@ type Logger struct {
 	quit   chan<- struct{}
 }
 
+// fieldLogger appends the fields to the logged messages, in the form of
+// key=value, ordered by the keys
+type fieldLogger struct {
+	logger *Logger
+	fields string
+}
+
 // ErrWaitTimeout is returned when a logging event doesn't happen
 // within a timeout.
 var ErrWaitTimeout = errors.New("timeout")
@@ -134,6 +144,24 @@ func (tl *Logger) Close() {
 	close(tl.quit)
 }
 
+// Returns a logger that appends the fields to the logged messages, in
+// the form of key=value, ordered by the keys. The messages can be
+// waited for the same way as the messages without fields.
+func (tl *Logger) WithFields(f logging.Fields) logging.Logger {
+	var keys []string
+	for k := range f {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%v", k, f[k])
+	}
+
+	return &fieldLogger{logger: tl, fields: strings.Join(pairs, " ")}
+}
+
 func (tl *Logger) Error(a ...interface{})            { tl.log(a...) }
 func (tl *Logger) Errorf(f string, a ...interface{}) { tl.logf(f, a...) }
 func (tl *Logger) Warn(a ...interface{})             { tl.log(a...) }
@@ -142,3 +170,18 @@ func (tl *Logger) Info(a ...interface{})             { tl.log(a...) }
 func (tl *Logger) Infof(f string, a ...interface{})  { tl.logf(f, a...) }
 func (tl *Logger) Debug(a ...interface{})            { tl.log(a...) }
 func (tl *Logger) Debugf(f string, a ...interface{}) { tl.logf(f, a...) }
+
+func (fl *fieldLogger) log(a ...interface{}) { fl.logger.log(fmt.Sprint(a...) + " " + fl.fields) }
+
+func (fl *fieldLogger) logf(f string, a ...interface{}) {
+	fl.logger.log(fmt.Sprintf(f, a...) + " " + fl.fields)
+}
+
+func (fl *fieldLogger) Error(a ...interface{})            { fl.log(a...) }
+func (fl *fieldLogger) Errorf(f string, a ...interface{}) { fl.logf(f, a...) }
+func (fl *fieldLogger) Warn(a ...interface{})             { fl.log(a...) }
+func (fl *fieldLogger) Warnf(f string, a ...interface{})  { fl.logf(f, a...) }
+func (fl *fieldLogger) Info(a ...interface{})             { fl.log(a...) }
+func (fl *fieldLogger) Infof(f string, a ...interface{})  { fl.logf(f, a...) }
+func (fl *fieldLogger) Debug(a ...interface{})            { fl.log(a...) }
+func (fl *fieldLogger) Debugf(f string, a ...interface{}) { fl.logf(f, a...) }
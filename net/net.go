@@ -22,6 +22,12 @@ func parse(addr string) net.IP {
 	return nil
 }
 
+// RemoteAddr returns the IP address of the direct peer of the request,
+// ignoring the X-Forwarded-For header.
+func RemoteAddr(r *http.Request) net.IP {
+	return parse(r.RemoteAddr)
+}
+
 // The remote address of the client. When the 'X-Forwarded-For'
 // header is set, then it is used instead.
 func RemoteHost(r *http.Request) net.IP {
@@ -33,3 +39,31 @@ func RemoteHost(r *http.Request) net.IP {
 
 	return parse(r.RemoteAddr)
 }
+
+// RemoteHostFromLast returns the IP address of the client by skipping
+// the last n entries of the X-Forwarded-For header, that were appended by
+// the trusted proxies in front of the current one. When the header has
+// fewer entries than n + 1, the first entry is used. When the header is
+// not present, the remote address of the request is returned. It returns
+// nil when the selected entry is not a valid address.
+func RemoteHostFromLast(r *http.Request, n int) net.IP {
+	var ffs []string
+	for _, h := range r.Header["X-Forwarded-For"] {
+		for _, ff := range strings.Split(h, ",") {
+			if ff = strings.TrimSpace(ff); ff != "" {
+				ffs = append(ffs, ff)
+			}
+		}
+	}
+
+	if len(ffs) == 0 {
+		return parse(r.RemoteAddr)
+	}
+
+	i := len(ffs) - 1 - n
+	if i < 0 {
+		i = 0
+	}
+
+	return parse(ffs[i])
+}
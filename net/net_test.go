@@ -37,6 +37,46 @@ func TestRemoteHost(t *testing.T) {
 	}
 }
 
+func TestRemoteAddr(t *testing.T) {
+	for _, test := range netTests {
+		r := &http.Request{RemoteAddr: test.input, Header: make(http.Header)}
+		if test.fwdHdr != "" {
+			r.Header.Set("x-forwarded-for", test.fwdHdr)
+		}
+
+		want := parse(test.input)
+		if got := RemoteAddr(r); !reflect.DeepEqual(got, want) {
+			t.Errorf("Unexpected IP address '%v'. Wanted '%v", got, want)
+		}
+	}
+}
+
+func TestRemoteHostFromLast(t *testing.T) {
+	for _, test := range []struct {
+		msg    string
+		n      int
+		fwdHdr []string
+		want   net.IP
+	}{
+		{"no header", 1, nil, net.IPv4(127, 0, 0, 1)},
+		{"last entry", 0, []string{"1.1.1.1, 2.2.2.2, 3.3.3.3"}, net.IPv4(3, 3, 3, 3)},
+		{"skip one", 1, []string{"1.1.1.1, 2.2.2.2, 3.3.3.3"}, net.IPv4(2, 2, 2, 2)},
+		{"skip two", 2, []string{"1.1.1.1, 2.2.2.2, 3.3.3.3"}, net.IPv4(1, 1, 1, 1)},
+		{"larger than chain", 5, []string{"1.1.1.1, 2.2.2.2, 3.3.3.3"}, net.IPv4(1, 1, 1, 1)},
+		{"multiple headers", 1, []string{"1.1.1.1, 2.2.2.2", "3.3.3.3"}, net.IPv4(2, 2, 2, 2)},
+		{"spaces and empty entries", 1, []string{" 1.1.1.1 ,,  2.2.2.2 , "}, net.IPv4(1, 1, 1, 1)},
+		{"with port", 0, []string{"1.1.1.1, 2.2.2.2:8080"}, net.IPv4(2, 2, 2, 2)},
+		{"malformed selected entry", 1, []string{"1.1.1.1, invalid, 3.3.3.3"}, nil},
+		{"malformed other entry", 0, []string{"invalid, 3.3.3.3"}, net.IPv4(3, 3, 3, 3)},
+		{"IPv6", 1, []string{"2001:db8::1, 3.3.3.3"}, net.ParseIP("2001:db8::1")},
+	} {
+		r := &http.Request{RemoteAddr: "127.0.0.1:4242", Header: http.Header{"X-Forwarded-For": test.fwdHdr}}
+		if got := RemoteHostFromLast(r, test.n); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: unexpected IP address '%v'. Wanted '%v", test.msg, got, test.want)
+		}
+	}
+}
+
 func BenchmarkRemoteHost(b *testing.B) {
 	r := &http.Request{RemoteAddr: "1.2.3.4"}
 	b.ResetTimer()
@@ -0,0 +1,32 @@
+package predicates
+
+import "github.com/zalando/skipper/internal/args"
+
+// Args wraps the arguments of a predicate, and provides typed access
+// to them. The returned errors wrap ErrInvalidPredicateParameters, and
+// contain the name of the predicate and the index of the invalid
+// argument. The filters use the same type, as filters.Args.
+//
+// Example:
+//
+// 	func (s *spec) Create(args []interface{}) (routing.Predicate, error) {
+// 		a := predicates.NewArgs(Name, args)
+// 		if err := a.Count(1, 1); err != nil {
+// 			return nil, err
+// 		}
+//
+// 		timeout, err := a.Duration(0)
+// 		if err != nil {
+// 			return nil, err
+// 		}
+//
+// 		return &predicate{timeout}, nil
+// 	}
+//
+type Args = args.Args
+
+// NewArgs creates an Args object for the arguments of the predicate
+// with the given name.
+func NewArgs(name string, a []interface{}) Args {
+	return args.New(ErrInvalidPredicateParameters, name, a)
+}
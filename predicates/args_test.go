@@ -0,0 +1,275 @@
+package predicates
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/routing"
+)
+
+type (
+	// matches the requests whose path has the given number of segments
+	segmentsSpec      struct{}
+	segmentsPredicate int
+
+	// matches the requests whose X-Age header is a duration not longer
+	// than the given one
+	maxAgeSpec      struct{}
+	maxAgePredicate time.Duration
+)
+
+func (segmentsSpec) Name() string { return "Segments" }
+
+func (segmentsSpec) Create(args []interface{}) (routing.Predicate, error) {
+	a := NewArgs("Segments", args)
+	if err := a.Count(1, 1); err != nil {
+		return nil, err
+	}
+
+	n, err := a.Int(0)
+	if err != nil {
+		return nil, err
+	}
+
+	return segmentsPredicate(n), nil
+}
+
+func (p segmentsPredicate) Match(r *http.Request) bool {
+	return strings.Count(strings.TrimSuffix(r.URL.Path, "/"), "/") == int(p)
+}
+
+func (maxAgeSpec) Name() string { return "MaxAge" }
+
+func (maxAgeSpec) Create(args []interface{}) (routing.Predicate, error) {
+	a := NewArgs("MaxAge", args)
+	if err := a.Count(1, 1); err != nil {
+		return nil, err
+	}
+
+	d, err := a.Duration(0)
+	if err != nil {
+		return nil, err
+	}
+
+	return maxAgePredicate(d), nil
+}
+
+func (p maxAgePredicate) Match(r *http.Request) bool {
+	d, err := time.ParseDuration(r.Header.Get("X-Age"))
+	return err == nil && d <= time.Duration(p)
+}
+
+func TestArgsCount(t *testing.T) {
+	for _, ti := range []struct {
+		msg      string
+		args     []interface{}
+		min, max int
+		err      string
+	}{{
+		"exact, ok",
+		[]interface{}{"foo"},
+		1, 1,
+		"",
+	}, {
+		"exact, too few",
+		nil,
+		1, 1,
+		"Test: expected 1 arguments, got 0",
+	}, {
+		"range, too many",
+		[]interface{}{"foo", "bar", "baz"},
+		1, 2,
+		"Test: expected 1 to 2 arguments, got 3",
+	}, {
+		"unlimited, ok",
+		[]interface{}{"foo", "bar", "baz"},
+		1, -1,
+		"",
+	}, {
+		"unlimited, too few",
+		nil,
+		1, -1,
+		"Test: expected at least 1 arguments, got 0",
+	}} {
+		err := NewArgs("Test", ti.args).Count(ti.min, ti.max)
+		if ti.err == "" && err != nil {
+			t.Error(ti.msg, err)
+		} else if ti.err != "" && (err == nil || !strings.HasSuffix(err.Error(), ti.err)) {
+			t.Error(ti.msg, "invalid error", err)
+		}
+	}
+}
+
+func TestArgsTypes(t *testing.T) {
+	args := NewArgs("Test", []interface{}{"foo", 42.0, 4.2, "1m30s", "bar", 42})
+
+	if s, err := args.String(0); err != nil || s != "foo" {
+		t.Error("failed to get string", s, err)
+	}
+
+	if n, err := args.Int(1); err != nil || n != 42 {
+		t.Error("failed to get number", n, err)
+	}
+
+	if n, err := args.Int(5); err != nil || n != 42 {
+		t.Error("failed to get int", n, err)
+	}
+
+	if f, err := args.Float(2); err != nil || f != 4.2 {
+		t.Error("failed to get float", f, err)
+	}
+
+	if f, err := args.Float(5); err != nil || f != 42 {
+		t.Error("failed to get float from int", f, err)
+	}
+
+	if d, err := args.Duration(3); err != nil || d != 90*time.Second {
+		t.Error("failed to get duration", d, err)
+	}
+
+	if d, err := NewArgs("Test", []interface{}{time.Minute}).Duration(0); err != nil || d != time.Minute {
+		t.Error("failed to get duration value", d, err)
+	}
+
+	for _, ti := range []struct {
+		msg string
+		get func() error
+		err string
+	}{{
+		"string from number",
+		func() error { _, err := args.String(1); return err },
+		"Test: argument 1: expected string, got float64",
+	}, {
+		"int from string",
+		func() error { _, err := args.Int(0); return err },
+		"Test: argument 0: expected integer, got string",
+	}, {
+		"int from fraction",
+		func() error { _, err := args.Int(2); return err },
+		"Test: argument 2: expected integer, got 4.2",
+	}, {
+		"float from string",
+		func() error { _, err := args.Float(0); return err },
+		"Test: argument 0: expected number, got string",
+	}, {
+		"duration from number",
+		func() error { _, err := args.Duration(1); return err },
+		"Test: argument 1: expected duration string, got float64",
+	}, {
+		"invalid duration",
+		func() error { _, err := args.Duration(4); return err },
+		`Test: argument 4: invalid duration: "bar"`,
+	}, {
+		"missing",
+		func() error { _, err := args.String(6); return err },
+		"Test: argument 6: missing",
+	}} {
+		err := ti.get()
+		if err == nil {
+			t.Error(ti.msg, "failed to fail")
+			continue
+		}
+
+		if !errors.Is(err, ErrInvalidPredicateParameters) ||
+			!strings.HasSuffix(err.Error(), ti.err) {
+			t.Error(ti.msg, "invalid error", err)
+		}
+	}
+}
+
+func TestArgsFromEskip(t *testing.T) {
+	cps := []routing.PredicateSpec{segmentsSpec{}, maxAgeSpec{}}
+
+	for _, ti := range []struct {
+		msg string
+		doc string
+		err string
+	}{{
+		"int",
+		`Segments(2) -> <shunt>`,
+		"",
+	}, {
+		"duration",
+		`MaxAge("5s") -> <shunt>`,
+		"",
+	}, {
+		"int from fraction",
+		`Segments(2.5) -> <shunt>`,
+		"Segments: argument 0: expected integer, got 2.5",
+	}, {
+		"int from string",
+		`Segments("2") -> <shunt>`,
+		"Segments: argument 0: expected integer, got string",
+	}, {
+		"duration from number",
+		`MaxAge(5) -> <shunt>`,
+		"MaxAge: argument 0: expected duration string, got float64",
+	}, {
+		"invalid duration",
+		`MaxAge("5 seconds") -> <shunt>`,
+		`MaxAge: argument 0: invalid duration: "5 seconds"`,
+	}} {
+		errs := routing.Validate(ti.doc, nil, cps)
+		if ti.err == "" {
+			if len(errs) != 0 {
+				t.Error(ti.msg, errs)
+			}
+
+			continue
+		}
+
+		if len(errs) != 1 || !strings.HasSuffix(errs[0].Error(), ti.err) {
+			t.Error(ti.msg, "invalid errors", errs)
+		}
+	}
+
+	defs, err := eskip.Parse(`
+		segments: Segments(2) -> <shunt>;
+		maxAge: MaxAge("5s") -> <shunt>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the routes created in code may use int and time.Duration
+	defs = append(defs, &eskip.Route{
+		Id: "inCode",
+		Predicates: []*eskip.Predicate{
+			{Name: "Segments", Args: []interface{}{3}},
+			{Name: "MaxAge", Args: []interface{}{time.Minute}}},
+		Shunt: true})
+
+	m, errs := routing.NewMatcher(defs, nil, cps)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	for _, ti := range []struct {
+		path, age, route string
+	}{
+		{"/foo/bar", "", "segments"},
+		{"/foo", "3s", "maxAge"},
+		{"/foo", "6s", ""},
+		{"/foo/bar/baz", "30s", "inCode"},
+		{"/foo/bar/baz", "2m", ""},
+	} {
+		req, err := http.NewRequest("GET", "https://www.example.org"+ti.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("X-Age", ti.age)
+
+		var id string
+		if r, _ := m.Match(req); r != nil {
+			id = r.Id
+		}
+
+		if id != ti.route {
+			t.Error("invalid route matched", ti.path, ti.age, id, ti.route)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Package clientcn implements a predicate to match the common name of the
+verified client certificate of TLS connections.
+*/
+package clientcn
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+// The predicate can be referenced in eskip by the name "ClientCN".
+const Name = "ClientCN"
+
+type (
+	spec struct{}
+
+	predicate struct {
+		cn *regexp.Regexp
+	}
+)
+
+// New creates a predicate specification, whose instances match the
+// subject common name of the client certificate against a regular
+// expression.
+//
+// The predicate accepts a single argument, the regular expression.
+// Only the requests received over TLS, with a client certificate that
+// was verified by the server, can match. The server needs to be
+// configured to request and verify the client certificates, otherwise
+// no request matches.
+//
+// Eskip example:
+//
+// 	ClientCN(/^svc-.*$/) -> "https://www.example.org";
+//
+func New() routing.PredicateSpec { return &spec{} }
+
+func (s *spec) Name() string { return Name }
+
+func (s *spec) Create(args []interface{}) (routing.Predicate, error) {
+	a := predicates.NewArgs(Name, args)
+	if err := a.Count(1, 1); err != nil {
+		return nil, err
+	}
+
+	expr, err := a.String(0)
+	if err != nil {
+		return nil, err
+	}
+
+	cn, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", predicates.ErrInvalidPredicateParameters, Name, err)
+	}
+
+	return &predicate{cn}, nil
+}
+
+func (p *predicate) Match(r *http.Request) bool {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.PeerCertificates) == 0 {
+		return false
+	}
+
+	return p.cn.MatchString(r.TLS.PeerCertificates[0].Subject.CommonName)
+}
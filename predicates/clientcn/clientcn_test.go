@@ -0,0 +1,99 @@
+package clientcn
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/zalando/skipper/predicates"
+)
+
+func TestClientCNArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg  string
+		args []interface{}
+		err  bool
+	}{{
+		"no args",
+		nil,
+		true,
+	}, {
+		"too many args",
+		[]interface{}{"^svc-", "something"},
+		true,
+	}, {
+		"not a string",
+		[]interface{}{float64(1)},
+		true,
+	}, {
+		"invalid regexp",
+		[]interface{}{`\`},
+		true,
+	}, {
+		"ok",
+		[]interface{}{"^svc-.*$"},
+		false,
+	}} {
+		p, err := New().Create(ti.args)
+		if ti.err && err == nil {
+			t.Error(ti.msg, "failed to fail")
+		} else if ti.err && !errors.Is(err, predicates.ErrInvalidPredicateParameters) {
+			t.Error(ti.msg, "invalid error", err)
+		} else if !ti.err && err != nil {
+			t.Error(ti.msg, err)
+		} else if !ti.err && p == nil {
+			t.Error(ti.msg, "failed to create predicate")
+		}
+	}
+}
+
+func connectionState(cn string, verified bool) *tls.ConnectionState {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+	ca := &x509.Certificate{Subject: pkix.Name{CommonName: "ca"}}
+	s := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert, ca}}
+	if verified {
+		s.VerifiedChains = [][]*x509.Certificate{{cert, ca}}
+	}
+
+	return s
+}
+
+func TestClientCNMatch(t *testing.T) {
+	p, err := New().Create([]interface{}{"^svc-.*$"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ti := range []struct {
+		msg   string
+		tls   *tls.ConnectionState
+		match bool
+	}{{
+		"no tls",
+		nil,
+		false,
+	}, {
+		"no client certificate",
+		&tls.ConnectionState{},
+		false,
+	}, {
+		"not verified",
+		connectionState("svc-orders", false),
+		false,
+	}, {
+		"not matching",
+		connectionState("user-orders", true),
+		false,
+	}, {
+		"matching",
+		connectionState("svc-orders", true),
+		true,
+	}} {
+		if m := p.Match(&http.Request{TLS: ti.tls}); m != ti.match {
+			t.Error(ti.msg, "failed to match", m, ti.match)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Package contenttype implements a predicate to match the media type of the
+request body, as set in the Content-Type header.
+*/
+package contenttype
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+// The predicate can be referenced in eskip by the name "ContentType".
+const Name = "ContentType"
+
+type (
+	spec struct{}
+
+	// an accepted media type, where the type or the subtype can be a
+	// wildcard, and the subtype can be a wildcard with a suffix, e.g.
+	// application/*+json
+	mediaType struct {
+		typ, subtype string
+	}
+
+	predicate struct {
+		accepted []mediaType
+	}
+)
+
+// New creates a predicate specification, whose instances match the
+// media type in the Content-Type header of the request against a set
+// of accepted media types.
+//
+// The predicate accepts one or more arguments, the accepted media
+// types. They are normalized to lower case. The subtype can be a
+// wildcard, e.g. application/*, or a wildcard with a structured syntax
+// suffix, e.g. application/*+json. The parameters of the Content-Type
+// header, like the charset, are ignored. Requests without the header
+// don't match.
+//
+// Eskip example:
+//
+// 	ContentType("application/json", "application/*+json") -> "https://www.example.org";
+//
+func New() routing.PredicateSpec { return &spec{} }
+
+func (s *spec) Name() string { return Name }
+
+func parseMediaType(s string) (mediaType, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return mediaType{}, false
+	}
+
+	return mediaType{parts[0], parts[1]}, true
+}
+
+func (s *spec) Create(args []interface{}) (routing.Predicate, error) {
+	a := predicates.NewArgs(Name, args)
+	if err := a.Count(1, -1); err != nil {
+		return nil, err
+	}
+
+	p := &predicate{}
+	for i := 0; i < a.Len(); i++ {
+		arg, err := a.String(i)
+		if err != nil {
+			return nil, err
+		}
+
+		mt, _, err := mime.ParseMediaType(arg)
+		if err != nil {
+			return nil, predicates.ErrInvalidPredicateParameters
+		}
+
+		accepted, ok := parseMediaType(mt)
+		if !ok || accepted.typ == "*" && accepted.subtype != "*" {
+			return nil, predicates.ErrInvalidPredicateParameters
+		}
+
+		p.accepted = append(p.accepted, accepted)
+	}
+
+	return p, nil
+}
+
+func (m mediaType) match(typ, subtype string) bool {
+	switch {
+	case m.typ != "*" && m.typ != typ:
+		return false
+	case m.subtype == "*" || m.subtype == subtype:
+		return true
+	case strings.HasPrefix(m.subtype, "*+"):
+		return strings.HasSuffix(subtype, m.subtype[1:])
+	default:
+		return false
+	}
+}
+
+func (p *predicate) Match(r *http.Request) bool {
+	h := r.Header.Get("Content-Type")
+	if i := strings.Index(h, ";"); i >= 0 {
+		h = h[:i]
+	}
+
+	mt, ok := parseMediaType(h)
+	if !ok {
+		return false
+	}
+
+	for _, a := range p.accepted {
+		if a.match(mt.typ, mt.subtype) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,145 @@
+package contenttype
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestContentTypeArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg  string
+		args []interface{}
+		err  bool
+	}{{
+		"no args",
+		nil,
+		true,
+	}, {
+		"not a string",
+		[]interface{}{float64(1)},
+		true,
+	}, {
+		"empty",
+		[]interface{}{""},
+		true,
+	}, {
+		"no subtype",
+		[]interface{}{"application"},
+		true,
+	}, {
+		"wildcard type with subtype",
+		[]interface{}{"*/json"},
+		true,
+	}, {
+		"ok",
+		[]interface{}{"application/json"},
+		false,
+	}, {
+		"ok, multiple, with wildcards and parameters",
+		[]interface{}{"Application/JSON", "application/*+json", "text/*", "text/plain; charset=utf-8", "*/*"},
+		false,
+	}} {
+		p, err := New().Create(ti.args)
+		if ti.err && err == nil {
+			t.Error(ti.msg, "failed to fail")
+		} else if !ti.err && err != nil {
+			t.Error(ti.msg, err)
+		} else if !ti.err && p == nil {
+			t.Error(ti.msg, "failed to create predicate")
+		}
+	}
+}
+
+func TestContentTypeMatch(t *testing.T) {
+	for _, ti := range []struct {
+		msg         string
+		args        []interface{}
+		contentType string
+		match       bool
+	}{{
+		"exact match",
+		[]interface{}{"application/json"},
+		"application/json",
+		true,
+	}, {
+		"no match",
+		[]interface{}{"application/json"},
+		"application/xml",
+		false,
+	}, {
+		"case insensitive",
+		[]interface{}{"application/json"},
+		"Application/JSON",
+		true,
+	}, {
+		"charset parameter stripped",
+		[]interface{}{"application/json"},
+		"application/json; charset=utf-8",
+		true,
+	}, {
+		"parameter in the argument ignored",
+		[]interface{}{"text/plain; charset=utf-8"},
+		"text/plain; charset=iso-8859-1",
+		true,
+	}, {
+		"one of multiple",
+		[]interface{}{"application/xml", "application/json"},
+		"application/json",
+		true,
+	}, {
+		"wildcard subtype",
+		[]interface{}{"application/*"},
+		"application/octet-stream",
+		true,
+	}, {
+		"wildcard subtype, different type",
+		[]interface{}{"application/*"},
+		"text/plain",
+		false,
+	}, {
+		"wildcard with suffix",
+		[]interface{}{"application/*+json"},
+		"application/problem+json; charset=utf-8",
+		true,
+	}, {
+		"wildcard with suffix, no suffix",
+		[]interface{}{"application/*+json"},
+		"application/json",
+		false,
+	}, {
+		"wildcard with suffix, different suffix",
+		[]interface{}{"application/*+json"},
+		"application/atom+xml",
+		false,
+	}, {
+		"any",
+		[]interface{}{"*/*"},
+		"image/png",
+		true,
+	}, {
+		"absent header",
+		[]interface{}{"*/*"},
+		"",
+		false,
+	}, {
+		"invalid header",
+		[]interface{}{"*/*"},
+		"json",
+		false,
+	}} {
+		p, err := New().Create(ti.args)
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		r := &http.Request{Header: make(http.Header)}
+		if ti.contentType != "" {
+			r.Header.Set("Content-Type", ti.contentType)
+		}
+
+		if p.Match(r) != ti.match {
+			t.Error(ti.msg, "failed to match as expected")
+		}
+	}
+}
@@ -1,5 +1,5 @@
 /*
-Package cookie implements prediate to check parsed cookie headers by name and value.
+Package cookie implements a predicate to check parsed cookie headers by name and value.
 */
 package cookie
 
@@ -25,8 +25,8 @@ type (
 
 // New creates a predicate specification, whose instances can be used to match parsed request cookies.
 //
-// The cookie predicate accpets two arguments, the cookie name, with what a cookie must exist in the request,
-// and an expression that the cookie value needs to match.
+// The cookie predicate accepts two arguments, the cookie name, with what a cookie must exist in the request,
+// and an expression that the cookie value needs to match. Requests without the cookie don't match.
 //
 // Eskip example:
 //
@@ -37,18 +37,19 @@ func New() routing.PredicateSpec { return &spec{} }
 func (s *spec) Name() string { return Name }
 
 func (s *spec) Create(args []interface{}) (routing.Predicate, error) {
-	if len(args) != 2 {
-		return nil, predicates.ErrInvalidPredicateParameters
+	a := predicates.NewArgs(Name, args)
+	if err := a.Count(2, 2); err != nil {
+		return nil, err
 	}
 
-	name, ok := args[0].(string)
-	if !ok {
-		return nil, predicates.ErrInvalidPredicateParameters
+	name, err := a.String(0)
+	if err != nil {
+		return nil, err
 	}
 
-	value, ok := args[1].(string)
-	if !ok {
-		return nil, predicates.ErrInvalidPredicateParameters
+	value, err := a.String(1)
+	if err != nil {
+		return nil, err
 	}
 
 	valueExp, err := regexp.Compile(value)
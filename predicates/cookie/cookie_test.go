@@ -60,6 +60,11 @@ func TestCookieMatch(t *testing.T) {
 		cookies string
 		match   bool
 	}{{
+		"no cookies",
+		[]interface{}{"tcial", "^enabled$"},
+		"",
+		false,
+	}, {
 		"not found",
 		[]interface{}{"tcial", "^enabled$"},
 		"some=value",
@@ -0,0 +1,452 @@
+/*
+Package expression implements a predicate matching the requests by a
+boolean expression over the attributes of the request.
+
+The expression is compiled when the route is created, and the routes
+with syntax or type errors in the expression are rejected.
+
+The expressions consist of the following elements:
+
+	request.method           the method of the request
+	request.path             the path of the request
+	request.host             the host of the request, without the port
+	request.header['Name']   the first value of a header, or ''
+	request.query['name']    the first value of a query parameter, or ''
+	'text', "text"           string literals, with \ escaping the quotes
+	true, false              boolean literals
+	a == b, a != b           comparison of two strings or two booleans
+	a && b, a || b, !a       logical operators over booleans
+	( ... )                  grouping
+
+The && operator takes precedence over the || operator. The expression
+as a whole needs to be a boolean.
+
+Eskip example:
+
+	Expression("request.method == 'POST' && request.header['X-Env'] == 'prod'") -> "https://prod.example.org";
+*/
+package expression
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+// The predicate can be referenced in eskip by the name "Expression".
+const Name = "Expression"
+
+type (
+	spec struct{}
+
+	predicate struct {
+		match func(*http.Request) bool
+	}
+)
+
+// New creates a predicate specification, whose instances match the
+// requests for which the expression, passed in as the only argument, is
+// true. See the package documentation for the syntax.
+func New() routing.PredicateSpec { return &spec{} }
+
+func (s *spec) Name() string { return Name }
+
+func (s *spec) Create(args []interface{}) (routing.Predicate, error) {
+	a := predicates.NewArgs(Name, args)
+	if err := a.Count(1, 1); err != nil {
+		return nil, err
+	}
+
+	expr, err := a.String(0)
+	if err != nil {
+		return nil, err
+	}
+
+	match, err := compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", predicates.ErrInvalidPredicateParameters, Name, err)
+	}
+
+	return &predicate{match: match}, nil
+}
+
+func (p *predicate) Match(r *http.Request) bool { return p.match(r) }
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenIdent
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	typ    tokenType
+	val    string
+	offset int
+}
+
+// the operators ordered so that the longer ones are tried first
+var operators = []string{"==", "!=", "&&", "||", "!", "(", ")", "[", "]", "."}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func scanString(expr string, offset int) (token, int, error) {
+	quote := expr[offset]
+	var b []byte
+	for i := offset + 1; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case c == '\\' && i+1 < len(expr):
+			i++
+			b = append(b, expr[i])
+		case c == quote:
+			return token{tokenString, string(b), offset}, i + 1, nil
+		default:
+			b = append(b, c)
+		}
+	}
+
+	return token{}, 0, fmt.Errorf("unterminated string at %d", offset)
+}
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '\'' || c == '"':
+			t, next, err := scanString(expr, i)
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, t)
+			i = next
+			continue
+		case isIdentChar(c):
+			start := i
+			for i < len(expr) && isIdentChar(expr[i]) {
+				i++
+			}
+
+			tokens = append(tokens, token{tokenIdent, expr[start:i], start})
+			continue
+		}
+
+		var found bool
+		for _, op := range operators {
+			if strings.HasPrefix(expr[i:], op) {
+				tokens = append(tokens, token{tokenOperator, op, i})
+				i += len(op)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("unexpected character at %d: %q", i, c)
+		}
+	}
+
+	return append(tokens, token{tokenEOF, "", len(expr)}), nil
+}
+
+type valueType int
+
+const (
+	typeString valueType = iota
+	typeBool
+)
+
+func (t valueType) String() string {
+	if t == typeBool {
+		return "boolean"
+	}
+
+	return "string"
+}
+
+// a compiled subexpression, evaluating to either a string or a boolean
+type node struct {
+	typ  valueType
+	str  func(*http.Request) string
+	bool func(*http.Request) bool
+}
+
+func stringNode(f func(*http.Request) string) node { return node{typ: typeString, str: f} }
+func boolNode(f func(*http.Request) bool) node     { return node{typ: typeBool, bool: f} }
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) isOperator(op string) bool {
+	t := p.peek()
+	return t.typ == tokenOperator && t.val == op
+}
+
+func (p *parser) expect(typ tokenType, val string) (token, error) {
+	t := p.next()
+	if t.typ != typ || val != "" && t.val != val {
+		return t, unexpected(t)
+	}
+
+	return t, nil
+}
+
+func unexpected(t token) error {
+	if t.typ == tokenEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+
+	return fmt.Errorf("unexpected token at %d: %s", t.offset, t.val)
+}
+
+func expectBool(n node, offset int) error {
+	if n.typ != typeBool {
+		return fmt.Errorf("expected boolean at %d, got %v", offset, n.typ)
+	}
+
+	return nil
+}
+
+// or := and ('||' and)*
+func (p *parser) parseOr() (node, error) {
+	offset := p.peek().offset
+	left, err := p.parseAnd()
+	if err != nil {
+		return node{}, err
+	}
+
+	for p.isOperator("||") {
+		p.next()
+		rightOffset := p.peek().offset
+		right, err := p.parseAnd()
+		if err != nil {
+			return node{}, err
+		}
+
+		if err := expectBool(left, offset); err != nil {
+			return node{}, err
+		}
+
+		if err := expectBool(right, rightOffset); err != nil {
+			return node{}, err
+		}
+
+		l, r := left.bool, right.bool
+		left = boolNode(func(req *http.Request) bool { return l(req) || r(req) })
+	}
+
+	return left, nil
+}
+
+// and := unary ('&&' unary)*
+func (p *parser) parseAnd() (node, error) {
+	offset := p.peek().offset
+	left, err := p.parseUnary()
+	if err != nil {
+		return node{}, err
+	}
+
+	for p.isOperator("&&") {
+		p.next()
+		rightOffset := p.peek().offset
+		right, err := p.parseUnary()
+		if err != nil {
+			return node{}, err
+		}
+
+		if err := expectBool(left, offset); err != nil {
+			return node{}, err
+		}
+
+		if err := expectBool(right, rightOffset); err != nil {
+			return node{}, err
+		}
+
+		l, r := left.bool, right.bool
+		left = boolNode(func(req *http.Request) bool { return l(req) && r(req) })
+	}
+
+	return left, nil
+}
+
+// unary := '!' unary | comparison
+func (p *parser) parseUnary() (node, error) {
+	if !p.isOperator("!") {
+		return p.parseComparison()
+	}
+
+	p.next()
+	offset := p.peek().offset
+	n, err := p.parseUnary()
+	if err != nil {
+		return node{}, err
+	}
+
+	if err := expectBool(n, offset); err != nil {
+		return node{}, err
+	}
+
+	f := n.bool
+	return boolNode(func(req *http.Request) bool { return !f(req) }), nil
+}
+
+// comparison := primary (('==' | '!=') primary)?
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return node{}, err
+	}
+
+	if !p.isOperator("==") && !p.isOperator("!=") {
+		return left, nil
+	}
+
+	op := p.next()
+	right, err := p.parsePrimary()
+	if err != nil {
+		return node{}, err
+	}
+
+	if left.typ != right.typ {
+		return node{}, fmt.Errorf("mismatched types at %d: %v %s %v", op.offset, left.typ, op.val, right.typ)
+	}
+
+	negate := op.val == "!="
+	if left.typ == typeBool {
+		l, r := left.bool, right.bool
+		return boolNode(func(req *http.Request) bool { return (l(req) == r(req)) != negate }), nil
+	}
+
+	l, r := left.str, right.str
+	return boolNode(func(req *http.Request) bool { return (l(req) == r(req)) != negate }), nil
+}
+
+// primary := '(' or ')' | string | 'true' | 'false' | attribute
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch {
+	case t.typ == tokenOperator && t.val == "(":
+		n, err := p.parseOr()
+		if err != nil {
+			return node{}, err
+		}
+
+		if _, err := p.expect(tokenOperator, ")"); err != nil {
+			return node{}, err
+		}
+
+		return n, nil
+	case t.typ == tokenString:
+		s := t.val
+		return stringNode(func(*http.Request) string { return s }), nil
+	case t.typ == tokenIdent && (t.val == "true" || t.val == "false"):
+		b := t.val == "true"
+		return boolNode(func(*http.Request) bool { return b }), nil
+	case t.typ == tokenIdent && t.val == "request":
+		return p.parseAttribute()
+	default:
+		return node{}, unexpected(t)
+	}
+}
+
+func requestHost(r *http.Request) string {
+	if h, _, err := net.SplitHostPort(r.Host); err == nil {
+		return h
+	}
+
+	return r.Host
+}
+
+// attribute := 'request' '.' name ('[' string ']')?
+func (p *parser) parseAttribute() (node, error) {
+	if _, err := p.expect(tokenOperator, "."); err != nil {
+		return node{}, err
+	}
+
+	name, err := p.expect(tokenIdent, "")
+	if err != nil {
+		return node{}, err
+	}
+
+	switch name.val {
+	case "method":
+		return stringNode(func(r *http.Request) string { return r.Method }), nil
+	case "path":
+		return stringNode(func(r *http.Request) string { return r.URL.Path }), nil
+	case "host":
+		return stringNode(requestHost), nil
+	case "header", "query":
+	default:
+		return node{}, fmt.Errorf("unknown attribute at %d: request.%s", name.offset, name.val)
+	}
+
+	if _, err := p.expect(tokenOperator, "["); err != nil {
+		return node{}, err
+	}
+
+	key, err := p.expect(tokenString, "")
+	if err != nil {
+		return node{}, err
+	}
+
+	if _, err := p.expect(tokenOperator, "]"); err != nil {
+		return node{}, err
+	}
+
+	k := key.val
+	if name.val == "header" {
+		k = http.CanonicalHeaderKey(k)
+		return stringNode(func(r *http.Request) string { return r.Header.Get(k) }), nil
+	}
+
+	return stringNode(func(r *http.Request) string { return r.URL.Query().Get(k) }), nil
+}
+
+// compiles an expression into a function evaluating it for a request
+func compile(expr string) (func(*http.Request) bool, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.typ != tokenEOF {
+		return nil, unexpected(t)
+	}
+
+	if err := expectBool(n, 0); err != nil {
+		return nil, err
+	}
+
+	return n.bool, nil
+}
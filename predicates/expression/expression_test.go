@@ -0,0 +1,176 @@
+package expression
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/zalando/skipper/predicates"
+)
+
+func TestExpressionArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg  string
+		args []interface{}
+		err  bool
+	}{{
+		"no args",
+		nil,
+		true,
+	}, {
+		"not a string",
+		[]interface{}{float64(1)},
+		true,
+	}, {
+		"too many args",
+		[]interface{}{"true", "true"},
+		true,
+	}, {
+		"empty",
+		[]interface{}{""},
+		true,
+	}, {
+		"unterminated string",
+		[]interface{}{"request.method == 'POST"},
+		true,
+	}, {
+		"invalid character",
+		[]interface{}{"request.method = 'POST'"},
+		true,
+	}, {
+		"unknown attribute",
+		[]interface{}{"request.body == 'foo'"},
+		true,
+	}, {
+		"missing header name",
+		[]interface{}{"request.header == 'foo'"},
+		true,
+	}, {
+		"missing closing paren",
+		[]interface{}{"(request.method == 'POST'"},
+		true,
+	}, {
+		"trailing tokens",
+		[]interface{}{"request.method == 'POST' 'GET'"},
+		true,
+	}, {
+		"not a boolean",
+		[]interface{}{"request.method"},
+		true,
+	}, {
+		"string in logical operator",
+		[]interface{}{"request.method == 'POST' && request.path"},
+		true,
+	}, {
+		"negated string",
+		[]interface{}{"!request.path"},
+		true,
+	}, {
+		"mismatched comparison",
+		[]interface{}{"request.method == true"},
+		true,
+	}, {
+		"ok",
+		[]interface{}{"request.method == 'POST' && request.header['X-Env'] == 'prod'"},
+		false,
+	}, {
+		"ok, complex",
+		[]interface{}{`!(request.host == "www.example.org" || request.query['debug'] != '') && (request.path == '/it\'s' || false)`},
+		false,
+	}} {
+		p, err := New().Create(ti.args)
+		if ti.err && !errors.Is(err, predicates.ErrInvalidPredicateParameters) {
+			t.Error(ti.msg, "failed to fail with invalid parameters", err)
+		} else if !ti.err && err != nil {
+			t.Error(ti.msg, err)
+		} else if !ti.err && p == nil {
+			t.Error(ti.msg, "failed to create predicate")
+		}
+	}
+}
+
+func TestExpressionMatch(t *testing.T) {
+	for _, ti := range []struct {
+		msg    string
+		expr   string
+		method string
+		url    string
+		header http.Header
+		match  bool
+	}{{
+		msg:    "method and header match",
+		expr:   "request.method == 'POST' && request.header['X-Env'] == 'prod'",
+		method: "POST",
+		url:    "https://www.example.org/foo",
+		header: http.Header{"X-Env": []string{"prod"}},
+		match:  true,
+	}, {
+		msg:    "header does not match",
+		expr:   "request.method == 'POST' && request.header['X-Env'] == 'prod'",
+		method: "POST",
+		url:    "https://www.example.org/foo",
+		header: http.Header{"X-Env": []string{"test"}},
+		match:  false,
+	}, {
+		msg:    "missing header",
+		expr:   "request.method == 'POST' && request.header['x-env'] == 'prod'",
+		method: "POST",
+		url:    "https://www.example.org/foo",
+		match:  false,
+	}, {
+		msg:    "or",
+		expr:   "request.method == 'PUT' || request.method == 'POST'",
+		method: "POST",
+		url:    "https://www.example.org/foo",
+		match:  true,
+	}, {
+		msg:    "and takes precedence",
+		expr:   "request.method == 'POST' || request.method == 'PUT' && false",
+		method: "POST",
+		url:    "https://www.example.org/foo",
+		match:  true,
+	}, {
+		msg:    "grouping",
+		expr:   "(request.method == 'POST' || request.method == 'PUT') && false",
+		method: "POST",
+		url:    "https://www.example.org/foo",
+		match:  false,
+	}, {
+		msg:    "host without port and path",
+		expr:   "request.host == 'www.example.org' && request.path == '/foo'",
+		method: "GET",
+		url:    "https://www.example.org:9090/foo",
+		match:  true,
+	}, {
+		msg:    "negated query",
+		expr:   "!(request.query['debug'] != '')",
+		method: "GET",
+		url:    "https://www.example.org/foo?debug=1",
+		match:  false,
+	}, {
+		msg:    "query",
+		expr:   "request.query['debug'] == '1'",
+		method: "GET",
+		url:    "https://www.example.org/foo?debug=1",
+		match:  true,
+	}} {
+		p, err := New().Create([]interface{}{ti.expr})
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		r, err := http.NewRequest(ti.method, ti.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ti.header != nil {
+			r.Header = ti.header
+		}
+
+		if m := p.Match(r); m != ti.match {
+			t.Error(ti.msg, "failed to match as expected", m, ti.match)
+		}
+	}
+}
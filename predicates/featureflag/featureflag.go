@@ -0,0 +1,100 @@
+/*
+Package featureflag implements a predicate to match the requests when a
+feature flag is enabled, e.g. for the dynamic rollout of a new feature.
+
+The flags are evaluated by a provider supplied by the application
+embedding skipper, e.g. a client of a feature flag service. Since the
+predicate is evaluated for every candidate route of every request, the
+provider is expected to answer from memory, without blocking.
+*/
+package featureflag
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+// The predicate can be referenced in eskip by the name "FeatureFlag".
+const Name = "FeatureFlag"
+
+// Provider evaluates the feature flags. The context of the request is
+// passed to the provider, so that it can evaluate the flag for the user
+// or the client of the request, when the application stores them in
+// the context.
+type Provider interface {
+
+	// Tells whether the flag is enabled in the context of a request.
+	Enabled(ctx context.Context, flag string) bool
+}
+
+// ProviderFunc adapts an ordinary function to be used as a Provider.
+type ProviderFunc func(ctx context.Context, flag string) bool
+
+// Enabled calls f(ctx, flag).
+func (f ProviderFunc) Enabled(ctx context.Context, flag string) bool { return f(ctx, flag) }
+
+// Options for the predicate specification.
+type Options struct {
+
+	// Evaluates the feature flags. When not set, the predicate
+	// doesn't match any request.
+	Provider Provider
+}
+
+type (
+	spec struct {
+		provider Provider
+	}
+
+	predicate struct {
+		provider Provider
+		flag     string
+	}
+)
+
+// New creates a predicate specification without a provider, whose
+// instances don't match any request. See NewWithOptions.
+func New() routing.PredicateSpec { return NewWithOptions(Options{}) }
+
+// NewWithOptions creates a predicate specification, whose instances
+// match the requests when the feature flag, passed as the only
+// argument, is enabled by the provider.
+//
+// When running skipper as a library, the provider can be set with the
+// FeatureFlagProvider field of skipper.Options.
+//
+// Eskip example:
+//
+// 	checkout: Path("/checkout") -> "https://checkout.example.org";
+// 	newCheckout: Path("/checkout") && FeatureFlag("new-checkout") -> "https://new-checkout.example.org";
+//
+func NewWithOptions(o Options) routing.PredicateSpec {
+	return &spec{provider: o.Provider}
+}
+
+func (s *spec) Name() string { return Name }
+
+func (s *spec) Create(args []interface{}) (routing.Predicate, error) {
+	a := predicates.NewArgs(Name, args)
+	if err := a.Count(1, 1); err != nil {
+		return nil, err
+	}
+
+	flag, err := a.String(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if flag == "" {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+
+	return &predicate{provider: s.provider, flag: flag}, nil
+}
+
+func (p *predicate) Match(r *http.Request) bool {
+	return p.provider != nil && p.provider.Enabled(r.Context(), p.flag)
+}
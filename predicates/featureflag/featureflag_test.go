@@ -0,0 +1,104 @@
+package featureflag
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+type userKey struct{}
+
+func TestFeatureFlagArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg  string
+		args []interface{}
+		err  bool
+	}{{
+		"no args",
+		nil,
+		true,
+	}, {
+		"not a string",
+		[]interface{}{float64(1)},
+		true,
+	}, {
+		"empty",
+		[]interface{}{""},
+		true,
+	}, {
+		"too many args",
+		[]interface{}{"new-checkout", "new-cart"},
+		true,
+	}, {
+		"ok",
+		[]interface{}{"new-checkout"},
+		false,
+	}} {
+		p, err := New().Create(ti.args)
+		if ti.err && err == nil {
+			t.Error(ti.msg, "failed to fail")
+		} else if !ti.err && err != nil {
+			t.Error(ti.msg, err)
+		} else if !ti.err && p == nil {
+			t.Error(ti.msg, "failed to create predicate")
+		}
+	}
+}
+
+func TestFeatureFlagMatch(t *testing.T) {
+	enabled := ProviderFunc(func(ctx context.Context, flag string) bool {
+		return flag == "new-checkout" && ctx.Value(userKey{}) == "beta-tester"
+	})
+
+	for _, ti := range []struct {
+		msg      string
+		provider Provider
+		flag     string
+		user     string
+		match    bool
+	}{{
+		msg:   "no provider",
+		flag:  "new-checkout",
+		user:  "beta-tester",
+		match: false,
+	}, {
+		msg:      "enabled",
+		provider: enabled,
+		flag:     "new-checkout",
+		user:     "beta-tester",
+		match:    true,
+	}, {
+		msg:      "disabled for the request",
+		provider: enabled,
+		flag:     "new-checkout",
+		user:     "someone-else",
+		match:    false,
+	}, {
+		msg:      "disabled flag",
+		provider: enabled,
+		flag:     "new-cart",
+		user:     "beta-tester",
+		match:    false,
+	}, {
+		msg:      "always disabled",
+		provider: ProviderFunc(func(context.Context, string) bool { return false }),
+		flag:     "new-checkout",
+		match:    false,
+	}} {
+		p, err := NewWithOptions(Options{Provider: ti.provider}).Create([]interface{}{ti.flag})
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		r, err := http.NewRequest("GET", "https://www.example.org/checkout", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), userKey{}, ti.user))
+		if m := p.Match(r); m != ti.match {
+			t.Error(ti.msg, "unexpected match result", m, ti.match)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Package headerabsent implements a predicate to match the requests that
+don't have a given header.
+
+It is a more readable alternative of the negated header conditions, for
+the common case of checking that a header is not set at all.
+*/
+package headerabsent
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+// The predicate can be referenced in eskip by the name "HeaderAbsent".
+const Name = "HeaderAbsent"
+
+type (
+	spec struct{}
+
+	predicate string
+)
+
+// New creates a predicate specification, whose instances match the
+// requests that don't have the header with the given name.
+//
+// The predicate accepts a single argument, the name of the header. The
+// name is case insensitive. A header that is present with an empty
+// value is considered present, and the request doesn't match.
+//
+// Eskip example:
+//
+// 	anonymous: Path("/profile") && HeaderAbsent("Authorization") -> redirectTo(302, "/login") -> <shunt>;
+//
+func New() routing.PredicateSpec { return &spec{} }
+
+func (s *spec) Name() string { return Name }
+
+func (s *spec) Create(args []interface{}) (routing.Predicate, error) {
+	a := predicates.NewArgs(Name, args)
+	if err := a.Count(1, 1); err != nil {
+		return nil, err
+	}
+
+	name, err := a.String(0)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+
+	return predicate(http.CanonicalHeaderKey(name)), nil
+}
+
+func (p predicate) Match(r *http.Request) bool {
+	// the Host header is not stored in the header map of the
+	// incoming requests
+	if p == "Host" {
+		return r.Host == ""
+	}
+
+	_, present := r.Header[string(p)]
+	return !present
+}
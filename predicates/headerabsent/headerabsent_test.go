@@ -0,0 +1,102 @@
+package headerabsent
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHeaderAbsentArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg  string
+		args []interface{}
+		err  bool
+	}{{
+		"no args",
+		nil,
+		true,
+	}, {
+		"not a string",
+		[]interface{}{float64(1)},
+		true,
+	}, {
+		"empty",
+		[]interface{}{" "},
+		true,
+	}, {
+		"too many args",
+		[]interface{}{"Authorization", "X-Foo"},
+		true,
+	}, {
+		"ok",
+		[]interface{}{"Authorization"},
+		false,
+	}} {
+		p, err := New().Create(ti.args)
+		if ti.err && err == nil {
+			t.Error(ti.msg, "failed to fail")
+		} else if !ti.err && err != nil {
+			t.Error(ti.msg, err)
+		} else if !ti.err && p == nil {
+			t.Error(ti.msg, "failed to create predicate")
+		}
+	}
+}
+
+func TestHeaderAbsentMatch(t *testing.T) {
+	for _, ti := range []struct {
+		msg    string
+		name   string
+		header http.Header
+		host   string
+		match  bool
+	}{{
+		msg:   "absent",
+		name:  "Authorization",
+		match: true,
+	}, {
+		msg:    "other header present",
+		name:   "Authorization",
+		header: http.Header{"X-Foo": []string{"bar"}},
+		match:  true,
+	}, {
+		msg:    "present with value",
+		name:   "Authorization",
+		header: http.Header{"Authorization": []string{"Bearer foo"}},
+		match:  false,
+	}, {
+		msg:    "present empty",
+		name:   "Authorization",
+		header: http.Header{"Authorization": []string{""}},
+		match:  false,
+	}, {
+		msg:    "case insensitive name",
+		name:   "authorization",
+		header: http.Header{"Authorization": []string{"Bearer foo"}},
+		match:  false,
+	}, {
+		msg:   "host absent",
+		name:  "Host",
+		match: true,
+	}, {
+		msg:   "host present",
+		name:  "Host",
+		host:  "www.example.org",
+		match: false,
+	}} {
+		p, err := New().Create([]interface{}{ti.name})
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		h := ti.header
+		if h == nil {
+			h = make(http.Header)
+		}
+
+		r := &http.Request{Header: h, Host: ti.host}
+		if p.Match(r) != ti.match {
+			t.Error(ti.msg, "failed to match as expected")
+		}
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Package hostany implements a predicate to match the host of the request
+against a list of host names, where the first label of a host name can
+be a wildcard.
+
+It is a more readable alternative of the Host condition with regular
+expressions, for the common case of matching a set of domains.
+*/
+package hostany
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+// The predicate can be referenced in eskip by the name "HostAny".
+const Name = "HostAny"
+
+const wildcardPrefix = "*."
+
+type (
+	spec struct{}
+
+	predicate struct {
+		exact map[string]bool
+
+		// the suffixes of the wildcard patterns, including the
+		// leading dot
+		suffixes []string
+	}
+)
+
+// New creates a predicate specification, whose instances match the
+// host of the request against one or more host names.
+//
+// The host names can start with a wildcard label, e.g. *.example.org,
+// that matches exactly one label, e.g. www.example.org, but not
+// example.org or www.eu.example.org. Other wildcards are not
+// supported. The host names are compared case insensitive, and the
+// port and the trailing dot of the request host are ignored.
+//
+// Eskip example:
+//
+// 	HostAny("*.example.org", "api.example.com") -> "https://www.example.org";
+//
+func New() routing.PredicateSpec { return &spec{} }
+
+func (s *spec) Name() string { return Name }
+
+// normalizes a host name to lower case, without the trailing dot
+func normalizeHost(h string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(h)), ".")
+}
+
+func (s *spec) Create(args []interface{}) (routing.Predicate, error) {
+	a := predicates.NewArgs(Name, args)
+	if err := a.Count(1, -1); err != nil {
+		return nil, err
+	}
+
+	p := &predicate{exact: make(map[string]bool)}
+	for i := 0; i < a.Len(); i++ {
+		arg, err := a.String(i)
+		if err != nil {
+			return nil, err
+		}
+
+		h := normalizeHost(arg)
+		if strings.HasPrefix(h, wildcardPrefix) {
+			suffix := h[len(wildcardPrefix)-1:]
+			if len(suffix) < 2 || strings.Contains(suffix, "*") {
+				return nil, predicates.ErrInvalidPredicateParameters
+			}
+
+			p.suffixes = append(p.suffixes, suffix)
+			continue
+		}
+
+		if h == "" || strings.Contains(h, "*") {
+			return nil, predicates.ErrInvalidPredicateParameters
+		}
+
+		p.exact[h] = true
+	}
+
+	return p, nil
+}
+
+// returns the host of the request without the port
+func requestHost(r *http.Request) string {
+	h := r.Host
+	if h == "" && r.URL != nil {
+		h = r.URL.Host
+	}
+
+	if hh, _, err := net.SplitHostPort(h); err == nil {
+		h = hh
+	}
+
+	return normalizeHost(h)
+}
+
+func (p *predicate) Match(r *http.Request) bool {
+	h := requestHost(r)
+	if p.exact[h] {
+		return true
+	}
+
+	for _, s := range p.suffixes {
+		if len(h) > len(s) && strings.HasSuffix(h, s) && strings.IndexByte(h[:len(h)-len(s)], '.') < 0 {
+			return true
+		}
+	}
+
+	return false
+}
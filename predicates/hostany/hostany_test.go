@@ -0,0 +1,161 @@
+package hostany
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestHostAnyArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg  string
+		args []interface{}
+		err  bool
+	}{{
+		"no args",
+		nil,
+		true,
+	}, {
+		"not a string",
+		[]interface{}{float64(1)},
+		true,
+	}, {
+		"empty",
+		[]interface{}{""},
+		true,
+	}, {
+		"wildcard only",
+		[]interface{}{"*."},
+		true,
+	}, {
+		"wildcard not in the first label",
+		[]interface{}{"www.*.example.org"},
+		true,
+	}, {
+		"partial wildcard label",
+		[]interface{}{"www*.example.org"},
+		true,
+	}, {
+		"multiple wildcard labels",
+		[]interface{}{"*.*.example.org"},
+		true,
+	}, {
+		"ok",
+		[]interface{}{"www.example.org"},
+		false,
+	}, {
+		"ok, multiple with wildcard",
+		[]interface{}{"*.example.org", "API.example.com."},
+		false,
+	}} {
+		p, err := New().Create(ti.args)
+		if ti.err && err == nil {
+			t.Error(ti.msg, "failed to fail")
+		} else if !ti.err && err != nil {
+			t.Error(ti.msg, err)
+		} else if !ti.err && p == nil {
+			t.Error(ti.msg, "failed to create predicate")
+		}
+	}
+}
+
+func TestHostAnyMatch(t *testing.T) {
+	for _, ti := range []struct {
+		msg   string
+		args  []interface{}
+		host  string
+		url   string
+		match bool
+	}{{
+		msg:   "exact match",
+		args:  []interface{}{"api.example.com"},
+		host:  "api.example.com",
+		match: true,
+	}, {
+		msg:   "exact, no match",
+		args:  []interface{}{"api.example.com"},
+		host:  "www.example.com",
+		match: false,
+	}, {
+		msg:   "one of multiple",
+		args:  []interface{}{"*.example.org", "api.example.com"},
+		host:  "api.example.com",
+		match: true,
+	}, {
+		msg:   "wildcard subdomain",
+		args:  []interface{}{"*.example.org", "api.example.com"},
+		host:  "www.example.org",
+		match: true,
+	}, {
+		msg:   "wildcard doesn't match the parent domain",
+		args:  []interface{}{"*.example.org"},
+		host:  "example.org",
+		match: false,
+	}, {
+		msg:   "wildcard matches a single label",
+		args:  []interface{}{"*.example.org"},
+		host:  "www.eu.example.org",
+		match: false,
+	}, {
+		msg:   "wildcard, different domain",
+		args:  []interface{}{"*.example.org"},
+		host:  "www.notexample.org",
+		match: false,
+	}, {
+		msg:   "port in host",
+		args:  []interface{}{"*.example.org"},
+		host:  "www.example.org:8080",
+		match: true,
+	}, {
+		msg:   "port in host, exact",
+		args:  []interface{}{"api.example.com"},
+		host:  "api.example.com:443",
+		match: true,
+	}, {
+		msg:   "ipv6 with port",
+		args:  []interface{}{"::1"},
+		host:  "[::1]:9090",
+		match: true,
+	}, {
+		msg:   "trailing dot in host",
+		args:  []interface{}{"*.example.org"},
+		host:  "www.example.org.",
+		match: true,
+	}, {
+		msg:   "trailing dot in the argument",
+		args:  []interface{}{"api.example.com."},
+		host:  "api.example.com",
+		match: true,
+	}, {
+		msg:   "case insensitive",
+		args:  []interface{}{"*.Example.org"},
+		host:  "WWW.example.ORG",
+		match: true,
+	}, {
+		msg:   "host from the url",
+		args:  []interface{}{"*.example.org"},
+		url:   "https://www.example.org/foo",
+		match: true,
+	}, {
+		msg:   "no host",
+		args:  []interface{}{"*.example.org"},
+		match: false,
+	}} {
+		p, err := New().Create(ti.args)
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		u, err := url.Parse(ti.url)
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		r := &http.Request{URL: u, Host: ti.host}
+		if p.Match(r) != ti.match {
+			t.Error(ti.msg, "failed to match as expected")
+		}
+	}
+}
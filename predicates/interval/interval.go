@@ -2,8 +2,8 @@
 Package interval implements custom predicates to match routes
 only during some period of time.
 
-Package includes three predicates:
-Between, Before and After. All predicates can be created using the date
+Package includes four predicates:
+Between, Before, After and BetweenTime. The first three predicates can be created using the date
 represented as a string in RFC3339 format (see https://golang.org/pkg/time/#pkg-constants),
 int64 or float64 number. float64 number will be converted into int64
 number.
@@ -19,6 +19,10 @@ date. Only one date is required to construct the predicate.
 After predicate matches only if current date is after or equal to
 the specified date. Only one date is required to construct the predicate.
 
+BetweenTime predicate matches only during a daily time window, defined
+by the time of day of its beginning and end, and an optional time zone,
+e.g. BetweenTime("02:00", "04:00", "Europe/Berlin").
+
 Examples:
 
 	example1: Path("/zalando") && Between("2016-01-01T12:00:00+02:00", "2016-02-01T12:00:00+02:00") -> "https://www.zalando.de";
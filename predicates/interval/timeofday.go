@@ -0,0 +1,112 @@
+package interval
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+// BetweenTimeName is the name of the time of day predicate.
+const BetweenTimeName = "BetweenTime"
+
+var timeOfDayLayouts = []string{"15:04", "15:04:05"}
+
+type timeOfDaySpec struct{}
+
+type timeOfDayPredicate struct {
+	begin, end time.Duration
+	location   *time.Location
+	getTime    func() time.Time
+}
+
+// Creates the BetweenTime predicate, that matches during a daily time
+// window. It accepts the beginning and the end of the window in the
+// format "15:04" or "15:04:05", and optionally the name of the time
+// zone in the IANA time zone database, e.g. "Europe/Berlin". The time
+// zone defaults to UTC. The window includes the beginning, but
+// excludes the end. When the end is before the beginning, the window
+// spans midnight.
+//
+// Examples:
+//
+// 	maintenance: BetweenTime("02:00", "04:00", "Europe/Berlin") -> "https://maintenance.example.org";
+// 	nightly: BetweenTime("22:00", "06:00") -> "https://night.example.org";
+//
+func NewBetweenTime() routing.PredicateSpec { return &timeOfDaySpec{} }
+
+func (s *timeOfDaySpec) Name() string { return BetweenTimeName }
+
+// parses a time of day as the duration since midnight
+func parseTimeOfDay(s string) (time.Duration, bool) {
+	for _, l := range timeOfDayLayouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return time.Duration(t.Hour())*time.Hour +
+				time.Duration(t.Minute())*time.Minute +
+				time.Duration(t.Second())*time.Second, true
+		}
+	}
+
+	return 0, false
+}
+
+func (s *timeOfDaySpec) Create(args []interface{}) (routing.Predicate, error) {
+	a := predicates.NewArgs(BetweenTimeName, args)
+	if err := a.Count(2, 3); err != nil {
+		return nil, err
+	}
+
+	beginArg, err := a.String(0)
+	if err != nil {
+		return nil, err
+	}
+
+	endArg, err := a.String(1)
+	if err != nil {
+		return nil, err
+	}
+
+	locationArg, err := a.OptionalString(2, "UTC")
+	if err != nil {
+		return nil, err
+	}
+
+	begin, ok := parseTimeOfDay(beginArg)
+	if !ok {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+
+	end, ok := parseTimeOfDay(endArg)
+	if !ok || begin == end {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+
+	location, err := time.LoadLocation(locationArg)
+	if err != nil {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+
+	return &timeOfDayPredicate{
+		begin:    begin,
+		end:      end,
+		location: location,
+		getTime:  time.Now}, nil
+}
+
+func (p *timeOfDayPredicate) Match(r *http.Request) bool {
+	now := p.getTime().In(p.location)
+
+	// the time of day is taken from the wall clock, also on the days
+	// of the daylight saving changes
+	t := time.Duration(now.Hour())*time.Hour +
+		time.Duration(now.Minute())*time.Minute +
+		time.Duration(now.Second())*time.Second +
+		time.Duration(now.Nanosecond())
+
+	if p.begin < p.end {
+		return t >= p.begin && t < p.end
+	}
+
+	return t >= p.begin || t < p.end
+}
@@ -0,0 +1,76 @@
+package interval
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCreateBetweenTime(t *testing.T) {
+	for _, c := range []struct {
+		msg  string
+		args []interface{}
+		err  bool
+	}{
+		{"no arguments", nil, true},
+		{"one argument", []interface{}{"02:00"}, true},
+		{"too many arguments", []interface{}{"02:00", "04:00", "UTC", "extra"}, true},
+		{"not a string", []interface{}{2.0, "04:00"}, true},
+		{"invalid time", []interface{}{"2am", "04:00"}, true},
+		{"out of range", []interface{}{"02:00", "25:00"}, true},
+		{"empty window", []interface{}{"02:00", "02:00"}, true},
+		{"invalid time zone", []interface{}{"02:00", "04:00", "Nowhere/Special"}, true},
+		{"time zone not a string", []interface{}{"02:00", "04:00", 1.0}, true},
+		{"valid", []interface{}{"02:00", "04:00"}, false},
+		{"valid with seconds", []interface{}{"02:00:30", "04:00:00"}, false},
+		{"valid with time zone", []interface{}{"02:00", "04:00", "UTC"}, false},
+		{"valid over midnight", []interface{}{"22:00", "02:00"}, false},
+	} {
+		_, err := NewBetweenTime().Create(c.args)
+		if c.err && err == nil || !c.err && err != nil {
+			t.Errorf("%q: failed to validate arguments: %v", c.msg, err)
+		}
+	}
+}
+
+func TestMatchBetweenTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone database not available", err)
+	}
+
+	at := func(hour, min int, loc *time.Location) func() time.Time {
+		return func() time.Time { return time.Date(2016, 6, 1, hour, min, 0, 0, loc) }
+	}
+
+	for _, c := range []struct {
+		msg     string
+		args    []interface{}
+		getTime func() time.Time
+		matches bool
+	}{
+		{"before the window", []interface{}{"02:00", "04:00"}, at(1, 59, time.UTC), false},
+		{"at the beginning", []interface{}{"02:00", "04:00"}, at(2, 0, time.UTC), true},
+		{"inside the window", []interface{}{"02:00", "04:00"}, at(3, 30, time.UTC), true},
+		{"at the end", []interface{}{"02:00", "04:00"}, at(4, 0, time.UTC), false},
+		{"after the window", []interface{}{"02:00", "04:00"}, at(12, 0, time.UTC), false},
+		{"other time zone of the clock", []interface{}{"02:00", "04:00"}, at(5, 0, berlin), true},
+		{"time zone of the window", []interface{}{"02:00", "04:00", "Europe/Berlin"}, at(1, 0, time.UTC), true},
+		{"outside in the time zone of the window", []interface{}{"02:00", "04:00", "Europe/Berlin"}, at(3, 0, time.UTC), false},
+		{"over midnight, before midnight", []interface{}{"22:00", "02:00"}, at(23, 0, time.UTC), true},
+		{"over midnight, after midnight", []interface{}{"22:00", "02:00"}, at(1, 0, time.UTC), true},
+		{"over midnight, outside", []interface{}{"22:00", "02:00"}, at(12, 0, time.UTC), false},
+	} {
+		p, err := NewBetweenTime().Create(c.args)
+		if err != nil {
+			t.Errorf("%q: failed to create predicate: %v", c.msg, err)
+			continue
+		}
+
+		tp := p.(*timeOfDayPredicate)
+		tp.getTime = c.getTime
+		if m := tp.Match(&http.Request{}); m != c.matches {
+			t.Errorf("%q: Expected result - %t; Actual result - %t", c.msg, c.matches, m)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Package jsonbody implements a predicate to match a field in the JSON
+body of the request.
+
+The predicate reads the body of the request up to a limited size, and
+restores it after matching, so that the filters and the backend receive
+the complete, unchanged body.
+*/
+package jsonbody
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/zalando/skipper/internal/jsonpath"
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+// The predicate can be referenced in eskip by the name "JSONBodyKV".
+const Name = "JSONBodyKV"
+
+// DefaultMaxBodySize is used when Options.MaxBodySize is not set.
+const DefaultMaxBodySize = 64 * 1024
+
+// Options for the predicate specification.
+type Options struct {
+
+	// The maximum number of bytes read from the request body. The
+	// requests with a larger body don't match. Defaults to
+	// DefaultMaxBodySize.
+	MaxBodySize int64
+}
+
+type (
+	spec struct {
+		maxBodySize int64
+	}
+
+	predicate struct {
+		maxBodySize int64
+		path        jsonpath.Path
+		value       string
+	}
+
+	// the restored request body, the bytes already read followed
+	// by the rest of the original body
+	body struct {
+		io.Reader
+		original io.ReadCloser
+	}
+)
+
+// New creates a predicate specification with the default options. See
+// NewWithOptions.
+func New() routing.PredicateSpec { return NewWithOptions(Options{}) }
+
+// NewWithOptions creates a predicate specification, whose instances
+// match a field of the JSON object in the request body.
+//
+// The predicate accepts two arguments, the name of the field and the
+// expected value. Nested fields can be referenced by a path separated
+// by dots, e.g. "payment.type". String fields are compared as they
+// are, while number and boolean fields are compared in their JSON
+// representation. The requests whose body is larger than the maximum
+// body size, or is not a valid JSON object, don't match.
+//
+// Eskip example:
+//
+// 	JSONBodyKV("type", "refund") -> "https://refunds.example.org";
+//
+func NewWithOptions(o Options) routing.PredicateSpec {
+	if o.MaxBodySize <= 0 {
+		o.MaxBodySize = DefaultMaxBodySize
+	}
+
+	return &spec{maxBodySize: o.MaxBodySize}
+}
+
+func (s *spec) Name() string { return Name }
+
+func (s *spec) Create(args []interface{}) (routing.Predicate, error) {
+	a := predicates.NewArgs(Name, args)
+	if err := a.Count(2, 2); err != nil {
+		return nil, err
+	}
+
+	key, err := a.String(0)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := a.String(1)
+	if err != nil {
+		return nil, err
+	}
+
+	path, ok := jsonpath.Parse(key)
+	if !ok {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+
+	return &predicate{maxBodySize: s.maxBodySize, path: path, value: value}, nil
+}
+
+func (b *body) Close() error { return b.original.Close() }
+
+// reads the body up to the limit, and replaces it with the restored
+// body. It returns false when the body is larger than the limit.
+func readBody(r *http.Request, limit int64) ([]byte, bool) {
+	if r.Body == nil || r.ContentLength > limit {
+		return nil, false
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, limit+1))
+	r.Body = &body{
+		Reader:   io.MultiReader(bytes.NewReader(b), r.Body),
+		original: r.Body}
+
+	return b, err == nil && int64(len(b)) <= limit
+}
+
+func (p *predicate) Match(r *http.Request) bool {
+	b, ok := readBody(r, p.maxBodySize)
+	if !ok {
+		return false
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return false
+	}
+
+	s, ok := p.path.Lookup(v)
+	return ok && s == p.value
+}
@@ -0,0 +1,195 @@
+package jsonbody
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type closeRecorder struct {
+	*strings.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestJSONBodyArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg  string
+		args []interface{}
+		err  bool
+	}{{
+		"no args",
+		nil,
+		true,
+	}, {
+		"missing value",
+		[]interface{}{"type"},
+		true,
+	}, {
+		"too many args",
+		[]interface{}{"type", "refund", "foo"},
+		true,
+	}, {
+		"not a string",
+		[]interface{}{"type", float64(1)},
+		true,
+	}, {
+		"empty path segment",
+		[]interface{}{"payment..type", "refund"},
+		true,
+	}, {
+		"ok",
+		[]interface{}{"type", "refund"},
+		false,
+	}, {
+		"ok, nested",
+		[]interface{}{"payment.type", "refund"},
+		false,
+	}} {
+		p, err := New().Create(ti.args)
+		if ti.err && err == nil {
+			t.Error(ti.msg, "failed to fail")
+		} else if !ti.err && err != nil {
+			t.Error(ti.msg, err)
+		} else if !ti.err && p == nil {
+			t.Error(ti.msg, "failed to create predicate")
+		}
+	}
+}
+
+func TestJSONBodyMatch(t *testing.T) {
+	for _, ti := range []struct {
+		msg           string
+		maxBodySize   int64
+		args          []interface{}
+		body          string
+		contentLength int64
+		match         bool
+	}{{
+		msg:   "match",
+		args:  []interface{}{"type", "refund"},
+		body:  `{"type": "refund", "amount": 42}`,
+		match: true,
+	}, {
+		msg:   "no match",
+		args:  []interface{}{"type", "refund"},
+		body:  `{"type": "payment", "amount": 42}`,
+		match: false,
+	}, {
+		msg:   "missing field",
+		args:  []interface{}{"type", "refund"},
+		body:  `{"amount": 42}`,
+		match: false,
+	}, {
+		msg:   "nested",
+		args:  []interface{}{"payment.type", "refund"},
+		body:  `{"payment": {"type": "refund"}}`,
+		match: true,
+	}, {
+		msg:   "number",
+		args:  []interface{}{"amount", "42"},
+		body:  `{"type": "refund", "amount": 42}`,
+		match: true,
+	}, {
+		msg:   "bool",
+		args:  []interface{}{"partial", "true"},
+		body:  `{"type": "refund", "partial": true}`,
+		match: true,
+	}, {
+		msg:   "object value",
+		args:  []interface{}{"payment", "refund"},
+		body:  `{"payment": {"type": "refund"}}`,
+		match: false,
+	}, {
+		msg:   "not an object",
+		args:  []interface{}{"type", "refund"},
+		body:  `["refund"]`,
+		match: false,
+	}, {
+		msg:   "malformed json",
+		args:  []interface{}{"type", "refund"},
+		body:  `{"type": "refund"`,
+		match: false,
+	}, {
+		msg:   "empty body",
+		args:  []interface{}{"type", "refund"},
+		match: false,
+	}, {
+		msg:         "body at the limit",
+		maxBodySize: 18,
+		args:        []interface{}{"type", "refund"},
+		body:        `{"type": "refund"}`,
+		match:       true,
+	}, {
+		msg:         "oversized body",
+		maxBodySize: 17,
+		args:        []interface{}{"type", "refund"},
+		body:        `{"type": "refund"}`,
+		match:       false,
+	}, {
+		msg:           "oversized content length",
+		maxBodySize:   32,
+		args:          []interface{}{"type", "refund"},
+		body:          `{"type": "refund"}`,
+		contentLength: 64,
+		match:         false,
+	}} {
+		p, err := NewWithOptions(Options{MaxBodySize: ti.maxBodySize}).Create(ti.args)
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		original := &closeRecorder{Reader: strings.NewReader(ti.body)}
+		r, err := http.NewRequest("POST", "https://www.example.org", original)
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		if ti.contentLength > 0 {
+			r.ContentLength = ti.contentLength
+		}
+
+		if p.Match(r) != ti.match {
+			t.Error(ti.msg, "failed to match as expected")
+		}
+
+		// evaluating the predicate again gives the same result
+		if p.Match(r) != ti.match {
+			t.Error(ti.msg, "failed to match as expected, when evaluated again")
+		}
+
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		if !bytes.Equal(b, []byte(ti.body)) {
+			t.Error(ti.msg, "failed to preserve the body", string(b))
+		}
+
+		r.Body.Close()
+		if !original.closed {
+			t.Error(ti.msg, "failed to close the original body")
+		}
+	}
+}
+
+func TestJSONBodyNoBody(t *testing.T) {
+	p, err := New().Create([]interface{}{"type", "refund"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Match(&http.Request{}) {
+		t.Error("failed to fail")
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Package jwt implements a predicate to match the claims in the payload of
+the JSON Web Token sent as the bearer token in the Authorization header.
+
+The predicate only decodes the token, and it doesn't verify its
+signature. It is meant for routing decisions, while the verification of
+the token is the responsibility of a filter or of the backend.
+*/
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/zalando/skipper/internal/jsonpath"
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+// The predicate can be referenced in eskip by the name "JWTPayloadAllKV".
+const Name = "JWTPayloadAllKV"
+
+const bearerPrefix = "Bearer "
+
+type (
+	spec struct{}
+
+	claim struct {
+		path  jsonpath.Path
+		value string
+	}
+
+	predicate struct {
+		claims []claim
+	}
+)
+
+// New creates a predicate specification, whose instances match the
+// claims of the JWT payload in the Authorization header of the request.
+//
+// The predicate accepts an even number of arguments, pairs of claim
+// names and expected values. A request matches when all the claims
+// exist in the token payload, and are equal to the expected values.
+// Nested claims can be referenced by a path separated by dots, e.g.
+// "realm.role". String claims are compared as they are, while number
+// and boolean claims are compared in their JSON representation.
+// Requests without a token, or with a malformed one, don't match.
+//
+// Eskip example:
+//
+// 	JWTPayloadAllKV("iss", "https://accounts.example.org", "realm.role", "admin") -> "https://www.example.org";
+//
+func New() routing.PredicateSpec { return &spec{} }
+
+func (s *spec) Name() string { return Name }
+
+func (s *spec) Create(args []interface{}) (routing.Predicate, error) {
+	a := predicates.NewArgs(Name, args)
+	if err := a.Count(2, -1); err != nil {
+		return nil, err
+	}
+
+	if a.Len()%2 != 0 {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+
+	p := &predicate{}
+	for i := 0; i < a.Len(); i += 2 {
+		key, err := a.String(i)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := a.String(i + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		path, ok := jsonpath.Parse(key)
+		if !ok {
+			return nil, predicates.ErrInvalidPredicateParameters
+		}
+
+		p.claims = append(p.claims, claim{path: path, value: value})
+	}
+
+	return p, nil
+}
+
+// decodes the payload of the token, without verifying the signature
+func parsePayload(token string) (map[string]interface{}, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, false
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, false
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return nil, false
+	}
+
+	return payload, true
+}
+
+func (c claim) match(payload map[string]interface{}) bool {
+	s, ok := c.path.Lookup(payload)
+	return ok && s == c.value
+}
+
+func (p *predicate) Match(r *http.Request) bool {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, bearerPrefix) {
+		return false
+	}
+
+	payload, ok := parsePayload(strings.TrimSpace(h[len(bearerPrefix):]))
+	if !ok {
+		return false
+	}
+
+	for _, c := range p.claims {
+		if !c.match(payload) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,164 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+)
+
+// creates an unsigned token with the payload. The signature is not
+// verified by the predicate.
+func token(payload string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	body := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return header + "." + body + ".c2lnbmF0dXJl"
+}
+
+func TestJWTArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg  string
+		args []interface{}
+		err  bool
+	}{{
+		"no args",
+		nil,
+		true,
+	}, {
+		"missing value",
+		[]interface{}{"role"},
+		true,
+	}, {
+		"odd number of args",
+		[]interface{}{"role", "admin", "iss"},
+		true,
+	}, {
+		"not a string",
+		[]interface{}{"role", float64(1)},
+		true,
+	}, {
+		"empty path segment",
+		[]interface{}{"realm..role", "admin"},
+		true,
+	}, {
+		"ok",
+		[]interface{}{"role", "admin"},
+		false,
+	}, {
+		"ok, multiple and nested",
+		[]interface{}{"role", "admin", "realm.team", "gateway"},
+		false,
+	}} {
+		p, err := New().Create(ti.args)
+		if ti.err && err == nil {
+			t.Error(ti.msg, "failed to fail")
+		} else if !ti.err && err != nil {
+			t.Error(ti.msg, err)
+		} else if !ti.err && p == nil {
+			t.Error(ti.msg, "failed to create predicate")
+		}
+	}
+}
+
+func TestJWTMatch(t *testing.T) {
+	for _, ti := range []struct {
+		msg    string
+		args   []interface{}
+		header string
+		match  bool
+	}{{
+		"matching claim",
+		[]interface{}{"role", "admin"},
+		"Bearer " + token(`{"sub":"jdoe","role":"admin"}`),
+		true,
+	}, {
+		"mismatching claim",
+		[]interface{}{"role", "admin"},
+		"Bearer " + token(`{"sub":"jdoe","role":"user"}`),
+		false,
+	}, {
+		"missing claim",
+		[]interface{}{"role", "admin"},
+		"Bearer " + token(`{"sub":"jdoe"}`),
+		false,
+	}, {
+		"all claims match",
+		[]interface{}{"role", "admin", "sub", "jdoe"},
+		"Bearer " + token(`{"sub":"jdoe","role":"admin"}`),
+		true,
+	}, {
+		"one of the claims doesn't match",
+		[]interface{}{"role", "admin", "sub", "jsmith"},
+		"Bearer " + token(`{"sub":"jdoe","role":"admin"}`),
+		false,
+	}, {
+		"nested claim",
+		[]interface{}{"realm.access.role", "admin"},
+		"Bearer " + token(`{"realm":{"access":{"role":"admin"}}}`),
+		true,
+	}, {
+		"nested claim, not an object",
+		[]interface{}{"realm.access.role", "admin"},
+		"Bearer " + token(`{"realm":{"access":"admin"}}`),
+		false,
+	}, {
+		"number and boolean claims",
+		[]interface{}{"level", "3", "active", "true"},
+		"Bearer " + token(`{"level":3,"active":true}`),
+		true,
+	}, {
+		"object claim doesn't match",
+		[]interface{}{"realm", "admin"},
+		"Bearer " + token(`{"realm":{"role":"admin"}}`),
+		false,
+	}, {
+		"padded payload",
+		[]interface{}{"role", "admin"},
+		"Bearer a." + base64.URLEncoding.EncodeToString([]byte(`{"role":"admin"}`)) + ".b",
+		true,
+	}, {
+		"no header",
+		[]interface{}{"role", "admin"},
+		"",
+		false,
+	}, {
+		"not a bearer token",
+		[]interface{}{"role", "admin"},
+		"Basic " + token(`{"role":"admin"}`),
+		false,
+	}, {
+		"malformed token, missing parts",
+		[]interface{}{"role", "admin"},
+		"Bearer foo.bar",
+		false,
+	}, {
+		"malformed token, invalid base64",
+		[]interface{}{"role", "admin"},
+		"Bearer foo.!!!.bar",
+		false,
+	}, {
+		"malformed token, invalid json",
+		[]interface{}{"role", "admin"},
+		"Bearer " + token(`{"role":`),
+		false,
+	}, {
+		"malformed token, not an object",
+		[]interface{}{"role", "admin"},
+		"Bearer " + token(`["role","admin"]`),
+		false,
+	}} {
+		p, err := New().Create(ti.args)
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		r := &http.Request{Header: make(http.Header)}
+		if ti.header != "" {
+			r.Header.Set("Authorization", ti.header)
+		}
+
+		if p.Match(r) != ti.match {
+			t.Error(ti.msg, "failed to match as expected")
+		}
+	}
+}
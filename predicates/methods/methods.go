@@ -0,0 +1,66 @@
+/*
+Package methods implements a predicate to match the request method against
+a set of accepted methods.
+*/
+package methods
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+// The predicate can be referenced in eskip by the name "Methods".
+const Name = "Methods"
+
+type (
+	spec struct{}
+
+	predicate struct {
+		methods map[string]bool
+	}
+)
+
+// New creates a predicate specification, whose instances can be used to match the request method
+// against a set of methods.
+//
+// The methods predicate accepts one or more arguments, the accepted methods. The arguments are
+// normalized to upper case, and so is the method of the request. Unlike the built-in Method
+// condition, it allows defining a single route for multiple methods.
+//
+// Eskip example:
+//
+// 	Methods("GET", "HEAD", "OPTIONS") -> "https://www.example.org";
+//
+func New() routing.PredicateSpec { return &spec{} }
+
+func (s *spec) Name() string { return Name }
+
+func (s *spec) Create(args []interface{}) (routing.Predicate, error) {
+	a := predicates.NewArgs(Name, args)
+	if err := a.Count(1, -1); err != nil {
+		return nil, err
+	}
+
+	p := &predicate{methods: make(map[string]bool)}
+	for i := 0; i < a.Len(); i++ {
+		m, err := a.String(i)
+		if err != nil {
+			return nil, err
+		}
+
+		if m == "" {
+			return nil, predicates.ErrInvalidPredicateParameters
+		}
+
+		p.methods[strings.ToUpper(m)] = true
+	}
+
+	return p, nil
+}
+
+func (p *predicate) Match(r *http.Request) bool {
+	return p.methods[strings.ToUpper(r.Method)]
+}
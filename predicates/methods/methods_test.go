@@ -0,0 +1,79 @@
+package methods
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCreate(t *testing.T) {
+	for _, ti := range []struct {
+		msg  string
+		args []interface{}
+		err  bool
+	}{{
+		"no args",
+		nil,
+		true,
+	}, {
+		"arg not string",
+		[]interface{}{"GET", 1},
+		true,
+	}, {
+		"empty method",
+		[]interface{}{""},
+		true,
+	}, {
+		"one method",
+		[]interface{}{"GET"},
+		false,
+	}, {
+		"multiple methods",
+		[]interface{}{"GET", "HEAD", "OPTIONS"},
+		false,
+	}} {
+		_, err := (&spec{}).Create(ti.args)
+		if err == nil && ti.err || err != nil && !ti.err {
+			t.Error(ti.msg, "failure case", err, ti.err)
+		}
+	}
+}
+
+func TestMatching(t *testing.T) {
+	for _, ti := range []struct {
+		msg     string
+		args    []interface{}
+		method  string
+		matches bool
+	}{{
+		"matching method",
+		[]interface{}{"GET", "HEAD"},
+		"HEAD",
+		true,
+	}, {
+		"not matching method",
+		[]interface{}{"GET", "HEAD"},
+		"POST",
+		false,
+	}, {
+		"lower case arguments are normalized",
+		[]interface{}{"get", "options"},
+		"OPTIONS",
+		true,
+	}, {
+		"lower case request method is normalized",
+		[]interface{}{"GET", "HEAD"},
+		"get",
+		true,
+	}} {
+		p, err := (&spec{}).Create(ti.args)
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		r := &http.Request{Method: ti.method}
+		if p.Match(r) != ti.matches {
+			t.Error(ti.msg, "failed to match as expected")
+		}
+	}
+}
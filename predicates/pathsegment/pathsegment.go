@@ -0,0 +1,123 @@
+/*
+Package pathsegment implements the predicates to match the requests by
+the number of segments in their path.
+
+The segments are the non-empty parts of the path between the slashes.
+The empty parts are not counted, so a trailing slash and repeated
+slashes don't change the count: both /foo/bar and /foo//bar/ have two
+segments, while / has none. The path is taken as it was received, in
+its escaped form, so an encoded slash, %2F, doesn't separate segments.
+The dot segments are counted as they are, without resolving them.
+
+Eskip example:
+
+	collection: Path("/api/:collection") -> "https://collections.example.org";
+	nested: PathSegmentCountBetween(3, 5) -> "https://nested.example.org";
+	item: PathSegmentCount(3) && PathRegexp("^/api/") -> "https://items.example.org";
+*/
+package pathsegment
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+const (
+	// The predicate matching an exact number of segments can be
+	// referenced in eskip by the name "PathSegmentCount".
+	NameCount = "PathSegmentCount"
+
+	// The predicate matching a range of segment counts can be
+	// referenced in eskip by the name "PathSegmentCountBetween".
+	NameBetween = "PathSegmentCountBetween"
+)
+
+type (
+	spec struct {
+		name string
+	}
+
+	predicate struct {
+		min, max int
+	}
+)
+
+// NewCount creates a predicate specification, whose instances match the
+// requests whose path has exactly the number of segments passed in as
+// the only argument.
+func NewCount() routing.PredicateSpec { return &spec{name: NameCount} }
+
+// NewBetween creates a predicate specification, whose instances match
+// the requests whose path has a number of segments between the two
+// arguments, inclusive.
+func NewBetween() routing.PredicateSpec { return &spec{name: NameBetween} }
+
+func (s *spec) Name() string { return s.name }
+
+func (s *spec) Create(args []interface{}) (routing.Predicate, error) {
+	a := predicates.NewArgs(s.name, args)
+
+	n := 1
+	if s.name == NameBetween {
+		n = 2
+	}
+
+	if err := a.Count(n, n); err != nil {
+		return nil, err
+	}
+
+	min, err := a.Int(0)
+	if err != nil {
+		return nil, err
+	}
+
+	max := min
+	if n == 2 {
+		if max, err = a.Int(1); err != nil {
+			return nil, err
+		}
+	}
+
+	if min < 0 || max < min {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+
+	return &predicate{min: min, max: max}, nil
+}
+
+// counts the non-empty segments of a path
+func countSegments(path string) int {
+	var n int
+	for _, s := range strings.Split(path, "/") {
+		if s != "" {
+			n++
+		}
+	}
+
+	return n
+}
+
+// returns the escaped path of the request. For the incoming requests, it
+// is taken from the request line, because the escaped form of the parsed
+// URL is not preserved when the path contains characters that should
+// have been escaped.
+func escapedPath(r *http.Request) string {
+	if strings.HasPrefix(r.RequestURI, "/") {
+		p := r.RequestURI
+		if i := strings.IndexByte(p, '?'); i >= 0 {
+			p = p[:i]
+		}
+
+		return p
+	}
+
+	return r.URL.EscapedPath()
+}
+
+func (p *predicate) Match(r *http.Request) bool {
+	n := countSegments(escapedPath(r))
+	return n >= p.min && n <= p.max
+}
@@ -0,0 +1,132 @@
+package pathsegment
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPathSegmentArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg  string
+		name string
+		args []interface{}
+		err  bool
+	}{{
+		"count, no args",
+		NameCount,
+		nil,
+		true,
+	}, {
+		"count, not a number",
+		NameCount,
+		[]interface{}{"3"},
+		true,
+	}, {
+		"count, fraction",
+		NameCount,
+		[]interface{}{2.5},
+		true,
+	}, {
+		"count, negative",
+		NameCount,
+		[]interface{}{-1.0},
+		true,
+	}, {
+		"count, too many args",
+		NameCount,
+		[]interface{}{2.0, 3.0},
+		true,
+	}, {
+		"count, ok",
+		NameCount,
+		[]interface{}{3.0},
+		false,
+	}, {
+		"count, zero",
+		NameCount,
+		[]interface{}{0.0},
+		false,
+	}, {
+		"between, missing max",
+		NameBetween,
+		[]interface{}{2.0},
+		true,
+	}, {
+		"between, max less than min",
+		NameBetween,
+		[]interface{}{4.0, 2.0},
+		true,
+	}, {
+		"between, not a number",
+		NameBetween,
+		[]interface{}{2.0, "4"},
+		true,
+	}, {
+		"between, ok",
+		NameBetween,
+		[]interface{}{2.0, 4.0},
+		false,
+	}} {
+		s := NewCount()
+		if ti.name == NameBetween {
+			s = NewBetween()
+		}
+
+		p, err := s.Create(ti.args)
+		if ti.err && err == nil {
+			t.Error(ti.msg, "failed to fail")
+		} else if !ti.err && err != nil {
+			t.Error(ti.msg, err)
+		} else if !ti.err && p == nil {
+			t.Error(ti.msg, "failed to create predicate")
+		}
+	}
+}
+
+func TestPathSegmentMatch(t *testing.T) {
+	for _, ti := range []struct {
+		path  string
+		count int
+	}{
+		{"/", 0},
+		{"//", 0},
+		{"/foo", 1},
+		{"/foo/", 1},
+		{"/foo/bar", 2},
+		{"/foo//bar/", 2},
+		{"/foo/bar%2Fbaz", 2},
+		{"/foo/../bar", 3},
+		{"/foo/bar?baz=/qux", 2},
+	} {
+		for _, received := range []bool{false, true} {
+			r, err := http.NewRequest("GET", "https://www.example.org"+ti.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if received {
+				r.RequestURI = ti.path
+			}
+
+			for n := 0; n < 4; n++ {
+				p, err := NewCount().Create([]interface{}{float64(n)})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if m := p.Match(r); m != (n == ti.count) {
+					t.Error("unexpected match result", ti.path, received, n, m)
+				}
+			}
+
+			p, err := NewBetween().Create([]interface{}{1.0, 2.0})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if m := p.Match(r); m != (ti.count >= 1 && ti.count <= 2) {
+				t.Error("unexpected match result, between", ti.path, received, m)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Package primitive implements the True and False predicates, that match
+every request or no request at all.
+
+They can be used to disable a route temporarily, without deleting it,
+or to enable it again, by editing only the predicate:
+
+	// disabled
+	example1: Path("/foo") && False() -> "https://www.example.org";
+
+	// enabled
+	example2: Path("/foo") && True() -> "https://www.example.org";
+*/
+package primitive
+
+import (
+	"net/http"
+
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+const (
+	// The predicate that matches every request can be referenced in
+	// eskip by the name "True".
+	NameTrue = "True"
+
+	// The predicate that matches no request can be referenced in eskip
+	// by the name "False".
+	NameFalse = "False"
+)
+
+type (
+	spec struct {
+		name  string
+		value bool
+	}
+
+	predicate bool
+)
+
+// NewTrue creates a predicate specification, whose instances match
+// every request. A route with only the True predicate is equivalent
+// with a route with the catch-all condition, *. The predicate doesn't
+// accept arguments.
+func NewTrue() routing.PredicateSpec { return &spec{name: NameTrue, value: true} }
+
+// NewFalse creates a predicate specification, whose instances match no
+// request, so the routes using it are never selected. The predicate
+// doesn't accept arguments.
+func NewFalse() routing.PredicateSpec { return &spec{name: NameFalse, value: false} }
+
+func (s *spec) Name() string { return s.name }
+
+func (s *spec) Create(args []interface{}) (routing.Predicate, error) {
+	if err := predicates.NewArgs(s.name, args).Count(0, 0); err != nil {
+		return nil, err
+	}
+
+	return predicate(s.value), nil
+}
+
+func (p predicate) Match(*http.Request) bool { return bool(p) }
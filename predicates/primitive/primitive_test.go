@@ -0,0 +1,97 @@
+package primitive
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/routing"
+)
+
+func TestPrimitiveArgs(t *testing.T) {
+	for _, s := range []routing.PredicateSpec{NewTrue(), NewFalse()} {
+		if _, err := s.Create(nil); err != nil {
+			t.Error(s.Name(), err)
+		}
+
+		if _, err := s.Create([]interface{}{"foo"}); err == nil {
+			t.Error(s.Name(), "failed to fail")
+		}
+	}
+}
+
+func TestPrimitiveMatch(t *testing.T) {
+	r := &http.Request{Header: make(http.Header)}
+	for _, ti := range []struct {
+		spec  routing.PredicateSpec
+		match bool
+	}{
+		{NewTrue(), true},
+		{NewFalse(), false},
+	} {
+		p, err := ti.spec.Create(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if p.Match(r) != ti.match {
+			t.Error(ti.spec.Name(), "failed to match as expected")
+		}
+	}
+}
+
+func TestPrimitiveRouting(t *testing.T) {
+	for _, ti := range []struct {
+		msg     string
+		doc     string
+		path    string
+		backend string
+	}{{
+		msg: "false doesn't win against the catch-all",
+		doc: `
+			disabled: Path("/foo") && False() -> "https://disabled.example.org";
+			catchAll: * -> "https://catchall.example.org"`,
+		path:    "/foo",
+		backend: "https://catchall.example.org",
+	}, {
+		msg:     "false never matches",
+		doc:     `disabled: False() -> "https://disabled.example.org"`,
+		path:    "/foo",
+		backend: "",
+	}, {
+		msg:     "true behaves like the catch-all",
+		doc:     `enabled: True() -> "https://enabled.example.org"`,
+		path:    "/foo",
+		backend: "https://enabled.example.org",
+	}, {
+		msg: "true with a path condition",
+		doc: `
+			enabled: Path("/foo") && True() -> "https://enabled.example.org";
+			catchAll: * -> "https://catchall.example.org"`,
+		path:    "/foo",
+		backend: "https://enabled.example.org",
+	}} {
+		defs, err := eskip.Parse(ti.doc)
+		if err != nil {
+			t.Fatal(ti.msg, err)
+		}
+
+		m, errs := routing.NewMatcher(defs, nil, []routing.PredicateSpec{NewTrue(), NewFalse()})
+		if len(errs) != 0 {
+			t.Fatal(ti.msg, errs)
+		}
+
+		req, err := http.NewRequest("GET", "https://www.example.org"+ti.path, nil)
+		if err != nil {
+			t.Fatal(ti.msg, err)
+		}
+
+		r, _ := m.Match(req)
+		switch {
+		case ti.backend == "" && r != nil:
+			t.Error(ti.msg, "unexpected match", r.Id)
+		case ti.backend != "" && (r == nil || r.Backend != ti.backend):
+			t.Error(ti.msg, "failed to match the expected route", r)
+		}
+	}
+}
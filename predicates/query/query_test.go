@@ -33,12 +33,12 @@ func TestQueryArgs(t *testing.T) {
 		false,
 	}, {
 		"match case regexp",
-		[]interface{}{"key", "value"},
+		[]interface{}{"key", "^value[0-9]+$"},
 		matches,
 		false,
 	}, {
 		"invalid regexp",
-		[]interface{}{"key", "value", `\`},
+		[]interface{}{"key", `\`},
 		0,
 		true,
 	}} {
@@ -108,11 +108,23 @@ func TestMatchArgs(t *testing.T) {
 		[]string{"value", "regexp"},
 		true,
 	}, {
-		"does not match non existing params",
+		"does not match non matching values",
 		[]interface{}{"key", "^regexp$"},
 		"key",
 		[]string{"value", "value2"},
 		false,
+	}, {
+		"does not match non existing params with regexp",
+		[]interface{}{"keyNot", ".*"},
+		"key",
+		[]string{"value"},
+		false,
+	}, {
+		"match empty value",
+		[]interface{}{"key", "^$"},
+		"key",
+		[]string{""},
+		true,
 	},
 	} {
 		func() {
@@ -0,0 +1,128 @@
+/*
+Package scheme implements a predicate to match the scheme of the request,
+http or https.
+
+For the requests received by the server, the scheme is https when the
+request was received over TLS, and http otherwise. When skipper runs
+behind a load balancer terminating TLS, the scheme of the original
+request can be taken from the X-Forwarded-Proto header. Since the header
+can be set by the clients, too, it is used only when the predicate is
+created with the TrustForwardedProto option.
+
+Examples:
+
+	// only match requests received over https
+	example1: Scheme("https") -> "https://www.example.org";
+
+	// redirect the plain http requests to https
+	example2: Scheme("http") -> redirectTo(308, "https:") -> <shunt>;
+*/
+package scheme
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+// The predicate can be referenced in eskip by the name "Scheme".
+const Name = "Scheme"
+
+const forwardedProtoHeader = "X-Forwarded-Proto"
+
+// Options for the scheme predicate.
+type Options struct {
+
+	// When set, the scheme is taken from the first entry of the
+	// X-Forwarded-Proto header, when it is present.
+	TrustForwardedProto bool
+}
+
+type (
+	spec struct {
+		trustForwardedProto bool
+	}
+
+	predicate struct {
+		trustForwardedProto bool
+		schemes             []string
+	}
+)
+
+// New creates a predicate specification, whose instances match the
+// scheme of the request, ignoring the X-Forwarded-Proto header.
+//
+// The predicate accepts one or more arguments, the accepted schemes.
+// They are compared case insensitive.
+//
+// Eskip example:
+//
+// 	Scheme("https") -> "https://www.example.org";
+//
+func New() routing.PredicateSpec { return NewWithOptions(Options{}) }
+
+// NewWithOptions creates a predicate specification with the provided
+// options.
+func NewWithOptions(o Options) routing.PredicateSpec {
+	return &spec{trustForwardedProto: o.TrustForwardedProto}
+}
+
+func (s *spec) Name() string { return Name }
+
+func (s *spec) Create(args []interface{}) (routing.Predicate, error) {
+	a := predicates.NewArgs(Name, args)
+	if err := a.Count(1, -1); err != nil {
+		return nil, err
+	}
+
+	p := &predicate{trustForwardedProto: s.trustForwardedProto}
+	for i := 0; i < a.Len(); i++ {
+		scheme, err := a.String(i)
+		if err != nil {
+			return nil, err
+		}
+
+		scheme = strings.ToLower(strings.TrimSpace(scheme))
+		if scheme == "" {
+			return nil, predicates.ErrInvalidPredicateParameters
+		}
+
+		p.schemes = append(p.schemes, scheme)
+	}
+
+	return p, nil
+}
+
+// returns the scheme of the request. The URL of the incoming requests
+// received by the server doesn't contain the scheme, in which case it
+// is determined by the connection.
+func (p *predicate) scheme(r *http.Request) string {
+	if p.trustForwardedProto {
+		if h := r.Header.Get(forwardedProtoHeader); h != "" {
+			return strings.TrimSpace(strings.Split(h, ",")[0])
+		}
+	}
+
+	if r.URL != nil && r.URL.Scheme != "" {
+		return r.URL.Scheme
+	}
+
+	if r.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
+
+func (p *predicate) Match(r *http.Request) bool {
+	scheme := p.scheme(r)
+	for _, s := range p.schemes {
+		if strings.EqualFold(s, scheme) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,153 @@
+package scheme
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestSchemeArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg  string
+		args []interface{}
+		err  bool
+	}{{
+		"no args",
+		nil,
+		true,
+	}, {
+		"not a string",
+		[]interface{}{float64(1)},
+		true,
+	}, {
+		"empty",
+		[]interface{}{" "},
+		true,
+	}, {
+		"ok",
+		[]interface{}{"https"},
+		false,
+	}, {
+		"ok, multiple",
+		[]interface{}{"HTTP", "https"},
+		false,
+	}} {
+		p, err := New().Create(ti.args)
+		if ti.err && err == nil {
+			t.Error(ti.msg, "failed to fail")
+		} else if !ti.err && err != nil {
+			t.Error(ti.msg, err)
+		} else if !ti.err && p == nil {
+			t.Error(ti.msg, "failed to create predicate")
+		}
+	}
+}
+
+func TestSchemeMatch(t *testing.T) {
+	for _, ti := range []struct {
+		msg            string
+		trustForwarded bool
+		args           []interface{}
+		url            string
+		tls            bool
+		forwardedProto string
+		match          bool
+	}{{
+		msg:   "https from the url",
+		args:  []interface{}{"https"},
+		url:   "https://www.example.org/foo",
+		match: true,
+	}, {
+		msg:   "http from the url",
+		args:  []interface{}{"https"},
+		url:   "http://www.example.org/foo",
+		match: false,
+	}, {
+		msg:   "case insensitive",
+		args:  []interface{}{"HTTPS"},
+		url:   "https://www.example.org/foo",
+		match: true,
+	}, {
+		msg:   "one of multiple",
+		args:  []interface{}{"http", "https"},
+		url:   "http://www.example.org/foo",
+		match: true,
+	}, {
+		msg:   "incoming request over tls",
+		args:  []interface{}{"https"},
+		url:   "/foo",
+		tls:   true,
+		match: true,
+	}, {
+		msg:   "incoming request without tls",
+		args:  []interface{}{"https"},
+		url:   "/foo",
+		match: false,
+	}, {
+		msg:   "incoming plain http request",
+		args:  []interface{}{"http"},
+		url:   "/foo",
+		match: true,
+	}, {
+		msg:            "forwarded proto ignored by default",
+		args:           []interface{}{"https"},
+		url:            "/foo",
+		forwardedProto: "https",
+		match:          false,
+	}, {
+		msg:            "forwarded proto trusted",
+		trustForwarded: true,
+		args:           []interface{}{"https"},
+		url:            "/foo",
+		forwardedProto: "https",
+		match:          true,
+	}, {
+		msg:            "forwarded proto overrides the connection",
+		trustForwarded: true,
+		args:           []interface{}{"https"},
+		url:            "/foo",
+		tls:            true,
+		forwardedProto: "http",
+		match:          false,
+	}, {
+		msg:            "forwarded proto, first entry",
+		trustForwarded: true,
+		args:           []interface{}{"https"},
+		url:            "/foo",
+		forwardedProto: "HTTPS, http",
+		match:          true,
+	}, {
+		msg:            "trusted, but no forwarded proto",
+		trustForwarded: true,
+		args:           []interface{}{"https"},
+		url:            "/foo",
+		tls:            true,
+		match:          true,
+	}} {
+		p, err := NewWithOptions(Options{TrustForwardedProto: ti.trustForwarded}).Create(ti.args)
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		u, err := url.Parse(ti.url)
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		r := &http.Request{URL: u, Header: make(http.Header)}
+		if ti.tls {
+			r.TLS = &tls.ConnectionState{}
+		}
+
+		if ti.forwardedProto != "" {
+			r.Header.Set("X-Forwarded-Proto", ti.forwardedProto)
+		}
+
+		if p.Match(r) != ti.match {
+			t.Error(ti.msg, "failed to match as expected")
+		}
+	}
+}
@@ -11,13 +11,17 @@ It is important to note, that this predicate should not be used as
 the only gatekeeper for secure endpoints. Always use proper authorization
 and authentication for access control!
 
-To enable usage of this predicate behind loadbalancers or proxies, the
-X-Forwared-For header is used to determine the source of a request if it
-is available. If the X-Forwarded-For header is not present or does not contain
-a valid source address, the source IP of the incoming request is used for
-matching.
-
-The source predicate supports one or more IP addresses with or without a netmask.
+By default, the source IP of the incoming request is used for matching.
+Since the X-Forwarded-For header can be set by the clients, it is used
+only when the TrustForwardedFor option is set, e.g. to enable usage of
+this predicate behind loadbalancers or proxies. Then the first entry of
+the X-Forwarded-For header determines the source of a request, and when
+the header is not present or does not contain a valid source address,
+the source IP of the incoming request is used.
+
+The source predicate supports one or more IP addresses with or without a
+netmask. Addresses without a netmask match a single address, /32 for IPv4
+and /128 for IPv6.
 
 Examples:
 
@@ -29,6 +33,23 @@ Examples:
 
     // only match requests from 1.2.3.4 and the 2.2.2.0/24 network
     example3: Source("1.2.3.4", "2.2.2.0/24") -> "http://example.org";
+
+When running behind a known number of trusted proxies, the SourceFromLast
+predicate can be used. It takes the number of the trusted proxies as its
+first argument, and determines the source address by skipping as many
+entries from the end of the X-Forwarded-For header, so that the entries
+set by the clients are not trusted. When the header has fewer entries,
+the first one is used. When the header is not present, the source IP of
+the incoming request is used.
+
+Examples:
+
+    // behind a single load balancer, match the address that it received
+    // the request from
+    example4: SourceFromLast(0, "10.0.0.0/8") -> "http://example.org";
+
+    // behind a load balancer and a CDN
+    example5: SourceFromLast(1, "1.2.3.4", "2.2.2.0/24") -> "http://example.org";
 */
 package source
 
@@ -43,13 +64,40 @@ import (
 
 var InvalidArgsError = errors.New("invalid arguments")
 
-type spec struct{}
+// Options for the source predicate.
+type Options struct {
+
+	// When set, the source address is taken from the first entry of
+	// the X-Forwarded-For header, when it is valid.
+	TrustForwardedFor bool
+}
+
+type spec struct {
+	trustForwardedFor bool
+}
 
 type predicate struct {
+	trustForwardedFor  bool
 	acceptedSourceNets []net.IPNet
 }
 
-func New() routing.PredicateSpec { return &spec{} }
+type fromLastSpec struct{}
+
+type fromLastPredicate struct {
+	skip               int
+	acceptedSourceNets []net.IPNet
+}
+
+// New creates a predicate specification that takes the source address
+// only from the incoming connection, without trusting the
+// X-Forwarded-For header.
+func New() routing.PredicateSpec { return NewWithOptions(Options{}) }
+
+// NewWithOptions creates a predicate specification with the provided
+// options.
+func NewWithOptions(o Options) routing.PredicateSpec {
+	return &spec{trustForwardedFor: o.TrustForwardedFor}
+}
 
 func (s *spec) Name() string {
 	return "Source"
@@ -60,13 +108,52 @@ func (s *spec) Create(args []interface{}) (routing.Predicate, error) {
 		return nil, InvalidArgsError
 	}
 
-	p := &predicate{}
+	nets, err := parseNets(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &predicate{trustForwardedFor: s.trustForwardedFor, acceptedSourceNets: nets}, nil
+}
+
+// NewFromLast creates a predicate specification, whose instances match the
+// source address determined by skipping the trusted proxies in the
+// X-Forwarded-For header.
+func NewFromLast() routing.PredicateSpec { return &fromLastSpec{} }
+
+func (s *fromLastSpec) Name() string {
+	return "SourceFromLast"
+}
+
+func (s *fromLastSpec) Create(args []interface{}) (routing.Predicate, error) {
+	if len(args) < 2 {
+		return nil, InvalidArgsError
+	}
+
+	skip, ok := args[0].(float64)
+	if !ok || skip < 0 || skip != float64(int(skip)) {
+		return nil, InvalidArgsError
+	}
+
+	nets, err := parseNets(args[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &fromLastPredicate{skip: int(skip), acceptedSourceNets: nets}, nil
+}
 
+func parseNets(args []interface{}) ([]net.IPNet, error) {
+	var nets []net.IPNet
 	for i := range args {
 		if s, ok := args[i].(string); ok {
 			var netmask = s
 			if !strings.Contains(s, "/") {
-				netmask = s + "/32"
+				if strings.Contains(s, ":") {
+					netmask = s + "/128"
+				} else {
+					netmask = s + "/32"
+				}
 			}
 			_, net, err := net.ParseCIDR(netmask)
 
@@ -74,19 +161,37 @@ func (s *spec) Create(args []interface{}) (routing.Predicate, error) {
 				return nil, InvalidArgsError
 			}
 
-			p.acceptedSourceNets = append(p.acceptedSourceNets, *net)
+			nets = append(nets, *net)
 		} else {
 			return nil, InvalidArgsError
 		}
 	}
 
-	return p, nil
+	return nets, nil
 }
 
 func (p *predicate) Match(r *http.Request) bool {
-	src := snet.RemoteHost(r)
-	for _, acceptedNet := range p.acceptedSourceNets {
-		if acceptedNet.Contains(src) {
+	var src net.IP
+	if p.trustForwardedFor {
+		src = snet.RemoteHost(r)
+	} else {
+		src = snet.RemoteAddr(r)
+	}
+
+	return containsIP(p.acceptedSourceNets, src)
+}
+
+func (p *fromLastPredicate) Match(r *http.Request) bool {
+	return containsIP(p.acceptedSourceNets, snet.RemoteHostFromLast(r, p.skip))
+}
+
+func containsIP(nets []net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, acceptedNet := range nets {
+		if acceptedNet.Contains(ip) {
 			return true
 		}
 	}
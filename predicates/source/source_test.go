@@ -105,6 +105,11 @@ func TestMatching(t *testing.T) {
 		[]interface{}{"C0:FF::EE"},
 		&http.Request{RemoteAddr: "C0:FF::EE"},
 		true,
+	}, {
+		"single IPv6 address should not match its neighbour",
+		[]interface{}{"C0:FF::EE"},
+		&http.Request{RemoteAddr: "C0:FF::EF"},
+		false,
 	}, {
 		"should work for IPv6 with mask - pass",
 		[]interface{}{"C0:FF::EE/127"},
@@ -116,7 +121,7 @@ func TestMatching(t *testing.T) {
 		&http.Request{RemoteAddr: "C0:FF::EC"},
 		false,
 	}} {
-		pred, err := (&spec{}).Create(ti.args)
+		pred, err := NewWithOptions(Options{TrustForwardedFor: true}).Create(ti.args)
 		if err != nil {
 			t.Error("failed to create predicate", err)
 		} else {
@@ -127,3 +132,156 @@ func TestMatching(t *testing.T) {
 		}
 	}
 }
+
+func TestTrustForwardedFor(t *testing.T) {
+	for _, ti := range []struct {
+		msg     string
+		trust   bool
+		args    []interface{}
+		req     *http.Request
+		matches bool
+	}{{
+		"in range, remote address",
+		false,
+		[]interface{}{"10.0.0.0/8", "192.168.0.0/16"},
+		&http.Request{RemoteAddr: "192.168.1.2:4242"},
+		true,
+	}, {
+		"out of range, remote address",
+		false,
+		[]interface{}{"10.0.0.0/8", "192.168.0.0/16"},
+		&http.Request{RemoteAddr: "172.16.1.2:4242"},
+		false,
+	}, {
+		"forwarded header ignored when not trusted",
+		false,
+		[]interface{}{"10.0.0.0/8"},
+		&http.Request{RemoteAddr: "172.16.1.2:4242", Header: http.Header{"X-Forwarded-For": []string{"10.1.2.3"}}},
+		false,
+	}, {
+		"forwarded header used when trusted",
+		true,
+		[]interface{}{"10.0.0.0/8"},
+		&http.Request{RemoteAddr: "172.16.1.2:4242", Header: http.Header{"X-Forwarded-For": []string{"10.1.2.3"}}},
+		true,
+	}, {
+		"remote address used when trusted and no forwarded header",
+		true,
+		[]interface{}{"10.0.0.0/8"},
+		&http.Request{RemoteAddr: "10.1.2.3:4242", Header: http.Header{}},
+		true,
+	}, {
+		"IPv6 remote address",
+		false,
+		[]interface{}{"2001:db8::/32"},
+		&http.Request{RemoteAddr: "[2001:db8::1]:4242"},
+		true,
+	}} {
+		p, err := NewWithOptions(Options{TrustForwardedFor: ti.trust}).Create(ti.args)
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		if p.Match(ti.req) != ti.matches {
+			t.Error(ti.msg, "failed to match as expected")
+		}
+	}
+}
+
+func TestForwardedForNotTrustedByDefault(t *testing.T) {
+	p, err := New().Create([]interface{}{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Match(&http.Request{RemoteAddr: "172.16.1.2:4242", Header: http.Header{"X-Forwarded-For": []string{"10.1.2.3"}}}) {
+		t.Error("failed to ignore the forwarded header")
+	}
+
+	if !p.Match(&http.Request{RemoteAddr: "10.1.2.3:4242"}) {
+		t.Error("failed to match the remote address")
+	}
+}
+
+func TestFromLast(t *testing.T) {
+	for _, ti := range []struct {
+		msg     string
+		args    []interface{}
+		xff     []string
+		err     bool
+		matches bool
+	}{{
+		msg:  "no networks",
+		args: []interface{}{1.0},
+		err:  true,
+	}, {
+		msg:  "skip not a number",
+		args: []interface{}{"1", "10.0.0.0/8"},
+		err:  true,
+	}, {
+		msg:  "negative skip",
+		args: []interface{}{-1.0, "10.0.0.0/8"},
+		err:  true,
+	}, {
+		msg:  "fractional skip",
+		args: []interface{}{1.5, "10.0.0.0/8"},
+		err:  true,
+	}, {
+		msg:  "invalid network",
+		args: []interface{}{1.0, "all the things"},
+		err:  true,
+	}, {
+		msg:     "no header, remote address",
+		args:    []interface{}{1.0, "127.0.0.0/8"},
+		matches: true,
+	}, {
+		msg:     "skip none",
+		args:    []interface{}{0.0, "10.0.0.3"},
+		xff:     []string{"10.0.0.1, 10.0.0.2, 10.0.0.3"},
+		matches: true,
+	}, {
+		msg:     "skip one",
+		args:    []interface{}{1.0, "10.0.0.2"},
+		xff:     []string{"10.0.0.1, 10.0.0.2, 10.0.0.3"},
+		matches: true,
+	}, {
+		msg:     "skip one, spoofed first entry does not match",
+		args:    []interface{}{1.0, "10.0.0.1"},
+		xff:     []string{"10.0.0.1, 10.0.0.2, 10.0.0.3"},
+		matches: false,
+	}, {
+		msg:     "skip more than the chain",
+		args:    []interface{}{7.0, "10.0.0.1"},
+		xff:     []string{"10.0.0.1, 10.0.0.2, 10.0.0.3"},
+		matches: true,
+	}, {
+		msg:     "malformed entry",
+		args:    []interface{}{1.0, "0.0.0.0/0"},
+		xff:     []string{"10.0.0.1, invalid, 10.0.0.3"},
+		matches: false,
+	}} {
+		p, err := NewFromLast().Create(ti.args)
+		if ti.err {
+			if err == nil {
+				t.Error(ti.msg, "failed to fail")
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		req := &http.Request{RemoteAddr: "127.0.0.1:4242", Header: http.Header{}}
+		if ti.xff != nil {
+			req.Header["X-Forwarded-For"] = ti.xff
+		}
+
+		if p.Match(req) != ti.matches {
+			t.Error(ti.msg, "failed to match as expected")
+		}
+	}
+}
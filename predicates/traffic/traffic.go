@@ -0,0 +1,89 @@
+/*
+Package traffic implements a predicate to match a given share of the
+requests, e.g. to route the traffic of the variants in A/B experiments.
+*/
+package traffic
+
+import (
+	"net/http"
+
+	"github.com/zalando/skipper/internal/sample"
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+// The predicate can be referenced in eskip by the name "Traffic".
+const Name = "Traffic"
+
+type (
+	spec struct{}
+
+	predicate struct {
+		chance float64
+		header string
+	}
+)
+
+// New creates a predicate specification, whose instances match a share
+// of the requests.
+//
+// The first argument is the matched share of the requests, between 0
+// and 1. The second, optional argument is the name of a header, e.g.
+// X-User-Id. When set, the requests are assigned to buckets by the hash
+// of the header value, so the requests with the same value are either
+// all matched or all not matched, and the users stick to the same
+// variant of an experiment. The requests without the header, or when
+// the header is not set in the arguments, are matched randomly.
+//
+// To split the traffic between two variants, the route of the variant
+// B can use the Traffic predicate, while the route of the variant A is
+// the same route without it. Since the route with more conditions takes
+// precedence, the variant B receives the given share of the requests,
+// and the variant A receives the rest.
+//
+// Eskip example:
+//
+// 	variantA: Path("/checkout") -> "https://a.example.org";
+// 	variantB: Path("/checkout") && Traffic(0.2, "X-User-Id") -> "https://b.example.org";
+//
+func New() routing.PredicateSpec { return &spec{} }
+
+func (s *spec) Name() string { return Name }
+
+func (s *spec) Create(args []interface{}) (routing.Predicate, error) {
+	a := predicates.NewArgs(Name, args)
+	if err := a.Count(1, 2); err != nil {
+		return nil, err
+	}
+
+	chance, err := a.Float(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if chance < 0 || chance > 1 {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+
+	p := &predicate{chance: chance}
+	if a.Len() == 2 {
+		if p.header, err = a.String(1); err != nil {
+			return nil, err
+		}
+
+		if p.header == "" {
+			return nil, predicates.ErrInvalidPredicateParameters
+		}
+	}
+
+	return p, nil
+}
+
+func (p *predicate) Match(r *http.Request) bool {
+	var key string
+	if p.header != "" {
+		key = r.Header.Get(p.header)
+	}
+
+	return sample.Selected(key, p.chance)
+}
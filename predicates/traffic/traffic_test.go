@@ -0,0 +1,149 @@
+package traffic
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters/builtin"
+	"github.com/zalando/skipper/routing"
+)
+
+func TestTrafficArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg  string
+		args []interface{}
+		err  bool
+	}{{
+		"no args",
+		nil,
+		true,
+	}, {
+		"not a number",
+		[]interface{}{"0.2"},
+		true,
+	}, {
+		"negative",
+		[]interface{}{-0.2},
+		true,
+	}, {
+		"greater than one",
+		[]interface{}{1.2},
+		true,
+	}, {
+		"header not a string",
+		[]interface{}{0.2, 42.0},
+		true,
+	}, {
+		"empty header",
+		[]interface{}{0.2, ""},
+		true,
+	}, {
+		"too many args",
+		[]interface{}{0.2, "X-User-Id", "foo"},
+		true,
+	}, {
+		"ok",
+		[]interface{}{0.2},
+		false,
+	}, {
+		"ok, with header",
+		[]interface{}{0.2, "X-User-Id"},
+		false,
+	}} {
+		p, err := New().Create(ti.args)
+		if ti.err && err == nil {
+			t.Error(ti.msg, "failed to fail")
+		} else if !ti.err && err != nil {
+			t.Error(ti.msg, err)
+		} else if !ti.err && p == nil {
+			t.Error(ti.msg, "failed to create predicate")
+		}
+	}
+}
+
+func newSplitMatcher(t *testing.T, traffic string) *routing.Matcher {
+	defs, err := eskip.Parse(fmt.Sprintf(`
+		variantA: Path("/checkout") -> "https://a.example.org";
+		variantB: Path("/checkout") && %s -> "https://b.example.org"`, traffic))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, errs := routing.NewMatcher(defs, builtin.MakeRegistry(), []routing.PredicateSpec{New()})
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	return m
+}
+
+func matchVariant(t *testing.T, m *routing.Matcher, userId string) string {
+	r, err := http.NewRequest("GET", "https://www.example.org/checkout", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if userId != "" {
+		r.Header.Set("X-User-Id", userId)
+	}
+
+	rt, _ := m.Match(r)
+	if rt == nil {
+		t.Fatal("failed to match a variant")
+	}
+
+	return rt.Id
+}
+
+func TestTrafficSplit(t *testing.T) {
+	const n = 20000
+	for _, ti := range []struct {
+		msg     string
+		traffic string
+		sticky  bool
+		chance  float64
+	}{
+		{"random", "Traffic(0.2)", false, 0.2},
+		{"random, none", "Traffic(0)", false, 0},
+		{"random, all", "Traffic(1)", false, 1},
+		{"by header", `Traffic(0.2, "X-User-Id")`, true, 0.2},
+		{"by header, half", `Traffic(0.5, "X-User-Id")`, true, 0.5},
+		{"by header, none", `Traffic(0, "X-User-Id")`, true, 0},
+		{"by header, all", `Traffic(1, "X-User-Id")`, true, 1},
+	} {
+		m := newSplitMatcher(t, ti.traffic)
+
+		var b int
+		for i := 0; i < n; i++ {
+			var userId string
+			if ti.sticky {
+				userId = fmt.Sprintf("user-%d", i)
+			}
+
+			if matchVariant(t, m, userId) == "variantB" {
+				b++
+			}
+		}
+
+		if d := math.Abs(float64(b)/n - ti.chance); d > 0.02 {
+			t.Error(ti.msg, "failed to split the traffic", float64(b)/n)
+		}
+	}
+}
+
+func TestTrafficSticky(t *testing.T) {
+	m := newSplitMatcher(t, `Traffic(0.5, "X-User-Id")`)
+	for i := 0; i < 100; i++ {
+		userId := fmt.Sprintf("user-%d", i)
+		first := matchVariant(t, m, userId)
+		for j := 0; j < 10; j++ {
+			if v := matchVariant(t, m, userId); v != first {
+				t.Error("failed to stick to the variant", userId, first, v)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+/*
+Package weight implements a predicate to set the priority of a route
+explicitly.
+*/
+package weight
+
+import (
+	"net/http"
+
+	"github.com/zalando/skipper/predicates"
+	"github.com/zalando/skipper/routing"
+)
+
+// The predicate can be referenced in eskip by the name "Weight".
+const Name = "Weight"
+
+type (
+	spec struct{}
+
+	predicate int
+)
+
+// New creates a predicate specification, whose instances match every
+// request, but raise the priority of the routes they are used in.
+//
+// The predicate accepts a single argument, a non-negative integer. The
+// weight is added to the weights of the other weighted predicates of
+// the route. The routes with a higher weight are matched first, and
+// routes without a path condition take precedence over the routes
+// matched in the lookup tree, when their weight is higher. (See the
+// documentation of the routing package.)
+//
+// Eskip example:
+//
+// 	maintenance: * && Weight(100) -> "https://maintenance.example.org";
+//
+func New() routing.PredicateSpec { return &spec{} }
+
+func (s *spec) Name() string { return Name }
+
+func (s *spec) Create(args []interface{}) (routing.Predicate, error) {
+	a := predicates.NewArgs(Name, args)
+	if err := a.Count(1, 1); err != nil {
+		return nil, err
+	}
+
+	w, err := a.Int(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if w < 0 {
+		return nil, predicates.ErrInvalidPredicateParameters
+	}
+
+	return predicate(w), nil
+}
+
+func (p predicate) Match(*http.Request) bool { return true }
+func (p predicate) Weight() int              { return int(p) }
@@ -0,0 +1,106 @@
+package weight
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/filters/builtin"
+	"github.com/zalando/skipper/routing"
+	"github.com/zalando/skipper/routing/testdataclient"
+)
+
+func TestWeightArgs(t *testing.T) {
+	for _, ti := range []struct {
+		msg  string
+		args []interface{}
+		err  bool
+	}{{
+		"no args",
+		nil,
+		true,
+	}, {
+		"too many args",
+		[]interface{}{1.0, 2.0},
+		true,
+	}, {
+		"not a number",
+		[]interface{}{"100"},
+		true,
+	}, {
+		"not an integer",
+		[]interface{}{1.5},
+		true,
+	}, {
+		"negative",
+		[]interface{}{-1.0},
+		true,
+	}, {
+		"zero",
+		[]interface{}{0.0},
+		false,
+	}, {
+		"ok",
+		[]interface{}{100.0},
+		false,
+	}} {
+		p, err := New().Create(ti.args)
+		if ti.err && err == nil {
+			t.Error(ti.msg, "failed to fail")
+		} else if !ti.err && err != nil {
+			t.Error(ti.msg, err)
+		} else if !ti.err && !p.Match(&http.Request{}) {
+			t.Error(ti.msg, "failed to match")
+		}
+	}
+}
+
+func TestWeightOutranksPath(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+		specific: Path("/some/path") -> "https://specific.example.org";
+		catchAll: * -> "https://catchall.example.org";
+		boosted: * && Weight(100) && Header("X-Maintenance", "true") -> "https://maintenance.example.org"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updates := make(chan routing.RouteUpdate, 1)
+	rt := routing.New(routing.Options{
+		FilterRegistry:    builtin.MakeRegistry(),
+		Predicates:        []routing.PredicateSpec{New()},
+		DataClients:       []routing.DataClient{dc},
+		SignalRouteUpdate: updates})
+	defer rt.Close()
+
+	select {
+	case <-updates:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for the routes")
+	}
+
+	for _, ti := range []struct {
+		msg         string
+		path        string
+		maintenance bool
+		expected    string
+	}{
+		{"specific path", "/some/path", false, "specific"},
+		{"other path", "/other", false, "catchAll"},
+		{"weighted catch-all over specific path", "/some/path", true, "boosted"},
+		{"weighted catch-all over catch-all", "/other", true, "boosted"},
+	} {
+		req, err := http.NewRequest("GET", "https://www.example.org"+ti.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ti.maintenance {
+			req.Header.Set("X-Maintenance", "true")
+		}
+
+		r, _ := rt.Route(req)
+		if r == nil || r.Id != ti.expected {
+			t.Error(ti.msg, "failed to match the expected route", r)
+		}
+	}
+}
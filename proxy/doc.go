@@ -48,6 +48,13 @@ will prevent the request from reaching the route endpoint. The filters
 that are defined in the route after the one that broke the chain
 will never handle the request.
 
+Filters can also reject the matched route. In this case, the filter chain
+is stopped, and the request is handled by the next route that matches it,
+starting again from step 2. The response filters of the rejected route are
+not executed, but the changes made to the request by its request filters
+are kept. When there is no other matching route, the proxy responds with
+404.
+
 
 3.a upstream request:
 
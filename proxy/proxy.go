@@ -16,6 +16,7 @@ package proxy
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/base64"
 	"fmt"
@@ -168,11 +169,14 @@ type filterContext struct {
 	res                *http.Response
 	served             bool
 	servedWithResponse bool // to support the deprecated way independently
+	rejected           bool
 	pathParams         map[string]string
 	stateBag           map[string]interface{}
 	originalRequest    *http.Request
 	originalResponse   *http.Response
 	backendUrl         string
+	backendScheme      string
+	backendHost        string
 	outgoingHost       string
 }
 
@@ -220,16 +224,18 @@ func copyStream(to flusherWriter, from io.Reader) error {
 
 // creates an outgoing http request to be forwarded to the route endpoint
 // based on the augmented incoming request
-func mapRequest(r *http.Request, rt *routing.Route, host string) (*http.Request, error) {
+func mapRequest(r *http.Request, c *filterContext) (*http.Request, error) {
 	u := r.URL
-	u.Scheme = rt.Scheme
-	u.Host = rt.Host
+	u.Scheme = c.backendScheme
+	u.Host = c.backendHost
+	host := c.outgoingHost
 
 	rr, err := http.NewRequest(r.Method, u.String(), r.Body)
 	if err != nil {
 		return nil, err
 	}
 
+	rr = rr.WithContext(r.Context())
 	rr.Header = cloneHeader(r.Header)
 	rr.Host = host
 
@@ -243,6 +249,40 @@ func mapRequest(r *http.Request, rt *routing.Route, host string) (*http.Request,
 	return rr, nil
 }
 
+// tells if the request was cancelled, e.g. by a filter on timeout, or
+// because the client went away
+func isCanceled(r *http.Request) bool {
+	return r.Context().Err() != nil
+}
+
+// tells if the request was cancelled by a filter on timeout
+func isTimedOut(r *http.Request) bool {
+	return r.Context().Err() == context.DeadlineExceeded
+}
+
+// request bodies wrapped by filters can tell the status code of the
+// response, when forwarding the request failed because of the body,
+// e.g. when it exceeded a size limit
+type bodyStatus interface {
+	Status() (int, bool)
+}
+
+// filters can ask the proxy to repeat the request to the backend, when
+// it failed with an error or with an unacceptable response, e.g. the
+// retry filter. They are responsible for resetting the request body.
+type backendRetry interface {
+	RetryBackend(ctx filters.FilterContext, attempt int, rsp *http.Response, err error) bool
+}
+
+// filters can release the resources acquired in the request phase, e.g.
+// the concurrencyLimit filter. Cleanup is called once the request was
+// handled, also when the response filters were skipped, because the
+// request to the backend failed, or the route was rejected by one of
+// its filters.
+type filterCleanup interface {
+	Cleanup(filters.FilterContext)
+}
+
 // Deprecated, see WithParams and Params instead.
 func New(r *routing.Routing, options Options, pr ...PriorityRoute) *Proxy {
 	return WithParams(Params{
@@ -315,11 +355,20 @@ func (p *Proxy) newFilterContext(
 	route *routing.Route) *filterContext {
 
 	c := &filterContext{
-		w:          w,
-		req:        r,
-		pathParams: params,
-		stateBag:   make(map[string]interface{}),
-		backendUrl: route.Backend}
+		w:             w,
+		req:           r,
+		pathParams:    params,
+		stateBag:      make(map[string]interface{}),
+		backendUrl:    route.Backend,
+		backendScheme: route.Scheme,
+		backendHost:   route.Host}
+
+	// load balanced routes use a different backend for every request
+	if ep, ok := route.PickLBEndpoint(); ok {
+		c.backendUrl = ep.Address
+		c.backendScheme = ep.Scheme
+		c.backendHost = ep.Host
+	}
 
 	if p.flags.PreserveOriginal() {
 		c.originalRequest = cloneRequestMetadata(r)
@@ -328,7 +377,7 @@ func (p *Proxy) newFilterContext(
 	if p.flags.PreserveHost() {
 		c.outgoingHost = r.Host
 	} else {
-		c.outgoingHost = route.Host
+		c.outgoingHost = c.backendHost
 	}
 
 	return c
@@ -385,6 +434,7 @@ func (c *filterContext) OriginalRequest() *http.Request      { return c.original
 func (c *filterContext) OriginalResponse() *http.Response    { return c.originalResponse }
 func (c *filterContext) OutgoingHost() string                { return c.outgoingHost }
 func (c *filterContext) SetOutgoingHost(h string)            { c.outgoingHost = h }
+func (c *filterContext) RejectRoute()                        { c.rejected = true }
 
 func (c *filterContext) Serve(res *http.Response) {
 	res.Request = c.Request()
@@ -419,7 +469,7 @@ func (p *Proxy) applyFiltersToRequest(f []*routing.RouteFilter, ctx *filterConte
 		tryCatch(func() { fi.Request(ctx) }, onErr)
 		p.metrics.MeasureFilterRequest(fi.Name, start)
 		filters = append(filters, fi)
-		if ctx.served || ctx.servedWithResponse {
+		if ctx.served || ctx.servedWithResponse || ctx.rejected {
 			break
 		}
 	}
@@ -438,21 +488,42 @@ func (p *Proxy) applyFiltersToResponse(filters []*routing.RouteFilter, ctx filte
 	}
 }
 
+// calls the cleanup of the filters in reverse order
+func cleanupFilters(f []*routing.RouteFilter, ctx filters.FilterContext, onErr func(err interface{})) {
+	for i := len(f) - 1; i >= 0; i-- {
+		if fc, ok := f[i].Filter.(filterCleanup); ok {
+			tryCatch(func() { fc.Cleanup(ctx) }, onErr)
+		}
+	}
+}
+
 // addBranding overwrites any existing `X-Powered-By` or `Server` header from headerMap
 func addBranding(headerMap http.Header) {
 	headerMap.Set("X-Powered-By", "Skipper")
 	headerMap.Set("Server", "Skipper")
 }
 
-func (p *Proxy) lookupRoute(r *http.Request) (rt *routing.Route, params map[string]string) {
+func isRejected(rt *routing.Route, rejected []*routing.Route) bool {
+	for _, rj := range rejected {
+		if rj == rt {
+			return true
+		}
+	}
+
+	return false
+}
+
+// finds the route for a request, skipping the routes that were
+// rejected by their filters
+func (p *Proxy) lookupRoute(t *routing.Table, r *http.Request, rejected []*routing.Route) (rt *routing.Route, params map[string]string) {
 	for _, prt := range p.priorityRoutes {
 		rt, params = prt.Match(r)
-		if rt != nil {
+		if rt != nil && !isRejected(rt, rejected) {
 			return rt, params
 		}
 	}
 
-	return p.routing.Route(r)
+	return t.RouteExcluding(r, rejected...)
 }
 
 // send a premature error response
@@ -461,30 +532,38 @@ func sendError(w http.ResponseWriter, error string, code int) {
 	addBranding(w.Header())
 }
 
-// http.Handler implementation
-func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	start := time.Now()
-	rt, params := p.lookupRoute(r)
-	if rt == nil {
-		if p.flags.Debug() {
-			dbgResponse(w, &debugInfo{
-				incoming: r,
-				response: &http.Response{StatusCode: http.StatusNotFound}})
-			return
+// sends the request to the backend, and repeats it as long as the last
+// filter of the route implementing backendRetry asks for it. It returns
+// the request of the last attempt.
+func (p *Proxy) roundTrip(r, rr *http.Request, c *filterContext, f []*routing.RouteFilter) (*http.Request, *http.Response, error) {
+	var retry backendRetry
+	for _, fi := range f {
+		if br, ok := fi.Filter.(backendRetry); ok {
+			retry = br
 		}
-
-		p.metrics.IncRoutingFailures()
-		sendError(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
-		log.Debugf("Could not find a route for %v", r.URL)
-		return
 	}
 
-	p.metrics.MeasureRouteLookup(start)
+	rs, err := p.roundTripper.RoundTrip(rr)
+	for attempt := 0; retry != nil && !isCanceled(rr) && retry.RetryBackend(c, attempt, rs, err); attempt++ {
+		if rs != nil {
+			rs.Body.Close()
+		}
 
-	start = time.Now()
-	routeFilters := rt.Filters
-	c := p.newFilterContext(w, r, params, rt)
+		log.Debugf("Retrying request to %s, attempt: %d", c.backendUrl, attempt+1)
+		var next *http.Request
+		if next, err = mapRequest(r, c); err != nil {
+			return rr, nil, err
+		}
+
+		rr = next
+		rs, err = p.roundTripper.RoundTrip(rr)
+	}
+
+	return rr, rs, err
+}
 
+// http.Handler implementation
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var (
 		onErr        func(err interface{})
 		filterPanics []interface{}
@@ -499,10 +578,58 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	processedFilters := p.applyFiltersToRequest(routeFilters, c, onErr)
-	p.metrics.MeasureAllFiltersRequest(rt.Id, start)
+	var (
+		rt               *routing.Route
+		c                *filterContext
+		processedFilters []*routing.RouteFilter
+		rejected         []*routing.Route
+	)
+
+	// hold the routing table until the request is processed
+	table := p.routing.Acquire()
+	defer table.Release()
 
-	var debugReq *http.Request
+	for {
+		start := time.Now()
+		var params map[string]string
+		rt, params = p.lookupRoute(table, r, rejected)
+		if rt == nil {
+			if p.flags.Debug() {
+				dbgResponse(w, &debugInfo{
+					incoming:     r,
+					response:     &http.Response{StatusCode: http.StatusNotFound},
+					filterPanics: filterPanics})
+				return
+			}
+
+			p.metrics.IncRoutingFailures()
+			sendError(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			log.Debugf("Could not find a route for %v", r.URL)
+			return
+		}
+
+		p.metrics.MeasureRouteLookup(start)
+
+		start = time.Now()
+		c = p.newFilterContext(w, r, params, rt)
+		processedFilters = p.applyFiltersToRequest(rt.Filters, c, onErr)
+		p.metrics.MeasureAllFiltersRequest(rt.Id, start)
+
+		if !c.rejected {
+			break
+		}
+
+		log.Debugf("Route %s rejected by its filters", rt.Id)
+		cleanupFilters(processedFilters, c, onErr)
+		rejected = append(rejected, rt)
+	}
+
+	defer cleanupFilters(processedFilters, c, onErr)
+
+	var (
+		start    time.Time
+		debugReq *http.Request
+	)
 	if !c.served && !c.servedWithResponse {
 		var (
 			rs  *http.Response
@@ -513,7 +640,7 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if rt.Shunt {
 			rs = shunt(r)
 		} else if p.flags.Debug() {
-			debugReq, err = mapRequest(r, rt, c.outgoingHost)
+			debugReq, err = mapRequest(r, c)
 			if err != nil {
 				dbgResponse(w, &debugInfo{
 					route:        &rt.Route,
@@ -527,7 +654,7 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			rs = &http.Response{Header: make(http.Header)}
 		} else {
 
-			rr, err := mapRequest(r, rt, c.outgoingHost)
+			rr, err := mapRequest(r, c)
 			if err != nil {
 				log.Errorf("Could not mapRequest, caused by: %v", err)
 				return
@@ -535,9 +662,9 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 			if p.experimentalUpgrade && isUpgradeRequest(rr) {
 				// have to parse url again, because path is not be copied by mapRequest
-				backendURL, err := url.Parse(rt.Backend)
+				backendURL, err := url.Parse(c.backendUrl)
 				if err != nil {
-					log.Errorf("Can not parse backend %s, caused by: %s", rt.Backend, err)
+					log.Errorf("Can not parse backend %s, caused by: %s", c.backendUrl, err)
 					sendError(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
 					return
 				}
@@ -556,13 +683,19 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			rs, err = p.roundTripper.RoundTrip(rr)
-
+			rr, rs, err = p.roundTrip(r, rr, c, processedFilters)
 			if err != nil {
 				p.metrics.IncErrorsBackend(rt.Id)
-				sendError(w,
-					http.StatusText(http.StatusInternalServerError),
-					http.StatusInternalServerError)
+				status := http.StatusInternalServerError
+				if isTimedOut(rr) {
+					status = http.StatusGatewayTimeout
+				} else if bs, ok := r.Body.(bodyStatus); ok {
+					if s, failed := bs.Status(); failed {
+						status = s
+					}
+				}
+
+				sendError(w, http.StatusText(status), status)
 				log.Error(err)
 				return
 			}
@@ -139,8 +139,8 @@ func newTestProxy(doc string, flags Flags, pr ...PriorityRoute) (*testProxy, err
 }
 
 func (tp *testProxy) close() {
-	tp.log.Close()
 	tp.routing.Close()
+	tp.log.Close()
 	tp.proxy.Close()
 }
 
@@ -514,6 +514,212 @@ func TestBreakFilterChain(t *testing.T) {
 	}
 }
 
+type rejecter struct{}
+
+func (_ *rejecter) Request(c filters.FilterContext)                       { c.RejectRoute() }
+func (_ *rejecter) Response(filters.FilterContext)                        {}
+func (r *rejecter) CreateFilter(fc []interface{}) (filters.Filter, error) { return r, nil }
+func (_ *rejecter) Name() string                                          { return "rejecter" }
+
+func TestRejectedRouteFallsThrough(t *testing.T) {
+	s := startTestServer([]byte("Hello World!"), 0, func(r *http.Request) {
+		if r.Header.Get("X-Rejected") != "" {
+			t.Error("filter after the rejecter was applied")
+		}
+	})
+	defer s.Close()
+
+	fr := make(filters.Registry)
+	fr.Register(builtin.NewRequestHeader())
+	fr.Register(builtin.NewResponseHeader())
+	fr.Register(&rejecter{})
+
+	doc := fmt.Sprintf(`
+		rejected: Path("/hello") && Header("X-Test", "foo") ->
+		responseHeader("X-Rejected-Response", "bar") ->
+		rejecter() ->
+		requestHeader("X-Rejected", "foo") ->
+		<shunt>;
+
+		fallback: Path("/hello") -> "%s"`, s.URL)
+	tp, err := newTestProxyWithFilters(fr, doc, FlagsNone)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tp.close()
+
+	r, _ := http.NewRequest("GET", "https://www.example.org/hello", nil)
+	r.Header.Set("X-Test", "foo")
+	w := httptest.NewRecorder()
+	tp.proxy.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK || w.Body.String() != "Hello World!" {
+		t.Errorf("failed to fall through to the next route: %d - %s", w.Code, w.Body.String())
+	}
+
+	if _, has := w.Header()["X-Rejected-Response"]; has {
+		t.Error("response filter of the rejected route was applied")
+	}
+}
+
+func TestRejectingAllRoutesReturnsNotFound(t *testing.T) {
+	fr := make(filters.Registry)
+	fr.Register(&rejecter{})
+
+	tp, err := newTestProxyWithFilters(fr, `Path("/hello") -> rejecter() -> <shunt>`, FlagsNone)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tp.close()
+
+	r, _ := http.NewRequest("GET", "https://www.example.org/hello", nil)
+	w := httptest.NewRecorder()
+	tp.proxy.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Error("failed to return not found", w.Code)
+	}
+}
+
+// copies the path parameters, whose names are set in the arguments, to
+// the response headers, prefixed with X-Param-
+type paramsEcho struct{ names []string }
+
+func (_ *paramsEcho) Request(filters.FilterContext) {}
+func (_ *paramsEcho) Name() string                  { return "paramsEcho" }
+
+func (p *paramsEcho) CreateFilter(args []interface{}) (filters.Filter, error) {
+	f := &paramsEcho{}
+	for _, a := range args {
+		f.names = append(f.names, a.(string))
+	}
+
+	return f, nil
+}
+
+func (p *paramsEcho) Response(c filters.FilterContext) {
+	for _, n := range p.names {
+		c.Response().Header.Set("X-Param-"+n, c.PathParam(n))
+	}
+}
+
+func TestPathParams(t *testing.T) {
+	fr := make(filters.Registry)
+	fr.Register(&paramsEcho{})
+
+	tp, err := newTestProxyWithFilters(fr, `
+		single: Path("/users/:id") -> paramsEcho("id") -> <shunt>;
+		multiple: Path("/users/:id/items/:item") -> paramsEcho("id", "item") -> <shunt>;
+		rest: Path("/files/*rest") -> paramsEcho("rest") -> <shunt>`, FlagsNone)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tp.close()
+
+	for _, ti := range []struct {
+		msg    string
+		path   string
+		params map[string]string
+	}{{
+		"single param",
+		"/users/42",
+		map[string]string{"id": "42"},
+	}, {
+		"multiple params",
+		"/users/42/items/foo",
+		map[string]string{"id": "42", "item": "foo"},
+	}, {
+		"rest",
+		"/files/foo/bar/baz.txt",
+		map[string]string{"rest": "/foo/bar/baz.txt"},
+	}, {
+		"rest, single segment",
+		"/files/foo",
+		map[string]string{"rest": "/foo"},
+	}} {
+		r, _ := http.NewRequest("GET", "https://www.example.org"+ti.path, nil)
+		w := httptest.NewRecorder()
+		tp.proxy.ServeHTTP(w, r)
+
+		if w.Code != http.StatusNotFound {
+			t.Error(ti.msg, "failed to match the route", w.Code)
+			continue
+		}
+
+		for n, v := range ti.params {
+			if got := w.Header().Get("X-Param-" + n); got != v {
+				t.Error(ti.msg, "invalid path parameter", n, got, v)
+			}
+		}
+	}
+}
+
+// counts the calls to its cleanup
+type cleanupCounter struct {
+	mx    sync.Mutex
+	count int
+}
+
+func (_ *cleanupCounter) Request(filters.FilterContext)                        {}
+func (_ *cleanupCounter) Response(filters.FilterContext)                       {}
+func (c *cleanupCounter) CreateFilter(_ []interface{}) (filters.Filter, error) { return c, nil }
+func (_ *cleanupCounter) Name() string                                         { return "cleanupCounter" }
+
+func (c *cleanupCounter) Cleanup(filters.FilterContext) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.count++
+}
+
+func (c *cleanupCounter) calls() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.count
+}
+
+func TestCallsFilterCleanup(t *testing.T) {
+	counter := &cleanupCounter{}
+	fr := make(filters.Registry)
+	fr.Register(counter)
+	fr.Register(&rejecter{})
+
+	tp, err := newTestProxyWithFilters(fr, `
+		rejected: Path("/rejected") && Header("X-Test", "foo") -> cleanupCounter() -> rejecter() -> <shunt>;
+		fallback: Path("/rejected") -> cleanupCounter() -> <shunt>;
+		shunt: Path("/shunt") -> cleanupCounter() -> <shunt>;
+		failing: Path("/failing") -> cleanupCounter() -> "http://127.0.0.1:1"`, FlagsNone)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tp.close()
+
+	for _, ti := range []struct {
+		msg   string
+		path  string
+		calls int
+	}{
+		{"shunt", "/shunt", 1},
+		{"rejected and fallback", "/rejected", 2},
+		{"failing backend", "/failing", 1},
+	} {
+		before := counter.calls()
+		r, _ := http.NewRequest("GET", "https://www.example.org"+ti.path, nil)
+		r.Header.Set("X-Test", "foo")
+		tp.proxy.ServeHTTP(httptest.NewRecorder(), r)
+		if n := counter.calls() - before; n != ti.calls {
+			t.Error(ti.msg, "unexpected number of cleanups", n, ti.calls)
+		}
+	}
+}
+
 func TestProcessesRequestWithShuntBackend(t *testing.T) {
 	u, _ := url.ParseRequestURI("https://www.example.org/hello")
 	r := &http.Request{
@@ -868,3 +1074,50 @@ func TestHostHeader(t *testing.T) {
 		closeAll()
 	}
 }
+
+func TestLoadBalancedBackends(t *testing.T) {
+	var countA, countB int
+	var mx sync.Mutex
+	sa := startTestServer(nil, 0, func(*http.Request) {
+		mx.Lock()
+		countA++
+		mx.Unlock()
+	})
+	defer sa.Close()
+
+	sb := startTestServer(nil, 0, func(*http.Request) {
+		mx.Lock()
+		countB++
+		mx.Unlock()
+	})
+	defer sb.Close()
+
+	doc := fmt.Sprintf(`* -> <random, "%s", 90, "%s", 10>`, sa.URL, sb.URL)
+	tp, err := newTestProxy(doc, FlagsNone)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tp.close()
+
+	const n = 1000
+	for i := 0; i < n; i++ {
+		r, _ := http.NewRequest("GET", "https://www.example.org/hello", nil)
+		w := httptest.NewRecorder()
+		tp.proxy.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Error("failed to proxy request", w.Code)
+			return
+		}
+	}
+
+	if countA+countB != n {
+		t.Error("invalid number of requests", countA, countB)
+		return
+	}
+
+	if ratio := float64(countA) / n; ratio < .85 || ratio > .95 {
+		t.Error("failed to split traffic by the weights", ratio)
+	}
+}
@@ -1,8 +1,14 @@
 package routing
 
 import (
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand"
 	"net/url"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/zalando/skipper/eskip"
@@ -35,9 +41,14 @@ type incomingData struct {
 	client         DataClient
 	upsertedRoutes []*eskip.Route
 	deletedIds     []string
+	invalid        []RouteError
 }
 
 func (d *incomingData) log(l logging.Logger) {
+	if logging.Discards(l) {
+		return
+	}
+
 	for _, r := range d.upsertedRoutes {
 		l.Infof("route settings, %v, route: %v: %v", d.typ, r.Id, r)
 	}
@@ -47,46 +58,166 @@ func (d *incomingData) log(l logging.Logger) {
 	}
 }
 
+// returns the delay before the next attempt of an exponential backoff
+// after n failures, with jitter. The delay is between the half and the
+// whole of the exponential value, and it is capped at max.
+func backoff(base, max time.Duration, n int) time.Duration {
+	d := base
+	for i := 1; i < n && d < max; i++ {
+		d *= 2
+	}
+
+	if d > max {
+		d = max
+	}
+
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// returns the poll timeout of a data client, or the one from the
+// options if the client doesn't define one
+func pollTimeout(c DataClient, o Options) time.Duration {
+	if pc, ok := c.(PollIntervalDataClient); ok {
+		if i := pc.PollInterval(); i > 0 {
+			return i
+		}
+	}
+
+	return o.PollTimeout
+}
+
+type loadResult struct {
+	routes     []*eskip.Route
+	deletedIDs []string
+	reset      bool
+	invalid    []RouteError
+	err        error
+}
+
+// returned to the streaming data clients, when the routing was closed
+// while receiving the chunks of route definitions
+var errLoadStopped = errors.New("loading stopped")
+
+// requests the whole set of routes from a data client, in chunks, when
+// it supports streaming, and assembles them. Stops receiving the chunks
+// when quit is closed.
+func loadAll(c DataClient, quit <-chan struct{}) ([]*eskip.Route, error) {
+	sc, ok := c.(StreamingDataClient)
+	if !ok {
+		return c.LoadAll()
+	}
+
+	var routes []*eskip.Route
+	err := sc.LoadAllChunks(func(chunk []*eskip.Route) error {
+		select {
+		case <-quit:
+			return errLoadStopped
+		default:
+		}
+
+		routes = append(routes, chunk...)
+		return nil
+	})
+
+	return routes, err
+}
+
+// requests the whole set of routes or the updates from a data client
+// in a separate goroutine, so that the polling loop can stop when quit
+// is closed. The goroutine is tracked by pending, so that the caller can
+// wait for the pending request to return.
+func load(c DataClient, initial bool, quit <-chan struct{}, pending *sync.WaitGroup) <-chan loadResult {
+	results := make(chan loadResult, 1)
+	pending.Add(1)
+	go func() {
+		defer pending.Done()
+		var lr loadResult
+		if initial {
+			lr.routes, lr.err = loadAll(c, quit)
+		} else {
+			lr.routes, lr.deletedIDs, lr.err = c.LoadUpdate()
+			if rc, ok := c.(ResetDataClient); ok && errors.Is(lr.err, ErrReset) {
+				lr.deletedIDs = nil
+				lr.routes, lr.err = rc.Reset()
+				lr.reset = true
+			}
+		}
+
+		if ic, ok := c.(InvalidRoutesDataClient); ok && lr.err == nil {
+			lr.invalid = ic.InvalidRoutes()
+		}
+
+		results <- lr
+	}()
+
+	return results
+}
+
 // continously receives route definitions from a data client on the the output channel.
-// The function does not return unless quit is closed. When started, it request for the
+// The function does not return unless quit is closed. When quit is closed, it cancels
+// the pending request to a CancelUpdateDataClient, waits for the pending request to
+// return, and discards its result. When started, it request for the
 // whole current set of routes, and continues polling for the subsequent updates. When a
 // communication error occurs, it re-requests the whole valid set, and continues polling.
-// Currently, the routes with the same id coming from different sources are merged in an
-// undeterministic way, but this may change in the future.
+// Failed requests for the whole set are retried with a backoff, when InitialPollBackoff
+// is set.
+// When a ResetDataClient signals a reset, the whole set is requested with Reset, and it
+// replaces the previously received routes of the client.
+// The routes with the same id coming from different data clients are resolved when
+// merging the received routes, according to the OnDuplicateId option, see
+// DuplicateIdPolicy.
 func receiveFromClient(c DataClient, o Options, out chan<- *incomingData, quit <-chan struct{}) {
 	initial := true
-	for {
-		var (
-			routes     []*eskip.Route
-			deletedIDs []string
-			err        error
-		)
+	failures := 0
+	pt := pollTimeout(c, o)
 
-		to := o.PollTimeout
+	var pending sync.WaitGroup
+	defer func() {
+		if cc, ok := c.(CancelUpdateDataClient); ok {
+			cc.CancelUpdate()
+		}
 
-		if initial {
-			routes, err = c.LoadAll()
-		} else {
-			routes, deletedIDs, err = c.LoadUpdate()
+		pending.Wait()
+	}()
+
+	for {
+		var lr loadResult
+		select {
+		case lr = <-load(c, initial, quit, &pending):
+		case <-quit:
+			return
 		}
 
+		routes, deletedIDs, reset, err := lr.routes, lr.deletedIDs, lr.reset, lr.err
+		to := pt
+
 		switch {
 		case err != nil && initial:
 			o.Log.Error("error while receiveing initial data;", err)
+			failures++
+			if o.InitialPollBackoff > 0 {
+				to = backoff(o.InitialPollBackoff, pt, failures)
+			}
 		case err != nil:
 			o.Log.Error("error while receiving update;", err)
 			initial = true
 			to = 0
-		case initial || len(routes) > 0 || len(deletedIDs) > 0:
-			initial = false
-
+		case initial || reset || len(routes) > 0 || len(deletedIDs) > 0:
 			var incoming *incomingData
-			if initial {
-				incoming = &incomingData{incomingReset, c, routes, nil}
+			if initial || reset {
+				incoming = &incomingData{incomingReset, c, routes, nil, lr.invalid}
 			} else {
-				incoming = &incomingData{incomingUpdate, c, routes, deletedIDs}
+				incoming = &incomingData{incomingUpdate, c, routes, deletedIDs, lr.invalid}
 			}
 
+			initial = false
+			failures = 0
+
 			select {
 			case out <- incoming:
 			case <-quit:
@@ -117,6 +248,10 @@ func applyIncoming(defs routeDefs, d *incomingData) routeDefs {
 
 	if d.typ == incomingReset || d.typ == incomingUpdate {
 		for _, def := range d.upsertedRoutes {
+			if def.Id == "" {
+				def = withContentId(def)
+			}
+
 			defs[def.Id] = def
 		}
 	}
@@ -124,21 +259,97 @@ func applyIncoming(defs routeDefs, d *incomingData) routeDefs {
 	return defs
 }
 
-// merges the route definitions from multiple data clients by route id
-func mergeDefs(defsByClient map[DataClient]routeDefs) []*eskip.Route {
+// returns a copy of an anonymous route definition, with the id derived
+// from its content, so that it is identified the same way across the
+// updates
+func withContentId(def *eskip.Route) *eskip.Route {
+	c := *def
+	c.Id = eskip.ContentId(def)
+	return &c
+}
+
+// the merged route definitions from the data clients
+type mergedDefs struct {
+	routes []*eskip.Route
+
+	// the route ids received from more than one data client
+	duplicates []RouteError
+
+	// the errors reported by the data clients
+	invalid []RouteError
+
+	// the sources of the route definitions by route id
+	sources map[string]string
+
+	// set when all the data clients delivered their initial set
+	// of route definitions
+	initialized bool
+}
+
+type routeErrorsById []RouteError
+
+func (e routeErrorsById) Len() int           { return len(e) }
+func (e routeErrorsById) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e routeErrorsById) Less(i, j int) bool { return e[i].Id < e[j].Id }
+
+// returns the identifier of a data client, used as the source of its
+// routes. The index is the position of the client in the options.
+func dataClientSource(c DataClient, index int) string {
+	if ic, ok := c.(IdDataClient); ok {
+		return ic.Id()
+	}
+
+	return fmt.Sprintf("%T/%d", c, index)
+}
+
+// merges the route definitions from multiple data clients by route id,
+// in the order of the data clients, resolving the duplicate ids by the
+// policy. Returns the merged definitions, the duplicate ids, and the
+// sources of the merged definitions by id.
+func mergeDefs(clients []DataClient, defsByClient map[DataClient]routeDefs, policy DuplicateIdPolicy) ([]*eskip.Route, []RouteError, map[string]string) {
+	var (
+		ids        []string
+		duplicates []RouteError
+	)
+
 	mergeById := make(routeDefs)
-	for _, defs := range defsByClient {
-		for id, def := range defs {
-			mergeById[id] = def
+	sourceById := make(map[string]string)
+	count := make(map[string]int)
+	for i, c := range clients {
+		source := dataClientSource(c, i)
+		for id, def := range defsByClient[c] {
+			count[id]++
+			if count[id] == 1 {
+				ids = append(ids, id)
+				mergeById[id] = def
+				sourceById[id] = source
+				continue
+			}
+
+			if count[id] == 2 && policy != DuplicateIdOverride {
+				duplicates = append(duplicates, RouteError{id, ErrDuplicateRouteId})
+			}
+
+			if policy == DuplicateIdLastWins || policy == DuplicateIdOverride {
+				mergeById[id] = def
+				sourceById[id] = source
+			}
 		}
 	}
 
+	sort.Sort(routeErrorsById(duplicates))
+
 	var all []*eskip.Route
-	for _, def := range mergeById {
-		all = append(all, def)
+	for _, id := range ids {
+		if count[id] > 1 && policy == DuplicateIdError {
+			delete(sourceById, id)
+			continue
+		}
+
+		all = append(all, mergeById[id])
 	}
 
-	return all
+	return all, duplicates, sourceById
 }
 
 // receives the initial set of the route definitiosn and their
@@ -147,16 +358,27 @@ func mergeDefs(defsByClient map[DataClient]routeDefs) []*eskip.Route {
 //
 // The active set of routes from last successful update are used until the
 // next successful update.
-func receiveRouteDefs(o Options, quit <-chan struct{}) <-chan []*eskip.Route {
+//
+// When the batch window is set, the updates received within the window,
+// from the same or different data clients, are merged and sent as a
+// single update.
+func receiveRouteDefs(o Options, quit <-chan struct{}, wg *sync.WaitGroup) <-chan mergedDefs {
 	in := make(chan *incomingData)
-	out := make(chan []*eskip.Route)
+	out := make(chan mergedDefs)
 	defsByClient := make(map[DataClient]routeDefs)
+	invalidByClient := make(map[DataClient][]RouteError)
 
 	for _, c := range o.DataClients {
-		go receiveFromClient(c, o, in, quit)
+		wg.Add(1)
+		go func(c DataClient) {
+			defer wg.Done()
+			receiveFromClient(c, o, in, quit)
+		}(c)
 	}
 
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
 		for {
 			var incoming *incomingData
 			select {
@@ -165,12 +387,48 @@ func receiveRouteDefs(o Options, quit <-chan struct{}) <-chan []*eskip.Route {
 				return
 			}
 
-			incoming.log(o.Log)
-			c := incoming.client
-			defsByClient[c] = applyIncoming(defsByClient[c], incoming)
+			apply := func(incoming *incomingData) {
+				incoming.log(o.Log)
+				c := incoming.client
+				defsByClient[c] = applyIncoming(defsByClient[c], incoming)
+				if _, ok := c.(InvalidRoutesDataClient); ok {
+					invalidByClient[c] = incoming.invalid
+				}
+			}
+
+			apply(incoming)
+
+			// collecting the updates arriving within the batch window
+			// from the first one, to apply them together
+			if o.BatchWindow > 0 {
+				window := time.After(o.BatchWindow)
+			batch:
+				for {
+					select {
+					case incoming = <-in:
+						apply(incoming)
+					case <-window:
+						break batch
+					case <-quit:
+						return
+					}
+				}
+			}
+
+			routes, duplicates, sources := mergeDefs(o.DataClients, defsByClient, o.OnDuplicateId)
+
+			var invalid []RouteError
+			for _, c := range o.DataClients {
+				invalid = append(invalid, invalidByClient[c]...)
+			}
 
 			select {
-			case out <- mergeDefs(defsByClient):
+			case out <- mergedDefs{
+				routes:      routes,
+				duplicates:  duplicates,
+				invalid:     invalid,
+				sources:     sources,
+				initialized: len(defsByClient) == len(o.DataClients)}:
 			case <-quit:
 				return
 			}
@@ -183,7 +441,7 @@ func receiveRouteDefs(o Options, quit <-chan struct{}) <-chan []*eskip.Route {
 // splits the backend address of a route definition into separate
 // scheme and host variables.
 func splitBackend(r *eskip.Route) (string, string, error) {
-	if r.Shunt {
+	if r.Shunt || len(r.LBBackends) > 0 {
 		return "", "", nil
 	}
 
@@ -195,6 +453,25 @@ func splitBackend(r *eskip.Route) (string, string, error) {
 	return bu.Scheme, bu.Host, nil
 }
 
+// parses the addresses of the load balanced backends
+func processLBBackends(r *eskip.Route) ([]LBEndpoint, error) {
+	var eps []LBEndpoint
+	for _, b := range r.LBBackends {
+		bu, err := url.ParseRequestURI(b.Address)
+		if err != nil {
+			return nil, err
+		}
+
+		eps = append(eps, LBEndpoint{
+			Address: b.Address,
+			Scheme:  bu.Scheme,
+			Host:    bu.Host,
+			Weight:  b.Weight})
+	}
+
+	return eps, nil
+}
+
 // creates a filter instance based on its definition and its
 // specification in the filter registry.
 func createFilter(fr filters.Registry, def *eskip.Filter) (filters.Filter, error) {
@@ -203,7 +480,38 @@ func createFilter(fr filters.Registry, def *eskip.Filter) (filters.Filter, error
 		return nil, fmt.Errorf("filter not found: '%s'", def.Name)
 	}
 
-	return spec.CreateFilter(def.Args)
+	var f filters.Filter
+	err := tryCreate("filter", def.Name, func() (err error) {
+		if f, err = spec.CreateFilter(def.Args); err == nil && f == nil {
+			err = errNilInstance
+		}
+
+		return
+	})
+
+	return f, err
+}
+
+// returned by the creation of a filter or a predicate, when the
+// specification returned neither an instance nor an error, that would
+// make the requests fail when matching the route
+var errNilInstance = errors.New("no instance created")
+
+// calls the creation of a filter or a predicate, and turns a panic in
+// the extension code into an error, so that only the route containing
+// it is dropped
+func tryCreate(kind, name string, create func() error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%s '%s' panicked while created: %v", kind, name, p)
+		}
+	}()
+
+	if err = create(); err == errNilInstance {
+		err = fmt.Errorf("%s '%s' failed: %v", kind, name, err)
+	}
+
+	return err
 }
 
 // creates filter instances based on their definition
@@ -222,16 +530,41 @@ func createFilters(fr filters.Registry, defs []*eskip.Filter) ([]*RouteFilter, e
 	return fs, nil
 }
 
-// initialize predicate instances from their spec with the concrete arguments
+// initialize predicate instances from their spec with the concrete arguments.
+// The negated predicates can be either custom or built-in ones, and they are
+// wrapped to match the inverse.
 func processPredicates(cpm map[string]PredicateSpec, defs []*eskip.Predicate) ([]Predicate, error) {
 	cps := make([]Predicate, len(defs))
 	for i, def := range defs {
+		if def.Negated {
+			if bp, ok, err := createBuiltinPredicate(def); ok {
+				if err != nil {
+					return nil, err
+				}
+
+				cps[i] = negatedPredicate{bp}
+				continue
+			}
+		}
+
 		if spec, ok := cpm[def.Name]; ok {
-			cp, err := spec.Create(def.Args)
+			var cp Predicate
+			err := tryCreate("predicate", def.Name, func() (err error) {
+				if cp, err = spec.Create(def.Args); err == nil && cp == nil {
+					err = errNilInstance
+				}
+
+				return
+			})
+
 			if err != nil {
 				return nil, err
 			}
 
+			if def.Negated {
+				cp = negatedPredicate{cp}
+			}
+
 			cps[i] = cp
 		} else {
 			return nil, fmt.Errorf("predicate not found: '%s'", def.Name)
@@ -241,6 +574,27 @@ func processPredicates(cpm map[string]PredicateSpec, defs []*eskip.Predicate) ([
 	return cps, nil
 }
 
+// applies the preprocessors in order on copies of the route definitions,
+// so that the definitions received from the data clients are not
+// modified
+func preProcess(pps []func([]*eskip.Route) []*eskip.Route, defs []*eskip.Route) []*eskip.Route {
+	if len(pps) == 0 {
+		return defs
+	}
+
+	copies := make([]*eskip.Route, len(defs))
+	for i, def := range defs {
+		c := copyDefinition(*def)
+		copies[i] = &c
+	}
+
+	for _, pp := range pps {
+		copies = pp(copies)
+	}
+
+	return copies
+}
+
 // processes a route definition for the routing table
 func processRouteDef(cpm map[string]PredicateSpec, fr filters.Registry, def *eskip.Route) (*Route, error) {
 	scheme, host, err := splitBackend(def)
@@ -248,6 +602,11 @@ func processRouteDef(cpm map[string]PredicateSpec, fr filters.Registry, def *esk
 		return nil, err
 	}
 
+	eps, err := processLBBackends(def)
+	if err != nil {
+		return nil, err
+	}
+
 	fs, err := createFilters(fr, def.Filters)
 	if err != nil {
 		return nil, err
@@ -258,7 +617,14 @@ func processRouteDef(cpm map[string]PredicateSpec, fr filters.Registry, def *esk
 		return nil, err
 	}
 
-	return &Route{*def, scheme, host, cps, fs}, nil
+	return &Route{
+		Route:         *def,
+		Scheme:        scheme,
+		Host:          host,
+		Predicates:    cps,
+		Filters:       fs,
+		LBEndpoints:   eps,
+		predicateInfo: definitionPredicates(def)}, nil
 }
 
 // convert a slice of predicate specs to a map keyed by their names
@@ -271,45 +637,327 @@ func mapPredicates(cps []PredicateSpec) map[string]PredicateSpec {
 	return cpm
 }
 
+// returns the filter registry and the custom predicates of the options,
+// without the disabled ones
+func enabledSpecs(o Options) (filters.Registry, []PredicateSpec) {
+	if len(o.DisabledFilters) == 0 && len(o.DisabledPredicates) == 0 {
+		return o.FilterRegistry, o.Predicates
+	}
+
+	disabled := make(map[string]bool)
+	for _, name := range o.DisabledFilters {
+		disabled[name] = true
+	}
+
+	fr := make(filters.Registry)
+	for name, spec := range o.FilterRegistry {
+		if !disabled[name] {
+			fr[name] = spec
+		}
+	}
+
+	disabled = make(map[string]bool)
+	for _, name := range o.DisabledPredicates {
+		disabled[name] = true
+	}
+
+	var cps []PredicateSpec
+	for _, cp := range o.Predicates {
+		if !disabled[cp.Name()] {
+			cps = append(cps, cp)
+		}
+	}
+
+	return fr, cps
+}
+
 // processes a set of route definitions for the routing table
 func processRouteDefs(o Options, fr filters.Registry, defs []*eskip.Route) []*Route {
-	cpm := mapPredicates(o.Predicates)
+	routes, invalid := processRouteDefsWithErrors(o.Predicates, fr, defs)
+	for _, ri := range invalid {
+		o.Log.Error(ri)
+	}
+
+	return routes
+}
+
+// processes a set of route definitions for the routing table, and
+// returns the errors of the dropped ones
+func processRouteDefsWithErrors(cps []PredicateSpec, fr filters.Registry, defs []*eskip.Route) ([]*Route, []RouteError) {
+	return processRouteDefsReusing(cps, fr, defs, nil, &buildCache{}, &buildCache{})
+}
+
+// formats the line of a route definition in the checksum of the
+// routing table, also used as the key of the route content
+func checksumLine(def *eskip.Route) string {
+	return fmt.Sprintf("%s: %s;\n", def.Id, eskip.Canonical(def).String())
+}
+
+// processes a set of route definitions like processRouteDefsWithErrors,
+// but the definitions found in the reuse cache, either as the same
+// object or with the same content, are not processed again, and their
+// routes are taken from there, unless their source changed. When the
+// maps of keep are not nil, the routes of the valid definitions are
+// stored in it. The sources of the created routes are taken from
+// sources by the route id.
+func processRouteDefsReusing(cps []PredicateSpec, fr filters.Registry, defs []*eskip.Route, sources map[string]string, reuse, keep *buildCache) ([]*Route, []RouteError) {
+	cpm := mapPredicates(cps)
+
+	var (
+		routes  []*Route
+		invalid []RouteError
+	)
 
-	var routes []*Route
 	for _, def := range defs {
-		route, err := processRouteDef(cpm, fr, def)
-		if err == nil {
-			routes = append(routes, route)
-		} else {
-			o.Log.Error(err)
+		route, ok := reuse.routes[def]
+
+		var line string
+		if ok {
+			line = reuse.checksums[route]
+		} else if keep.byContent != nil {
+			line = checksumLine(def)
+			route, ok = reuse.byContent[line]
+		}
+
+		source := sources[def.Id]
+		if !ok || route.source != source {
+			var err error
+			if route, err = processRouteDef(cpm, fr, def); err != nil {
+				invalid = append(invalid, RouteError{def.Id, err})
+				continue
+			}
+
+			route.source = source
+		}
+
+		if keep.routes != nil {
+			keep.routes[def] = route
+			keep.checksums[route] = line
+			keep.byContent[line] = route
 		}
+
+		routes = append(routes, route)
 	}
 
-	return routes
+	return routes, invalid
+}
+
+// processes the default route from the options, if set
+func processDefaultRoute(o Options) (*Route, error) {
+	if o.DefaultRoute == nil {
+		return nil, nil
+	}
+
+	fr, cps := enabledSpecs(o)
+	return processRouteDef(mapPredicates(cps), fr, o.DefaultRoute)
+}
+
+// calculates a checksum of the route definitions, that are expected to
+// be ordered by id. The lines of the routes found in lines are not
+// formatted again.
+func checksum(routes []*Route, lines map[*Route]string) string {
+	h := fnv.New64a()
+	for _, r := range routes {
+		line, ok := lines[r]
+		if !ok {
+			line = checksumLine(&r.Route)
+		}
+
+		io.WriteString(h, line)
+	}
+
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// the results of processing the route definitions of a routing table,
+// kept for the next build when ReuseUnchangedRoutes is set
+type buildCache struct {
+
+	// the routes by their definitions
+	routes map[*eskip.Route]*Route
+
+	// the routes by the content of their definitions, for the data
+	// clients returning new objects for the unchanged definitions
+	byContent map[string]*Route
+
+	// the leaf matchers by their routes
+	leaves map[*Route]*leafMatcher
+
+	// the lines of the checksum by the routes
+	checksums map[*Route]string
+
+	// the matcher created from the routes, updated by the next build
+	// when possible
+	matcher *matcher
+}
+
+func newBuildCache() *buildCache {
+	return &buildCache{
+		routes:    make(map[*eskip.Route]*Route),
+		byContent: make(map[string]*Route),
+		leaves:    make(map[*Route]*leafMatcher),
+		checksums: make(map[*Route]string)}
+}
+
+// tells whether the processed routes can be reused by the next build.
+// They cannot be reused when the postprocessors may have modified them,
+// or when the retired routing tables are cleaned up.
+func reuseUnchangedRoutes(o Options) bool {
+	return o.ReuseUnchangedRoutes &&
+		len(o.PostProcessors) == 0 &&
+		o.RouteTableRetired == nil
+}
+
+// processes the merged route definitions, and creates the next routing
+// table from them. When ReuseUnchangedRoutes is set, the routes
+// of the unchanged definitions are taken from the cache of the previous
+// build, when not nil, and the matcher of the previous build is updated
+// instead of creating a new one, when possible. It returns the cache
+// for the next build, which is empty when the processed routes are not
+// reused.
+func buildMatcher(o Options, defs mergedDefs, prev *buildCache) (*matcher, *buildCache) {
+	start := time.Now()
+
+	reuse, keep := &buildCache{}, &buildCache{}
+	if reuseUnchangedRoutes(o) {
+		keep = newBuildCache()
+		if prev != nil {
+			reuse = prev
+		}
+	}
+
+	fr, cps := enabledSpecs(o)
+	routes, rejected := processRouteDefsReusing(cps, fr, preProcess(o.PreProcessors, defs.routes), defs.sources, reuse, keep)
+
+	var invalid []RouteError
+	invalid = append(invalid, defs.invalid...)
+	invalid = append(invalid, defs.duplicates...)
+	invalid = append(invalid, rejected...)
+	if !logging.Discards(o.Log) {
+		for _, ri := range invalid {
+			o.Log.Error(ri)
+		}
+	}
+
+	for _, pp := range o.PostProcessors {
+		routes = pp(routes)
+	}
+
+	var (
+		m       *matcher
+		errs    []*definitionError
+		updated bool
+	)
+
+	if reuse.matcher != nil {
+		m, errs, updated = reuse.matcher.update(routes, o.MatchingOptions, o.RouteOrdering, reuse.leaves, keep.leaves)
+	}
+
+	if !updated {
+		m, errs = newMatcherWith(routes, o.MatchingOptions, o.RouteOrdering, reuse.leaves, keep.leaves)
+	}
+
+	if keep.leaves != nil {
+		keep.matcher = m
+	}
+
+	for _, err := range errs {
+		o.Log.Error(err)
+		if err.Index >= 0 {
+			invalid = append(invalid, RouteError{err.Id, err.Original})
+		}
+	}
+
+	if dr, err := processDefaultRoute(o); err == nil {
+		m.defaultRoute = dr
+	} else {
+		o.Log.Error(err)
+		invalid = append(invalid, RouteError{o.DefaultRoute.Id, err})
+	}
+
+	m.invalidRoutes = invalid
+	m.checksum = checksum(m.routes, keep.checksums)
+	m.buildDuration = time.Since(start)
+	m.initialized = defs.initialized
+	return m, keep
+}
+
+// returns the route definitions of an update rejected because of the
+// MaxRoutes limit that differ from the active ones, as errors
+func rejectedRoutes(defs, active []*eskip.Route, err error) []RouteError {
+	activeById := make(map[string]*eskip.Route, len(active))
+	for _, d := range active {
+		activeById[d.Id] = d
+	}
+
+	var rejected []RouteError
+	for _, d := range defs {
+		if a, ok := activeById[d.Id]; !ok || !a.Equal(d) {
+			rejected = append(rejected, RouteError{d.Id, err})
+		}
+	}
+
+	return rejected
 }
 
 // receives the next version of the routing table on the output channel,
-// when an update is received on one of the data clients.
-func receiveRouteMatcher(o Options, out chan<- *matcher, quit <-chan struct{}) {
-	updates := receiveRouteDefs(o, quit)
+// when an update is received on one of the data clients, or when the
+// filter registry or the predicates were replaced. The new and changed
+// route definitions of the updates rejected because of the MaxRoutes
+// limit are passed to rejected, and rejected is called with nil when
+// the next update is accepted.
+func receiveRouteMatcher(o Options, optionsUpdates <-chan func(*Options), out chan<- *matcher, rejected func([]RouteError), quit <-chan struct{}, wg *sync.WaitGroup) {
+	updates := receiveRouteDefs(o, quit, wg)
 	var (
 		mout         *matcher
 		outRelay     chan<- *matcher
-		updatesRelay <-chan []*eskip.Route
+		updatesRelay <-chan mergedDefs
+
+		// the last received route definitions, to rebuild the
+		// routing table when the options change
+		last *mergedDefs
+
+		// the processed routes of the last routing table, when
+		// ReuseUnchangedRoutes is set
+		cache *buildCache
 	)
 
+	if len(o.DataClients) == 0 {
+		last = &mergedDefs{initialized: true}
+	}
+
 	updatesRelay = updates
 	for {
 		select {
 		case defs := <-updatesRelay:
 			o.Log.Info("route settings received")
-			routes := processRouteDefs(o, o.FilterRegistry, defs)
-			m, errs := newMatcher(routes, o.MatchingOptions)
-			for _, err := range errs {
-				o.Log.Error(err)
+			if o.MaxRoutes > 0 && len(defs.routes) > o.MaxRoutes {
+				var active []*eskip.Route
+				if last != nil {
+					active = last.routes
+				}
+
+				err := fmt.Errorf("%w: %d, limit: %d", ErrTooManyRoutes, len(defs.routes), o.MaxRoutes)
+				rejected(rejectedRoutes(defs.routes, active, err))
+				o.Log.Errorf("route settings rejected, too many routes: %d, limit: %d", len(defs.routes), o.MaxRoutes)
+				continue
+			}
+
+			rejected(nil)
+			last = &defs
+			mout, cache = buildMatcher(o, defs, cache)
+			updatesRelay = nil
+			outRelay = out
+		case update := <-optionsUpdates:
+			update(&o)
+			if last == nil {
+				continue
 			}
 
-			mout = m
+			// the filters or the predicates changed, so nothing
+			// can be reused
+			o.Log.Info("route settings reprocessed")
+			mout, cache = buildMatcher(o, *last, nil)
 			updatesRelay = nil
 			outRelay = out
 		case outRelay <- mout:
@@ -0,0 +1,59 @@
+package routing
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/logging"
+)
+
+func incomingRoutes(n int) *incomingData {
+	d := &incomingData{typ: incomingUpdate}
+	for i := 0; i < n; i++ {
+		d.upsertedRoutes = append(d.upsertedRoutes, &eskip.Route{
+			Id:      fmt.Sprintf("route%d", i),
+			Path:    fmt.Sprintf("/route%d", i),
+			Backend: "https://www.example.org"})
+		d.deletedIds = append(d.deletedIds, fmt.Sprintf("deleted%d", i))
+	}
+
+	return d
+}
+
+func TestNoopLoggerIncomingData(t *testing.T) {
+	d := incomingRoutes(100)
+	allocs := testing.AllocsPerRun(10, func() { d.log(logging.NoopLogger{}) })
+	if allocs != 0 {
+		t.Error("unexpected allocations when logging the incoming routes", allocs)
+	}
+}
+
+func BenchmarkLogIncomingNoop(b *testing.B) {
+	d := incomingRoutes(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.log(logging.NoopLogger{})
+	}
+}
+
+func TestDiffRoutesSorted(t *testing.T) {
+	routes := func(ids ...string) []*Route {
+		var r []*Route
+		for _, id := range ids {
+			r = append(r, &Route{Route: eskip.Route{Id: id, Backend: "https://www.example.org"}})
+		}
+
+		return r
+	}
+
+	prev := routes("route5", "route3", "route1", "route4", "route2")
+	for i := 0; i < 30; i++ {
+		u := diffRoutes(prev, routes("route9", "route8", "route7"))
+		if fmt.Sprint(u.Deleted) != "[route1 route2 route3 route4 route5]" ||
+			fmt.Sprint(u.Added) != "[route7 route8 route9]" {
+			t.Fatal("failed to sort the ids", u)
+		}
+	}
+}
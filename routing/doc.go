@@ -43,7 +43,22 @@ The following types of conditions are supported in the route definitions.
 - Path: the route definitions may contain a single path condition,
 optionally with wildcards, used for looking up routes in the lookup tree.
 
-- PathRegexp: regular expressions to match the path.
+- PathSubtree: a path prefix, used for looking up routes in the lookup
+tree, matching the prefix itself and any path under it. A route may
+contain either a Path or a PathSubtree condition.
+
+By default, the trailing slash of the path is significant for the Path
+conditions, e.g. Path("/foo") doesn't match /foo/. With the
+IgnoreTrailingSlash matching option, the Path conditions match the
+request paths with and without the trailing slash, while the routes are
+still looked up and prioritized the same way in the lookup tree. The
+PathSubtree conditions always match the prefix with and without the
+trailing slash, so they are not affected by the option.
+
+- PathRegexp: regular expressions to match the path. They don't take part
+in the lookup tree, so a route with only a PathRegexp condition has a lower
+priority than any route whose Path or PathSubtree condition matches the
+request, unless it has a higher predicate weight.
 
 - Host: regular expressions that the host header in the request must
 match.
@@ -80,6 +95,26 @@ predicate is matched based on the path tree, the predicate receives the
 request object, and it returns true or false meaning that the request is
 a match or not.
 
+Custom predicates can implement the WeightedPredicate interface, too. The
+weights of the predicates in a route are summed up, and they affect the
+route priority in the following order:
+
+1. Among the routes with the same path condition, the routes with a
+higher predicate weight are matched first.
+
+2. Among the routes with the same path condition and predicate weight,
+the routes with more conditions are matched first. This step can be
+replaced by a custom ordering, set in the RouteOrdering option.
+
+3. The routes without a path condition are matched only when no route
+was found in the lookup tree, unless their predicate weight is higher
+than the one of the route found in the lookup tree.
+
+Custom predicates that are expensive to evaluate can implement the
+CostPredicate interface. The custom predicates of a route are evaluated
+in the order of their cost, so that the expensive ones are not evaluated
+when a cheaper one doesn't match the request.
+
 
 Data Clients
 
@@ -93,14 +128,30 @@ on the filter specifications in the filter registry.
 During operation, the router regularly polls the data clients for
 updates, and, if an update is received, generates a new lookup tree. In
 case of communication failure during polling, it reloads the whole set
-of routes from the failing client.
+of routes from the failing client, and replaces the routes previously
+received from it. Data clients implementing the ResetDataClient
+interface can request the same explicitly, e.g. after a full resync of
+their backing store.
 
 The active set of routes from the last successful update are used until
 the next successful update happens.
 
-Currently, the routes with the same id coming from different sources are
-merged in an undeterministic way, but this behavior may change in the
-future.
+When the routes with the same id come from different sources, by
+default, the one from the data client that comes first in the
+DataClients option is used. Alternatively, the last one can be used, or
+all of them can be dropped, see Options.OnDuplicateId. Every collision
+is reported as an invalid route with ErrDuplicateRouteId.
+
+For layered configuration, the DuplicateIdOverride policy lets the data
+clients coming later in the DataClients option override the routes of
+the ones coming earlier, e.g. a base data client with the default routes
+followed by an override data client. In this case, the overrides are
+not reported as collisions.
+
+The routes received without an id get a synthetic one, derived from
+their content, see eskip.ContentId. The same anonymous route gets the
+same id on every update, while the identical anonymous routes from
+different data clients are treated as routes with the same id.
 
 For a full description of the route definitions, see the documentation
 of the skipper/eskip package.
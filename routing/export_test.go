@@ -0,0 +1,9 @@
+package routing
+
+// GeneratePaths exposes the path generator to the tests of the
+// routing_test package, returning a reproducible sequence of paths.
+func GeneratePaths(seed int64, count int, encodedChars bool) []string {
+	return takePaths(newPathGenerator(pathGeneratorOptions{
+		RandSeed:            seed,
+		IncludeEncodedChars: encodedChars}), count)
+}
@@ -21,28 +21,81 @@ import (
 	"net/http"
 	"regexp"
 	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type leafRequestMatcher struct {
-	r    *http.Request
-	path string
+	r         *http.Request
+	path      string
+	excluded  map[*Route]bool
+	overrides map[*pathMatcher]*pathMatcher
+
+	// the path matcher whose leaves were already evaluated, when set
+	skip *pathMatcher
 }
 
+// reused between the lookups, to avoid allocating the matcher passed
+// to the path tree for every request
+var leafRequestMatcherPool = sync.Pool{New: func() interface{} { return &leafRequestMatcher{} }}
+
 func (m *leafRequestMatcher) Match(value interface{}) (bool, interface{}) {
 	v := value.(*pathMatcher)
-	l := matchLeaves(v.leaves, m.r, m.path)
+	if v == m.skip {
+		return false, nil
+	}
+
+	v = currentPathMatcher(m.overrides, v)
+	l := matchLeavesExcluding(v.leaves, m.r, m.path, m.excluded)
 
 	return l != nil, l
 }
 
+// collects all the matching leaves while traversing the path tree.
+// It never reports a match, so that the lookup continues with the
+// remaining candidates.
+type allLeavesRequestMatcher struct {
+	r         *http.Request
+	path      string
+	overrides map[*pathMatcher]*pathMatcher
+	matches   []*leafMatcher
+}
+
+func (m *allLeavesRequestMatcher) Match(value interface{}) (bool, interface{}) {
+	v := currentPathMatcher(m.overrides, value.(*pathMatcher))
+	m.matches = append(m.matches, matchAllLeaves(v.leaves, m.r, m.path)...)
+	return false, nil
+}
+
+// collects all the leaf matchers of the paths matching a request,
+// without evaluating them. Like allLeavesRequestMatcher, it never
+// reports a match.
+type candidateLeavesRequestMatcher struct {
+	overrides  map[*pathMatcher]*pathMatcher
+	candidates []*leafMatcher
+}
+
+func (m *candidateLeavesRequestMatcher) Match(value interface{}) (bool, interface{}) {
+	v := currentPathMatcher(m.overrides, value.(*pathMatcher))
+	m.candidates = append(m.candidates, v.leaves...)
+	return false, nil
+}
+
 type leafMatcher struct {
-	method        string
-	hostRxs       []*regexp.Regexp
-	pathRxs       []*regexp.Regexp
-	headersExact  map[string]string
-	headersRegexp map[string][]*regexp.Regexp
-	predicates    []Predicate
-	route         *Route
+	method         string
+	hostRxs        []*regexp.Regexp
+	pathRxs        []*regexp.Regexp
+	headersExact   map[string]string
+	headersRegexp  map[string][]*regexp.Regexp
+	predicates     []Predicate
+	predicateNames []string
+	weight         int
+	subtree        bool
+	wildcardParam  string
+	ignoreHost     bool
+	route          *Route
 }
 
 type leafMatchers []*leafMatcher
@@ -63,10 +116,115 @@ func leafWeight(l *leafMatcher) int {
 	return w
 }
 
-// Sorting of leaf matchers:
-func (ls leafMatchers) Len() int           { return len(ls) }
-func (ls leafMatchers) Swap(i, j int)      { ls[i], ls[j] = ls[j], ls[i] }
-func (ls leafMatchers) Less(i, j int) bool { return leafWeight(ls[i]) > leafWeight(ls[j]) }
+// sums the weights of the weighted predicates
+func predicateWeight(ps []Predicate) int {
+	w := 0
+	for _, p := range ps {
+		if wp, ok := p.(WeightedPredicate); ok {
+			w += wp.Weight()
+		}
+	}
+
+	return w
+}
+
+func predicateCost(p Predicate) int {
+	if cp, ok := p.(CostPredicate); ok {
+		return cp.Cost()
+	}
+
+	return 0
+}
+
+// sorts the custom predicates of a route by their cost, keeping the
+// original order of the predicates with the same cost
+type predicatesByCost struct {
+	predicates []Predicate
+	names      []string
+}
+
+func (p predicatesByCost) Len() int { return len(p.predicates) }
+
+func (p predicatesByCost) Less(i, j int) bool {
+	return predicateCost(p.predicates[i]) < predicateCost(p.predicates[j])
+}
+
+func (p predicatesByCost) Swap(i, j int) {
+	p.predicates[i], p.predicates[j] = p.predicates[j], p.predicates[i]
+	p.names[i], p.names[j] = p.names[j], p.names[i]
+}
+
+// returns a copy of the custom predicates of a route and their names,
+// sorted by their cost
+func sortPredicates(r *Route) ([]Predicate, []string) {
+	p := predicatesByCost{
+		predicates: append([]Predicate(nil), r.Predicates...),
+		names:      make([]string, len(r.Predicates))}
+	for i := range p.names {
+		if i < len(r.Route.Predicates) {
+			p.names[i] = r.Route.Predicates[i].Name
+			if r.Route.Predicates[i].Negated {
+				p.names[i] = "!" + p.names[i]
+			}
+		}
+	}
+
+	sort.Stable(p)
+	return p.predicates, p.names
+}
+
+// Sorting of leaf matchers, first by the sum of the predicate
+// weights, then by the number of conditions:
+func (ls leafMatchers) Len() int      { return len(ls) }
+func (ls leafMatchers) Swap(i, j int) { ls[i], ls[j] = ls[j], ls[i] }
+func (ls leafMatchers) Less(i, j int) bool {
+	if ls[i].weight != ls[j].weight {
+		return ls[i].weight > ls[j].weight
+	}
+
+	wi, wj := leafWeight(ls[i]), leafWeight(ls[j])
+	if wi != wj {
+		return wi > wj
+	}
+
+	return !ls[i].subtree && ls[j].subtree
+}
+
+// Sorting of leaf matchers with a custom ordering of the routes. The
+// predicate weights take precedence over the custom ordering, too,
+// because the matching of the routes without path conditions relies
+// on it.
+type leavesByOrdering struct {
+	leafMatchers
+	less func(a, b *Route) bool
+}
+
+func (ls leavesByOrdering) Less(i, j int) bool {
+	li, lj := ls.leafMatchers[i], ls.leafMatchers[j]
+	if li.weight != lj.weight {
+		return li.weight > lj.weight
+	}
+
+	return ls.less(li.route, lj.route)
+}
+
+// sorts the leaves by the custom ordering, or, when it is nil, by the
+// default one
+func sortLeaves(ls leafMatchers, ordering func(a, b *Route) bool) {
+	if ordering == nil {
+		sort.Sort(ls)
+		return
+	}
+
+	sort.Sort(leavesByOrdering{ls, ordering})
+}
+
+// Sorting of routes by id:
+type routesById []*Route
+
+func (rs routesById) Len() int           { return len(rs) }
+func (rs routesById) Swap(i, j int)      { rs[i], rs[j] = rs[j], rs[i] }
+func (rs routesById) Less(i, j int) bool { return rs[i].Id < rs[j].Id }
 
 type pathMatcher struct {
 	leaves            leafMatchers
@@ -75,9 +233,62 @@ type pathMatcher struct {
 
 // root structure representing the routing tree.
 type matcher struct {
-	paths           *pathmux.Tree
-	rootLeaves      leafMatchers
+
+	// reference count, starts with the reference held by the routing
+	// while the matcher is the current one. Kept as the first field
+	// for the alignment required by the atomic operations.
+	refs int64
+
+	paths      *pathmux.Tree
+	rootLeaves leafMatchers
+
+	// the path matchers of the paths without wildcards, also stored
+	// in the tree. The path tree tries them first, so they can be
+	// looked up without the tree, avoiding its allocations.
+	staticPaths map[string]*pathMatcher
+
+	// the path matchers stored in the tree by their paths. Nil when
+	// the tree failed to store some of the paths, and the matcher
+	// cannot be updated.
+	pathMatchers map[string]*pathMatcher
+
+	// the path matchers replacing the ones stored in the tree, when
+	// the routes of their paths were changed by an update. The tree,
+	// and the path matchers stored in it, are shared with the
+	// matcher that was updated, so they are never modified.
+	overrides map[*pathMatcher]*pathMatcher
+
 	matchingOptions MatchingOptions
+	routes          []*Route
+
+	// route hit counters by route id, only set when the route
+	// metrics are enabled
+	hits map[string]*int64
+
+	// the match counters of the deprecated routes by route id
+	deprecations map[string]*deprecation
+
+	// the route definitions dropped while creating the matcher
+	invalidRoutes []RouteError
+
+	// returned when no other route matches, when set
+	defaultRoute *Route
+
+	// the time spent processing the route definitions and
+	// creating the matcher
+	buildDuration time.Duration
+
+	// identifies the routing table by the definitions of its
+	// routes
+	checksum string
+
+	// set when the matcher contains the initial routes of all the
+	// data clients
+	initialized bool
+
+	// the version of the routing table, increased by every applied
+	// update
+	version uint64
 }
 
 // An error created if a route definition cannot be processed.
@@ -98,11 +309,21 @@ func (err *definitionError) Error() string {
 // rx identifying the 'free form' wildcards at the end of the paths
 var freeWildcardRx = regexp.MustCompile("/[*][^/]+$")
 
+// the name used for all the free form wildcards in the lookup tree, to
+// allow routes with different wildcard names and subtrees under the same
+// path. The parameters are renamed after the lookup.
+const freeWildcardKey = "__free"
+
 // compiles all rxs or fails
 func compileRxs(exps []string) ([]*regexp.Regexp, error) {
+	return compileRxsPrefix("", exps)
+}
+
+// compiles all the regular expressions in a slice, prefixed with flags
+func compileRxsPrefix(prefix string, exps []string) ([]*regexp.Regexp, error) {
 	rxs := make([]*regexp.Regexp, len(exps))
 	for i, exp := range exps {
-		rx, err := regexp.Compile(exp)
+		rx, err := regexp.Compile(prefix + exp)
 		if err != nil {
 			return nil, err
 		}
@@ -136,8 +357,13 @@ func canonicalizeHeaderRegexps(hrx map[string][]*regexp.Regexp) map[string][]*re
 // creates a new leaf matcher. preprocesses the
 // Host, PathRegexp, Header and HeaderRegexp
 // conditions.
-func newLeaf(r *Route) (*leafMatcher, error) {
-	hostRxs, err := compileRxs(r.HostRegexps)
+func newLeaf(r *Route, o MatchingOptions) (*leafMatcher, error) {
+	var hostFlags string
+	if o.ignoreHostCase() {
+		hostFlags = "(?i)"
+	}
+
+	hostRxs, err := compileRxsPrefix(hostFlags, r.HostRegexps)
 	if err != nil {
 		return nil, err
 	}
@@ -158,14 +384,19 @@ func newLeaf(r *Route) (*leafMatcher, error) {
 		allHeaderRxs[k] = headerRxs
 	}
 
+	predicates, predicateNames := sortPredicates(r)
 	return &leafMatcher{
-		method:        r.Method,
-		hostRxs:       hostRxs,
-		pathRxs:       pathRxs,
-		headersExact:  canonicalizeHeaders(r.Headers),
-		headersRegexp: canonicalizeHeaderRegexps(allHeaderRxs),
-		predicates:    r.Predicates,
-		route:         r}, nil
+		method:         r.Method,
+		hostRxs:        hostRxs,
+		pathRxs:        pathRxs,
+		headersExact:   canonicalizeHeaders(r.Headers),
+		headersRegexp:  canonicalizeHeaderRegexps(allHeaderRxs),
+		predicates:     predicates,
+		predicateNames: predicateNames,
+		weight:         predicateWeight(r.Predicates),
+		subtree:        r.PathSubtree != "",
+		ignoreHost:     o.ignoreHostCase(),
+		route:          r}, nil
 }
 
 // returns the free form wildcard parameter of a path
@@ -179,6 +410,50 @@ func freeWildcardParam(path string) string {
 	return param[2:]
 }
 
+// normalizes a path for the lookup tree.
+// in case ignoring trailing slashes, store and match all paths
+// without the trailing slash
+func normalizeTreePath(p string, o MatchingOptions) string {
+	p = httppath.Clean(p)
+	if o.ignoreTrailingSlash() && p[len(p)-1] == '/' {
+		p = p[:len(p)-1]
+	}
+
+	return p
+}
+
+// returns the paths under which a route needs to be stored in the lookup
+// tree, and the name of its free form wildcard parameter, if any. Routes
+// with a path subtree are stored both with the exact path and with a
+// free form wildcard under it.
+func treePaths(r *Route, o MatchingOptions) ([]string, string) {
+	if r.PathSubtree != "" {
+		p := httppath.Clean(r.PathSubtree)
+		if p[len(p)-1] == '/' {
+			p = p[:len(p)-1]
+		}
+
+		if p == "" {
+			return []string{"/", "/*" + freeWildcardKey}, ""
+		}
+
+		return []string{p, p + "/*" + freeWildcardKey}, ""
+	}
+
+	p := normalizeTreePath(r.Path, o)
+	param := freeWildcardParam(p)
+	if param == "" {
+		return []string{p}, ""
+	}
+
+	return []string{p[:len(p)-len(param)] + freeWildcardKey}, param
+}
+
+// tells if a path in the lookup tree has no wildcard segments
+func isStaticPath(p string) bool {
+	return !strings.Contains(p, "/:") && !strings.Contains(p, "/*")
+}
+
 // constructs a matcher based on the provided definitions.
 //
 // If `ignoreTrailingSlash` is true, the matcher handles
@@ -191,59 +466,272 @@ func freeWildcardParam(path string) string {
 // on the rest of the conditions so that most strict route
 // definition matches first.
 func newMatcher(rs []*Route, o MatchingOptions) (*matcher, []*definitionError) {
+	return newMatcherWith(rs, o, nil, nil, nil)
+}
+
+// constructs a matcher like newMatcher, but orders the routes with the
+// same path condition, and the routes without a path condition, with
+// the provided ordering, when it is not nil. The leaf matchers of the
+// routes found in reuse are not created again, and when keep is not
+// nil, the leaf matchers of all the routes are stored in it.
+func newMatcherWith(rs []*Route, o MatchingOptions, ordering func(a, b *Route) bool, reuse, keep map[*Route]*leafMatcher) (*matcher, []*definitionError) {
 	var (
 		errors     []*definitionError
 		rootLeaves leafMatchers
+		routes     []*Route
 	)
 
 	pathMatchers := make(map[string]*pathMatcher)
 
 	for i, r := range rs {
-		l, err := newLeaf(r)
-		if err != nil {
-			errors = append(errors, &definitionError{r.Id, i, err})
-			continue
+		l, reused := reuse[r]
+		if !reused {
+			var err error
+			if l, err = newLeaf(r, o); err != nil {
+				errors = append(errors, &definitionError{r.Id, i, err})
+				continue
+			}
 		}
 
-		p := r.Path
-		if p == "" {
+		if keep != nil {
+			keep[r] = l
+		}
+
+		if r.Path == "" && r.PathSubtree == "" {
 			rootLeaves = append(rootLeaves, l)
 			continue
 		}
 
-		// normalize path
-		// in case ignoring trailing slashes, store and match all paths
-		// without the trailing slash
-		p = httppath.Clean(p)
-		if o.ignoreTrailingSlash() && p[len(p)-1] == '/' {
-			p = p[:len(p)-1]
+		// the reused leaves can be used by the concurrent requests
+		// already, so they are not modified
+		paths, param := treePaths(r, o)
+		if !reused {
+			l.wildcardParam = param
 		}
 
-		pm := pathMatchers[p]
-		if pm == nil {
-			pm = &pathMatcher{freeWildcardParam: freeWildcardParam(p)}
-			pathMatchers[p] = pm
-		}
+		for _, p := range paths {
+			pm := pathMatchers[p]
+			if pm == nil {
+				pm = &pathMatcher{freeWildcardParam: freeWildcardParam(p)}
+				pathMatchers[p] = pm
+			}
 
-		pm.leaves = append(pm.leaves, l)
+			pm.leaves = append(pm.leaves, l)
+		}
 	}
 
 	pathTree := &pathmux.Tree{}
+	staticPaths := make(map[string]*pathMatcher)
+	stored := make(map[*Route]bool)
+	complete := true
 	for p, m := range pathMatchers {
 
 		// sort leaves during construction time, based on their priority
-		sort.Sort(m.leaves)
+		sortLeaves(m.leaves, ordering)
 
 		err := pathTree.Add(p, m)
 		if err != nil {
 			errors = append(errors, &definitionError{"", -1, err})
+			complete = false
+			continue
+		}
+
+		if isStaticPath(p) {
+			staticPaths[p] = m
+		}
+
+		for _, l := range m.leaves {
+			if !stored[l.route] {
+				routes = append(routes, l.route)
+				stored[l.route] = true
+			}
 		}
 	}
 
 	// sort root leaves during construction time, based on their priority
-	sort.Sort(rootLeaves)
+	sortLeaves(rootLeaves, ordering)
+
+	for _, l := range rootLeaves {
+		routes = append(routes, l.route)
+	}
+
+	sort.Sort(routesById(routes))
+
+	// the routes of the paths missing from the tree were dropped, and
+	// an update could not tell them from the deleted ones
+	if !complete {
+		pathMatchers = nil
+	}
+
+	return &matcher{
+		refs:            1,
+		paths:           pathTree,
+		rootLeaves:      rootLeaves,
+		staticPaths:     staticPaths,
+		pathMatchers:    pathMatchers,
+		matchingOptions: o,
+		routes:          routes}, errors
+}
+
+// the maximum number of the path matchers replaced by the updates of a
+// matcher, before it is built from scratch again
+const maxPathOverrides = 1024
+
+// returns the path matcher replacing pm, when an update replaced it,
+// otherwise pm
+func currentPathMatcher(overrides map[*pathMatcher]*pathMatcher, pm *pathMatcher) *pathMatcher {
+	if o, ok := overrides[pm]; ok {
+		return o
+	}
+
+	return pm
+}
+
+// creates the next matcher from m, when the routes changed only on the
+// paths already stored in its path tree. The next matcher shares the
+// path tree with m, and only the leaves of the paths of the new, the
+// changed and the deleted routes are sorted again. The leaf matchers
+// of the routes found in reuse are taken from there, these are the
+// unchanged routes, and the leaf matchers of all the routes are stored
+// in keep. It returns false when the matcher needs to be built from
+// scratch instead, e.g. when a route with a new path was added.
+func (m *matcher) update(rs []*Route, o MatchingOptions, ordering func(a, b *Route) bool, reuse, keep map[*Route]*leafMatcher) (*matcher, []*definitionError, bool) {
+	if m.pathMatchers == nil || keep == nil || o != m.matchingOptions {
+		return nil, nil, false
+	}
+
+	var (
+		errors    []*definitionError
+		added     []*Route
+		rootAdded leafMatchers
+	)
+
+	pathsAdded := make(map[string]leafMatchers)
+	for i, r := range rs {
+		l, reused := reuse[r]
+		if !reused {
+			var err error
+			if l, err = newLeaf(r, o); err != nil {
+				errors = append(errors, &definitionError{r.Id, i, err})
+				continue
+			}
+		}
+
+		keep[r] = l
+		if reused {
+			continue
+		}
+
+		added = append(added, r)
+		if r.Path == "" && r.PathSubtree == "" {
+			rootAdded = append(rootAdded, l)
+			continue
+		}
+
+		paths, param := treePaths(r, o)
+		l.wildcardParam = param
+		for _, p := range paths {
+			if m.pathMatchers[p] == nil {
+				return nil, nil, false
+			}
+
+			pathsAdded[p] = append(pathsAdded[p], l)
+		}
+	}
+
+	// the routes of m not found in the new routes are deleted, and
+	// the old version of the changed routes, too
+	deleted := make(map[*Route]bool)
+	changedPaths := make(map[string]bool)
+	rootChanged := len(rootAdded) > 0
+	for _, r := range m.routes {
+		if _, ok := keep[r]; ok {
+			continue
+		}
+
+		deleted[r] = true
+		if r.Path == "" && r.PathSubtree == "" {
+			rootChanged = true
+			continue
+		}
+
+		paths, _ := treePaths(r, o)
+		for _, p := range paths {
+			changedPaths[p] = true
+		}
+	}
+
+	for p := range pathsAdded {
+		changedPaths[p] = true
+	}
+
+	if len(m.overrides)+len(changedPaths) > maxPathOverrides {
+		return nil, nil, false
+	}
+
+	overrides := m.overrides
+	if len(changedPaths) > 0 {
+		overrides = make(map[*pathMatcher]*pathMatcher, len(m.overrides)+len(changedPaths))
+		for pm, current := range m.overrides {
+			overrides[pm] = current
+		}
+
+		for p := range changedPaths {
+			pm := m.pathMatchers[p]
+			var leaves leafMatchers
+			for _, l := range currentPathMatcher(m.overrides, pm).leaves {
+				if !deleted[l.route] {
+					leaves = append(leaves, l)
+				}
+			}
+
+			leaves = append(leaves, pathsAdded[p]...)
+			sortLeaves(leaves, ordering)
+			overrides[pm] = &pathMatcher{leaves: leaves, freeWildcardParam: pm.freeWildcardParam}
+		}
+	}
+
+	rootLeaves := m.rootLeaves
+	if rootChanged {
+		rootLeaves = nil
+		for _, l := range m.rootLeaves {
+			if !deleted[l.route] {
+				rootLeaves = append(rootLeaves, l)
+			}
+		}
+
+		rootLeaves = append(rootLeaves, rootAdded...)
+		sortLeaves(rootLeaves, ordering)
+	}
 
-	return &matcher{pathTree, rootLeaves, o}, errors
+	// merge the new routes into the remaining ones, keeping them
+	// sorted by id
+	sort.Sort(routesById(added))
+	routes := make([]*Route, 0, len(m.routes)-len(deleted)+len(added))
+	for _, r := range m.routes {
+		if deleted[r] {
+			continue
+		}
+
+		for len(added) > 0 && added[0].Id < r.Id {
+			routes = append(routes, added[0])
+			added = added[1:]
+		}
+
+		routes = append(routes, r)
+	}
+
+	routes = append(routes, added...)
+
+	return &matcher{
+		refs:            1,
+		paths:           m.paths,
+		rootLeaves:      rootLeaves,
+		staticPaths:     m.staticPaths,
+		pathMatchers:    m.pathMatchers,
+		overrides:       overrides,
+		matchingOptions: o,
+		routes:          routes}, errors, true
 }
 
 // matches a path in the path trie structure.
@@ -254,14 +742,18 @@ func matchPathTree(tree *pathmux.Tree, path string, lrm *leafRequestMatcher) (ma
 	}
 
 	// prepend slash in case of free form wildcards path segments (`/*name`),
+	// and rename the parameter to the one in the route, if any
 	pm := v.(*pathMatcher)
+	l := value.(*leafMatcher)
 	if pm.freeWildcardParam != "" {
 		freeParam := params[pm.freeWildcardParam]
-		freeParam = "/" + freeParam
-		params[pm.freeWildcardParam] = freeParam
+		delete(params, pm.freeWildcardParam)
+		if l.wildcardParam != "" {
+			params[l.wildcardParam] = "/" + freeParam
+		}
 	}
 
-	return params, value.(*leafMatcher)
+	return params, l
 }
 
 // matches the path regexp conditions in a leaf matcher.
@@ -323,13 +815,33 @@ func matchPredicates(cps []Predicate, req *http.Request) bool {
 	return true
 }
 
+// removes the trailing dot of a fully qualified host name,
+// keeping the port if any
+func trimHostDot(h string) string {
+	var port string
+	if i := strings.LastIndexByte(h, ':'); i >= 0 && !strings.Contains(h[i:], "]") {
+		h, port = h[:i], h[i:]
+	}
+
+	return strings.TrimSuffix(h, ".") + port
+}
+
+// returns the host of a request as it is matched by a leaf
+func leafHost(l *leafMatcher, req *http.Request) string {
+	if l.ignoreHost {
+		return trimHostDot(req.Host)
+	}
+
+	return req.Host
+}
+
 // matches a request to the conditions in a leaf matcher
 func matchLeaf(l *leafMatcher, req *http.Request, path string) bool {
 	if l.method != "" && l.method != req.Method {
 		return false
 	}
 
-	if !matchRegexps(l.hostRxs, req.Host) {
+	if !matchRegexps(l.hostRxs, leafHost(l, req)) {
 		return false
 	}
 
@@ -348,9 +860,54 @@ func matchLeaf(l *leafMatcher, req *http.Request, path string) bool {
 	return true
 }
 
+// returns the name of the first condition of a leaf matcher that the
+// request doesn't match, or an empty string if it matches all
+func failedCondition(l *leafMatcher, req *http.Request, path string) string {
+	if l.method != "" && l.method != req.Method {
+		return "Method"
+	}
+
+	if !matchRegexps(l.hostRxs, leafHost(l, req)) {
+		return "Host"
+	}
+
+	if !matchRegexps(l.pathRxs, path) {
+		return "PathRegexp"
+	}
+
+	if !matchHeaders(l.headersExact, nil, req.Header) {
+		return "Header"
+	}
+
+	if !matchHeaders(nil, l.headersRegexp, req.Header) {
+		return "HeaderRegexp"
+	}
+
+	for i, p := range l.predicates {
+		if !p.Match(req) {
+			if l.predicateNames[i] != "" {
+				return l.predicateNames[i]
+			}
+
+			return "Predicate"
+		}
+	}
+
+	return ""
+}
+
 // matches a request to a set of leaf matchers
 func matchLeaves(leaves leafMatchers, req *http.Request, path string) *leafMatcher {
+	return matchLeavesExcluding(leaves, req, path, nil)
+}
+
+// matches a request to a set of leaf matchers, skipping the excluded routes
+func matchLeavesExcluding(leaves leafMatchers, req *http.Request, path string, excluded map[*Route]bool) *leafMatcher {
 	for _, l := range leaves {
+		if excluded[l.route] {
+			continue
+		}
+
 		if matchLeaf(l, req, path) {
 			return l
 		}
@@ -359,31 +916,179 @@ func matchLeaves(leaves leafMatchers, req *http.Request, path string) *leafMatch
 	return nil
 }
 
+// matches a request to a set of leaf matchers, and returns all the matching
+// ones in the order of their priority
+func matchAllLeaves(leaves leafMatchers, req *http.Request, path string) []*leafMatcher {
+	var matches []*leafMatcher
+	for _, l := range leaves {
+		if matchLeaf(l, req, path) {
+			matches = append(matches, l)
+		}
+	}
+
+	return matches
+}
+
+// normalize path before matching
+// in case ignoring trailing slashes, match without the trailing slash
+func (m *matcher) normalizePath(r *http.Request) string {
+	return normalizeTreePath(r.URL.Path, m.matchingOptions)
+}
+
 // tries to match a request against the available definitions. If a match is found,
 // returns the associated value, and the wildcard parameters from the path definition,
 // if any.
 func (m *matcher) match(r *http.Request) (*Route, map[string]string) {
-	// normalize path before matching
-	// in case ignoring trailing slashes, match without the trailing slash
-	path := httppath.Clean(r.URL.Path)
-	if m.matchingOptions.ignoreTrailingSlash() && path[len(path)-1] == '/' {
-		path = path[:len(path)-1]
+	return m.matchExcluding(r, nil)
+}
+
+// increments the hit counter of a matched route, when the route
+// metrics are enabled
+func (m *matcher) countHit(r *Route) {
+	if r == nil || m.hits == nil {
+		return
+	}
+
+	if h, ok := m.hits[r.Id]; ok {
+		atomic.AddInt64(h, 1)
+	}
+}
+
+// takes a reference to the matcher, unless it was already retired
+func (m *matcher) acquire() bool {
+	for {
+		n := atomic.LoadInt64(&m.refs)
+		if n == 0 {
+			return false
+		}
+
+		if atomic.CompareAndSwapInt64(&m.refs, n, n+1) {
+			return true
+		}
+	}
+}
+
+// releases a reference to the matcher, and returns true if it was
+// the last one
+func (m *matcher) release() bool {
+	return atomic.AddInt64(&m.refs, -1) == 0
+}
+
+// tries to match a request like match, but skips the excluded routes.
+func (m *matcher) matchExcluding(r *http.Request, excluded map[*Route]bool) (*Route, map[string]string) {
+	path := m.normalizePath(r)
+
+	// first match fixed and wildcard paths. The fixed path is tried
+	// first without the tree, the same way as the tree would try it,
+	// to avoid allocating when it matches. When none of its leaves
+	// match, the tree skips them, so that the predicates are not
+	// evaluated twice
+	var (
+		params map[string]string
+		l      *leafMatcher
+		tried  *pathMatcher
+	)
+
+	if pm, ok := m.staticPaths[path]; ok {
+		l = matchLeavesExcluding(currentPathMatcher(m.overrides, pm).leaves, r, path, excluded)
+		tried = pm
 	}
 
-	lrm := &leafRequestMatcher{r, path}
+	if l == nil {
+		lrm := leafRequestMatcherPool.Get().(*leafRequestMatcher)
+		*lrm = leafRequestMatcher{r, path, excluded, m.overrides, tried}
+		params, l = matchPathTree(m.paths, path, lrm)
+		*lrm = leafRequestMatcher{}
+		leafRequestMatcherPool.Put(lrm)
+	}
 
-	// first match fixed and wildcard paths
-	params, l := matchPathTree(m.paths, path, lrm)
+	// match root leaves, if there was no path match, or if they have a
+	// higher predicate weight than the path match
+	for _, rl := range m.rootLeaves {
+		if l != nil && rl.weight <= l.weight {
+			break
+		}
+
+		if excluded[rl.route] {
+			continue
+		}
+
+		if matchLeaf(rl, r, path) {
+			return rl.route, nil
+		}
+	}
 
 	if l != nil {
 		return l.route, params
 	}
 
-	// if no path match, match root leaves for other conditions
-	l = matchLeaves(m.rootLeaves, r, path)
-	if l != nil {
-		return l.route, nil
+	if m.defaultRoute != nil && !excluded[m.defaultRoute] {
+		return m.defaultRoute, nil
 	}
 
 	return nil, nil
 }
+
+// returns the routes of the matcher, including the default route
+func (m *matcher) allRoutes() []*Route {
+	if m.defaultRoute == nil {
+		return m.routes
+	}
+
+	return append(m.routes[:len(m.routes):len(m.routes)], m.defaultRoute)
+}
+
+// returns all the routes matching a request, in the same order as they
+// are evaluated by match.
+func (m *matcher) matchAll(r *http.Request) []*Route {
+	path := m.normalizePath(r)
+	am := &allLeavesRequestMatcher{r: r, path: path, overrides: m.overrides}
+	m.paths.LookupMatcher(path, am)
+
+	leaves := mergeLeaves(am.matches, matchAllLeaves(m.rootLeaves, r, path))
+	routes := make([]*Route, len(leaves))
+	for i, l := range leaves {
+		routes[i] = l.route
+	}
+
+	if m.defaultRoute != nil {
+		routes = append(routes, m.defaultRoute)
+	}
+
+	return routes
+}
+
+// merges the root leaves into the path leaves by the predicate weight,
+// the same way as match does
+func mergeLeaves(pathLeaves, rootLeaves []*leafMatcher) []*leafMatcher {
+	var leaves []*leafMatcher
+	for len(pathLeaves) > 0 || len(rootLeaves) > 0 {
+		if len(rootLeaves) > 0 && (len(pathLeaves) == 0 || rootLeaves[0].weight > pathLeaves[0].weight) {
+			leaves = append(leaves, rootLeaves[0])
+			rootLeaves = rootLeaves[1:]
+		} else {
+			leaves = append(leaves, pathLeaves[0])
+			pathLeaves = pathLeaves[1:]
+		}
+	}
+
+	return leaves
+}
+
+// returns the routes that were candidates for matching a request, in
+// the order of their priority, with the first condition that the
+// request failed to match.
+func (m *matcher) trace(r *http.Request) *MatchTrace {
+	path := m.normalizePath(r)
+	cm := &candidateLeavesRequestMatcher{overrides: m.overrides}
+	m.paths.LookupMatcher(path, cm)
+
+	t := &MatchTrace{}
+	for _, l := range mergeLeaves(cm.candidates, m.rootLeaves) {
+		t.Candidates = append(t.Candidates, MatchCandidate{
+			RouteId:         l.route.Id,
+			FailedCondition: failedCondition(l, r, path)})
+	}
+
+	return t
+}
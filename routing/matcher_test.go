@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"github.com/zalando/pathmux"
 	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/logging"
 	"log"
 	"net/http"
 	"net/url"
 	"regexp"
 	"testing"
+	"time"
 )
 
 type truePredicate struct{}
@@ -18,6 +20,43 @@ func (tp *truePredicate) Name() string                                 { return
 func (tp *truePredicate) Create(args []interface{}) (Predicate, error) { return tp, nil }
 func (tp *truePredicate) Match(r *http.Request) bool                   { return true }
 
+type weightedPredicate struct{ weight int }
+
+func (wp *weightedPredicate) Name() string               { return "Weighted" }
+func (wp *weightedPredicate) Match(r *http.Request) bool { return true }
+func (wp *weightedPredicate) Weight() int                { return wp.weight }
+
+func (wp *weightedPredicate) Create(args []interface{}) (Predicate, error) {
+	if len(args) != 1 {
+		return nil, errors.New("invalid number of args")
+	}
+
+	w, ok := args[0].(float64)
+	if !ok {
+		return nil, errors.New("invalid arg")
+	}
+
+	return &weightedPredicate{int(w)}, nil
+}
+
+type costPredicate struct {
+	name  string
+	cost  int
+	match bool
+}
+
+func (cp *costPredicate) Name() string                                 { return cp.name }
+func (cp *costPredicate) Create(args []interface{}) (Predicate, error) { return cp, nil }
+func (cp *costPredicate) Cost() int                                    { return cp.cost }
+
+func (cp *costPredicate) Match(r *http.Request) bool {
+	if !cp.match {
+		return false
+	}
+
+	panic("expensive predicate evaluated")
+}
+
 const (
 	benchmarkingCountPhase1 = 1
 	benchmarkingCountPhase2 = 100
@@ -100,7 +139,7 @@ func docToRoutes(doc string) ([]*Route, error) {
 		return nil, err
 	}
 
-	return processRouteDefs(Options{Predicates: []PredicateSpec{&truePredicate{}}}, nil, defs), nil
+	return processRouteDefs(Options{Predicates: []PredicateSpec{&truePredicate{}, &weightedPredicate{}}}, nil, defs), nil
 }
 
 // parse a routing document with a single route
@@ -659,7 +698,7 @@ func TestMatchPathTree(t *testing.T) {
 		t.Error(err)
 	}
 
-	p, v := matchPathTree(tree, "/some/path", &leafRequestMatcher{&http.Request{}, ""})
+	p, v := matchPathTree(tree, "/some/path", &leafRequestMatcher{&http.Request{}, "", nil, nil, nil})
 
 	if len(p) != 0 || v.route.Route.Id != "1" {
 		t.Error("failed to match path", len(p))
@@ -678,7 +717,7 @@ func TestMatchPathTreeWithWildcards(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	p, v := matchPathTree(tree, "/some/path/and/params", &leafRequestMatcher{&http.Request{}, ""})
+	p, v := matchPathTree(tree, "/some/path/and/params", &leafRequestMatcher{&http.Request{}, "", nil, nil, nil})
 	if len(p) != 2 || p["param0"] != "and" || p["param1"] != "params" || v.route.Route.Id != "1" {
 		t.Error("failed to match path", len(p))
 	}
@@ -787,7 +826,7 @@ func TestMakeLeafInvalidHostRx(t *testing.T) {
 		t.Error(err)
 	}
 
-	_, err = newLeaf(r)
+	_, err = newLeaf(r, MatchingOptionsNone)
 	if err == nil {
 		t.Error("failed to fail")
 	}
@@ -800,7 +839,7 @@ func TestMakeLeafInvalidPathRx(t *testing.T) {
 		t.Error(err)
 	}
 
-	_, err = newLeaf(r)
+	_, err = newLeaf(r, MatchingOptionsNone)
 	if err == nil {
 		t.Error("failed to fail")
 	}
@@ -813,7 +852,7 @@ func TestMakeLeafInvalidHeaderRegexp(t *testing.T) {
 		t.Error(err)
 	}
 
-	_, err = newLeaf(r)
+	_, err = newLeaf(r, MatchingOptionsNone)
 	if err == nil {
 		t.Error("failed to fail")
 	}
@@ -832,7 +871,7 @@ func TestMakeLeaf(t *testing.T) {
 		t.Error(err)
 	}
 
-	l, err := newLeaf(r)
+	l, err := newLeaf(r, MatchingOptionsNone)
 	if err != nil || l.method != "PUT" ||
 		len(l.hostRxs) != 1 || len(l.pathRxs) != 1 ||
 		len(l.headersExact) != 1 || len(l.headersRegexp) != 1 ||
@@ -954,6 +993,56 @@ func TestMatchFromSlash(t *testing.T) {
 	}
 }
 
+func TestIgnoreTrailingSlash(t *testing.T) {
+	const doc = `
+		exact: Path("/foo/bar") -> "https://exact.example.org";
+		exactSlash: Path("/baz/") -> "https://exact.example.org";
+		wildcard: Path("/foo/:id") -> "https://wildcard.example.org";
+		subtree: PathSubtree("/api") -> "https://subtree.example.org"`
+
+	for _, ti := range []struct {
+		path             string
+		expected         string
+		expectedIgnoring string
+	}{
+		{"/foo/bar", "exact", "exact"},
+		{"/foo/bar/", "", "exact"},
+		{"/foo/qux", "wildcard", "wildcard"},
+		{"/foo/qux/", "", "wildcard"},
+		{"/baz", "", "exactSlash"},
+		{"/baz/", "exactSlash", "exactSlash"},
+		{"/api", "subtree", "subtree"},
+		{"/api/", "subtree", "subtree"},
+		{"/api/foo/", "subtree", "subtree"},
+	} {
+		for _, o := range []MatchingOptions{MatchingOptionsNone, IgnoreTrailingSlash} {
+			m, err := docToMatcherOpts(doc, o)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := newRequest("GET", ti.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := ti.expected
+			if o == IgnoreTrailingSlash {
+				expected = ti.expectedIgnoring
+			}
+
+			var id string
+			if r, _ := m.match(req); r != nil {
+				id = r.Id
+			}
+
+			if id != expected {
+				t.Errorf("failed to match the expected route, path: %s, options: %d, expected: %s, got: %s", ti.path, o, expected, id)
+			}
+		}
+	}
+}
+
 func TestWildcardParam(t *testing.T) {
 	m, err := docToMatcher(`Path("/some/:wildcard0/path/:wildcard1") -> "https://example.org"`)
 	if err != nil {
@@ -1026,6 +1115,353 @@ func TestHeaderMatchCaseInsensitive(t *testing.T) {
 	}
 }
 
+func TestMatchAll(t *testing.T) {
+	m, err := docToMatcher(`
+		a: Path("/foo/*_") -> "https://foo.org";
+		b: Path("/foo/bar") && Method("GET") -> "https://bar.org";
+		c: Path("/foo/bar") -> "https://bar-any.org";
+		d: Path("/foo/baz") -> "https://baz.org";
+		e: Method("GET") -> "https://get.org";
+		z: * -> "https://catch.all"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	req, err := newRequest("GET", "/foo/bar")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	rs := m.matchAll(req)
+	var ids []string
+	for _, r := range rs {
+		ids = append(ids, r.Id)
+	}
+
+	expected := []string{"b", "c", "a", "e", "z"}
+	if len(ids) != len(expected) {
+		t.Error("failed to match all routes", ids)
+		return
+	}
+
+	for i, id := range expected {
+		if ids[i] != id {
+			t.Error("failed to match all routes in order", ids)
+			return
+		}
+	}
+
+	if r, _ := m.match(req); r != rs[0] {
+		t.Error("failed to match the first route")
+	}
+}
+
+func TestWeightedCatchAllBeatsPath(t *testing.T) {
+	m, err := docToMatcher(`
+		path: Path("/foo/bar") && Method("GET") -> "https://bar.org";
+		catchAll: Weighted(2) -> "https://catch.all"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	req, err := newRequest("GET", "/foo/bar")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if r, _ := m.match(req); r == nil || r.Id != "catchAll" {
+		t.Error("failed to match the weighted catch-all route")
+	}
+
+	if rs := m.matchAll(req); len(rs) != 2 || rs[0].Id != "catchAll" || rs[1].Id != "path" {
+		t.Error("failed to match all routes in order")
+	}
+}
+
+func TestWeightedPathBeatsWeightedCatchAll(t *testing.T) {
+	m, err := docToMatcher(`
+		path: Path("/foo/bar") && Weighted(3) -> "https://bar.org";
+		catchAll: Weighted(2) -> "https://catch.all"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	req, err := newRequest("GET", "/foo/bar")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if r, _ := m.match(req); r == nil || r.Id != "path" {
+		t.Error("failed to match the weighted path route")
+	}
+}
+
+func TestWeightBreaksTieBeforeConditionCount(t *testing.T) {
+	m, err := docToMatcher(`
+		specific: Path("/foo") && Method("GET") && Header("X-Foo", "bar") -> "https://specific.org";
+		weighted: Path("/foo") && Weighted(1) -> "https://weighted.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	req, err := newRequest("GET", "/foo")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	req.Header.Set("X-Foo", "bar")
+	if r, _ := m.match(req); r == nil || r.Id != "weighted" {
+		t.Error("failed to match the weighted route")
+	}
+}
+
+func TestUnweightedCatchAllDoesNotBeatPath(t *testing.T) {
+	m, err := docToMatcher(`
+		path: Path("/foo/bar") -> "https://bar.org";
+		catchAll: Weighted(0) -> "https://catch.all"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	req, err := newRequest("GET", "/foo/bar")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if r, _ := m.match(req); r == nil || r.Id != "path" {
+		t.Error("failed to match the path route")
+	}
+}
+
+func TestPathSubtree(t *testing.T) {
+	m, err := docToMatcher(`
+		api: PathSubtree("/api") -> "https://api.example.org";
+		apiV1: PathSubtree("/api/v1/") -> "https://v1.api.example.org";
+		apiV1Exact: Path("/api/v1") -> "https://exact.v1.api.example.org";
+		apiV1Users: Path("/api/v1/users/:id") -> "https://users.v1.api.example.org";
+		catchAll: * -> "https://www.example.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, ti := range []struct {
+		path string
+		id   string
+	}{
+		{"/api", "api"},
+		{"/api/", "api"},
+		{"/api/v2/foo", "api"},
+		{"/apis", "catchAll"},
+		{"/api/v1", "apiV1Exact"},
+		{"/api/v1/", "apiV1"},
+		{"/api/v1/foo/bar", "apiV1"},
+		{"/api/v1/users/42", "apiV1Users"},
+		{"/api/v1/users/42/orders", "apiV1"},
+		{"/", "catchAll"},
+	} {
+		req, err := newRequest("GET", ti.path)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		r, params := m.match(req)
+		if r == nil || r.Id != ti.id {
+			t.Error("failed to match path subtree", ti.path, ti.id, r)
+			continue
+		}
+
+		if r.PathSubtree != "" && len(params) != 0 {
+			t.Error("unexpected path params", ti.path, params)
+		}
+	}
+}
+
+func TestPathSubtreeIgnoreTrailingSlash(t *testing.T) {
+	m, err := docToMatcherOpts(`PathSubtree("/api/") -> "https://api.example.org"`, IgnoreTrailingSlash)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, p := range []string{"/api", "/api/", "/api/foo/"} {
+		req, err := newRequest("GET", p)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if r, _ := m.match(req); r == nil {
+			t.Error("failed to match path subtree", p)
+		}
+	}
+}
+
+func TestPathSubtreeAndFreeWildcard(t *testing.T) {
+	m, err := docToMatcher(`
+		subtree: PathSubtree("/api") -> "https://api.example.org";
+		wildcard: Path("/api/*resource") && Method("POST") -> "https://post.api.example.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	req, err := newRequest("POST", "/api/foo/bar")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	r, params := m.match(req)
+	if r == nil || r.Id != "wildcard" || len(params) != 1 || params["resource"] != "/foo/bar" {
+		t.Error("failed to match free wildcard", r, params)
+	}
+
+	req.Method = "GET"
+	r, params = m.match(req)
+	if r == nil || r.Id != "subtree" || len(params) != 0 {
+		t.Error("failed to match path subtree", r, params)
+	}
+
+	if len(m.routes) != 2 {
+		t.Error("failed to store the routes once", len(m.routes))
+	}
+}
+
+const staticPathDoc = `
+	foo: Path("/foo/bar") -> "https://foo.example.org";
+	baz: Path("/foo/baz") && Method("POST") -> "https://baz.example.org";
+	users: Path("/users/:id") -> "https://users.example.org";
+	catchAll: * -> "https://www.example.org"`
+
+// creates a routing with the static path test routes, and waits until
+// the routes are loaded
+func staticPathRouting(tb testing.TB) *Routing {
+	defs, err := eskip.Parse(staticPathDoc)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	updates := make(chan RouteUpdate, 1)
+	rt := New(Options{
+		DataClients:       []DataClient{&staticDataClient{defs}},
+		PollTimeout:       time.Hour,
+		Log:               logging.NoopLogger{},
+		SignalRouteUpdate: updates})
+
+	select {
+	case <-updates:
+	case <-time.After(3 * time.Second):
+		rt.Close()
+		tb.Fatal("timeout while loading the routes")
+	}
+
+	return rt
+}
+
+func TestStaticPathMatchDoesNotAllocate(t *testing.T) {
+	rt := staticPathRouting(t)
+	defer rt.Close()
+
+	req, err := newRequest("GET", "/foo/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r *Route
+	allocs := testing.AllocsPerRun(100, func() { r, _ = rt.Route(req) })
+	if r == nil || r.Id != "foo" {
+		t.Fatal("failed to match the static path", r)
+	}
+
+	if allocs != 0 {
+		t.Error("unexpected allocations", allocs)
+	}
+}
+
+// counts its evaluations, and never matches
+type countingPredicate struct{ evaluations int }
+
+func (cp *countingPredicate) Name() string                                 { return "Counting" }
+func (cp *countingPredicate) Create(args []interface{}) (Predicate, error) { return cp, nil }
+
+func (cp *countingPredicate) Match(r *http.Request) bool {
+	cp.evaluations++
+	return false
+}
+
+func TestStaticPathMissEvaluatesPredicatesOnce(t *testing.T) {
+	for _, ti := range []struct {
+		msg      string
+		doc      string
+		expected string
+	}{{
+		msg: "no other match",
+		doc: `counted: Path("/foo/bar") && Counting() -> "https://foo.example.org"`,
+	}, {
+		msg: "wildcard match",
+		doc: `
+			counted: Path("/foo/bar") && Counting() -> "https://foo.example.org";
+			wildcard: Path("/foo/:name") -> "https://wildcard.example.org"`,
+		expected: "wildcard",
+	}} {
+		defs, err := eskip.Parse(ti.doc)
+		if err != nil {
+			t.Fatal(ti.msg, err)
+		}
+
+		cp := &countingPredicate{}
+		m, errs := newMatcher(processRouteDefs(Options{Predicates: []PredicateSpec{cp}}, nil, defs), MatchingOptionsNone)
+		if len(errs) != 0 {
+			t.Fatal(ti.msg, errs[0])
+		}
+
+		req, err := newRequest("GET", "/foo/bar")
+		if err != nil {
+			t.Fatal(ti.msg, err)
+		}
+
+		r, _ := m.match(req)
+		if ti.expected == "" && r != nil || ti.expected != "" && (r == nil || r.Id != ti.expected) {
+			t.Error(ti.msg, "unexpected route", r)
+		}
+
+		if cp.evaluations != 1 {
+			t.Error(ti.msg, "unexpected number of predicate evaluations", cp.evaluations)
+		}
+	}
+}
+
+func benchmarkRoute(b *testing.B, path string) {
+	rt := staticPathRouting(b)
+	defer rt.Close()
+
+	req, err := newRequest("GET", path)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rt.Route(req)
+	}
+}
+
+func BenchmarkStaticPathMatch(b *testing.B)   { benchmarkRoute(b, "/foo/bar") }
+func BenchmarkWildcardPathMatch(b *testing.B) { benchmarkRoute(b, "/users/42") }
+
 func BenchmarkGeneric(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		testMatch(b, "GET", "/tessera/header", "https://header.my-department.example.org")
@@ -1125,3 +1561,228 @@ func BenchmarkConstructionMass(b *testing.B) {
 		}
 	}
 }
+
+func TestHostCaseSensitiveByDefault(t *testing.T) {
+	m, err := docToMatcher(`Host(/^api[.]example[.]com$/) -> "https://api.example.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, h := range []string{"API.Example.COM", "api.example.com."} {
+		req, err := newRequest("GET", "/")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		req.Host = h
+		if r, _ := m.match(req); r != nil {
+			t.Error("unexpected match", h)
+		}
+	}
+}
+
+func TestIgnoreHostCase(t *testing.T) {
+	m, err := docToMatcherOpts(`Host(/^api[.]example[.]com(:[0-9]+)?$/) -> "https://api.example.org"`, IgnoreHostCase)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, ti := range []struct {
+		host    string
+		matches bool
+	}{
+		{"api.example.com", true},
+		{"API.Example.COM", true},
+		{"api.example.com.", true},
+		{"API.Example.COM.:8080", true},
+		{"api.example.com..", false},
+		{"www.example.com", false},
+	} {
+		req, err := newRequest("GET", "/")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		req.Host = ti.host
+		if r, _ := m.match(req); (r != nil) != ti.matches {
+			t.Error("failed to match host", ti.host, ti.matches)
+		}
+	}
+}
+
+func TestCheapPredicatesEvaluatedFirst(t *testing.T) {
+	defs, err := eskip.Parse(`
+		route: Path("/foo") && Expensive() && Cheap() -> "https://foo.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	rs := processRouteDefs(Options{Predicates: []PredicateSpec{
+		&costPredicate{name: "Expensive", cost: 100, match: true},
+		&costPredicate{name: "Cheap", cost: 1, match: false},
+	}}, nil, defs)
+
+	m, err := newTestMatcherOpts(rs, MatchingOptionsNone)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	req, err := newRequest("GET", "/foo")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if r, _ := m.match(req); r != nil {
+		t.Error("unexpected match")
+	}
+
+	if c := m.trace(req).Candidates; len(c) != 1 || c[0].FailedCondition != "Cheap" {
+		t.Error("invalid failed condition", c)
+	}
+}
+
+func TestMatcherUpdate(t *testing.T) {
+	const initial = `
+		root: Method("POST") -> "https://root.example.org";
+		foo: Path("/foo") -> "https://foo.example.org";
+		fooPost: Path("/foo") && Method("POST") -> "https://foo-post.example.org";
+		bar: Path("/bar/:id") -> "https://bar.example.org";
+		sub: PathSubtree("/sub") -> "https://sub.example.org"`
+
+	requests := []struct{ method, path string }{
+		{"GET", "/foo"},
+		{"POST", "/foo"},
+		{"PUT", "/foo"},
+		{"GET", "/bar/42"},
+		{"POST", "/bar/42"},
+		{"GET", "/sub"},
+		{"GET", "/sub/foo"},
+		{"GET", "/other"},
+		{"POST", "/other"},
+	}
+
+	for _, ti := range []struct {
+		msg         string
+		changed     string
+		deleted     []string
+		incremental bool
+	}{{
+		msg:         "changed route",
+		changed:     `foo: Path("/foo") -> "https://foo2.example.org"`,
+		incremental: true,
+	}, {
+		msg:         "new route on an existing path",
+		changed:     `fooPut: Path("/foo") && Method("PUT") -> "https://foo-put.example.org"`,
+		incremental: true,
+	}, {
+		msg:         "deleted route",
+		deleted:     []string{"fooPost"},
+		incremental: true,
+	}, {
+		msg:         "all routes of a path deleted",
+		deleted:     []string{"bar"},
+		incremental: true,
+	}, {
+		msg:         "new route without path",
+		changed:     `get: Method("GET") -> "https://get.example.org"`,
+		incremental: true,
+	}, {
+		msg:         "deleted route without path",
+		deleted:     []string{"root"},
+		incremental: true,
+	}, {
+		msg:     "new path",
+		changed: `baz: Path("/baz") -> "https://baz.example.org"`,
+	}, {
+		msg:     "changed path",
+		changed: `foo: Path("/foo/bar") -> "https://foo.example.org"`,
+	}} {
+		initialRoutes, err := docToRoutes(initial)
+		if err != nil {
+			t.Fatal(ti.msg, err)
+		}
+
+		leaves := make(map[*Route]*leafMatcher)
+		prev, errs := newMatcherWith(initialRoutes, MatchingOptionsNone, nil, nil, leaves)
+		if len(errs) != 0 {
+			t.Fatal(ti.msg, errs[0])
+		}
+
+		changedRoutes, err := docToRoutes(ti.changed)
+		if err != nil {
+			t.Fatal(ti.msg, err)
+		}
+
+		replaced := make(map[string]bool)
+		for _, id := range ti.deleted {
+			replaced[id] = true
+		}
+
+		for _, r := range changedRoutes {
+			replaced[r.Id] = true
+		}
+
+		var routes []*Route
+		for _, r := range initialRoutes {
+			if !replaced[r.Id] {
+				routes = append(routes, r)
+			}
+		}
+
+		routes = append(routes, changedRoutes...)
+
+		m, errs, ok := prev.update(routes, MatchingOptionsNone, nil, leaves, make(map[*Route]*leafMatcher))
+		if len(errs) != 0 {
+			t.Fatal(ti.msg, errs[0])
+		}
+
+		if ok != ti.incremental {
+			t.Error(ti.msg, "unexpected incremental update", ok)
+			continue
+		}
+
+		if !ok {
+			continue
+		}
+
+		if m.paths != prev.paths {
+			t.Error(ti.msg, "failed to share the path tree")
+		}
+
+		full, errs := newMatcher(routes, MatchingOptionsNone)
+		if len(errs) != 0 {
+			t.Fatal(ti.msg, errs[0])
+		}
+
+		if len(m.routes) != len(full.routes) {
+			t.Error(ti.msg, "invalid number of routes", len(m.routes), len(full.routes))
+			continue
+		}
+
+		for i := range m.routes {
+			if m.routes[i] != full.routes[i] {
+				t.Error(ti.msg, "invalid routes", m.routes[i].Id, full.routes[i].Id)
+			}
+		}
+
+		for _, rq := range requests {
+			req, err := newRequest(rq.method, rq.path)
+			if err != nil {
+				t.Fatal(ti.msg, err)
+			}
+
+			r, params := m.match(req)
+			expected, expectedParams := full.match(req)
+			if r != expected || len(params) != len(expectedParams) {
+				t.Error(ti.msg, "unexpected match", rq.method, rq.path, r, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package routing
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// wraps a predicate, and matches the requests that the wrapped one
+// doesn't match. The weight of the wrapped predicate is not inherited,
+// but its cost is.
+type negatedPredicate struct {
+	predicate Predicate
+}
+
+type methodPredicate string
+
+type headerPredicate struct {
+	key, value string
+}
+
+type headerRegexpPredicate struct {
+	key string
+	rx  *regexp.Regexp
+}
+
+func (p negatedPredicate) Match(r *http.Request) bool { return !p.predicate.Match(r) }
+func (p negatedPredicate) Cost() int                  { return predicateCost(p.predicate) }
+
+func (p methodPredicate) Match(r *http.Request) bool { return r.Method == string(p) }
+
+func (p headerPredicate) Match(r *http.Request) bool {
+	return matchHeader(r.Header, p.key, func(v string) bool { return v == p.value })
+}
+
+func (p headerRegexpPredicate) Match(r *http.Request) bool {
+	return matchHeader(r.Header, p.key, p.rx.MatchString)
+}
+
+// creates the built-in predicates that can be negated in the route
+// definitions. The second return value tells whether the predicate is
+// a built-in one.
+func createBuiltinPredicate(def *eskip.Predicate) (Predicate, bool, error) {
+	var n int
+	switch def.Name {
+	case "Method":
+		n = 1
+	case "Header", "HeaderRegexp":
+		n = 2
+	default:
+		return nil, false, nil
+	}
+
+	args, err := eskip.StringArgs(n, def.Args)
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid arguments of predicate '%s': %v", def.Name, err)
+	}
+
+	switch def.Name {
+	case "Method":
+		return methodPredicate(args[0]), true, nil
+	case "Header":
+		return headerPredicate{http.CanonicalHeaderKey(args[0]), args[1]}, true, nil
+	default:
+		rx, err := regexp.Compile(args[1])
+		if err != nil {
+			return nil, true, err
+		}
+
+		return headerRegexpPredicate{http.CanonicalHeaderKey(args[0]), rx}, true, nil
+	}
+}
@@ -16,7 +16,10 @@ package routing
 
 import (
 	"math/rand"
+	"net/http"
 	"strings"
+	"testing"
+	"time"
 )
 
 const (
@@ -27,8 +30,13 @@ const (
 	defaultMaxNamesInPath       = 9
 	defaultClosingSlashInEveryN = 3
 	defaultSeparator            = "/"
+	defaultSpecialInEveryN      = 5
 )
 
+// segments used in place of random names when IncludeEncodedChars
+// is set, and no custom SpecialSegments are specified
+var defaultSpecialSegments = []string{".", "..", "%2F", "%20", "%25", "foo%2Ebar", "%C3%A9t%C3%A9", "日本"}
+
 type pathGeneratorOptions struct {
 	FilenameChars        string
 	MinFilenameLength    int
@@ -38,13 +46,25 @@ type pathGeneratorOptions struct {
 	ClosingSlashInEveryN int
 	RandSeed             int64
 	Separator            string
+
+	// When set, and RandSeed is zero, the generator is seeded from
+	// the current time, and the generated sequences are not
+	// reproducible. A non-zero RandSeed takes precedence.
+	Nondeterministic bool
+
+	// When set, a name in the path is replaced with one of the
+	// SpecialSegments, with a chance of 1 / SpecialInEveryN.
+	IncludeEncodedChars bool
+	SpecialSegments     []string
+	SpecialInEveryN     int
 }
 
 // Generates paths, separated with a slash or custom separator.
 // The paths have a random number of filenames in them, and the
 // filenames consist of random characters of random length.
 // The generated sequences are reproducible, controlled by
-// the RandSeed option.
+// the RandSeed option, unless the Nondeterministic option is
+// set without a RandSeed.
 type pathGenerator struct {
 	options *pathGeneratorOptions
 	rnd     *rand.Rand
@@ -78,6 +98,14 @@ func applyDefaults(o *pathGeneratorOptions) {
 	if o.Separator == "" {
 		o.Separator = defaultSeparator
 	}
+
+	if len(o.SpecialSegments) == 0 {
+		o.SpecialSegments = defaultSpecialSegments
+	}
+
+	if o.SpecialInEveryN == 0 {
+		o.SpecialInEveryN = defaultSpecialInEveryN
+	}
 }
 
 // Creates a path generator with the provided options,
@@ -88,7 +116,12 @@ func newPathGenerator(o pathGeneratorOptions) *pathGenerator {
 	// options taken as value, free to modify
 	applyDefaults(&o)
 
-	return &pathGenerator{&o, rand.New(rand.NewSource(o.RandSeed))}
+	seed := o.RandSeed
+	if seed == 0 && o.Nondeterministic {
+		seed = time.Now().UnixNano()
+	}
+
+	return &pathGenerator{&o, rand.New(rand.NewSource(seed))}
 }
 
 // takes a random number positioned between [min, max)
@@ -114,12 +147,27 @@ func (pg *pathGenerator) name() string {
 	return string(name)
 }
 
+// tells if using a special segment instead of a name, based on the defined
+// chance
+func (pg *pathGenerator) special() bool {
+	return pg.options.IncludeEncodedChars && pg.rnd.Intn(pg.options.SpecialInEveryN) == 0
+}
+
+// takes a random segment from the special segments
+func (pg *pathGenerator) specialSegment() string {
+	return pg.options.SpecialSegments[pg.rnd.Intn(len(pg.options.SpecialSegments))]
+}
+
 // generates random names of count between the defined boundaries
 func (pg *pathGenerator) names() []string {
 	len := pg.between(pg.options.MinNamesInPath, pg.options.MaxNamesInPath)
 	names := make([]string, len)
 	for i := 0; i < len; i++ {
-		names[i] = pg.name()
+		if pg.special() {
+			names[i] = pg.specialSegment()
+		} else {
+			names[i] = pg.name()
+		}
 	}
 
 	return names
@@ -148,9 +196,14 @@ func (pg *pathGenerator) closingSlash() bool {
 // The names in the path will have a random length, equally distributed
 // between `MinFilenameLength` and `MaxFilenameLength`.
 //
+// If `IncludeEncodedChars` is set, the names will be replaced by one of the
+// `SpecialSegments` with a chance of `1 / SpecialInEveryN`. The default
+// special segments contain dots, percent encoded characters and unicode,
+// while the path remains valid for http.NewRequest.
+//
 // The sequence followed by `Next` is reproducible, to get a different
 // sequence, a new pathGenerator instance is required, with a
-// different `RandSeed` value.
+// different `RandSeed` value, or with the `Nondeterministic` option.
 func (pg *pathGenerator) Next() string {
 	names := pg.names()
 
@@ -165,3 +218,81 @@ func (pg *pathGenerator) Next() string {
 
 	return strings.Join(names, pg.options.Separator)
 }
+
+func takePaths(pg *pathGenerator, count int) []string {
+	paths := make([]string, count)
+	for i := range paths {
+		paths[i] = pg.Next()
+	}
+
+	return paths
+}
+
+func TestPathGeneratorSeed(t *testing.T) {
+	const count = 32
+
+	equal := func(left, right []string) bool {
+		for i := range left {
+			if left[i] != right[i] {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for _, o := range []pathGeneratorOptions{
+		{},
+		{RandSeed: 42},
+		{RandSeed: 42, Nondeterministic: true},
+	} {
+		if !equal(
+			takePaths(newPathGenerator(o), count),
+			takePaths(newPathGenerator(o), count),
+		) {
+			t.Error("failed to generate the same sequence", o.RandSeed, o.Nondeterministic)
+		}
+	}
+
+	o := pathGeneratorOptions{Nondeterministic: true}
+	left := takePaths(newPathGenerator(o), count)
+	time.Sleep(time.Millisecond)
+	if equal(left, takePaths(newPathGenerator(o), count)) {
+		t.Error("failed to generate different sequences")
+	}
+}
+
+func TestPathGeneratorSpecialSegments(t *testing.T) {
+	const count = 512
+
+	isSpecial := func(p string) bool {
+		for _, n := range strings.Split(p, "/") {
+			for _, s := range defaultSpecialSegments {
+				if n == s {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	var found bool
+	for _, p := range takePaths(newPathGenerator(pathGeneratorOptions{IncludeEncodedChars: true}), count) {
+		if _, err := http.NewRequest("GET", "https://www.example.org"+p, nil); err != nil {
+			t.Error("invalid path generated", p, err)
+		}
+
+		found = found || isSpecial(p)
+	}
+
+	if !found {
+		t.Error("failed to generate special segments")
+	}
+
+	for _, p := range takePaths(newPathGenerator(pathGeneratorOptions{}), count) {
+		if isSpecial(p) || strings.ContainsAny(p, ".%") {
+			t.Error("unexpected special segment", p)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package routing
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/filters"
+	"github.com/zalando/skipper/filters/filtertest"
+	"github.com/zalando/skipper/logging/loggingtest"
+)
+
+const (
+	defaultHostCount      = 12
+	defaultHostInEveryN   = 2
+	defaultMethodInEveryN = 2
+	defaultHeaderInEveryN = 3
+	defaultMaxFilters     = 3
+)
+
+var (
+	generatedMethods     = []string{"GET", "POST", "PUT", "DELETE"}
+	defaultFilterNames   = []string{"filter0", "filter1", "filter2"}
+	generatedHeaderNames = []string{"X-Test-A", "X-Test-B", "X-Test-C"}
+)
+
+type routeGeneratorOptions struct {
+	HostCount      int
+	HostInEveryN   int
+	MethodInEveryN int
+	HeaderInEveryN int
+	MaxFilters     int
+	FilterNames    []string
+	RandSeed       int64
+}
+
+// Generates route definitions with paths taken from a path generator,
+// and with a random mix of Host, Method and Header conditions and
+// filters. The generated sequences are reproducible, controlled by the
+// RandSeed option and by the path generator.
+type routeGenerator struct {
+	options *routeGeneratorOptions
+	paths   *pathGenerator
+	rnd     *rand.Rand
+	count   int
+}
+
+func applyRouteDefaults(o *routeGeneratorOptions) {
+	if o.HostCount == 0 {
+		o.HostCount = defaultHostCount
+	}
+
+	if o.HostInEveryN == 0 {
+		o.HostInEveryN = defaultHostInEveryN
+	}
+
+	if o.MethodInEveryN == 0 {
+		o.MethodInEveryN = defaultMethodInEveryN
+	}
+
+	if o.HeaderInEveryN == 0 {
+		o.HeaderInEveryN = defaultHeaderInEveryN
+	}
+
+	if o.MaxFilters == 0 {
+		o.MaxFilters = defaultMaxFilters
+	}
+
+	if len(o.FilterNames) == 0 {
+		o.FilterNames = defaultFilterNames
+	}
+}
+
+// Creates a route generator with the provided path generator and
+// options, falling back to the default value for each non-specified
+// option field.
+func newRouteGenerator(pg *pathGenerator, o routeGeneratorOptions) *routeGenerator {
+	applyRouteDefaults(&o)
+	return &routeGenerator{options: &o, paths: pg, rnd: rand.New(rand.NewSource(o.RandSeed))}
+}
+
+// tells if applying an optional condition, with a chance of 1 / n
+func (rg *routeGenerator) chance(n int) bool {
+	return n > 0 && rg.rnd.Intn(n) == 0
+}
+
+func (rg *routeGenerator) pick(from []string) string {
+	return from[rg.rnd.Intn(len(from))]
+}
+
+// Generates the next route definition.
+//
+// The route always has a Path condition, while the Host, Method and
+// Header conditions are set with a chance of 1 / n, defined by the
+// corresponding options. The host names are taken from a set of
+// `HostCount` names. The route has a random number of filters, equally
+// distributed between 0 and `MaxFilters`, with names taken from
+// `FilterNames`. The route ids are unique.
+func (rg *routeGenerator) Next() *eskip.Route {
+	r := &eskip.Route{
+		Id:      fmt.Sprintf("route%d", rg.count),
+		Path:    rg.paths.Next(),
+		Backend: "https://backend.example.org"}
+	rg.count++
+
+	if rg.chance(rg.options.HostInEveryN) {
+		r.HostRegexps = []string{fmt.Sprintf("^www%d[.]example[.]org$", rg.rnd.Intn(rg.options.HostCount))}
+	}
+
+	if rg.chance(rg.options.MethodInEveryN) {
+		r.Method = rg.pick(generatedMethods)
+	}
+
+	if rg.chance(rg.options.HeaderInEveryN) {
+		r.Headers = map[string]string{rg.pick(generatedHeaderNames): rg.paths.name()}
+	}
+
+	for i := rg.rnd.Intn(rg.options.MaxFilters + 1); i > 0; i-- {
+		r.Filters = append(r.Filters, &eskip.Filter{Name: rg.pick(rg.options.FilterNames)})
+	}
+
+	return r
+}
+
+// Generates count route definitions.
+func (rg *routeGenerator) Routes(count int) []*eskip.Route {
+	routes := make([]*eskip.Route, count)
+	for i := range routes {
+		routes[i] = rg.Next()
+	}
+
+	return routes
+}
+
+// creates a request that matches the conditions of a generated route
+func requestForGenerated(r *eskip.Route) (*http.Request, error) {
+	host := "www.example.org"
+	if len(r.HostRegexps) > 0 {
+		host = strings.Trim(strings.Replace(r.HostRegexps[0], "[.]", ".", -1), "^$")
+	}
+
+	u, err := url.Parse(fmt.Sprintf("https://%s%s", host, r.Path))
+	if err != nil {
+		return nil, err
+	}
+
+	method := r.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	req := &http.Request{Method: method, URL: u, Host: host, Header: make(http.Header)}
+	for n, v := range r.Headers {
+		req.Header.Set(n, v)
+	}
+
+	return req, nil
+}
+
+// registers the no-op filters used by the generated routes
+func generatedFilterRegistry(o routeGeneratorOptions) filters.Registry {
+	applyRouteDefaults(&o)
+	fr := make(filters.Registry)
+	for _, n := range o.FilterNames {
+		fr.Register(&filtertest.Filter{FilterName: n})
+	}
+
+	return fr
+}
+
+type staticDataClient struct {
+	routes []*eskip.Route
+}
+
+func (dc *staticDataClient) LoadAll() ([]*eskip.Route, error) { return dc.routes, nil }
+
+func (dc *staticDataClient) LoadUpdate() ([]*eskip.Route, []string, error) {
+	return nil, nil, nil
+}
+
+func TestRouteGenerator(t *testing.T) {
+	const count = 300
+
+	generate := func() []*eskip.Route {
+		pg := newPathGenerator(pathGeneratorOptions{MinNamesInPath: 2})
+		return newRouteGenerator(pg, routeGeneratorOptions{}).Routes(count)
+	}
+
+	routes := generate()
+	if len(routes) != count {
+		t.Error("failed to generate routes", len(routes))
+		return
+	}
+
+	var hosts, methods, headers, withFilters int
+	ids := make(map[string]bool)
+	for _, r := range routes {
+		if ids[r.Id] {
+			t.Error("duplicate route id", r.Id)
+		}
+
+		ids[r.Id] = true
+
+		if len(r.HostRegexps) > 0 {
+			hosts++
+		}
+
+		if r.Method != "" {
+			methods++
+		}
+
+		if len(r.Headers) > 0 {
+			headers++
+		}
+
+		if len(r.Filters) > 0 {
+			withFilters++
+		}
+
+		if len(r.Filters) > defaultMaxFilters {
+			t.Error("too many filters", r.Id, len(r.Filters))
+		}
+	}
+
+	if hosts == 0 || hosts == count ||
+		methods == 0 || methods == count ||
+		headers == 0 || headers == count ||
+		withFilters == 0 || withFilters == count {
+		t.Error("failed to generate a mix of conditions", hosts, methods, headers, withFilters)
+	}
+
+	if !eskip.RoutesEqual(routes, generate()) {
+		t.Error("failed to generate the same sequence")
+	}
+
+	defs := processRouteDefs(Options{}, generatedFilterRegistry(routeGeneratorOptions{}), routes)
+	if len(defs) != count {
+		t.Error("failed to process generated routes", len(defs))
+	}
+}
+
+func BenchmarkGeneratedRoutes(b *testing.B) {
+	const count = 10000
+
+	pg := newPathGenerator(pathGeneratorOptions{MinNamesInPath: 2, MaxNamesInPath: 15})
+	defs := newRouteGenerator(pg, routeGeneratorOptions{}).Routes(count)
+
+	requests := make([]*http.Request, count)
+	for i, d := range defs {
+		req, err := requestForGenerated(d)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		requests[i] = req
+	}
+
+	updates := make(chan RouteUpdate, 1)
+	rt := New(Options{
+		DataClients:       []DataClient{&staticDataClient{defs}},
+		FilterRegistry:    generatedFilterRegistry(routeGeneratorOptions{}),
+		PollTimeout:       time.Hour,
+		SignalRouteUpdate: updates})
+	defer rt.Close()
+
+	select {
+	case <-updates:
+	case <-time.After(30 * time.Second):
+		b.Fatal("timeout while loading the routes")
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if r, _ := rt.Route(requests[i%count]); r == nil {
+			b.Fatal("failed to match route", requests[i%count].URL)
+		}
+	}
+}
+
+// benchmarks rebuilding a routing table of 50k routes after an update of
+// a single route, with or without reusing the unchanged routes. When
+// incremental is set, too, the previous matcher is updated, otherwise a
+// new one is built from scratch. When copyAll is set, all the
+// definitions are new objects, the same way as when the data client
+// parses them again on every update.
+func benchmarkRebuild(b *testing.B, reuse, incremental, copyAll bool) {
+	const count = 50000
+
+	pg := newPathGenerator(pathGeneratorOptions{MinNamesInPath: 2, MaxNamesInPath: 15})
+	defs := newRouteGenerator(pg, routeGeneratorOptions{}).Routes(count)
+	o := Options{
+		FilterRegistry:       generatedFilterRegistry(routeGeneratorOptions{}),
+		ReuseUnchangedRoutes: reuse,
+		Log:                  loggingtest.New()}
+	defer o.Log.(*loggingtest.Logger).Close()
+
+	_, cache := buildMatcher(o, mergedDefs{routes: defs}, nil)
+	if !incremental {
+		cache.matcher = nil
+	}
+
+	// the updated definition is a new object, the same way as the
+	// data clients return the changed routes
+	updated := make([]*eskip.Route, count)
+	copy(updated, defs)
+	if copyAll {
+		for i, d := range defs {
+			c := copyDefinition(*d)
+			updated[i] = &c
+		}
+	}
+
+	changed := *defs[count/2]
+	changed.Backend = "https://changed.example.org"
+	updated[count/2] = &changed
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m, _ := buildMatcher(o, mergedDefs{routes: updated}, cache)
+		if len(m.routes) != count {
+			b.Fatal("failed to build the routing table", len(m.routes))
+		}
+
+		if incremental && m.paths != cache.matcher.paths {
+			b.Fatal("failed to update the routing table incrementally")
+		}
+	}
+}
+
+func BenchmarkFullRebuild(b *testing.B) {
+	benchmarkRebuild(b, false, false, false)
+}
+
+func BenchmarkRebuildReusingRoutes(b *testing.B) {
+	benchmarkRebuild(b, true, false, false)
+}
+
+func BenchmarkRebuildReusingRoutesByContent(b *testing.B) {
+	benchmarkRebuild(b, true, false, true)
+}
+
+func BenchmarkIncrementalRebuild(b *testing.B) {
+	benchmarkRebuild(b, true, true, false)
+}
+
+func BenchmarkIncrementalRebuildByContent(b *testing.B) {
+	benchmarkRebuild(b, true, true, true)
+}
@@ -15,7 +15,14 @@
 package routing
 
 import (
+	"errors"
+	"fmt"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -31,14 +38,54 @@ const (
 	// All options are default.
 	MatchingOptionsNone MatchingOptions = 0
 
-	// Ignore trailing slash in paths.
+	// Ignore trailing slash in paths. With this option, the
+	// condition Path("/foo/bar") matches both /foo/bar and
+	// /foo/bar/, and the same applies to the paths with wildcards.
+	// The PathSubtree conditions match the paths with and without
+	// the trailing slash regardless of this option.
 	IgnoreTrailingSlash MatchingOptions = 1 << iota
+
+	// Ignore the case of the host when matching the Host conditions,
+	// and ignore the trailing dot of fully qualified host names, e.g.
+	// API.Example.COM. matches Host(/^api[.]example[.]com$/).
+	IgnoreHostCase
+)
+
+// DuplicateIdPolicy tells how the routing resolves the route definitions
+// with the same id, received from different data clients.
+type DuplicateIdPolicy int
+
+const (
+	// The definition from the data client that comes first in the
+	// DataClients option is used.
+	DuplicateIdFirstWins DuplicateIdPolicy = iota
+
+	// The definition from the data client that comes last in the
+	// DataClients option is used.
+	DuplicateIdLastWins
+
+	// All the definitions with the duplicate id are dropped.
+	DuplicateIdError
+
+	// The data clients are layered by their order in the DataClients
+	// option: the definitions from the data clients coming later
+	// override the ones with the same id from the data clients coming
+	// earlier, e.g. a base data client providing the defaults can be
+	// followed by an override data client. Unlike with the other
+	// policies, the overrides are not reported as invalid routes. When
+	// an overriding definition is deleted, the overridden one takes
+	// effect again.
+	DuplicateIdOverride
 )
 
 func (o MatchingOptions) ignoreTrailingSlash() bool {
 	return o&IgnoreTrailingSlash > 0
 }
 
+func (o MatchingOptions) ignoreHostCase() bool {
+	return o&IgnoreHostCase > 0
+}
+
 // DataClient instances provide data sources for
 // route definitions.
 type DataClient interface {
@@ -46,6 +93,130 @@ type DataClient interface {
 	LoadUpdate() ([]*eskip.Route, []string, error)
 }
 
+// ResetDataClient instances are data clients that can signal that all
+// the route definitions received from them earlier need to be replaced,
+// e.g. after the backing store went through a full resync.
+//
+// When LoadUpdate returns ErrReset, the routing calls Reset, and
+// replaces the route definitions received from the client with the
+// returned ones, without the need of explicitly deleting the stale ones.
+type ResetDataClient interface {
+	DataClient
+
+	// Returns the complete, current set of route definitions.
+	Reset() ([]*eskip.Route, error)
+}
+
+// PollIntervalDataClient instances are data clients that define their
+// own interval between the requests for route definition updates,
+// overriding the PollTimeout in the routing options.
+type PollIntervalDataClient interface {
+	DataClient
+
+	// Returns the interval between the requests for updates. When
+	// it is not greater than zero, PollTimeout is used.
+	PollInterval() time.Duration
+}
+
+// CancelUpdateDataClient instances are data clients whose LoadUpdate
+// blocks until there is an update, e.g. with long polling.
+//
+// When the routing is closed, it calls CancelUpdate, and waits for the
+// pending LoadUpdate to return. After CancelUpdate was called, LoadUpdate
+// needs to return without blocking.
+type CancelUpdateDataClient interface {
+	DataClient
+
+	// Makes the pending and the subsequent calls to LoadUpdate return.
+	CancelUpdate()
+}
+
+// InvalidRoutesDataClient instances are data clients that report the
+// route definitions that they failed to load, e.g. because of a syntax
+// error in their source. The reported errors are listed by the
+// InvalidRoutes method of the routing, together with the routes
+// rejected by the routing itself.
+//
+// The errors are requested after every load of the route definitions
+// from the client, and they replace the ones reported earlier.
+type InvalidRoutesDataClient interface {
+	DataClient
+
+	// Returns the errors of the route definitions that could not be
+	// loaded.
+	InvalidRoutes() []RouteError
+}
+
+// StreamingDataClient instances are data clients that can deliver the
+// whole set of route definitions in chunks, as they are loaded, e.g.
+// page by page from a remote store, so that they don't need to hold
+// the complete response of the store together with the parsed routes.
+// The routing uses LoadAllChunks instead of LoadAll, when available.
+type StreamingDataClient interface {
+	DataClient
+
+	// Loads the whole set of route definitions, like LoadAll, and
+	// calls receive with the subsequent chunks of them. When
+	// receive returns an error, e.g. because the routing was
+	// closed, loading should stop, and the error should be
+	// returned.
+	LoadAllChunks(receive func([]*eskip.Route) error) error
+}
+
+// IdDataClient instances are data clients with a name, identifying them
+// as the source of their routes. See Route.Source.
+type IdDataClient interface {
+	DataClient
+
+	// Returns the name of the data client, e.g. the address of the
+	// backing store.
+	Id() string
+}
+
+// LoadAllChunks requests the whole set of route definitions from a data
+// client, and calls receive with the subsequent chunks of them, so that
+// the clients returning a slice can be used where the route definitions
+// are expected in chunks. When the data client implements
+// StreamingDataClient, it calls its LoadAllChunks. Otherwise it calls
+// LoadAll, and delivers the route definitions in chunks of at most
+// chunkSize. When chunkSize is not greater than zero, the route
+// definitions are delivered in a single chunk. When receive returns an
+// error, loading stops and the error is returned.
+//
+// The data client is not wrapped, so that the other optional
+// interfaces that it implements, e.g. ResetDataClient, remain
+// available to the routing.
+func LoadAllChunks(c DataClient, chunkSize int, receive func([]*eskip.Route) error) error {
+	if sc, ok := c.(StreamingDataClient); ok {
+		return sc.LoadAllChunks(receive)
+	}
+
+	routes, err := c.LoadAll()
+	if err != nil {
+		return err
+	}
+
+	size := chunkSize
+	if size <= 0 {
+		size = len(routes)
+	}
+
+	for len(routes) > 0 {
+		n := size
+		if n > len(routes) {
+			n = len(routes)
+		}
+
+		if err := receive(routes[:n]); err != nil {
+			return err
+		}
+
+		routes = routes[n:]
+	}
+
+	return nil
+}
+
 // Predicate instances are used as custom user defined route
 // matching predicates.
 type Predicate interface {
@@ -54,6 +225,33 @@ type Predicate interface {
 	Match(*http.Request) bool
 }
 
+// WeightedPredicate instances are predicates that affect the priority
+// of the routes they are used in. Predicates that don't implement this
+// interface have the weight 0.
+//
+// The weights of the predicates in a route are summed up, and the
+// routes with a higher sum take precedence over those with a lower
+// one. See the package documentation for the exact order.
+type WeightedPredicate interface {
+	Predicate
+
+	// Returns the weight of the predicate.
+	Weight() int
+}
+
+// CostPredicate instances are predicates that declare the relative cost
+// of their evaluation, e.g. when they need to make a network call. The
+// custom predicates of a route are evaluated in the order of their cost,
+// the cheaper ones first, so that a failing cheap predicate prevents the
+// evaluation of the more expensive ones. Predicates that don't implement
+// this interface have the cost 0.
+type CostPredicate interface {
+	Predicate
+
+	// Returns the cost of the predicate.
+	Cost() int
+}
+
 // PredicateSpec instances are used to create custom predicates
 // (of type Predicate) with concrete arguments during the
 // construction of the routing tree.
@@ -62,7 +260,9 @@ type PredicateSpec interface {
 	// Name of the predicate as used in the route definitions.
 	Name() string
 
-	// Creates a predicate instance with concrete arguments.
+	// Creates a predicate instance with concrete arguments. When it
+	// returns neither a predicate nor an error, the route is rejected,
+	// the same way as when it returns an error.
 	Create([]interface{}) (Predicate, error)
 }
 
@@ -71,7 +271,8 @@ type Options struct {
 
 	// Registry containing the available filter
 	// specifications that are used during processing
-	// the filter chains in the route definitions.
+	// the filter chains in the route definitions. It
+	// can be replaced with Routing.UpdateFilterRegistry.
 	FilterRegistry filters.Registry
 
 	// Matching options are flags that control the
@@ -79,16 +280,109 @@ type Options struct {
 	MatchingOptions MatchingOptions
 
 	// The timeout between requests to the data
-	// clients for route definition updates.
+	// clients for route definition updates. Data
+	// clients implementing PollIntervalDataClient
+	// can override it.
 	PollTimeout time.Duration
 
+	// When set, the updates received from the data clients within
+	// this duration, counted from the first one, are applied
+	// together, as a single update of the routing table. It avoids
+	// the intermediate states of the routing table when multiple
+	// data clients are updated at the same time, e.g. during a
+	// deployment, and reduces the number of times the routing table
+	// is rebuilt. It delays the updates, including the initial
+	// ones, by at most the window.
+	BatchWindow time.Duration
+
+	// When set, the failed requests for the initial set of
+	// route definitions are retried with an exponential
+	// backoff starting from this value, with jitter, and
+	// capped at the poll timeout of the data client. When
+	// not set, the failed requests are retried after the
+	// poll timeout.
+	InitialPollBackoff time.Duration
+
 	// The set of different data clients where the
 	// route definitions are read from.
 	DataClients []DataClient
 
-	// Specifications of custom, user defined predicates.
+	// Specifications of custom, user defined predicates. They
+	// can be replaced with Routing.UpdatePredicates.
 	Predicates []PredicateSpec
 
+	// Tells how the route definitions with the same id from
+	// different data clients are resolved. The default is
+	// DuplicateIdFirstWins. Every collision is reported as an
+	// invalid route with ErrDuplicateRouteId, including the ones
+	// where one of the definitions is kept, except with
+	// DuplicateIdOverride.
+	OnDuplicateId DuplicateIdPolicy
+
+	// Functions transforming the route definitions, e.g. to add a
+	// filter to every route, or to change the backends. They are
+	// applied in order to the merged route definitions of all the
+	// data clients, on every update, before the routes are
+	// processed. They receive copies of the definitions, that they
+	// can modify. The routes that are invalid after the
+	// transformation are dropped and reported the same way as the
+	// invalid routes from the data clients. The default route is not
+	// passed to the preprocessors.
+	PreProcessors []func([]*eskip.Route) []*eskip.Route
+
+	// Functions transforming the processed routes, with the filter
+	// and predicate instances already created, e.g. to attach
+	// runtime state to the routes. They are applied in order, on
+	// every update, before the routing table is built, and the
+	// returned routes become the routes of the new routing table.
+	// They are called from the goroutine building the routing
+	// tables, one at a time. Once returned, the routes are used by
+	// the concurrent requests, so they must not be modified anymore.
+	// The default route is not passed to the postprocessors.
+	PostProcessors []func([]*Route) []*Route
+
+	// Custom ordering of the routes that are candidates for the same
+	// request, replacing the default one, which prefers the routes
+	// with more conditions. It tells whether route a should be
+	// evaluated before route b. It must define a strict total order
+	// over the routes, e.g. by breaking ties with the route id,
+	// otherwise the order of the equal routes is undefined. The
+	// candidates are the routes with the same path condition, and
+	// the routes without a path condition. The sum of the weights
+	// of the weighted predicates takes precedence over the custom
+	// ordering, too, and the routes with path conditions are still
+	// evaluated before those without, unless they have a lower
+	// predicate weight.
+	RouteOrdering func(a, b *Route) bool
+
+	// When set, only the new and the changed route definitions are
+	// processed on an update, while the unchanged ones keep their
+	// filter and predicate instances from the previous routing
+	// table. When the paths of the new, the changed and the deleted
+	// routes are all found in the previous routing table, the
+	// routing table is updated incrementally: the path tree is
+	// shared with the previous routing table, and only the routes
+	// of the affected paths are ordered again. Otherwise, e.g. when
+	// a route with a new path is added, or after many incremental
+	// updates, the path tree is built from scratch. A route
+	// definition is considered unchanged when the data client
+	// returns it as the same object, or when its id and its
+	// canonical eskip representation are the same as before, e.g.
+	// when the data client parses the definitions again on every
+	// update, or when they are created by the PreProcessors.
+	// The option is ignored, and the routing table is rebuilt from
+	// scratch, when PostProcessors or RouteTableRetired are set.
+	// Replacing the filter registry or the predicates always
+	// rebuilds the routing table from scratch.
+	//
+	// The filter and predicate instances are only reused by the
+	// same route, so they are never shared between different routes
+	// of a routing table. The instances holding state, e.g. the
+	// counters of the concurrencyLimit filter, keep their state
+	// across the updates while the route is unchanged, the same way
+	// as when the routing table is not updated.
+	ReuseUnchangedRoutes bool
+
 	// Performance tuning option.
 	//
 	// When zero, the newly constructed routing
@@ -106,6 +400,96 @@ type Options struct {
 
 	// Set a custom logger if necessary.
 	Log logging.Logger
+
+	// When set, the routing sends the changes of the routing table on
+	// this channel, every time a new routing table was applied. The
+	// routing doesn't wait for the receiver, and the changes are
+	// dropped when the channel is not ready, so using a buffered
+	// channel is recommended.
+	SignalRouteUpdate chan<- RouteUpdate
+
+	// Enables RouteAll, that returns all the routes matching a
+	// request. It is meant for debugging overlapping route
+	// definitions.
+	EnableRouteAll bool
+
+	// Enables counting the matched requests for every route. The
+	// counters can be read with the Metrics method. The counters of
+	// the routes are kept when the routing table is updated, unless
+	// the route was deleted.
+	EnableRouteMetrics bool
+
+	// When set, it is called with the routes of a replaced routing
+	// table, once no request references it anymore. Requests can hold
+	// references to a routing table by looking up the routes with
+	// Acquire. It is called from the goroutine releasing the last
+	// reference, so it should not block. It is not called for empty
+	// routing tables.
+	RouteTableRetired func([]*Route)
+
+	// Enables RouteWithDiagnostics, that reports the conditions
+	// that the request failed to match in the candidate routes. It
+	// is meant for debugging, and it is expensive, because it
+	// evaluates the conditions of all the candidates.
+	EnableMatchTrace bool
+
+	// When set, this route is returned when no other route matches a
+	// request. The conditions of the default route are ignored, only
+	// its filters and backend are used, and any matching route takes
+	// precedence over it. Its filters are created with the filter
+	// registry, the same way as for the routes from the data clients.
+	DefaultRoute *eskip.Route
+
+	// When set, it is called with the statistics of every applied
+	// update of the routing table. It is called from the goroutine
+	// applying the updates, so it should not block.
+	UpdateMetrics func(UpdateStats)
+
+	// When set, the updates whose merged route definitions exceed
+	// this number are rejected, and the previous routing table stays
+	// active until the next acceptable update. It protects against a
+	// data client flooding the routing table. The new and changed
+	// route definitions of the rejected update are reported by
+	// InvalidRoutes with ErrTooManyRoutes, until the next acceptable
+	// update. Zero means no limit.
+	MaxRoutes int
+
+	// The minimum interval between the warnings logged when a
+	// deprecated route matches a request, per route. Defaults to one
+	// minute. See DeprecatedAnnotation.
+	DeprecationLogInterval time.Duration
+
+	// The names of the filters that the routes must not use, e.g.
+	// the ones that can access the local file system. The routes
+	// referencing them are rejected and reported as invalid, the same
+	// way as when the filter is not found in the registry. It applies
+	// to the registries set with UpdateFilterRegistry, too.
+	DisabledFilters []string
+
+	// The names of the custom predicates that the routes must not use,
+	// rejected the same way as the disabled filters. The conditions
+	// built into the route definitions, like Path or Method, cannot be
+	// disabled.
+	DisabledPredicates []string
+}
+
+// UpdateStats describes an applied update of the routing table.
+type UpdateStats struct {
+
+	// The number of routes in the new routing table.
+	Routes int
+
+	// The number of the route definitions dropped, because they failed
+	// to be processed.
+	Invalid int
+
+	// The number of the added, updated and deleted routes, compared to
+	// the previous routing table.
+	Added, Updated, Deleted int
+
+	// The time spent processing the route definitions and building
+	// the new routing table.
+	Duration time.Duration
 }
 
 // Filter contains extensions to generic filter
@@ -117,6 +501,16 @@ type RouteFilter struct {
 	Index int
 }
 
+// LBEndpoint is one of the backends of a load balanced route.
+type LBEndpoint struct {
+
+	// The backend address, scheme and host.
+	Address, Scheme, Host string
+
+	// The weight of the backend.
+	Weight float64
+}
+
 // Route object with preprocessed filter instances.
 type Route struct {
 
@@ -131,16 +525,484 @@ type Route struct {
 
 	// The preprocessed filter instances.
 	Filters []*RouteFilter
+
+	// The backends of a load balanced route.
+	LBEndpoints []LBEndpoint
+
+	// the predicates of the definition, captured when the route
+	// was processed
+	predicateInfo []PredicateInfo
+
+	// identifies the data client that the definition was received
+	// from
+	source string
+}
+
+// Source identifies the data client that the definition of the route
+// was received from. It is the id of the data clients implementing
+// IdDataClient, otherwise the type and the position of the data client
+// in the routing options, e.g. "*etcd.Client/0". It is empty for the
+// default route, for the routes created by the preprocessors with a new
+// id, and for the routes created with NewMatcher.
+func (r *Route) Source() string { return r.source }
+
+// PredicateInfo describes a condition of a route, as it was set in the
+// route definition.
+type PredicateInfo struct {
+
+	// The name of the predicate, e.g. Path, Host, Method, or the
+	// name of a custom predicate.
+	Name string
+
+	// The arguments of the predicate. The regular expressions are
+	// represented by strings.
+	Args []interface{}
+
+	// Set when the predicate is negated.
+	Negated bool
+}
+
+// returns the conditions of a route definition, in the same order as
+// they are printed in eskip format
+func definitionPredicates(r *eskip.Route) []PredicateInfo {
+	var p []PredicateInfo
+	add := func(name string, args ...interface{}) {
+		p = append(p, PredicateInfo{Name: name, Args: args})
+	}
+
+	if r.Path != "" {
+		add("Path", r.Path)
+	}
+
+	if r.PathSubtree != "" {
+		add("PathSubtree", r.PathSubtree)
+	}
+
+	for _, h := range r.HostRegexps {
+		add("Host", h)
+	}
+
+	for _, rx := range r.PathRegexps {
+		add("PathRegexp", rx)
+	}
+
+	if r.Method != "" {
+		add("Method", r.Method)
+	}
+
+	var keys []string
+	for k := range r.Headers {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	for _, k := range keys {
+		add("Header", k, r.Headers[k])
+	}
+
+	keys = nil
+	for k := range r.HeaderRegexps {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, rx := range r.HeaderRegexps[k] {
+			add("HeaderRegexp", k, rx)
+		}
+	}
+
+	for _, cp := range r.Predicates {
+		p = append(p, PredicateInfo{
+			Name:    cp.Name,
+			Args:    append([]interface{}(nil), cp.Args...),
+			Negated: cp.Negated})
+	}
+
+	return p
+}
+
+// PredicateInfo returns the conditions of the route, e.g. to log why
+// the route was selected for a request. When the route was created by
+// the routing, the conditions are captured when the route definition
+// was processed, and the returned slice must not be modified.
+// Otherwise, e.g. for a cloned route, they are taken from the current
+// route definition.
+func (r *Route) PredicateInfo() []PredicateInfo {
+	if r.predicateInfo != nil {
+		return r.predicateInfo
+	}
+
+	return definitionPredicates(&r.Route)
+}
+
+// Picks one of the backends of a load balanced route randomly,
+// proportionally to their weight. It returns false, when the route
+// is not load balanced.
+func (r *Route) PickLBEndpoint() (LBEndpoint, bool) {
+	if len(r.LBEndpoints) == 0 {
+		return LBEndpoint{}, false
+	}
+
+	var total float64
+	for _, ep := range r.LBEndpoints {
+		total += ep.Weight
+	}
+
+	n := rand.Float64() * total
+	for _, ep := range r.LBEndpoints {
+		if n < ep.Weight {
+			return ep, true
+		}
+
+		n -= ep.Weight
+	}
+
+	return r.LBEndpoints[len(r.LBEndpoints)-1], true
+}
+
+// copies the slices and maps of a route definition, so that
+// the copy can be modified without affecting the original.
+func copyDefinition(r eskip.Route) eskip.Route {
+	c := r
+
+	c.HostRegexps = append([]string(nil), r.HostRegexps...)
+	c.PathRegexps = append([]string(nil), r.PathRegexps...)
+
+	if r.Headers != nil {
+		c.Headers = make(map[string]string)
+		for k, v := range r.Headers {
+			c.Headers[k] = v
+		}
+	}
+
+	if r.HeaderRegexps != nil {
+		c.HeaderRegexps = make(map[string][]string)
+		for k, v := range r.HeaderRegexps {
+			c.HeaderRegexps[k] = append([]string(nil), v...)
+		}
+	}
+
+	if r.Annotations != nil {
+		c.Annotations = make(map[string]string)
+		for k, v := range r.Annotations {
+			c.Annotations[k] = v
+		}
+	}
+
+	c.LBBackends = nil
+	for _, b := range r.LBBackends {
+		bc := *b
+		c.LBBackends = append(c.LBBackends, &bc)
+	}
+
+	c.Predicates = nil
+	for _, p := range r.Predicates {
+		c.Predicates = append(c.Predicates, &eskip.Predicate{
+			Name:    p.Name,
+			Args:    append([]interface{}(nil), p.Args...),
+			Negated: p.Negated})
+	}
+
+	c.Filters = nil
+	for _, f := range r.Filters {
+		c.Filters = append(c.Filters, &eskip.Filter{
+			Name: f.Name,
+			Args: append([]interface{}(nil), f.Args...)})
+	}
+
+	return c
+}
+
+// Clone returns a deep copy of the route, that can be modified
+// without affecting the routing table. The predicate and filter
+// instances are shared with the original route, only the slices
+// and the RouteFilter wrappers are copied.
+func (r *Route) Clone() *Route {
+	c := &Route{
+		Route:       copyDefinition(r.Route),
+		Scheme:      r.Scheme,
+		Host:        r.Host,
+		Predicates:  append([]Predicate(nil), r.Predicates...),
+		LBEndpoints: append([]LBEndpoint(nil), r.LBEndpoints...),
+		source:      r.source}
+
+	for _, f := range r.Filters {
+		fc := *f
+		c.Filters = append(c.Filters, &fc)
+	}
+
+	return c
+}
+
+// RouteUpdate contains the ids of the routes that were changed in the
+// routing table, sorted.
+type RouteUpdate struct {
+	Added   []string
+	Updated []string
+	Deleted []string
+}
+
+// compares the routes of two routing tables by their ids and their
+// definitions
+func diffRoutes(prev, next []*Route) RouteUpdate {
+	var u RouteUpdate
+	prevById := make(map[string]*Route)
+	for _, r := range prev {
+		prevById[r.Id] = r
+	}
+
+	for _, r := range next {
+		if pr, ok := prevById[r.Id]; !ok {
+			u.Added = append(u.Added, r.Id)
+		} else if pr != r && !pr.Route.Equal(&r.Route) {
+			u.Updated = append(u.Updated, r.Id)
+		}
+
+		delete(prevById, r.Id)
+	}
+
+	for id := range prevById {
+		u.Deleted = append(u.Deleted, id)
+	}
+
+	sort.Strings(u.Added)
+	sort.Strings(u.Updated)
+	sort.Strings(u.Deleted)
+	return u
+}
+
+// counts the requests matched by a deprecated route, and holds the time
+// of the last warning about it
+type deprecation struct {
+	hits    int64
+	lastLog int64
+}
+
+// creates the counters of the deprecated routes for a new routing
+// table, keeping the counters of the routes that were already in the
+// previous one
+func carryDeprecations(prev map[string]*deprecation, routes []*Route) map[string]*deprecation {
+	var d map[string]*deprecation
+	for _, r := range routes {
+		if _, ok := r.Annotations[DeprecatedAnnotation]; !ok {
+			continue
+		}
+
+		if d == nil {
+			d = make(map[string]*deprecation)
+		}
+
+		if pd, ok := prev[r.Id]; ok {
+			d[r.Id] = pd
+		} else {
+			d[r.Id] = &deprecation{}
+		}
+	}
+
+	return d
+}
+
+// creates the hit counters for a new routing table, keeping the counters
+// of the routes that were already in the previous one
+func carryHits(prev map[string]*int64, routes []*Route) map[string]*int64 {
+	hits := make(map[string]*int64)
+	for _, r := range routes {
+		if h, ok := prev[r.Id]; ok {
+			hits[r.Id] = h
+		} else {
+			hits[r.Id] = new(int64)
+		}
+	}
+
+	return hits
+}
+
+// MatchCandidate is a route that was evaluated while matching a request.
+type MatchCandidate struct {
+	RouteId string
+
+	// The name of the first condition of the route that the request
+	// didn't match, e.g. Method, Host or the name of a custom
+	// predicate. Empty if the request matched the route.
+	FailedCondition string
+}
+
+// MatchTrace lists the routes that were evaluated while matching a
+// request, in the order of their priority. The candidates are the routes
+// whose path condition matched the request, and the routes without a
+// path condition.
+type MatchTrace struct {
+	Candidates []MatchCandidate
+}
+
+// RouteError describes a route definition that was dropped from the
+// routing table, because it failed to be processed.
+type RouteError struct {
+	Id  string
+	Err error
+}
+
+func (e RouteError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Id, e.Err)
+}
+
+// Validate parses an eskip document, and processes the route definitions
+// the same way as the routing does when receiving them from the data
+// clients, with the provided filter registry and custom predicates, but
+// without creating a routing table. It returns all the errors found. The
+// errors of the individual route definitions are of type RouteError.
+func Validate(doc string, fr filters.Registry, cps []PredicateSpec) []error {
+	defs, err := eskip.Parse(doc)
+	if err != nil {
+		return []error{err}
+	}
+
+	_, errs := NewMatcher(defs, fr, cps)
+	return errs
+}
+
+// Matcher is a routing table created from a static set of route
+// definitions, without data clients and without polling, e.g. for
+// tests or offline tooling. See NewMatcher.
+type Matcher struct {
+	matcher *matcher
+}
+
+// NewMatcher processes the route definitions the same way as the routing
+// does when receiving them from the data clients, with the provided
+// filter registry and custom predicates, and creates a routing table
+// from the valid ones. It returns the errors of the rejected routes, of
+// type RouteError, and the errors of the table itself, if any. The
+// returned matcher is safe for concurrent use.
+func NewMatcher(defs []*eskip.Route, fr filters.Registry, cps []PredicateSpec) (*Matcher, []error) {
+	routes, invalid := processRouteDefsWithErrors(cps, fr, defs)
+
+	var errs []error
+	for _, ri := range invalid {
+		errs = append(errs, ri)
+	}
+
+	m, merrs := newMatcher(routes, MatchingOptionsNone)
+	for _, err := range merrs {
+		if err.Index >= 0 {
+			errs = append(errs, RouteError{err.Id, err.Original})
+		} else {
+			errs = append(errs, err)
+		}
+	}
+
+	return &Matcher{m}, errs
+}
+
+// Match returns the route matching the request, and the wildcard
+// parameters from the path conditions, if any. It returns nil when no
+// route matches.
+func (m *Matcher) Match(req *http.Request) (*Route, map[string]string) {
+	return m.matcher.match(req)
+}
+
+// Routes returns the routes of the matcher, ordered by their id. The
+// returned slice is a copy, and changing it doesn't affect the matcher.
+func (m *Matcher) Routes() []*Route {
+	routes := make([]*Route, len(m.matcher.routes))
+	copy(routes, m.matcher.routes)
+	return routes
+}
+
+// RouteInput describes a request for matching it without an
+// http.Request, e.g. in offline tooling. See Routing.RouteFor.
+type RouteInput struct {
+
+	// The request method, defaults to GET.
+	Method string
+
+	// The host of the request, used by the Host conditions.
+	Host string
+
+	// The absolute, unescaped path of the request, defaults to /.
+	Path string
+
+	// The request headers, by name.
+	Headers map[string]string
+
+	// The query parameters, by name.
+	Query map[string]string
 }
 
 // Routing ('router') instance providing live
 // updatable request matching.
 type Routing struct {
-	matcher atomic.Value
-	log     logging.Logger
-	quit    chan struct{}
+	matcher                atomic.Value
+	rejected               atomic.Value
+	log                    logging.Logger
+	deprecationLogInterval time.Duration
+	routeAll               bool
+	routeMetrics           bool
+	matchTrace             bool
+	retired                func([]*Route)
+	quit                   chan struct{}
+	closeOnce              sync.Once
+	ready                  chan struct{}
+	readyOnce              sync.Once
+	wg                     sync.WaitGroup
+
+	// changes the options of the goroutine building the
+	// routing tables
+	optionsUpdates chan func(*Options)
+
+	subscriptionsMx     sync.Mutex
+	subscriptions       map[*subscription]struct{}
+	subscriptionsClosed bool
+}
+
+// Table is a reference to a routing table, obtained by Acquire. The
+// routing table is not retired until the reference is released.
+type Table struct {
+	routing *Routing
+	matcher *matcher
 }
 
+var (
+	// Error returned by RouteAll when it was not enabled in the options.
+	ErrRouteAllDisabled = errors.New("matching all routes is not enabled")
+
+	// Error returned by RouteWithDiagnostics when it was not enabled in
+	// the options.
+	ErrMatchTraceDisabled = errors.New("match trace is not enabled")
+
+	// Error returned by RouteFor when the path of the input is not
+	// absolute.
+	ErrInvalidRouteInput = errors.New("invalid route input, the path must be absolute")
+
+	// Error reported as the reason of an invalid route, when multiple
+	// data clients provide route definitions with the same id. See
+	// Options.OnDuplicateId.
+	ErrDuplicateRouteId = errors.New("duplicate route id")
+
+	// Error returned by the LoadUpdate method of a ResetDataClient,
+	// when the previously received route definitions need to be
+	// replaced by the result of Reset.
+	ErrReset = errors.New("data client reset")
+
+	// Error reported for the new and changed route definitions of an
+	// update that was rejected, because it exceeded the MaxRoutes
+	// limit. See Routing.InvalidRoutes.
+	ErrTooManyRoutes = errors.New("too many routes")
+)
+
+// DeprecatedAnnotation marks a route deprecated. When a deprecated route
+// matches a request, the routing counts it, and logs a warning with the
+// value of the annotation, rate limited by the DeprecationLogInterval
+// option. The counters can be read with DeprecatedMetrics.
+//
+// Example:
+//
+//	old: Path("/v1/users") && Annotation("deprecated", "use the route users") -> "https://v1.example.org";
+const DeprecatedAnnotation = "deprecated"
+
+const defaultDeprecationLogInterval = time.Minute
+
 // Initializes a new routing instance, and starts listening for route
 // definition updates.
 func New(o Options) *Routing {
@@ -148,8 +1010,38 @@ func New(o Options) *Routing {
 		o.Log = &logging.DefaultLog{}
 	}
 
-	r := &Routing{log: o.Log, quit: make(chan struct{})}
+	if o.DeprecationLogInterval <= 0 {
+		o.DeprecationLogInterval = defaultDeprecationLogInterval
+	}
+
+	r := &Routing{
+		log:                    o.Log,
+		deprecationLogInterval: o.DeprecationLogInterval,
+		routeAll:               o.EnableRouteAll,
+		routeMetrics:           o.EnableRouteMetrics,
+		matchTrace:             o.EnableMatchTrace,
+		retired:                o.RouteTableRetired,
+		quit:                   make(chan struct{}),
+		ready:                  make(chan struct{}),
+		optionsUpdates:         make(chan func(*Options))}
+
+	if len(o.DataClients) == 0 {
+		r.setReady()
+	}
+
 	initialMatcher, _ := newMatcher(nil, MatchingOptionsNone)
+	if dr, err := processDefaultRoute(o); err == nil {
+		initialMatcher.defaultRoute = dr
+	} else {
+		o.Log.Error(err)
+	}
+
+	if r.routeMetrics {
+		initialMatcher.hits = carryHits(nil, initialMatcher.allRoutes())
+	}
+
+	initialMatcher.deprecations = carryDeprecations(nil, initialMatcher.allRoutes())
+	initialMatcher.version = 1
 	r.matcher.Store(initialMatcher)
 	r.startReceivingUpdates(o)
 	return r
@@ -157,13 +1049,59 @@ func New(o Options) *Routing {
 
 func (r *Routing) startReceivingUpdates(o Options) {
 	c := make(chan *matcher)
-	go receiveRouteMatcher(o, c, r.quit)
+
+	// the diff of the consecutive routing tables is calculated only
+	// when there is a consumer for it
+	_, fieldLog := r.log.(logging.FieldLogger)
+	useDiff := fieldLog || o.UpdateMetrics != nil || o.SignalRouteUpdate != nil
+
+	r.wg.Add(2)
 	go func() {
+		defer r.wg.Done()
+		receiveRouteMatcher(o, r.optionsUpdates, c, r.setRejected, r.quit, &r.wg)
+	}()
+
+	go func() {
+		defer r.wg.Done()
 		for {
 			select {
 			case m := <-c:
-				r.matcher.Store(m)
-				r.log.Info("route settings applied")
+				prev := r.matcher.Load().(*matcher)
+				if r.routeMetrics {
+					m.hits = carryHits(prev.hits, m.allRoutes())
+				}
+
+				m.deprecations = carryDeprecations(prev.deprecations, m.allRoutes())
+
+				m.version = prev.version + 1
+				r.storeMatcher(m)
+				var diff RouteUpdate
+				if useDiff {
+					diff = diffRoutes(prev.routes, m.routes)
+				}
+
+				logApplied(r.log, m, diff)
+				r.release(prev)
+				if m.initialized {
+					r.setReady()
+				}
+
+				if o.UpdateMetrics != nil {
+					o.UpdateMetrics(UpdateStats{
+						Routes:   len(m.routes),
+						Invalid:  len(m.invalidRoutes),
+						Added:    len(diff.Added),
+						Updated:  len(diff.Updated),
+						Deleted:  len(diff.Deleted),
+						Duration: m.buildDuration})
+				}
+
+				if o.SignalRouteUpdate != nil {
+					select {
+					case o.SignalRouteUpdate <- diff:
+					default:
+					}
+				}
 			case <-r.quit:
 				return
 			}
@@ -171,6 +1109,63 @@ func (r *Routing) startReceivingUpdates(o Options) {
 	}()
 }
 
+// logs the applied routing table, with the details as structured fields,
+// when the logger supports it
+func logApplied(l logging.Logger, m *matcher, diff RouteUpdate) {
+	const msg = "route settings applied"
+	fl, ok := l.(logging.FieldLogger)
+	if !ok {
+		l.Info(msg)
+		return
+	}
+
+	fl.WithFields(logging.Fields{
+		"routes":   len(m.routes),
+		"invalid":  len(m.invalidRoutes),
+		"added":    len(diff.Added),
+		"updated":  len(diff.Updated),
+		"deleted":  len(diff.Deleted),
+		"duration": m.buildDuration,
+		"checksum": m.checksum}).Info(msg)
+}
+
+func (r *Routing) updateOptions(update func(*Options)) {
+	select {
+	case r.optionsUpdates <- update:
+	case <-r.quit:
+	}
+}
+
+// UpdateFilterRegistry replaces the filter registry used to create the
+// filters of the routes, and rebuilds the routing table from the
+// current route definitions, e.g. when a filter plugin was loaded. The
+// routes that were rejected because of an unknown filter become active
+// when the filter is available in the new registry. The registry must
+// not be modified once passed to the routing.
+func (r *Routing) UpdateFilterRegistry(fr filters.Registry) {
+	r.updateOptions(func(o *Options) { o.FilterRegistry = fr })
+}
+
+// UpdatePredicates replaces the custom predicates, and rebuilds the
+// routing table from the current route definitions, the same way as
+// UpdateFilterRegistry.
+func (r *Routing) UpdatePredicates(cps []PredicateSpec) {
+	r.updateOptions(func(o *Options) { o.Predicates = cps })
+}
+
+func (r *Routing) setReady() {
+	r.readyOnce.Do(func() { close(r.ready) })
+}
+
+// Ready returns a channel that is closed once the routing table
+// contains the initial set of routes from every data client. The
+// failed requests for the initial routes are retried, so when a data
+// client keeps failing, the channel stays open. It can be used to
+// delay serving the incoming requests until the routes are loaded.
+func (r *Routing) Ready() <-chan struct{} {
+	return r.ready
+}
+
 // Matches a request in the current routing tree.
 //
 // If the request matches a route, returns the route and a map of
@@ -178,10 +1173,249 @@ func (r *Routing) startReceivingUpdates(o Options) {
 // condition if any. If there is no match, it returns nil.
 func (r *Routing) Route(req *http.Request) (*Route, map[string]string) {
 	m := r.matcher.Load().(*matcher)
-	return m.match(req)
+	rt, params := m.match(req)
+	r.matched(m, rt)
+	return rt, params
+}
+
+func excludedSet(excluded []*Route) map[*Route]bool {
+	if len(excluded) == 0 {
+		return nil
+	}
+
+	ex := make(map[*Route]bool)
+	for _, e := range excluded {
+		ex[e] = true
+	}
+
+	return ex
+}
+
+// Matches a request in the current routing tree like Route, but skips
+// the excluded routes. It can be used to find the next matching route,
+// when a matched route was rejected during processing the request.
+func (r *Routing) RouteExcluding(req *http.Request, excluded ...*Route) (*Route, map[string]string) {
+	m := r.matcher.Load().(*matcher)
+	rt, params := m.matchExcluding(req, excludedSet(excluded))
+	r.matched(m, rt)
+	return rt, params
+}
+
+// Matches a request in the current routing tree, and returns all the
+// matching routes, in the order of their priority. The first route in
+// the list is the one that Route returns. It returns an error, unless
+// EnableRouteAll was set in the options.
+func (r *Routing) RouteAll(req *http.Request) ([]*Route, error) {
+	if !r.routeAll {
+		return nil, ErrRouteAllDisabled
+	}
+
+	m := r.matcher.Load().(*matcher)
+	return m.matchAll(req), nil
+}
+
+// Returns a copy of the route definitions in the current routing table,
+// sorted by their id. The returned routes reflect the merged definitions
+// from all the data clients, without the ones that failed to be
+// processed.
+func (r *Routing) CurrentRoutes() []*eskip.Route {
+	m := r.matcher.Load().(*matcher)
+	routes := make([]*eskip.Route, len(m.routes))
+	for i, mr := range m.routes {
+		def := copyDefinition(mr.Route)
+		routes[i] = &def
+	}
+
+	return routes
+}
+
+// creates the minimal request that the built-in conditions need
+func (in RouteInput) request() (*http.Request, error) {
+	method := in.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	p := in.Path
+	if p == "" {
+		p = "/"
+	} else if !strings.HasPrefix(p, "/") {
+		return nil, ErrInvalidRouteInput
+	}
+
+	q := make(url.Values)
+	for k, v := range in.Query {
+		q.Set(k, v)
+	}
+
+	h := make(http.Header)
+	for k, v := range in.Headers {
+		h.Set(k, v)
+	}
+
+	return &http.Request{
+		Method:     method,
+		URL:        &url.URL{Path: p, RawQuery: q.Encode(), Host: in.Host},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     h,
+		Host:       in.Host}, nil
+}
+
+// Matches the described request in the current routing tree, without
+// an http.Request, e.g. for offline analysis of the routing table. It
+// returns nil when no route matches. The matched requests are not
+// counted in the route metrics.
+//
+// The synthesized request has no body, remote address or TLS state,
+// so the custom predicates depending on these, e.g. Source, are
+// evaluated against the empty values. The built-in conditions, and the
+// custom predicates checking only the method, the host, the path, the
+// headers or the query are supported.
+func (r *Routing) RouteFor(in RouteInput) (*Route, error) {
+	req, err := in.request()
+	if err != nil {
+		return nil, err
+	}
+
+	m := r.matcher.Load().(*matcher)
+	rt, _ := m.match(req)
+	return rt, nil
+}
+
+func (r *Routing) setRejected(rejected []RouteError) {
+	r.rejected.Store(rejected)
+}
+
+// Returns the errors of the route definitions that were dropped when
+// the current routing table was created, e.g. because of an invalid
+// backend address or an unknown filter. The list is replaced on every
+// update of the routing table. When the last update was rejected,
+// because of the MaxRoutes limit, its new and changed route
+// definitions are listed, too.
+func (r *Routing) InvalidRoutes() []RouteError {
+	m := r.matcher.Load().(*matcher)
+	rejected, _ := r.rejected.Load().([]RouteError)
+	invalid := make([]RouteError, 0, len(m.invalidRoutes)+len(rejected))
+	invalid = append(invalid, m.invalidRoutes...)
+	return append(invalid, rejected...)
+}
+
+// Matches a request in the current routing tree like Route, and returns
+// the trace of the evaluated candidate routes. It returns an error,
+// unless EnableMatchTrace was set in the options.
+func (r *Routing) RouteWithDiagnostics(req *http.Request) (*Route, *MatchTrace, error) {
+	if !r.matchTrace {
+		return nil, nil, ErrMatchTraceDisabled
+	}
+
+	m := r.matcher.Load().(*matcher)
+	rt, _ := m.match(req)
+	return rt, m.trace(req), nil
+}
+
+// Returns a snapshot of the number of the matched requests by route id,
+// for the routes in the current routing table. It returns nil, unless
+// EnableRouteMetrics was set in the options.
+func (r *Routing) Metrics() map[string]int64 {
+	if !r.routeMetrics {
+		return nil
+	}
+
+	m := r.matcher.Load().(*matcher)
+	s := make(map[string]int64)
+	for id, h := range m.hits {
+		s[id] = atomic.LoadInt64(h)
+	}
+
+	return s
+}
+
+// Returns a snapshot of the number of the requests matched by the
+// deprecated routes, by route id, for the deprecated routes in the
+// current routing table. See DeprecatedAnnotation.
+func (r *Routing) DeprecatedMetrics() map[string]int64 {
+	m := r.matcher.Load().(*matcher)
+	s := make(map[string]int64)
+	for id, d := range m.deprecations {
+		s[id] = atomic.LoadInt64(&d.hits)
+	}
+
+	return s
+}
+
+// counts a matched route, and warns about it when it is deprecated
+func (r *Routing) matched(m *matcher, rt *Route) {
+	m.countHit(rt)
+	if rt == nil || m.deprecations == nil {
+		return
+	}
+
+	d, ok := m.deprecations[rt.Id]
+	if !ok {
+		return
+	}
+
+	atomic.AddInt64(&d.hits, 1)
+
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&d.lastLog)
+	if last != 0 && now-last < int64(r.deprecationLogInterval) {
+		return
+	}
+
+	// only one of the concurrent requests logs the warning
+	if atomic.CompareAndSwapInt64(&d.lastLog, last, now) {
+		r.log.Warnf("deprecated route matched: %s, %s", rt.Id, rt.Annotations[DeprecatedAnnotation])
+	}
+}
+
+// Returns a reference to the current routing table. The routing table
+// is not retired until Release is called on the returned reference.
+func (r *Routing) Acquire() *Table {
+	for {
+		m := r.matcher.Load().(*matcher)
+		if m.acquire() {
+			return &Table{routing: r, matcher: m}
+		}
+	}
+}
+
+// releases a reference to a matcher, and signals its retirement if it
+// was the last one
+func (r *Routing) release(m *matcher) {
+	if m.release() && r.retired != nil && len(m.routes) > 0 {
+		r.retired(m.routes)
+	}
+}
+
+// Matches a request in the referenced routing table, like Routing.Route.
+func (t *Table) Route(req *http.Request) (*Route, map[string]string) {
+	return t.RouteExcluding(req)
+}
+
+// Matches a request in the referenced routing table, like
+// Routing.RouteExcluding.
+func (t *Table) RouteExcluding(req *http.Request, excluded ...*Route) (*Route, map[string]string) {
+	rt, params := t.matcher.matchExcluding(req, excludedSet(excluded))
+	t.routing.matched(t.matcher, rt)
+	return rt, params
+}
+
+// Releases the reference to the routing table. It must be called
+// exactly once for every reference returned by Acquire.
+func (t *Table) Release() {
+	t.routing.release(t.matcher)
 }
 
-// Closes routing, stops receiving routes.
+// Closes routing, stops receiving routes. It blocks until the background
+// goroutines polling the data clients exit, which includes waiting for
+// the pending requests to the data clients. The data clients that block
+// in LoadUpdate need to implement CancelUpdateDataClient. It is safe to
+// call it more than once.
 func (r *Routing) Close() {
-	close(r.quit)
+	r.closeOnce.Do(func() { close(r.quit) })
+	r.wg.Wait()
+	r.closeSubscriptions()
 }
@@ -2,7 +2,12 @@ package routing_test
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -11,6 +16,8 @@ import (
 	"github.com/zalando/skipper/filters/builtin"
 	"github.com/zalando/skipper/filters/filtertest"
 	"github.com/zalando/skipper/logging/loggingtest"
+	"github.com/zalando/skipper/predicates/pathsegment"
+	"github.com/zalando/skipper/predicates/query"
 	"github.com/zalando/skipper/routing"
 	"github.com/zalando/skipper/routing/testdataclient"
 )
@@ -101,9 +108,11 @@ func (tr *testRouting) checkGetRequest(url string) (*routing.Route, error) {
 	return tr.checkRequest(req)
 }
 
+// closes the routing first, because it waits for the goroutines that
+// may still log
 func (tr *testRouting) close() {
-	tr.log.Close()
 	tr.routing.Close()
+	tr.log.Close()
 }
 
 func TestKeepsReceivingInitialRouteDataUntilSucceeds(t *testing.T) {
@@ -125,6 +134,140 @@ func TestKeepsReceivingInitialRouteDataUntilSucceeds(t *testing.T) {
 	}
 }
 
+type failingDataClient struct {
+	mx       sync.Mutex
+	failures int
+	attempts []time.Time
+	routes   []*eskip.Route
+}
+
+func (dc *failingDataClient) LoadAll() ([]*eskip.Route, error) {
+	dc.mx.Lock()
+	defer dc.mx.Unlock()
+
+	dc.attempts = append(dc.attempts, time.Now())
+	if dc.failures > 0 {
+		dc.failures--
+		return nil, errors.New("failed to get routes")
+	}
+
+	return dc.routes, nil
+}
+
+func (dc *failingDataClient) LoadUpdate() ([]*eskip.Route, []string, error) {
+	return nil, nil, nil
+}
+
+func (dc *failingDataClient) delays() []time.Duration {
+	dc.mx.Lock()
+	defer dc.mx.Unlock()
+
+	var d []time.Duration
+	for i := 1; i < len(dc.attempts); i++ {
+		d = append(d, dc.attempts[i].Sub(dc.attempts[i-1]))
+	}
+
+	return d
+}
+
+type countingDataClient struct {
+	mx      sync.Mutex
+	updates int
+	routes  []*eskip.Route
+}
+
+type intervalDataClient struct {
+	*countingDataClient
+	interval time.Duration
+}
+
+func (dc *countingDataClient) LoadAll() ([]*eskip.Route, error) {
+	return dc.routes, nil
+}
+
+func (dc *countingDataClient) LoadUpdate() ([]*eskip.Route, []string, error) {
+	dc.mx.Lock()
+	defer dc.mx.Unlock()
+	dc.updates++
+	return nil, nil, nil
+}
+
+func (dc *countingDataClient) count() int {
+	dc.mx.Lock()
+	defer dc.mx.Unlock()
+	return dc.updates
+}
+
+func (dc *intervalDataClient) PollInterval() time.Duration { return dc.interval }
+
+func TestPollsClientsWithTheirOwnInterval(t *testing.T) {
+	fast := &intervalDataClient{
+		&countingDataClient{routes: []*eskip.Route{{Id: "route1", Path: "/some-path", Backend: "https://www.example.org"}}},
+		6 * time.Millisecond}
+	slow := &countingDataClient{routes: []*eskip.Route{{Id: "route2", Path: "/some-other", Backend: "https://other.example.org"}}}
+
+	tl := loggingtest.New()
+	rt := routing.New(routing.Options{
+		FilterRegistry: builtin.MakeRegistry(),
+		DataClients:    []routing.DataClient{fast, slow},
+		PollTimeout:    60 * time.Millisecond,
+		Log:            tl})
+	tr := &testRouting{tl, rt}
+	defer tr.close()
+
+	if err := tr.waitForNRouteSettingsTO(2, time.Second); err != nil {
+		t.Error(err)
+		return
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	fc, sc := fast.count(), slow.count()
+	if sc == 0 || sc > 6 || fc < 3*sc {
+		t.Error("failed to poll the clients with their own interval", fc, sc)
+	}
+}
+
+func TestBacksOffFailedInitialRequests(t *testing.T) {
+	dc := &failingDataClient{
+		failures: 4,
+		routes:   []*eskip.Route{{Id: "route1", Path: "/some-path", Backend: "https://www.example.org"}}}
+
+	tl := loggingtest.New()
+	rt := routing.New(routing.Options{
+		FilterRegistry:     builtin.MakeRegistry(),
+		DataClients:        []routing.DataClient{dc},
+		PollTimeout:        time.Second,
+		InitialPollBackoff: 10 * time.Millisecond,
+		Log:                tl})
+	tr := &testRouting{tl, rt}
+	defer tr.close()
+
+	if err := tr.waitForNRouteSettingsTO(1, time.Second); err != nil {
+		t.Error(err)
+		return
+	}
+
+	d := dc.delays()
+	if len(d) != 4 {
+		t.Error("invalid number of attempts", len(d)+1)
+		return
+	}
+
+	for i := 1; i < len(d); i++ {
+		if d[i] < d[i-1]*3/4 {
+			t.Error("failed to increase the delay", d)
+		}
+	}
+
+	if d[3] < 2*d[0] || d[3] >= time.Second {
+		t.Error("failed to back off exponentially", d)
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.com/some-path"); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestReceivesInitial(t *testing.T) {
 	dc := testdataclient.New([]*eskip.Route{{Id: "route1", Path: "/some-path", Backend: "https://www.example.org"}})
 	tr, err := newTestRouting(dc)
@@ -259,6 +402,163 @@ func TestMergesMultipleSources(t *testing.T) {
 	}
 }
 
+type namedDataClient struct {
+	*testdataclient.Client
+	name string
+}
+
+func (dc namedDataClient) Id() string { return dc.name }
+
+func TestRouteSource(t *testing.T) {
+	dc1 := testdataclient.New([]*eskip.Route{{Id: "route1", Path: "/some-path", Backend: "https://www.example.org"}})
+	dc2 := namedDataClient{
+		testdataclient.New([]*eskip.Route{{Id: "route2", Path: "/some-other", Backend: "https://other.example.org"}}),
+		"named"}
+
+	l := loggingtest.New()
+	tr := &testRouting{l, routing.New(routing.Options{
+		DataClients:          []routing.DataClient{dc1, dc2},
+		PollTimeout:          pollTimeout,
+		ReuseUnchangedRoutes: true,
+		Log:                  l})}
+	defer tr.close()
+
+	select {
+	case <-tr.routing.Ready():
+	case <-time.After(12 * pollTimeout):
+		t.Fatal("timeout")
+	}
+
+	checkSource := func(url, source string) {
+		if r, err := tr.checkGetRequest(url); err != nil {
+			t.Error(err)
+		} else if r.Source() != source {
+			t.Error("invalid source", r.Id, r.Source(), source)
+		}
+	}
+
+	checkSource("https://www.example.org/some-path", "*testdataclient.Client/0")
+	checkSource("https://www.example.org/some-other", "named")
+
+	// the same definition moved to the other data client, while the
+	// first one wins until it is deleted
+	dc2.Update([]*eskip.Route{{Id: "route1", Path: "/some-path", Backend: "https://www.example.org"}}, nil)
+	dc1.Update(nil, []string{"route1"})
+
+	timeout := time.After(12 * pollTimeout)
+	for {
+		req, err := http.NewRequest("GET", "https://www.example.org/some-path", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if r, _ := tr.routing.Route(req); r != nil && r.Source() == "named" {
+			return
+		}
+
+		select {
+		case <-timeout:
+			t.Fatal("failed to update the source of the moved route")
+		case <-time.After(pollTimeout / 4):
+		}
+	}
+}
+
+func TestDuplicateRouteIds(t *testing.T) {
+	for _, ti := range []struct {
+		msg     string
+		policy  routing.DuplicateIdPolicy
+		backend string
+	}{
+		{"default, first wins", routing.DuplicateIdFirstWins, "https://first.example.org"},
+		{"last wins", routing.DuplicateIdLastWins, "https://last.example.org"},
+		{"error", routing.DuplicateIdError, ""},
+	} {
+		func() {
+			dc1 := testdataclient.New([]*eskip.Route{
+				{Id: "route1", Path: "/some-path", Backend: "https://first.example.org"},
+				{Id: "route2", Path: "/other-path", Backend: "https://other.example.org"}})
+			dc2 := testdataclient.New([]*eskip.Route{{Id: "route1", Path: "/some-path", Backend: "https://last.example.org"}})
+
+			tl := loggingtest.New()
+			rt := routing.New(routing.Options{
+				FilterRegistry: builtin.MakeRegistry(),
+				DataClients:    []routing.DataClient{dc1, dc2},
+				PollTimeout:    pollTimeout,
+				OnDuplicateId:  ti.policy,
+				Log:            tl})
+			tr := &testRouting{tl, rt}
+			defer tr.close()
+
+			select {
+			case <-rt.Ready():
+			case <-time.After(12 * pollTimeout):
+				t.Error(ti.msg, "timeout")
+				return
+			}
+
+			if _, err := tr.checkGetRequest("https://www.example.com/other-path"); err != nil {
+				t.Error(ti.msg, "failed to keep the other route", err)
+			}
+
+			r, _ := tr.checkGetRequest("https://www.example.com/some-path")
+			if ti.backend == "" && r != nil {
+				t.Error(ti.msg, "failed to drop the duplicate route", r.Backend)
+			} else if ti.backend != "" && (r == nil || r.Backend != ti.backend) {
+				t.Error(ti.msg, "failed to resolve the duplicate route", r)
+			}
+
+			invalid := rt.InvalidRoutes()
+			if len(invalid) != 1 || invalid[0].Id != "route1" || invalid[0].Err != routing.ErrDuplicateRouteId {
+				t.Error(ti.msg, "failed to report the collision", invalid)
+			}
+		}()
+	}
+}
+
+func TestDuplicateIdOverride(t *testing.T) {
+	base := testdataclient.New([]*eskip.Route{
+		{Id: "route1", Path: "/some-path", Backend: "https://base.example.org"},
+		{Id: "route2", Path: "/other-path", Backend: "https://other.example.org"}})
+	override := testdataclient.New([]*eskip.Route{{Id: "route1", Path: "/some-path", Backend: "https://override.example.org"}})
+
+	tl := loggingtest.New()
+	rt := routing.New(routing.Options{
+		DataClients:   []routing.DataClient{base, override},
+		PollTimeout:   pollTimeout,
+		OnDuplicateId: routing.DuplicateIdOverride,
+		Log:           tl})
+	tr := &testRouting{tl, rt}
+	defer tr.close()
+
+	if err := tr.waitForNRouteSettings(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if r, err := tr.checkGetRequest("https://www.example.com/some-path"); err != nil || r.Backend != "https://override.example.org" {
+		t.Error("failed to override the route", err)
+	}
+
+	if r, err := tr.checkGetRequest("https://www.example.com/other-path"); err != nil || r.Backend != "https://other.example.org" {
+		t.Error("failed to keep the base route", err)
+	}
+
+	if invalid := rt.InvalidRoutes(); len(invalid) != 0 {
+		t.Error("unexpected invalid routes", invalid)
+	}
+
+	// deleting the override restores the base route
+	tl.Reset()
+	override.Update(nil, []string{"route1"})
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Fatal(err)
+	}
+
+	if r, err := tr.checkGetRequest("https://www.example.com/some-path"); err != nil || r.Backend != "https://base.example.org" {
+		t.Error("failed to restore the base route", err)
+	}
+}
+
 func TestMergesUpdatesFromMultipleSources(t *testing.T) {
 	dc1 := testdataclient.New([]*eskip.Route{{Id: "route1", Path: "/some-path", Backend: "https://www.example.org"}})
 	dc2 := testdataclient.New([]*eskip.Route{{Id: "route2", Path: "/some-other", Backend: "https://other.example.org"}})
@@ -307,116 +607,157 @@ func TestMergesUpdatesFromMultipleSources(t *testing.T) {
 	}
 }
 
-func TestIgnoresInvalidBackend(t *testing.T) {
-	dc := testdataclient.New([]*eskip.Route{{Id: "route1", Path: "/some-path", Backend: "invalid backend"}})
-	tr, err := newTestRouting(dc)
-	if err != nil {
-		t.Error(err)
-	}
+func TestBatchWindow(t *testing.T) {
+	const batchWindow = 20 * pollTimeout
+
+	dc1 := testdataclient.New([]*eskip.Route{{Id: "route1", Path: "/some-path", Backend: "https://www.example.org"}})
+	dc2 := testdataclient.New([]*eskip.Route{{Id: "route2", Path: "/some-other", Backend: "https://other.example.org"}})
+	dc3 := testdataclient.New([]*eskip.Route{{Id: "route3", Path: "/another", Backend: "https://another.example.org"}})
 
+	tl := loggingtest.New()
+	rt := routing.New(routing.Options{
+		FilterRegistry: builtin.MakeRegistry(),
+		DataClients:    []routing.DataClient{dc1, dc2, dc3},
+		PollTimeout:    pollTimeout,
+		BatchWindow:    batchWindow,
+		Log:            tl})
+	tr := &testRouting{tl, rt}
 	defer tr.close()
 
-	if err = tr.log.WaitFor("invalid backend", time.Second); err != nil {
+	if err := tr.waitForNRouteSettingsTO(1, 3*batchWindow); err != nil {
 		t.Error(err)
+		return
 	}
-}
 
-func TestProcessesFilterDefinitions(t *testing.T) {
-	fr := make(filters.Registry)
-	fs := &filtertest.Filter{FilterName: "filter1"}
-	fr.Register(fs)
+	// the initial loads may still be split into multiple batches
+	timeout := time.After(3 * batchWindow)
+	for {
+		_, err1 := tr.checkGetRequest("https://www.example.com/some-path")
+		_, err2 := tr.checkGetRequest("https://www.example.com/some-other")
+		_, err3 := tr.checkGetRequest("https://www.example.com/another")
+		if err1 == nil && err2 == nil && err3 == nil {
+			break
+		}
 
-	dc := testdataclient.New([]*eskip.Route{{
-		Id:      "route1",
-		Path:    "/some-path",
-		Filters: []*eskip.Filter{{Name: "filter1", Args: []interface{}{3.14, "Hello, world!"}}},
-		Backend: "https://www.example.org"}})
+		select {
+		case <-timeout:
+			t.Error("failed to load the initial routes")
+			return
+		case <-time.After(pollTimeout):
+		}
+	}
 
-	tr, err := newTestRoutingWithFilters(fr, dc)
-	if err != nil {
+	tr.log.Reset()
+
+	dc1.Update([]*eskip.Route{{Id: "route1", Path: "/some-changed-path", Backend: "https://www.example.org"}}, nil)
+	dc2.Update([]*eskip.Route{{Id: "route2", Path: "/some-other-changed", Backend: "https://www.example.org"}}, nil)
+	dc3.Update(nil, []string{"route3"})
+
+	if err := tr.waitForNRouteSettingsTO(1, 3*batchWindow); err != nil {
 		t.Error(err)
 		return
 	}
 
-	defer tr.close()
+	if _, err := tr.checkGetRequest("https://www.example.com/some-changed-path"); err != nil {
+		t.Error(err)
+	}
 
-	if r, err := tr.checkGetRequest("https://www.example.com/some-path"); r == nil || err != nil {
+	if _, err := tr.checkGetRequest("https://www.example.com/some-other-changed"); err != nil {
 		t.Error(err)
-	} else {
-		if len(r.Filters) != 1 {
-			t.Error("failed to process filters")
-			return
-		}
+	}
 
-		if f, ok := r.Filters[0].Filter.(*filtertest.Filter); !ok ||
-			f.FilterName != fs.Name() || len(f.Args) != 2 ||
-			f.Args[0] != float64(3.14) || f.Args[1] != "Hello, world!" {
-			t.Error("failed to process filters")
-		}
+	if _, err := tr.checkGetRequest("https://www.example.com/another"); err == nil {
+		t.Error("failed to delete route")
+	}
+
+	if err := tr.waitForNRouteSettingsTO(2, 2*batchWindow); err != loggingtest.ErrWaitTimeout {
+		t.Error("failed to apply the updates in a single batch", err)
 	}
 }
 
-func TestProcessesPredicates(t *testing.T) {
-	dc, err := testdataclient.NewDoc(`
-        route1: CustomPredicate("custom1") -> "https://route1.example.org";
-        route2: CustomPredicate("custom2") -> "https://route2.example.org";
-        catchAll: * -> "https://route.example.org"`)
-	if err != nil {
-		t.Error(err)
-		return
+func TestReady(t *testing.T) {
+	dc1 := testdataclient.New([]*eskip.Route{{Id: "route1", Path: "/some-path", Backend: "https://www.example.org"}})
+	dc2 := testdataclient.New([]*eskip.Route{{Id: "route2", Path: "/some-other", Backend: "https://other.example.org"}})
+	for i := 0; i < 3; i++ {
+		dc2.FailNext()
 	}
 
-	cps := []routing.PredicateSpec{&predicate{}, &predicate{}}
+	tl := loggingtest.New()
+	rt := routing.New(routing.Options{
+		FilterRegistry: builtin.MakeRegistry(),
+		DataClients:    []routing.DataClient{dc1, dc2},
+		PollTimeout:    pollTimeout,
+		Log:            tl})
+	tr := &testRouting{tl, rt}
+	defer tr.close()
 
-	tr, err := newTestRoutingWithPredicates(cps, dc)
-	if err != nil {
+	if err := tr.waitForRouteSetting(); err != nil {
 		t.Error(err)
 		return
 	}
 
-	defer tr.close()
-
-	req, err := http.NewRequest("GET", "https://www.example.com", nil)
-	if err != nil {
+	if _, err := tr.checkGetRequest("https://www.example.org/some-path"); err != nil {
 		t.Error(err)
 		return
 	}
 
-	req.Header.Set(predicateHeader, "custom1")
-	if r, err := tr.checkRequest(req); r == nil || err != nil {
-		t.Error(err)
-	} else {
-		if r.Backend != "https://route1.example.org" {
-			t.Error("custom predicate matching failed, route1")
+	select {
+	case <-rt.Ready():
+		if _, err := tr.checkGetRequest("https://www.example.org/some-other"); err != nil {
+			t.Error("ready before the initial routes of all the data clients were loaded")
 			return
 		}
+	default:
 	}
 
-	req.Header.Del(predicateHeader)
-	if r, err := tr.checkRequest(req); r == nil || err != nil {
+	select {
+	case <-rt.Ready():
+	case <-time.After(30 * pollTimeout):
+		t.Error("timeout waiting for ready")
+		return
+	}
+
+	if err := tl.WaitForN("error while receiveing initial data", 3, pollTimeout); err != nil {
+		t.Error("ready before the data client succeeded", err)
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.org/some-other"); err != nil {
 		t.Error(err)
-	} else {
-		if r.Backend != "https://route.example.org" {
-			t.Error("custom predicate matching failed, catch-all")
-			return
-		}
 	}
 }
 
-// TestNonMatchedStaticRoute for bug #116: non-matched static route supress wild-carded route
-func TestNonMatchedStaticRoute(t *testing.T) {
-	dc, err := testdataclient.NewDoc(`
-		a: Path("/foo/*_") -> "https://foo.org";
-		b: Path("/foo/bar") && CustomPredicate("custom1") -> "https://bar.org";
-		z: * -> "https://catch.all"`)
+func TestReadyWithoutDataClients(t *testing.T) {
+	rt := routing.New(routing.Options{})
+	defer rt.Close()
+
+	select {
+	case <-rt.Ready():
+	default:
+		t.Error("failed to be ready without data clients")
+	}
+}
+
+func TestIgnoresInvalidBackend(t *testing.T) {
+	dc := testdataclient.New([]*eskip.Route{{Id: "route1", Path: "/some-path", Backend: "invalid backend"}})
+	tr, err := newTestRouting(dc)
 	if err != nil {
 		t.Error(err)
-		return
 	}
 
-	cps := []routing.PredicateSpec{&predicate{}}
+	defer tr.close()
 
-	tr, err := newTestRoutingWithPredicates(cps, dc)
+	if err = tr.log.WaitFor("invalid backend", time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReportsInvalidRoutes(t *testing.T) {
+	dc := testdataclient.New([]*eskip.Route{
+		{Id: "valid", Path: "/some-path", Backend: "https://www.example.org"},
+		{Id: "invalidBackend", Path: "/some-other", Backend: "invalid backend"},
+		{Id: "unknownFilter", Filters: []*eskip.Filter{{Name: "unknownFilter"}}, Backend: "https://www.example.org"},
+		{Id: "invalidRegexp", HostRegexps: []string{"["}, Backend: "https://www.example.org"}})
+	tr, err := newTestRouting(dc)
 	if err != nil {
 		t.Error(err)
 		return
@@ -424,17 +765,2468 @@ func TestNonMatchedStaticRoute(t *testing.T) {
 
 	defer tr.close()
 
-	req, err := http.NewRequest("GET", "https://www.example.com/foo/bar", nil)
-	if err != nil {
-		t.Error(err)
+	invalid := tr.routing.InvalidRoutes()
+	if len(invalid) != 3 {
+		t.Error("failed to report invalid routes", invalid)
 		return
 	}
 
-	if r, err := tr.checkRequest(req); r == nil || err != nil {
-		t.Error(err)
-	} else {
-		if r.Backend != "https://foo.org" {
-			t.Error("non-matched static route supress wild-carded route")
+	ids := make(map[string]bool)
+	for _, ri := range invalid {
+		if ri.Err == nil {
+			t.Error("missing error", ri.Id)
 		}
+
+		ids[ri.Id] = true
+	}
+
+	if !ids["invalidBackend"] || !ids["unknownFilter"] || !ids["invalidRegexp"] {
+		t.Error("failed to report invalid routes", invalid)
+	}
+
+	tr.log.Reset()
+	dc.Update([]*eskip.Route{{Id: "invalidBackend", Path: "/some-other", Backend: "https://www.example.org"}},
+		[]string{"unknownFilter"})
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	invalid = tr.routing.InvalidRoutes()
+	if len(invalid) != 1 || invalid[0].Id != "invalidRegexp" {
+		t.Error("failed to update invalid routes", invalid)
+	}
+}
+
+type panickingPredicate struct{}
+
+type panickingFilter struct{ filtertest.Filter }
+
+func (p panickingPredicate) Name() string { return "Panicking" }
+
+func (p panickingPredicate) Create([]interface{}) (routing.Predicate, error) {
+	panic("failed to create predicate")
+}
+
+func (f *panickingFilter) CreateFilter([]interface{}) (filters.Filter, error) {
+	panic("failed to create filter")
+}
+
+func TestDropsRoutesWithPanickingExtensions(t *testing.T) {
+	fr := builtin.MakeRegistry()
+	fr.Register(&panickingFilter{filtertest.Filter{FilterName: "panicking"}})
+
+	dc := testdataclient.New([]*eskip.Route{
+		{Id: "valid", Path: "/some-path", Backend: "https://www.example.org"},
+		{Id: "panickingPredicate", Path: "/other-path", Predicates: []*eskip.Predicate{{Name: "Panicking"}}, Backend: "https://www.example.org"},
+		{Id: "panickingFilter", Path: "/another-path", Filters: []*eskip.Filter{{Name: "panicking"}}, Backend: "https://www.example.org"}})
+
+	tr, err := newTestRoutingWithFiltersPredicates(fr, []routing.PredicateSpec{panickingPredicate{}}, dc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	if _, err := tr.checkGetRequest("https://www.example.com/some-path"); err != nil {
+		t.Error(err)
+	}
+
+	if err := tr.log.WaitFor("panickingPredicate: predicate 'Panicking' panicked", pollTimeout); err != nil {
+		t.Error("failed to log the panic", err)
+	}
+
+	invalid := tr.routing.InvalidRoutes()
+	if len(invalid) != 2 {
+		t.Error("failed to report the routes", invalid)
+		return
+	}
+
+	ids := make(map[string]bool)
+	for _, ri := range invalid {
+		ids[ri.Id] = true
+	}
+
+	if !ids["panickingPredicate"] || !ids["panickingFilter"] {
+		t.Error("failed to report the routes", invalid)
+	}
+}
+
+type nilPredicate struct{}
+
+type nilFilter struct{ filtertest.Filter }
+
+func (p nilPredicate) Name() string { return "Nil" }
+
+func (p nilPredicate) Create([]interface{}) (routing.Predicate, error) { return nil, nil }
+
+func (f *nilFilter) CreateFilter([]interface{}) (filters.Filter, error) { return nil, nil }
+
+func TestDropsRoutesWithNilExtensions(t *testing.T) {
+	fr := builtin.MakeRegistry()
+	fr.Register(&nilFilter{filtertest.Filter{FilterName: "nil"}})
+
+	dc := testdataclient.New([]*eskip.Route{
+		{Id: "valid", Path: "/some-path", Backend: "https://www.example.org"},
+		{Id: "nilPredicate", Path: "/other-path", Predicates: []*eskip.Predicate{{Name: "Nil"}}, Backend: "https://www.example.org"},
+		{Id: "nilFilter", Path: "/another-path", Filters: []*eskip.Filter{{Name: "nil"}}, Backend: "https://www.example.org"}})
+
+	tr, err := newTestRoutingWithFiltersPredicates(fr, []routing.PredicateSpec{nilPredicate{}}, dc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer tr.close()
+
+	if _, err := tr.checkGetRequest("https://www.example.com/some-path"); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.com/other-path"); err == nil {
+		t.Error("failed to drop the route with the nil predicate")
+	}
+
+	if err := tr.log.WaitFor("nilPredicate: predicate 'Nil' failed: no instance created", pollTimeout); err != nil {
+		t.Error("failed to log the error", err)
+	}
+
+	invalid := tr.routing.InvalidRoutes()
+	ids := make(map[string]bool)
+	for _, ri := range invalid {
+		ids[ri.Id] = true
+	}
+
+	if len(invalid) != 2 || !ids["nilPredicate"] || !ids["nilFilter"] {
+		t.Error("failed to report the routes", invalid)
+	}
+}
+
+func TestProcessesFilterDefinitions(t *testing.T) {
+	fr := make(filters.Registry)
+	fs := &filtertest.Filter{FilterName: "filter1"}
+	fr.Register(fs)
+
+	dc := testdataclient.New([]*eskip.Route{{
+		Id:      "route1",
+		Path:    "/some-path",
+		Filters: []*eskip.Filter{{Name: "filter1", Args: []interface{}{3.14, "Hello, world!"}}},
+		Backend: "https://www.example.org"}})
+
+	tr, err := newTestRoutingWithFilters(fr, dc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	if r, err := tr.checkGetRequest("https://www.example.com/some-path"); r == nil || err != nil {
+		t.Error(err)
+	} else {
+		if len(r.Filters) != 1 {
+			t.Error("failed to process filters")
+			return
+		}
+
+		if f, ok := r.Filters[0].Filter.(*filtertest.Filter); !ok ||
+			f.FilterName != fs.Name() || len(f.Args) != 2 ||
+			f.Args[0] != float64(3.14) || f.Args[1] != "Hello, world!" {
+			t.Error("failed to process filters")
+		}
+	}
+}
+
+func TestProcessesPredicates(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+        route1: CustomPredicate("custom1") -> "https://route1.example.org";
+        route2: CustomPredicate("custom2") -> "https://route2.example.org";
+        catchAll: * -> "https://route.example.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	cps := []routing.PredicateSpec{&predicate{}, &predicate{}}
+
+	tr, err := newTestRoutingWithPredicates(cps, dc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	req, err := http.NewRequest("GET", "https://www.example.com", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	req.Header.Set(predicateHeader, "custom1")
+	if r, err := tr.checkRequest(req); r == nil || err != nil {
+		t.Error(err)
+	} else {
+		if r.Backend != "https://route1.example.org" {
+			t.Error("custom predicate matching failed, route1")
+			return
+		}
+	}
+
+	req.Header.Del(predicateHeader)
+	if r, err := tr.checkRequest(req); r == nil || err != nil {
+		t.Error(err)
+	} else {
+		if r.Backend != "https://route.example.org" {
+			t.Error("custom predicate matching failed, catch-all")
+			return
+		}
+	}
+}
+
+func TestUpdateFilterRegistryAndPredicates(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+		route1: Path("/filter") -> customFilter() -> "https://filter.example.org";
+		route2: Path("/predicate") && CustomPredicate("custom1") -> "https://predicate.example.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tr, err := newTestRoutingWithFiltersPredicates(builtin.MakeRegistry(), nil, dc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	if invalid := tr.routing.InvalidRoutes(); len(invalid) != 2 {
+		t.Error("failed to reject the routes", invalid)
+		return
+	}
+
+	if r, _ := tr.checkGetRequest("https://www.example.org/filter"); r != nil {
+		t.Error("unexpected route", r.Id)
+		return
+	}
+
+	fr := builtin.MakeRegistry()
+	fr.Register(&filtertest.Filter{FilterName: "customFilter"})
+
+	tr.log.Reset()
+	tr.routing.UpdateFilterRegistry(fr)
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if r, err := tr.checkGetRequest("https://www.example.org/filter"); err != nil || r.Id != "route1" {
+		t.Error("failed to activate the route with the new filter", err)
+	}
+
+	if invalid := tr.routing.InvalidRoutes(); len(invalid) != 1 || invalid[0].Id != "route2" {
+		t.Error("failed to update the invalid routes", invalid)
+	}
+
+	tr.log.Reset()
+	tr.routing.UpdatePredicates([]routing.PredicateSpec{&predicate{}})
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	req, err := http.NewRequest("GET", "https://www.example.org/predicate", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	req.Header.Set(predicateHeader, "custom1")
+	if r, err := tr.checkRequest(req); err != nil || r.Id != "route2" {
+		t.Error("failed to activate the route with the new predicate", err)
+	}
+
+	if r, err := tr.checkGetRequest("https://www.example.org/filter"); err != nil || r.Id != "route1" {
+		t.Error("failed to keep the filter registry", err)
+	}
+
+	if invalid := tr.routing.InvalidRoutes(); len(invalid) != 0 {
+		t.Error("unexpected invalid routes", invalid)
+	}
+}
+
+func TestRouteFor(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+        route1: CustomPredicate("custom1") -> "https://route1.example.org";
+        route2: CustomPredicate("custom2") -> "https://route2.example.org";
+        route3: Host(/^api[.]example[.]org$/) && Method("POST") && Path("/items") -> "https://route3.example.org";
+        route4: Path("/items") && QueryParam("q", "^foo$") -> "https://route4.example.org";
+        items: Path("/items") -> "https://items.example.org";
+        catchAll: * -> "https://route.example.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tr, err := newTestRoutingWithPredicates([]routing.PredicateSpec{&predicate{}, query.New()}, dc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	for _, ti := range []struct {
+		msg     string
+		input   routing.RouteInput
+		routeId string
+	}{{
+		"custom predicate",
+		routing.RouteInput{Headers: map[string]string{predicateHeader: "custom1"}},
+		"route1",
+	}, {
+		"custom predicate, other value",
+		routing.RouteInput{Headers: map[string]string{predicateHeader: "custom2"}},
+		"route2",
+	}, {
+		"catch-all",
+		routing.RouteInput{},
+		"catchAll",
+	}, {
+		"host and method",
+		routing.RouteInput{Method: "POST", Host: "api.example.org", Path: "/items"},
+		"route3",
+	}, {
+		"default method",
+		routing.RouteInput{Host: "api.example.org", Path: "/items"},
+		"items",
+	}, {
+		"query",
+		routing.RouteInput{Path: "/items", Query: map[string]string{"q": "foo"}},
+		"route4",
+	}} {
+		r, err := tr.routing.RouteFor(ti.input)
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		if r == nil || r.Id != ti.routeId {
+			t.Error(ti.msg, "failed to match the expected route", r)
+		}
+	}
+
+	if _, err := tr.routing.RouteFor(routing.RouteInput{Path: "items"}); err != routing.ErrInvalidRouteInput {
+		t.Error("failed to fail with a relative path", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cps := []routing.PredicateSpec{&predicate{}}
+	for _, ti := range []struct {
+		msg string
+		doc string
+		ids []string
+	}{{
+		"valid",
+		`route1: CustomPredicate("custom1") -> setRequestHeader("X-Foo", "bar") -> "https://www.example.org";
+		route2: Path("/foo") -> <shunt>`,
+		nil,
+	}, {
+		"unknown filter",
+		`route1: * -> unknownFilter() -> "https://www.example.org";
+		route2: Path("/foo") -> <shunt>`,
+		[]string{"route1"},
+	}, {
+		"bad predicate args",
+		`route1: CustomPredicate("custom1", "custom2") -> "https://www.example.org"`,
+		[]string{"route1"},
+	}, {
+		"unknown predicate",
+		`route1: UnknownPredicate() -> "https://www.example.org"`,
+		[]string{"route1"},
+	}, {
+		"all errors",
+		`route1: * -> unknownFilter() -> "https://www.example.org";
+		route2: Host(/[/) -> "https://www.example.org";
+		route3: * -> "invalid backend";
+		route4: Path("/foo") -> <shunt>`,
+		[]string{"route1", "route3", "route2"},
+	}} {
+		errs := routing.Validate(ti.doc, builtin.MakeRegistry(), cps)
+		if len(errs) != len(ti.ids) {
+			t.Error(ti.msg, "unexpected errors", errs)
+			continue
+		}
+
+		for i, err := range errs {
+			if re, ok := err.(routing.RouteError); !ok || re.Id != ti.ids[i] || re.Err == nil {
+				t.Error(ti.msg, "unexpected error", err)
+			}
+		}
+	}
+
+	if errs := routing.Validate(`route1: * ->`, builtin.MakeRegistry(), nil); len(errs) != 1 {
+		t.Error("failed to report the parse error", errs)
+	}
+}
+
+func TestStaticMatcher(t *testing.T) {
+	defs, err := eskip.Parse(`
+		route1: CustomPredicate("custom1") -> "https://route1.example.org";
+		wildcard: Path("/users/*_") -> "https://wildcard.org";
+		exact: Path("/users/admin") -> "https://exact.org";
+		custom: Path("/users/:id") && CustomPredicate("custom1") -> "https://custom.org";
+		regexp: PathRegexp("^/other/[0-9]+$") -> "https://other.org";
+		filters: Path("/filters") -> setRequestHeader("X-Foo", "bar") -> "https://filters.org";
+		catchAll: * -> "https://route.example.org"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, errs := routing.NewMatcher(defs, builtin.MakeRegistry(), []routing.PredicateSpec{&predicate{}})
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	if len(m.Routes()) != len(defs) {
+		t.Error("failed to create all routes", len(m.Routes()))
+	}
+
+	routes := m.Routes()
+	routes[0] = nil
+	if m.Routes()[0] == nil {
+		t.Error("the routes of the matcher were changed through the returned slice")
+	}
+
+	for _, ti := range []struct {
+		msg     string
+		path    string
+		custom  string
+		routeId string
+		params  map[string]string
+	}{
+		{"catch-all", "/", "", "catchAll", nil},
+		{"custom predicate", "/", "custom1", "route1", nil},
+		{"exact path", "/users/admin", "", "exact", nil},
+		{"wildcard path", "/users/42", "", "wildcard", map[string]string{"_": "/42"}},
+		{"path with custom predicate", "/users/42", "custom1", "custom", map[string]string{"id": "42"}},
+		{"path regexp", "/other/42", "", "regexp", nil},
+		{"path regexp not matching", "/other/foo", "", "catchAll", nil},
+	} {
+		req, err := http.NewRequest("GET", "https://www.example.org"+ti.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ti.custom != "" {
+			req.Header.Set(predicateHeader, ti.custom)
+		}
+
+		r, params := m.Match(req)
+		if r == nil || r.Id != ti.routeId {
+			t.Error(ti.msg, "failed to match the expected route", r)
+			continue
+		}
+
+		for k, v := range ti.params {
+			if params[k] != v {
+				t.Error(ti.msg, "invalid path parameter", k, params[k], v)
+			}
+		}
+	}
+
+	req, err := http.NewRequest("GET", "https://www.example.org/filters", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, _ := m.Match(req)
+	if r == nil || len(r.Filters) != 1 || r.Filters[0].Name != builtin.SetRequestHeaderName {
+		t.Error("failed to create the filters", r)
+	}
+}
+
+func TestStaticMatcherRejectsInvalidRoutes(t *testing.T) {
+	defs, err := eskip.Parse(`
+		route1: * -> unknownFilter() -> "https://www.example.org";
+		route2: Host(/[/) -> "https://www.example.org";
+		route3: UnknownPredicate() -> "https://www.example.org";
+		route4: Path("/foo") -> <shunt>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, errs := routing.NewMatcher(defs, builtin.MakeRegistry(), nil)
+
+	ids := []string{"route1", "route3", "route2"}
+	if len(errs) != len(ids) {
+		t.Fatal("unexpected errors", errs)
+	}
+
+	for i, err := range errs {
+		if re, ok := err.(routing.RouteError); !ok || re.Id != ids[i] {
+			t.Error("unexpected error", err)
+		}
+	}
+
+	if len(m.Routes()) != 1 || m.Routes()[0].Id != "route4" {
+		t.Error("failed to keep the valid route", m.Routes())
+	}
+
+	req, err := http.NewRequest("GET", "https://www.example.org/bar", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r, _ := m.Match(req); r != nil {
+		t.Error("unexpected match", r.Id)
+	}
+}
+
+func TestDefaultRoute(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+		route1: Path("/foo") -> "https://foo.example.org";
+		route2: Host(/^bar[.]example[.]org$/) -> "https://bar.example.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tl := loggingtest.New()
+	rt := routing.New(routing.Options{
+		DataClients:    []routing.DataClient{dc},
+		FilterRegistry: builtin.MakeRegistry(),
+		PollTimeout:    pollTimeout,
+		Log:            tl,
+		EnableRouteAll: true,
+		DefaultRoute: &eskip.Route{
+			Id:      "default",
+			Filters: []*eskip.Filter{{Name: "status", Args: []interface{}{float64(404)}}},
+			Backend: "https://default.example.org"}})
+	tr := &testRouting{tl, rt}
+	defer tr.close()
+
+	if err := tr.waitForNRouteSettings(1); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, ti := range []struct {
+		url     string
+		routeId string
+	}{
+		{"https://www.example.org/foo", "route1"},
+		{"https://bar.example.org/baz", "route2"},
+		{"https://www.example.org/baz", "default"},
+	} {
+		r, err := tr.checkGetRequest(ti.url)
+		if err != nil {
+			t.Error(ti.url, err)
+			continue
+		}
+
+		if r.Id != ti.routeId {
+			t.Error(ti.url, "unexpected route", r.Id)
+		}
+	}
+
+	r, _ := tr.checkGetRequest("https://www.example.org/baz")
+	if len(r.Filters) != 1 || r.Filters[0].Name != "status" {
+		t.Error("failed to create the filters of the default route")
+	}
+
+	req, err := http.NewRequest("GET", "https://www.example.org/foo", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	matched, _ := rt.Route(req)
+	if r, _ := rt.RouteExcluding(req, matched); r == nil || r.Id != "default" {
+		t.Error("failed to fall back to the default route", r)
+	}
+
+	if r, _ := rt.RouteExcluding(req, matched, r); r != nil {
+		t.Error("failed to exclude the default route", r)
+	}
+
+	if all, err := rt.RouteAll(req); err != nil || len(all) != 2 || all[1].Id != "default" {
+		t.Error("failed to list the default route last", all, err)
+	}
+}
+
+func TestUpdateMetrics(t *testing.T) {
+	const count = 20000
+
+	routes := make([]*eskip.Route, count)
+	for i := range routes {
+		routes[i] = &eskip.Route{
+			Id:      fmt.Sprintf("route%d", i),
+			Path:    fmt.Sprintf("/route%d", i),
+			Backend: "https://www.example.org"}
+	}
+
+	routes = append(routes, &eskip.Route{Id: "invalid", Backend: "invalid backend"})
+
+	dc := testdataclient.New(routes)
+	stats := make(chan routing.UpdateStats, 2)
+	tl := loggingtest.New()
+	rt := routing.New(routing.Options{
+		DataClients:    []routing.DataClient{dc},
+		FilterRegistry: builtin.MakeRegistry(),
+		PollTimeout:    pollTimeout,
+		Log:            tl,
+		UpdateMetrics:  func(s routing.UpdateStats) { stats <- s }})
+	tr := &testRouting{tl, rt}
+	defer tr.close()
+
+	receive := func() (routing.UpdateStats, bool) {
+		select {
+		case s := <-stats:
+			return s, true
+		case <-time.After(3 * time.Second):
+			t.Error("timeout waiting for the update metrics")
+			return routing.UpdateStats{}, false
+		}
+	}
+
+	s, ok := receive()
+	if !ok {
+		return
+	}
+
+	if s.Routes != count || s.Invalid != 1 || s.Added != count || s.Updated != 0 || s.Deleted != 0 || s.Duration <= 0 {
+		t.Error("invalid initial stats", s)
+	}
+
+	dc.Update([]*eskip.Route{
+		{Id: "route0", Path: "/route0", Backend: "https://other.example.org"},
+		{Id: "newRoute", Path: "/new-route", Backend: "https://www.example.org"},
+	}, []string{"route1", "invalid"})
+
+	s, ok = receive()
+	if !ok {
+		return
+	}
+
+	if s.Routes != count || s.Invalid != 0 || s.Added != 1 || s.Updated != 1 || s.Deleted != 1 {
+		t.Error("invalid update stats", s)
+	}
+}
+
+func TestLogsAppliedRoutesWithFields(t *testing.T) {
+	dc := testdataclient.New([]*eskip.Route{
+		{Id: "route1", Path: "/route1", Backend: "https://www.example.org"},
+		{Id: "route2", Path: "/route2", Backend: "https://www.example.org"},
+		{Id: "invalid", Backend: "invalid backend"}})
+
+	tr, err := newTestRouting(dc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	for _, exp := range []string{
+		"route settings applied",
+		"routes=2",
+		"invalid=1",
+		"added=2",
+		"updated=0",
+		"deleted=0",
+		"duration=",
+		"checksum=",
+	} {
+		if err := tr.log.WaitFor(exp, pollTimeout); err != nil {
+			t.Error("failed to log", exp)
+		}
+	}
+
+	tr.log.Reset()
+	dc.Update([]*eskip.Route{
+		{Id: "route1", Path: "/route1", Backend: "https://other.example.org"},
+		{Id: "route3", Path: "/route3", Backend: "https://www.example.org"},
+	}, []string{"route2", "invalid"})
+
+	if err := tr.log.WaitFor("route settings applied added=1", 12*pollTimeout); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, exp := range []string{"routes=2", "invalid=0", "updated=1", "deleted=1"} {
+		if err := tr.log.WaitFor(exp, pollTimeout); err != nil {
+			t.Error("failed to log", exp)
+		}
+	}
+}
+
+// TestNonMatchedStaticRoute for bug #116: non-matched static route supress wild-carded route
+func TestNonMatchedStaticRoute(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+		a: Path("/foo/*_") -> "https://foo.org";
+		b: Path("/foo/bar") && CustomPredicate("custom1") -> "https://bar.org";
+		z: * -> "https://catch.all"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	cps := []routing.PredicateSpec{&predicate{}}
+
+	tr, err := newTestRoutingWithPredicates(cps, dc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	req, err := http.NewRequest("GET", "https://www.example.com/foo/bar", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if r, err := tr.checkRequest(req); r == nil || err != nil {
+		t.Error(err)
+	} else {
+		if r.Backend != "https://foo.org" {
+			t.Error("non-matched static route supress wild-carded route")
+		}
+	}
+}
+
+func TestPathRegexpPriority(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+		wildcard: Path("/users/*_") -> "https://wildcard.org";
+		exact: Path("/users/admin") -> "https://exact.org";
+		custom: Path("/users/:id") && PathRegexp("^/users/[0-9]+$") && CustomPredicate("custom1") -> "https://custom.org";
+		regexp: PathRegexp("^/users/[0-9]+$") -> "https://regexp.org";
+		other: PathRegexp("^/other/[0-9]+$") -> "https://other.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tr, err := newTestRoutingWithPredicates([]routing.PredicateSpec{&predicate{}}, dc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	for _, ti := range []struct {
+		path    string
+		backend string
+	}{
+		{"/users/admin", "https://exact.org"},
+
+		// the non-matching route with the path regexp doesn't suppress
+		// the wildcard route, and the route with only a path regexp has
+		// lower priority than the wildcard route
+		{"/users/42", "https://wildcard.org"},
+
+		{"/other/42", "https://other.org"},
+	} {
+		r, err := tr.checkGetRequest("https://www.example.org" + ti.path)
+		if err != nil {
+			t.Error(ti.path, err)
+			continue
+		}
+
+		if r.Backend != ti.backend {
+			t.Error(ti.path, "unexpected route", r.Backend)
+		}
+	}
+
+	req, err := http.NewRequest("GET", "https://www.example.org/users/42", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	req.Header.Set(predicateHeader, "custom1")
+	if r, err := tr.checkRequest(req); err != nil || r.Backend != "https://custom.org" {
+		t.Error("failed to match the route with the path regexp", err)
+	}
+}
+
+func TestRouteOrdering(t *testing.T) {
+	const doc = `
+		specific: Path("/foo") && Header("X-Foo", "bar") -> "https://specific.org";
+		generic: Path("/foo") -> "https://generic.org";
+		rootSpecific: Method("GET") && Header("X-Foo", "bar") -> "https://root-specific.org";
+		rootGeneric: Header("X-Foo", "bar") -> "https://root-generic.org"`
+
+	byId := func(a, b *routing.Route) bool { return a.Id < b.Id }
+
+	for _, ti := range []struct {
+		msg      string
+		ordering func(a, b *routing.Route) bool
+		path     string
+		backend  string
+	}{
+		{"default, path", nil, "/foo", "https://specific.org"},
+		{"default, no path", nil, "/bar", "https://root-specific.org"},
+		{"custom, path", byId, "/foo", "https://generic.org"},
+		{"custom, no path", byId, "/bar", "https://root-generic.org"},
+	} {
+		func() {
+			dc, err := testdataclient.NewDoc(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l := loggingtest.New()
+			defer l.Close()
+
+			rt := routing.New(routing.Options{
+				FilterRegistry: builtin.MakeRegistry(),
+				DataClients:    []routing.DataClient{dc},
+				PollTimeout:    pollTimeout,
+				RouteOrdering:  ti.ordering,
+				Log:            l})
+			defer rt.Close()
+
+			if err := l.WaitFor("route settings applied", 12*pollTimeout); err != nil {
+				t.Fatal(ti.msg, err)
+			}
+
+			req, err := http.NewRequest("GET", "https://www.example.org"+ti.path, nil)
+			if err != nil {
+				t.Fatal(ti.msg, err)
+			}
+
+			req.Header.Set("X-Foo", "bar")
+			if r, _ := rt.Route(req); r == nil || r.Backend != ti.backend {
+				t.Error(ti.msg, "unexpected route", r)
+			}
+		}()
+	}
+}
+
+func TestReuseUnchangedRoutes(t *testing.T) {
+	for _, ti := range []struct {
+		msg            string
+		options        routing.Options
+		keepsUnchanged bool
+	}{{
+		msg:            "disabled",
+		options:        routing.Options{},
+		keepsUnchanged: false,
+	}, {
+		msg:            "enabled",
+		options:        routing.Options{ReuseUnchangedRoutes: true},
+		keepsUnchanged: true,
+	}, {
+		msg: "ignored with postprocessors",
+		options: routing.Options{
+			ReuseUnchangedRoutes: true,
+			PostProcessors:       []func([]*routing.Route) []*routing.Route{func(r []*routing.Route) []*routing.Route { return r }}},
+		keepsUnchanged: false,
+	}, {
+		msg: "enabled with preprocessors",
+		options: routing.Options{
+			ReuseUnchangedRoutes: true,
+			PreProcessors:        []func([]*eskip.Route) []*eskip.Route{func(r []*eskip.Route) []*eskip.Route { return r }}},
+		keepsUnchanged: true,
+	}} {
+		func() {
+			dc, err := testdataclient.NewDoc(`
+				route1: Path("/foo") -> setRequestHeader("X-Foo", "bar") -> "https://foo.example.org";
+				route2: Path("/bar") -> "https://bar.example.org";
+				route3: Path("/baz") -> "https://baz.example.org"`)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l := loggingtest.New()
+			defer l.Close()
+
+			o := ti.options
+			o.FilterRegistry = builtin.MakeRegistry()
+			o.DataClients = []routing.DataClient{dc}
+			o.PollTimeout = pollTimeout
+			o.Log = l
+			rt := routing.New(o)
+			defer rt.Close()
+
+			if err := l.WaitFor("route settings applied", 12*pollTimeout); err != nil {
+				t.Fatal(ti.msg, err)
+			}
+
+			route := func(path string) *routing.Route {
+				req, err := http.NewRequest("GET", "https://www.example.org"+path, nil)
+				if err != nil {
+					t.Fatal(ti.msg, err)
+				}
+
+				r, _ := rt.Route(req)
+				return r
+			}
+
+			before := route("/foo")
+			if before == nil {
+				t.Fatal(ti.msg, "failed to match route1")
+			}
+
+			// route1 is sent again as a new object with the same
+			// content, the same way as when a data client parses the
+			// definitions again
+			unchanged, err := eskip.Parse(`route1: Path("/foo") -> setRequestHeader("X-Foo", "bar") -> "https://foo.example.org"`)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l.Reset()
+			dc.Update([]*eskip.Route{unchanged[0], {Id: "route2", Path: "/bar", Backend: "https://bar2.example.org"}}, []string{"route3"})
+			if err := l.WaitFor("route settings applied", 12*pollTimeout); err != nil {
+				t.Fatal(ti.msg, err)
+			}
+
+			after := route("/foo")
+			if after == nil || after.Backend != "https://foo.example.org" || len(after.Filters) != 1 {
+				t.Error(ti.msg, "failed to keep route1", after)
+			} else if (after == before) != ti.keepsUnchanged {
+				t.Error(ti.msg, "unexpected reuse of the unchanged route", after == before)
+			}
+
+			if r := route("/bar"); r == nil || r.Backend != "https://bar2.example.org" {
+				t.Error(ti.msg, "failed to update route2", r)
+			}
+
+			if r := route("/baz"); r != nil {
+				t.Error(ti.msg, "failed to delete route3", r)
+			}
+		}()
+	}
+}
+
+type streamingClient struct {
+	t      *testing.T
+	chunks [][]*eskip.Route
+}
+
+func (c *streamingClient) LoadAll() ([]*eskip.Route, error) {
+	c.t.Error("unexpected call to LoadAll")
+	return nil, errors.New("not supported")
+}
+
+func (c *streamingClient) LoadUpdate() ([]*eskip.Route, []string, error) {
+	return nil, nil, nil
+}
+
+func (c *streamingClient) LoadAllChunks(receive func([]*eskip.Route) error) error {
+	for _, chunk := range c.chunks {
+		if err := receive(chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestStreamingDataClient(t *testing.T) {
+	var chunks [][]*eskip.Route
+	for i := 0; i < 3; i++ {
+		var chunk []*eskip.Route
+		for j := 0; j < 4; j++ {
+			id := fmt.Sprintf("route%d_%d", i, j)
+			chunk = append(chunk, &eskip.Route{Id: id, Path: "/" + id, Backend: "https://" + id + ".example.org"})
+		}
+
+		chunks = append(chunks, chunk)
+	}
+
+	tr, err := newTestRouting(&streamingClient{t: t, chunks: chunks})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer tr.close()
+
+	for _, chunk := range chunks {
+		for _, r := range chunk {
+			if rt, err := tr.checkGetRequest("https://www.example.org" + r.Path); err != nil {
+				t.Error(r.Id, err)
+			} else if rt.Backend != r.Backend {
+				t.Error(r.Id, "unexpected route", rt.Id)
+			}
+		}
+	}
+}
+
+func TestLoadAllChunks(t *testing.T) {
+	var routes []*eskip.Route
+	for i := 0; i < 5; i++ {
+		id := fmt.Sprintf("route%d", i)
+		routes = append(routes, &eskip.Route{Id: id, Path: "/" + id, Backend: "https://www.example.org"})
+	}
+
+	for _, ti := range []struct {
+		msg       string
+		chunkSize int
+		sizes     []int
+	}{
+		{"chunks", 2, []int{2, 2, 1}},
+		{"single chunk", 0, []int{5}},
+		{"larger chunk than the routes", 8, []int{5}},
+	} {
+		var (
+			sizes []int
+			ids   = make(map[string]bool)
+		)
+
+		err := routing.LoadAllChunks(testdataclient.New(routes), ti.chunkSize, func(chunk []*eskip.Route) error {
+			sizes = append(sizes, len(chunk))
+			for _, r := range chunk {
+				ids[r.Id] = true
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		if fmt.Sprint(sizes) != fmt.Sprint(ti.sizes) || len(ids) != len(routes) {
+			t.Error(ti.msg, "unexpected chunks", sizes, len(ids))
+		}
+	}
+
+	stop := errors.New("stop")
+	var calls int
+	err := routing.LoadAllChunks(testdataclient.New(routes), 2, func([]*eskip.Route) error {
+		calls++
+		return stop
+	})
+
+	if err != stop || calls != 1 {
+		t.Error("failed to stop loading", err, calls)
+	}
+
+	// the streaming clients deliver their own chunks
+	sc := &streamingClient{t: t, chunks: [][]*eskip.Route{routes[:1], routes[1:]}}
+	var sizes []int
+	if err := routing.LoadAllChunks(sc, 2, func(chunk []*eskip.Route) error {
+		sizes = append(sizes, len(chunk))
+		return nil
+	}); err != nil || fmt.Sprint(sizes) != fmt.Sprint([]int{1, 4}) {
+		t.Error("failed to load the chunks of the streaming client", err, sizes)
+	}
+}
+
+func TestCloneDoesNotAffectRoutingTable(t *testing.T) {
+	fr := make(filters.Registry)
+	fr.Register(&filtertest.Filter{FilterName: "filter1"})
+
+	dc, err := testdataclient.NewDoc(`
+		route1: Path("/some-path") && Header("X-Foo", "bar")
+		-> filter1("baz")
+		-> "https://www.example.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tr, err := newTestRoutingWithFilters(fr, dc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	req, err := http.NewRequest("GET", "https://www.example.com/some-path", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	req.Header.Set("X-Foo", "bar")
+	r, err := tr.checkRequest(req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	c := r.Clone()
+	c.Backend = "https://other.example.org"
+	c.Host = "other.example.org"
+	c.Headers["X-Foo"] = "qux"
+	c.Filters[0].Name = "filter2"
+	c.Filters = append(c.Filters, &routing.RouteFilter{Name: "filter3"})
+	c.Route.Filters[0].Args[0] = "qux"
+
+	r, err = tr.checkRequest(req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if r.Backend != "https://www.example.org" || r.Host != "www.example.org" {
+		t.Error("failed to preserve the backend", r.Backend, r.Host)
+	}
+
+	if r.Headers["X-Foo"] != "bar" {
+		t.Error("failed to preserve the header condition")
+	}
+
+	if len(r.Filters) != 1 || r.Filters[0].Name != "filter1" {
+		t.Error("failed to preserve the filters")
+	}
+
+	if r.Route.Filters[0].Args[0] != "baz" {
+		t.Error("failed to preserve the filter args")
+	}
+
+	if c.Filters[0].Filter != r.Filters[0].Filter {
+		t.Error("failed to share the filter instance")
+	}
+}
+
+func TestRouteAllDisabled(t *testing.T) {
+	dc := testdataclient.New([]*eskip.Route{{Id: "route1", Path: "/some-path", Backend: "https://www.example.org"}})
+	tr, err := newTestRouting(dc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	req, err := http.NewRequest("GET", "https://www.example.com/some-path", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := tr.routing.RouteAll(req); err != routing.ErrRouteAllDisabled {
+		t.Error("failed to fail")
+	}
+}
+
+func TestRouteAll(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+		a: Path("/foo/*_") -> "https://foo.org";
+		b: Path("/foo/bar") -> "https://bar.org";
+		z: * -> "https://catch.all"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tl := loggingtest.New()
+	rt := routing.New(routing.Options{
+		FilterRegistry: builtin.MakeRegistry(),
+		DataClients:    []routing.DataClient{dc},
+		PollTimeout:    pollTimeout,
+		Log:            tl,
+		EnableRouteAll: true})
+	tr := &testRouting{tl, rt}
+	defer tr.close()
+
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	req, err := http.NewRequest("GET", "https://www.example.com/foo/bar", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	rs, err := tr.routing.RouteAll(req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(rs) != 3 || rs[0].Id != "b" || rs[1].Id != "a" || rs[2].Id != "z" {
+		t.Error("failed to match all routes")
+	}
+}
+
+func TestHeaderRegexpMatchesAnyValue(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+		json: HeaderRegexp("Accept", "application/(json|xml)") -> "https://json.example.org";
+		catchAll: * -> "https://www.example.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tr, err := newTestRouting(dc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	req, err := http.NewRequest("GET", "https://www.example.com", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	req.Header.Add("Accept", "text/html")
+	req.Header.Add("Accept", "application/xml")
+	if r, err := tr.checkRequest(req); err != nil || r.Id != "json" {
+		t.Error("failed to match header regexp", err)
+	}
+
+	req.Header.Del("Accept")
+	req.Header.Add("Accept", "text/html")
+	if r, err := tr.checkRequest(req); err != nil || r.Id != "catchAll" {
+		t.Error("failed not to match header regexp", err)
+	}
+}
+
+func TestPreProcessors(t *testing.T) {
+	fr := make(filters.Registry)
+	fr.Register(&filtertest.Filter{FilterName: "filter1"})
+	fr.Register(&filtertest.Filter{FilterName: "injected"})
+
+	dc, err := testdataclient.NewDoc(`
+		route1: Path("/some-path") -> filter1() -> "https://www.example.org";
+		route2: Path("/other-path") -> "https://www.example.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	addFilter := func(routes []*eskip.Route) []*eskip.Route {
+		for _, r := range routes {
+			r.Filters = append(r.Filters, &eskip.Filter{Name: "injected"})
+		}
+
+		return routes
+	}
+
+	staging := func(routes []*eskip.Route) []*eskip.Route {
+		for _, r := range routes {
+			r.Backend = "https://staging.example.org"
+		}
+
+		return append(routes, &eskip.Route{Id: "invalid", Path: "/invalid", Filters: []*eskip.Filter{{Name: "unknown"}}})
+	}
+
+	tl := loggingtest.New()
+	rt := routing.New(routing.Options{
+		FilterRegistry: fr,
+		DataClients:    []routing.DataClient{dc},
+		PollTimeout:    pollTimeout,
+		PreProcessors:  []func([]*eskip.Route) []*eskip.Route{addFilter, staging},
+		Log:            tl})
+	tr := &testRouting{tl, rt}
+	defer tr.close()
+
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, ti := range []struct {
+		path    string
+		filters []string
+	}{
+		{"/some-path", []string{"filter1", "injected"}},
+		{"/other-path", []string{"injected"}},
+	} {
+		r, err := tr.checkGetRequest("https://www.example.com" + ti.path)
+		if err != nil {
+			t.Error(ti.path, err)
+			continue
+		}
+
+		if r.Backend != "https://staging.example.org" {
+			t.Error(ti.path, "failed to rewrite the backend", r.Backend)
+		}
+
+		if len(r.Filters) != len(ti.filters) {
+			t.Error(ti.path, "invalid filters", len(r.Filters))
+			continue
+		}
+
+		for i, f := range r.Filters {
+			if f.Name != ti.filters[i] {
+				t.Error(ti.path, "invalid filter", i, f.Name)
+			}
+		}
+	}
+
+	invalid := rt.InvalidRoutes()
+	if len(invalid) != 1 || invalid[0].Id != "invalid" {
+		t.Error("failed to report the invalid route", invalid)
+	}
+
+	// the preprocessors are applied on copies, the filter is not
+	// injected again on the next update
+	dc.Update([]*eskip.Route{{Id: "route3", Path: "/another", Backend: "https://www.example.org"}}, nil)
+	if err := tr.waitForNRouteSettings(2); err != nil {
+		t.Error(err)
+		return
+	}
+
+	r, err := tr.checkGetRequest("https://www.example.com/some-path")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(r.Filters) != 2 {
+		t.Error("failed to preserve the original route definitions", len(r.Filters))
+	}
+}
+
+func TestPostProcessors(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+		route1: Path("/some-path") -> "https://www.example.org";
+		route2: Path("/other-path") && Annotation("owner", "team-a") -> "https://www.example.org";
+		route3: Path("/dropped") -> "https://www.example.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var calls int
+	annotate := func(routes []*routing.Route) []*routing.Route {
+		calls++
+		var result []*routing.Route
+		for _, r := range routes {
+			if r.Id == "route3" {
+				continue
+			}
+
+			annotations := map[string]string{"pool": "pool-" + r.Id}
+			for k, v := range r.Annotations {
+				annotations[k] = v
+			}
+
+			r.Annotations = annotations
+			result = append(result, r)
+		}
+
+		return result
+	}
+
+	tl := loggingtest.New()
+	rt := routing.New(routing.Options{
+		FilterRegistry: builtin.MakeRegistry(),
+		DataClients:    []routing.DataClient{dc},
+		PollTimeout:    pollTimeout,
+		PostProcessors: []func([]*routing.Route) []*routing.Route{annotate},
+		Log:            tl})
+	tr := &testRouting{tl, rt}
+	defer tr.close()
+
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if calls != 1 {
+		t.Error("invalid number of calls", calls)
+	}
+
+	for _, ti := range []struct {
+		path        string
+		annotations map[string]string
+	}{
+		{"/some-path", map[string]string{"pool": "pool-route1"}},
+		{"/other-path", map[string]string{"pool": "pool-route2", "owner": "team-a"}},
+	} {
+		r, err := tr.checkGetRequest("https://www.example.com" + ti.path)
+		if err != nil {
+			t.Error(ti.path, err)
+			continue
+		}
+
+		if len(r.Annotations) != len(ti.annotations) {
+			t.Error(ti.path, "invalid annotations", r.Annotations)
+			continue
+		}
+
+		for k, v := range ti.annotations {
+			if r.Annotations[k] != v {
+				t.Error(ti.path, "invalid annotation", k, r.Annotations[k])
+			}
+		}
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.com/dropped"); err == nil {
+		t.Error("failed to drop the route")
+	}
+}
+
+func TestNegatedPredicates(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+		notJson: !Header("Accept", "application/json") && !Method("OPTIONS") -> "https://www.example.org";
+		notXml: !HeaderRegexp("Accept", /xml/) && Method("OPTIONS") -> "https://options.example.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tr, err := newTestRouting(dc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	for _, ti := range []struct {
+		msg      string
+		method   string
+		accept   []string
+		expected string
+	}{
+		{"no header", "GET", nil, "notJson"},
+		{"other header value", "GET", []string{"text/html"}, "notJson"},
+		{"negated header", "GET", []string{"application/json"}, ""},
+		{"negated header, any value", "GET", []string{"text/html", "application/json"}, ""},
+		{"negated method", "OPTIONS", []string{"application/json"}, "notXml"},
+		{"negated method and header regexp", "OPTIONS", []string{"application/xml"}, ""},
+	} {
+		req, err := http.NewRequest(ti.method, "https://www.example.com", nil)
+		if err != nil {
+			t.Error(ti.msg, err)
+			continue
+		}
+
+		for _, a := range ti.accept {
+			req.Header.Add("Accept", a)
+		}
+
+		r, _ := tr.routing.Route(req)
+		switch {
+		case ti.expected == "" && r != nil:
+			t.Error(ti.msg, "unexpected match", r.Id)
+		case ti.expected != "" && (r == nil || r.Id != ti.expected):
+			t.Error(ti.msg, "failed to match", r)
+		}
+	}
+
+	req, err := http.NewRequest("GET", "https://www.example.com", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	r, _ := tr.routing.Route(req)
+	if r == nil {
+		t.Error("failed to match")
+		return
+	}
+
+	c := r.Clone()
+	for i, p := range c.Route.Predicates {
+		if !p.Negated {
+			t.Error("failed to clone the negated predicate", i, p.Name)
+		}
+	}
+}
+
+func TestInvalidHeaderRegexpRejectsRoute(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+		invalid: Path("/invalid") && HeaderRegexp("Accept", "application/(json") -> "https://invalid.example.org";
+		valid: Path("/valid") -> "https://valid.example.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tr, err := newTestRouting(dc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	if err := tr.log.WaitFor("error parsing regexp", time.Second); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.com/invalid"); err == nil {
+		t.Error("failed to reject route")
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.com/valid"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestResetDropsRoutesWithoutDeletedIds(t *testing.T) {
+	dc := testdataclient.New([]*eskip.Route{
+		{Id: "route1", Path: "/some-path", Backend: "https://www.example.org"},
+		{Id: "route2", Path: "/some-other", Backend: "https://other.example.org"}})
+	tr, err := newTestRouting(dc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	tr.log.Reset()
+	dc.ResetWith([]*eskip.Route{{Id: "route3", Path: "/another", Backend: "https://another.example.org"}})
+
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.com/some-path"); err == nil {
+		t.Error("failed to drop route1")
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.com/some-other"); err == nil {
+		t.Error("failed to drop route2")
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.com/another"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFullReloadAfterFailedUpdateDropsDeletedRoutes(t *testing.T) {
+	dc := testdataclient.New([]*eskip.Route{
+		{Id: "route1", Path: "/some-path", Backend: "https://www.example.org"},
+		{Id: "route2", Path: "/some-other", Backend: "https://other.example.org"}})
+	tr, err := newTestRouting(dc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	tr.log.Reset()
+	dc.FailNext()
+	dc.Update(nil, []string{"route1"})
+
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.com/some-path"); err == nil {
+		t.Error("failed to drop route1")
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.com/some-other"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSignalsRouteUpdate(t *testing.T) {
+	dc := testdataclient.New([]*eskip.Route{
+		{Id: "route1", Path: "/some-path", Backend: "https://www.example.org"},
+		{Id: "route2", Path: "/some-other", Backend: "https://other.example.org"}})
+
+	updates := make(chan routing.RouteUpdate, 2)
+	tl := loggingtest.New()
+	rt := routing.New(routing.Options{
+		FilterRegistry:    builtin.MakeRegistry(),
+		DataClients:       []routing.DataClient{dc},
+		PollTimeout:       pollTimeout,
+		Log:               tl,
+		SignalRouteUpdate: updates})
+	tr := &testRouting{tl, rt}
+	defer tr.close()
+
+	receive := func() (routing.RouteUpdate, error) {
+		select {
+		case u := <-updates:
+			return u, nil
+		case <-time.After(12 * pollTimeout):
+			return routing.RouteUpdate{}, errors.New("timeout")
+		}
+	}
+
+	u, err := receive()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(u.Added) != 2 || len(u.Updated) != 0 || len(u.Deleted) != 0 {
+		t.Error("invalid initial update", u)
+		return
+	}
+
+	dc.Update([]*eskip.Route{
+		{Id: "route2", Path: "/some-other-changed", Backend: "https://other.example.org"},
+		{Id: "route3", Path: "/another", Backend: "https://another.example.org"}},
+		[]string{"route1"})
+
+	u, err = receive()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(u.Added) != 1 || u.Added[0] != "route3" ||
+		len(u.Updated) != 1 || u.Updated[0] != "route2" ||
+		len(u.Deleted) != 1 || u.Deleted[0] != "route1" {
+		t.Error("invalid update", u)
+	}
+}
+
+func TestCurrentRoutes(t *testing.T) {
+	dc1, err := testdataclient.NewDoc(`
+		route1: Path("/some-path") -> "https://www.example.org";
+		route2: Path("/some-other") -> "https://other.example.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	dc2, err := testdataclient.NewDoc(`
+		route3: Path("/another") && Header("X-Foo", "bar") -> "https://another.example.org";
+		invalid: Path("/invalid") -> "invalid backend"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tr, err := newTestRouting(dc1, dc2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	tr.log.Reset()
+	dc1.Update(nil, []string{"route2"})
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	routes := tr.routing.CurrentRoutes()
+	expected := `route1: Path("/some-path") -> "https://www.example.org";
+route3: Path("/another") && Header("X-Foo", "bar") -> "https://another.example.org"`
+	if s := eskip.String(routes...); s != expected {
+		t.Error("invalid current routes", s)
+		return
+	}
+
+	routes[1].Headers["X-Foo"] = "baz"
+	routes[0].Backend = "https://changed.example.org"
+	if s := eskip.String(tr.routing.CurrentRoutes()...); s != expected {
+		t.Error("failed to return a copy", s)
+	}
+}
+
+func TestRouteMetricsDisabled(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`Path("/foo") -> "https://foo.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tr, err := newTestRouting(dc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	if _, err := tr.checkGetRequest("https://www.example.com/foo"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if m := tr.routing.Metrics(); m != nil {
+		t.Error("unexpected metrics", m)
+	}
+}
+
+func TestRouteMetrics(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+		foo: Path("/foo") -> "https://foo.org";
+		bar: Path("/bar") -> "https://bar.org";
+		baz: Path("/baz") -> "https://baz.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tl := loggingtest.New()
+	rt := routing.New(routing.Options{
+		FilterRegistry:     builtin.MakeRegistry(),
+		DataClients:        []routing.DataClient{dc},
+		PollTimeout:        pollTimeout,
+		Log:                tl,
+		EnableRouteMetrics: true})
+	tr := &testRouting{tl, rt}
+	defer tr.close()
+
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	request := func(path string, n int) {
+		for i := 0; i < n; i++ {
+			if _, err := tr.checkGetRequest("https://www.example.com" + path); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+
+	request("/foo", 3)
+	request("/bar", 5)
+	if _, err := tr.checkGetRequest("https://www.example.com/qux"); err == nil {
+		t.Error("unexpected match")
+	}
+
+	m := tr.routing.Metrics()
+	if len(m) != 3 || m["foo"] != 3 || m["bar"] != 5 || m["baz"] != 0 {
+		t.Error("invalid route metrics", m)
+		return
+	}
+
+	tr.log.Reset()
+	dc.Update(nil, []string{"foo"})
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	request("/bar", 1)
+
+	m = tr.routing.Metrics()
+	if len(m) != 2 || m["bar"] != 6 || m["baz"] != 0 {
+		t.Error("failed to keep the route metrics after update", m)
+	}
+}
+
+func TestRetiresRoutingTableAfterRelease(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`foo: Path("/foo") -> "https://foo.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	retired := make(chan []*routing.Route, 3)
+	tl := loggingtest.New()
+	rt := routing.New(routing.Options{
+		FilterRegistry:    builtin.MakeRegistry(),
+		DataClients:       []routing.DataClient{dc},
+		PollTimeout:       pollTimeout,
+		Log:               tl,
+		RouteTableRetired: func(r []*routing.Route) { retired <- r }})
+	tr := &testRouting{tl, rt}
+	defer tr.close()
+
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	table := tr.routing.Acquire()
+	req, err := http.NewRequest("GET", "https://www.example.com/foo", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if r, _ := table.Route(req); r == nil || r.Id != "foo" {
+		t.Error("failed to match route")
+		return
+	}
+
+	tr.log.Reset()
+	dc.Update(nil, []string{"foo"})
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if r, _ := table.Route(req); r == nil || r.Id != "foo" {
+		t.Error("failed to match route in the held routing table")
+	}
+
+	select {
+	case <-retired:
+		t.Error("routing table retired while referenced")
+		return
+	case <-time.After(3 * pollTimeout):
+	}
+
+	table.Release()
+
+	select {
+	case r := <-retired:
+		if len(r) != 1 || r[0].Id != "foo" {
+			t.Error("invalid retired routes")
+		}
+	case <-time.After(3 * pollTimeout):
+		t.Error("failed to retire routing table")
+	}
+}
+
+func TestMatchTraceDisabled(t *testing.T) {
+	tr, err := newTestRouting()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	req, err := http.NewRequest("GET", "https://www.example.com/foo", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, _, err := tr.routing.RouteWithDiagnostics(req); err != routing.ErrMatchTraceDisabled {
+		t.Error("failed to fail")
+	}
+}
+
+func TestMatchTrace(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+		custom: Path("/foo") && Method("GET") && CustomPredicate("custom") -> "https://custom.org";
+		method: Path("/foo") && Method("POST") -> "https://post.org";
+		other: Path("/bar") -> "https://bar.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tl := loggingtest.New()
+	rt := routing.New(routing.Options{
+		FilterRegistry:   builtin.MakeRegistry(),
+		Predicates:       []routing.PredicateSpec{&predicate{}},
+		DataClients:      []routing.DataClient{dc},
+		PollTimeout:      pollTimeout,
+		Log:              tl,
+		EnableMatchTrace: true})
+	tr := &testRouting{tl, rt}
+	defer tr.close()
+
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	req, err := http.NewRequest("GET", "https://www.example.com/foo", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	req.Header.Set(predicateHeader, "other")
+	r, trace, err := tr.routing.RouteWithDiagnostics(req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if r != nil {
+		t.Error("unexpected match", r.Id)
+	}
+
+	expected := []routing.MatchCandidate{
+		{RouteId: "custom", FailedCondition: "CustomPredicate"},
+		{RouteId: "method", FailedCondition: "Method"},
+	}
+
+	if len(trace.Candidates) != len(expected) {
+		t.Error("invalid candidates", trace.Candidates)
+		return
+	}
+
+	for i, c := range trace.Candidates {
+		if c != expected[i] {
+			t.Error("invalid candidate", c, expected[i])
+		}
+	}
+
+	req.Header.Set(predicateHeader, "custom")
+	r, trace, err = tr.routing.RouteWithDiagnostics(req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if r == nil || r.Id != "custom" || trace.Candidates[0].FailedCondition != "" {
+		t.Error("failed to match route", trace.Candidates)
+	}
+}
+
+func TestExposesAnnotations(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+		annotated: Path("/foo") && Annotation("owner", "team-a") -> "https://foo.org"`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tr, err := newTestRouting(dc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	r, err := tr.checkGetRequest("https://www.example.com/foo")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if r.Annotations["owner"] != "team-a" {
+		t.Error("failed to expose annotations", r.Annotations)
+	}
+
+	current := tr.routing.CurrentRoutes()
+	current[0].Annotations["owner"] = "team-b"
+	if r.Annotations["owner"] != "team-a" {
+		t.Error("failed to copy annotations")
+	}
+}
+
+func TestLBEndpoints(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+		lb: Path("/lb") -> <random, "https://a.example.org", 90, "https://b.example.org", 10>;
+		invalid: Path("/invalid") -> <random, "https://a.example.org", "invalid backend">`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tr, err := newTestRouting(dc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer tr.close()
+
+	if _, err := tr.checkGetRequest("https://www.example.com/invalid"); err == nil {
+		t.Error("failed to reject invalid backend")
+	}
+
+	r, err := tr.checkGetRequest("https://www.example.com/lb")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(r.LBEndpoints) != 2 ||
+		r.LBEndpoints[0].Scheme != "https" || r.LBEndpoints[0].Host != "a.example.org" ||
+		r.LBEndpoints[1].Scheme != "https" || r.LBEndpoints[1].Host != "b.example.org" {
+		t.Error("invalid endpoints", r.LBEndpoints)
+		return
+	}
+
+	const n = 10000
+	var countA int
+	for i := 0; i < n; i++ {
+		ep, ok := r.PickLBEndpoint()
+		if !ok {
+			t.Error("failed to pick endpoint")
+			return
+		}
+
+		if ep.Host == "a.example.org" {
+			countA++
+		}
+	}
+
+	if ratio := float64(countA) / n; ratio < .87 || ratio > .93 {
+		t.Error("failed to split traffic by the weights", ratio)
+	}
+}
+
+type blockingDataClient struct {
+	routes   []*eskip.Route
+	blocked  chan struct{}
+	release  chan struct{}
+	once     sync.Once
+	mx       sync.Mutex
+	returned bool
+}
+
+func (dc *blockingDataClient) LoadAll() ([]*eskip.Route, error) {
+	return dc.routes, nil
+}
+
+func (dc *blockingDataClient) LoadUpdate() ([]*eskip.Route, []string, error) {
+	dc.once.Do(func() { close(dc.blocked) })
+	<-dc.release
+
+	dc.mx.Lock()
+	defer dc.mx.Unlock()
+	dc.returned = true
+	return nil, nil, nil
+}
+
+func TestCloseWaitsForBlockingDataClient(t *testing.T) {
+	dc := &blockingDataClient{
+		routes:  []*eskip.Route{{Id: "route1", Path: "/foo", Backend: "https://foo.org"}},
+		blocked: make(chan struct{}),
+		release: make(chan struct{})}
+	rt := routing.New(routing.Options{
+		DataClients: []routing.DataClient{dc},
+		PollTimeout: time.Millisecond})
+
+	select {
+	case <-dc.blocked:
+	case <-time.After(120 * time.Millisecond):
+		t.Fatal("data client not polled")
+	}
+
+	const delay = 30 * time.Millisecond
+	go func() {
+		time.Sleep(delay)
+		close(dc.release)
+	}()
+
+	start := time.Now()
+	rt.Close()
+
+	if d := time.Since(start); d < delay {
+		t.Error("close returned before the pending request", d)
+	}
+
+	dc.mx.Lock()
+	defer dc.mx.Unlock()
+	if !dc.returned {
+		t.Error("close returned before the pending request")
+	}
+}
+
+func TestCloseStopsGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	dc1 := &countingDataClient{routes: []*eskip.Route{{Id: "route1", Path: "/foo", Backend: "https://foo.org"}}}
+	dc2 := &countingDataClient{routes: []*eskip.Route{{Id: "route2", Path: "/bar", Backend: "https://bar.org"}}}
+
+	// blocks in LoadUpdate until canceled
+	dc3 := testdataclient.New([]*eskip.Route{{Id: "route3", Path: "/baz", Backend: "https://baz.org"}})
+
+	rt := routing.New(routing.Options{
+		DataClients: []routing.DataClient{dc1, dc2, dc3},
+		PollTimeout: time.Millisecond})
+
+	time.Sleep(30 * time.Millisecond)
+	rt.Close()
+	rt.Close()
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Error("goroutines left running after close", before, after)
+	}
+}
+
+func TestPredicateInfo(t *testing.T) {
+	defs, err := eskip.Parse(`
+		custom: Path("/users/:id") && Method("GET") && Header("X-Foo", "foo") && !CustomPredicate("custom1")
+			-> "https://custom.org"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, errs := routing.NewMatcher(defs, builtin.MakeRegistry(), []routing.PredicateSpec{&predicate{}})
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	expected := []routing.PredicateInfo{
+		{Name: "Path", Args: []interface{}{"/users/:id"}},
+		{Name: "Method", Args: []interface{}{"GET"}},
+		{Name: "Header", Args: []interface{}{"X-Foo", "foo"}},
+		{Name: "CustomPredicate", Args: []interface{}{"custom1"}, Negated: true},
+	}
+
+	r := m.Routes()[0]
+	if p := r.PredicateInfo(); !reflect.DeepEqual(p, expected) {
+		t.Error("failed to capture the predicates", p)
+	}
+
+	// the captured predicates don't change with the definition
+	r.Route.Predicates[0].Args[0] = "custom2"
+	if p := r.PredicateInfo(); !reflect.DeepEqual(p, expected) {
+		t.Error("the captured predicates changed", p)
+	}
+
+	c := r.Clone()
+	c.Route.Method = "POST"
+	expected[1].Args = []interface{}{"POST"}
+	expected[3].Args = []interface{}{"custom2"}
+	if p := c.PredicateInfo(); !reflect.DeepEqual(p, expected) {
+		t.Error("failed to get the predicates of the cloned route", p)
+	}
+}
+
+func TestMaxRoutes(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+		route1: Path("/foo") -> "https://foo.example.org";
+		route2: Path("/bar") -> "https://bar.example.org"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := loggingtest.New()
+	tr := &testRouting{l, routing.New(routing.Options{
+		DataClients: []routing.DataClient{dc},
+		PollTimeout: pollTimeout,
+		MaxRoutes:   2,
+		Log:         l})}
+	defer tr.close()
+
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.org/foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Reset()
+	dc.Update([]*eskip.Route{{Id: "route3", Path: "/baz", Backend: "https://baz.example.org"}}, nil)
+	if err := l.WaitFor("route settings rejected", 12*pollTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tr.waitForNRouteSettingsTO(1, 3*pollTimeout); err != loggingtest.ErrWaitTimeout {
+		t.Error("failed to reject the update")
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.org/foo"); err != nil {
+		t.Error("failed to keep the previous routing table", err)
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.org/baz"); err == nil {
+		t.Error("failed to reject the new route")
+	}
+
+	invalid := tr.routing.InvalidRoutes()
+	if len(invalid) != 1 || invalid[0].Id != "route3" || !errors.Is(invalid[0].Err, routing.ErrTooManyRoutes) {
+		t.Error("failed to report the rejected route", invalid)
+	}
+
+	l.Reset()
+	dc.Update([]*eskip.Route{{Id: "route3", Path: "/baz", Backend: "https://baz.example.org"}}, []string{"route2"})
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.org/baz"); err != nil {
+		t.Error("failed to apply the update within the limit", err)
+	}
+
+	if invalid := tr.routing.InvalidRoutes(); len(invalid) != 0 {
+		t.Error("failed to clear the rejected routes", invalid)
+	}
+}
+
+func TestDeprecatedRoutes(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+		old: Path("/v1") && Annotation("deprecated", "use the route current") -> "https://v1.example.org";
+		current: Path("/v2") -> "https://v2.example.org"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := loggingtest.New()
+	tr := &testRouting{l, routing.New(routing.Options{
+		DataClients:            []routing.DataClient{dc},
+		PollTimeout:            pollTimeout,
+		DeprecationLogInterval: time.Hour,
+		Log:                    l})}
+	defer tr.close()
+
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, u := range []string{"/v1", "/v2", "/v1"} {
+		if _, err := tr.checkGetRequest("https://www.example.org" + u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := tr.routing.DeprecatedMetrics()
+	if len(m) != 1 || m["old"] != 2 {
+		t.Error("failed to count the deprecated route", m)
+	}
+
+	if err := l.WaitForN("deprecated route matched: old, use the route current", 1, pollTimeout); err != nil {
+		t.Error("failed to log the warning", err)
+	}
+
+	if err := l.WaitForN("deprecated route matched", 2, 3*pollTimeout); err != loggingtest.ErrWaitTimeout {
+		t.Error("failed to rate limit the warnings", err)
+	}
+
+	// the counter is kept when the routing table is updated
+	l.Reset()
+	dc.Update([]*eskip.Route{{Id: "new", Path: "/v3", Backend: "https://v3.example.org"}}, nil)
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.org/v1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if m := tr.routing.DeprecatedMetrics(); m["old"] != 3 {
+		t.Error("failed to keep the counter of the deprecated route", m)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`route1: Path("/foo") -> "https://foo.example.org"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := newTestRouting(dc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer tr.close()
+
+	versions, cancel := tr.routing.Subscribe()
+	defer cancel()
+
+	initial := <-versions
+	if len(initial.Routes) != 1 || initial.Routes[0].Id != "route1" {
+		t.Fatal("failed to receive the current version")
+	}
+
+	// not receiving the versions, while applying multiple updates
+	for i := 2; i <= 4; i++ {
+		tr.log.Reset()
+		id := fmt.Sprintf("route%d", i)
+		dc.Update([]*eskip.Route{{Id: id, Path: "/" + id, Backend: "https://www.example.org"}}, nil)
+		if err := tr.waitForRouteSetting(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var last routing.TableVersion
+	select {
+	case last = <-versions:
+	case <-time.After(12 * pollTimeout):
+		t.Fatal("failed to receive the latest version")
+	}
+
+	if last.Id <= initial.Id || len(last.Routes) != 4 {
+		t.Error("failed to receive the latest version", initial.Id, last.Id, len(last.Routes))
+	}
+
+	select {
+	case v := <-versions:
+		t.Error("failed to conflate the versions", v.Id)
+	default:
+	}
+
+	cancel()
+	if _, ok := <-versions; ok {
+		t.Error("failed to close the canceled subscription")
+	}
+
+	// the subscriptions are closed together with the routing
+	versions, _ = tr.routing.Subscribe()
+	<-versions
+	tr.routing.Close()
+	if _, ok := <-versions; ok {
+		t.Error("failed to close the subscription")
+	}
+}
+
+func TestDisabledFiltersAndPredicates(t *testing.T) {
+	dc, err := testdataclient.NewDoc(`
+		disabledFilter: Path("/filter") -> customFilter() -> "https://filter.example.org";
+		disabledPredicate: Path("/predicate") && CustomPredicate("custom1") -> "https://predicate.example.org";
+		enabled: Path("/enabled") -> "https://enabled.example.org"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fr := make(filters.Registry)
+	fr.Register(&filtertest.Filter{FilterName: "customFilter"})
+
+	l := loggingtest.New()
+	tr := &testRouting{l, routing.New(routing.Options{
+		FilterRegistry:     fr,
+		Predicates:         []routing.PredicateSpec{&predicate{}},
+		DataClients:        []routing.DataClient{dc},
+		PollTimeout:        pollTimeout,
+		DisabledFilters:    []string{"customFilter"},
+		DisabledPredicates: []string{"CustomPredicate"},
+		Log:                l})}
+	defer tr.close()
+
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := tr.routing.InvalidRoutes()
+	ids := make(map[string]bool)
+	for _, ri := range invalid {
+		ids[ri.Id] = true
+	}
+
+	if len(invalid) != 2 || !ids["disabledFilter"] || !ids["disabledPredicate"] {
+		t.Error("failed to reject the routes", invalid)
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.org/enabled"); err != nil {
+		t.Error("failed to apply the valid route", err)
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.org/filter"); err == nil {
+		t.Error("failed to reject the route with the disabled filter")
+	}
+
+	l.Reset()
+	tr.routing.UpdateFilterRegistry(fr)
+	if err := tr.waitForRouteSetting(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.org/filter"); err == nil {
+		t.Error("failed to reject the route with the disabled filter from the updated registry")
+	}
+}
+
+func TestPathSegmentCountGeneratedPaths(t *testing.T) {
+	var doc string
+	for i := 0; i < 10; i++ {
+		doc += fmt.Sprintf("count%d: PathSegmentCount(%d) -> <shunt>;\n", i, i)
+	}
+
+	doc += `between: PathSegmentCountBetween(2, 4) -> <shunt>`
+
+	defs, err := eskip.Parse(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cps := []routing.PredicateSpec{pathsegment.NewCount(), pathsegment.NewBetween()}
+	counts, errs := routing.NewMatcher(defs[:10], nil, cps)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	between, errs := routing.NewMatcher(defs[10:], nil, cps)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	for _, p := range routing.GeneratePaths(42, 512, true) {
+		var expected int
+		for _, s := range strings.Split(p, "/") {
+			if s != "" {
+				expected++
+			}
+		}
+
+		req, err := http.NewRequest("GET", "https://www.example.org"+p, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// as received by the server
+		req.RequestURI = p
+
+		if r, _ := counts.Match(req); r == nil || r.Id != fmt.Sprintf("count%d", expected) {
+			t.Error("failed to match the segment count", p, expected, r)
+		}
+
+		r, _ := between.Match(req)
+		if (r != nil) != (expected >= 2 && expected <= 4) {
+			t.Error("failed to match the segment count range", p, expected, r != nil)
+		}
+	}
+}
+
+func TestAnonymousRoutes(t *testing.T) {
+	const doc = `Path("/foo") -> "https://foo.example.org"`
+	dc1, err := testdataclient.NewDoc(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dc2, err := testdataclient.NewDoc(`Path("/bar") -> "https://bar.example.org"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := loggingtest.New()
+	tr := &testRouting{l, routing.New(routing.Options{
+		DataClients: []routing.DataClient{dc1, dc2},
+		PollTimeout: pollTimeout,
+		Log:         l})}
+	defer tr.close()
+
+	select {
+	case <-tr.routing.Ready():
+	case <-time.After(12 * pollTimeout):
+		t.Fatal("timeout")
+	}
+
+	if invalid := tr.routing.InvalidRoutes(); len(invalid) != 0 {
+		t.Error("unexpected invalid routes", invalid)
+	}
+
+	foo, err := tr.checkGetRequest("https://www.example.org/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tr.checkGetRequest("https://www.example.org/bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	defs, err := eskip.Parse(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if foo.Id == "" || foo.Id != eskip.ContentId(defs[0]) {
+		t.Error("failed to assign the content id", foo.Id)
+	}
+
+	// the same route parsed again replaces the previous one
+	versions, cancel := tr.routing.Subscribe()
+	defer cancel()
+	<-versions
+
+	l.Reset()
+	dc1.Update(defs, nil)
+	select {
+	case v := <-versions:
+		if len(v.Routes) != 2 {
+			t.Error("failed to replace the anonymous route", len(v.Routes))
+		}
+	case <-time.After(12 * pollTimeout):
+		t.Fatal("timeout")
+	}
+
+	if r, err := tr.checkGetRequest("https://www.example.org/foo"); err != nil || r.Id != foo.Id {
+		t.Error("failed to keep the id of the anonymous route", r, err)
 	}
 }
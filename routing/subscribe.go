@@ -0,0 +1,110 @@
+package routing
+
+// TableVersion is a snapshot of a routing table, sent to the subscribers
+// of the routing. See Routing.Subscribe.
+type TableVersion struct {
+
+	// Identifies the version of the routing table. It is increased
+	// by every applied update, the initial, empty routing table has
+	// the id 1.
+	Id uint64
+
+	// The routes of the routing table. The routes are shared with
+	// the routing, and must not be modified.
+	Routes []*Route
+
+	// Identifies the routing table by the definitions of its routes.
+	Checksum string
+}
+
+type subscription struct {
+	versions chan TableVersion
+	closed   bool
+}
+
+func (m *matcher) tableVersion() TableVersion {
+	return TableVersion{Id: m.version, Routes: m.routes, Checksum: m.checksum}
+}
+
+// sends the version to the subscriber, replacing the pending version
+// that the subscriber didn't receive yet. It must be called while
+// holding the subscription lock, so that the channel has room after the
+// pending version was dropped.
+func (s *subscription) send(v TableVersion) {
+	select {
+	case s.versions <- v:
+	default:
+		select {
+		case <-s.versions:
+		default:
+		}
+
+		s.versions <- v
+	}
+}
+
+// Subscribe returns a channel receiving the versions of the routing
+// table, starting with the current one, and a function to cancel the
+// subscription. A subscriber falling behind receives only the latest
+// version, without the ones that it missed. The channel is closed when
+// the subscription is canceled, or when the routing is closed.
+func (r *Routing) Subscribe() (<-chan TableVersion, func()) {
+	s := &subscription{versions: make(chan TableVersion, 1)}
+
+	r.subscriptionsMx.Lock()
+	defer r.subscriptionsMx.Unlock()
+
+	s.send(r.matcher.Load().(*matcher).tableVersion())
+	if r.subscriptionsClosed {
+		close(s.versions)
+		s.closed = true
+		return s.versions, func() {}
+	}
+
+	if r.subscriptions == nil {
+		r.subscriptions = make(map[*subscription]struct{})
+	}
+
+	r.subscriptions[s] = struct{}{}
+
+	return s.versions, func() { r.unsubscribe(s) }
+}
+
+func (r *Routing) unsubscribe(s *subscription) {
+	r.subscriptionsMx.Lock()
+	defer r.subscriptionsMx.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	delete(r.subscriptions, s)
+	close(s.versions)
+	s.closed = true
+}
+
+// stores the next version of the routing table, and sends it to the
+// subscribers
+func (r *Routing) storeMatcher(m *matcher) {
+	r.subscriptionsMx.Lock()
+	defer r.subscriptionsMx.Unlock()
+
+	r.matcher.Store(m)
+	v := m.tableVersion()
+	for s := range r.subscriptions {
+		s.send(v)
+	}
+}
+
+func (r *Routing) closeSubscriptions() {
+	r.subscriptionsMx.Lock()
+	defer r.subscriptionsMx.Unlock()
+
+	for s := range r.subscriptions {
+		close(s.versions)
+		s.closed = true
+	}
+
+	r.subscriptions = nil
+	r.subscriptionsClosed = true
+}
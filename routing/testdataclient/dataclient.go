@@ -18,22 +18,39 @@ interface of the skipper/routing package.
 
 It uses in-memory route definitions that are passed in on construction,
 and can upserted/deleted programmatically.
+
+To reproduce races between the routing and the data sources, the responses
+of the client can be delayed with DelayNext, and updates can be queued with
+QueueUpdate and delivered in a custom order with ReleaseQueued.
 */
 package testdataclient
 
 import (
 	"errors"
+	"sync"
+	"time"
+
 	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/routing"
 )
 
 // DataClient implementation.
 type Client struct {
 	initDoc      string
 	routes       map[string]*eskip.Route
-	upsert       []*eskip.Route
-	deletedIds   []string
 	failNext     int
-	signalUpdate chan int
+	signalUpdate chan update
+	queue        []update
+	mx           sync.Mutex
+	delayNext    time.Duration
+	cancel       chan struct{}
+	cancelOnce   sync.Once
+}
+
+type update struct {
+	upsert     []*eskip.Route
+	deletedIds []string
+	reset      bool
 }
 
 // Creates a Client with an initial set of route definitions.
@@ -45,7 +62,8 @@ func New(initial []*eskip.Route) *Client {
 
 	return &Client{
 		routes:       routes,
-		signalUpdate: make(chan int)}
+		signalUpdate: make(chan update),
+		cancel:       make(chan struct{})}
 }
 
 // Creates a Client with an initial set of route definitions in eskip
@@ -59,10 +77,21 @@ func NewDoc(doc string) (*Client, error) {
 	return New(routes), nil
 }
 
+// waits for the delay set by DelayNext, if any
+func (c *Client) delay() {
+	c.mx.Lock()
+	d := c.delayNext
+	c.delayNext = 0
+	c.mx.Unlock()
+
+	time.Sleep(d)
+}
+
 // Returns the initial/current set of route definitions.
 func (c *Client) LoadAll() ([]*eskip.Route, error) {
+	c.delay()
+
 	if c.failNext > 0 {
-		c.upsert, c.deletedIds = nil, nil
 		c.failNext--
 		return nil, errors.New("failed to get routes")
 	}
@@ -76,38 +105,58 @@ func (c *Client) LoadAll() ([]*eskip.Route, error) {
 }
 
 // Returns the route definitions upserted/deleted since the last call to
-// LoadAll.
+// LoadAll. After ResetWith was called, it returns routing.ErrReset. It
+// blocks until the next update, or until CancelUpdate is called.
 func (c *Client) LoadUpdate() ([]*eskip.Route, []string, error) {
-	<-c.signalUpdate
+	var u update
+	select {
+	case u = <-c.signalUpdate:
+	case <-c.cancel:
+		return nil, nil, nil
+	}
 
-	for _, id := range c.deletedIds {
+	c.delay()
+
+	if u.reset {
+		c.routes = make(map[string]*eskip.Route)
+	}
+
+	for _, id := range u.deletedIds {
 		delete(c.routes, id)
 	}
 
-	for _, r := range c.upsert {
+	for _, r := range u.upsert {
 		c.routes[r.Id] = r
 	}
 
 	if c.failNext > 0 {
-		c.upsert, c.deletedIds = nil, nil
 		c.failNext--
 		return nil, nil, errors.New("failed to get routes")
 	}
 
-	var (
-		u []*eskip.Route
-		d []string
-	)
+	if u.reset {
+		return nil, nil, routing.ErrReset
+	}
+
+	return u.upsert, u.deletedIds, nil
+}
+
+// Returns the current set of route definitions. Called by the routing
+// after LoadUpdate signaled a reset.
+func (c *Client) Reset() ([]*eskip.Route, error) {
+	return c.LoadAll()
+}
 
-	u, d, c.upsert, c.deletedIds = c.upsert, c.deletedIds, nil, nil
-	return u, d, nil
+// Makes the pending and the subsequent calls to LoadUpdate return without
+// an update. Called by the routing when it is closed.
+func (c *Client) CancelUpdate() {
+	c.cancelOnce.Do(func() { close(c.cancel) })
 }
 
 // Updates the current set of routes with new/modified and deleted
 // route definitions.
 func (c *Client) Update(upsert []*eskip.Route, deletedIds []string) {
-	c.upsert, c.deletedIds = upsert, deletedIds
-	c.signalUpdate <- 42
+	c.signalUpdate <- update{upsert: upsert, deletedIds: deletedIds}
 }
 
 // Updates the current set of routes with new/modified and deleted
@@ -123,9 +172,49 @@ func (c *Client) UpdateDoc(upsertDoc string, deletedIds []string) error {
 	return nil
 }
 
+// Replaces the current set of routes, and signals a reset on the next
+// call to LoadUpdate, without reporting the ids of the dropped routes.
+func (c *Client) ResetWith(routes []*eskip.Route) {
+	c.signalUpdate <- update{upsert: routes, reset: true}
+}
+
 // Sets the Client to fail on the next call to LoadAll or LoadUpdate.
 // Repeated call to FailNext will result the Client to fail as many
 // times as it was called.
 func (c *Client) FailNext() {
 	c.failNext++
 }
+
+// Sets the Client to wait for the duration d in the next call to LoadAll
+// or LoadUpdate, before returning the routes. In case of LoadUpdate, the
+// delay starts when the update is received.
+func (c *Client) DelayNext(d time.Duration) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.delayNext = d
+}
+
+// Stores an update without delivering it. The queued updates can be
+// delivered by calling ReleaseQueued.
+func (c *Client) QueueUpdate(upsert []*eskip.Route, deletedIds []string) {
+	c.queue = append(c.queue, update{upsert: upsert, deletedIds: deletedIds})
+}
+
+// Delivers the queued updates one by one, the same way as Update, in the
+// order defined by the indexes of the updates in the queue. Without
+// arguments, the updates are delivered in the order they were queued.
+// The indexes must be valid. The queue is emptied.
+func (c *Client) ReleaseQueued(order ...int) {
+	q := c.queue
+	c.queue = nil
+
+	if len(order) == 0 {
+		for i := range q {
+			order = append(order, i)
+		}
+	}
+
+	for _, i := range order {
+		c.Update(q[i].upsert, q[i].deletedIds)
+	}
+}
@@ -0,0 +1,102 @@
+package testdataclient
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/logging/loggingtest"
+	"github.com/zalando/skipper/routing"
+)
+
+func newRouting(dc *Client) (*routing.Routing, <-chan routing.RouteUpdate, func()) {
+	updates := make(chan routing.RouteUpdate, 1)
+	tl := loggingtest.New()
+	rt := routing.New(routing.Options{
+		DataClients:       []routing.DataClient{dc},
+		PollTimeout:       3 * time.Millisecond,
+		SignalRouteUpdate: updates,
+		Log:               tl})
+	return rt, updates, func() {
+		rt.Close()
+		tl.Close()
+	}
+}
+
+func backendFor(rt *routing.Routing, path string) string {
+	r, _ := rt.Route(&http.Request{URL: &url.URL{Path: path}})
+	if r == nil {
+		return ""
+	}
+
+	return r.Backend
+}
+
+func waitForUpdate(t *testing.T, updates <-chan routing.RouteUpdate) bool {
+	select {
+	case <-updates:
+		return true
+	case <-time.After(3 * time.Second):
+		t.Error("timeout waiting for the route update")
+		return false
+	}
+}
+
+func TestDelayedUpdate(t *testing.T) {
+	dc := New([]*eskip.Route{{Id: "route1", Path: "/some/path", Backend: "https://www1.example.org"}})
+	rt, updates, closeRouting := newRouting(dc)
+	defer closeRouting()
+
+	if !waitForUpdate(t, updates) {
+		return
+	}
+
+	dc.DelayNext(90 * time.Millisecond)
+	dc.Update([]*eskip.Route{{Id: "route1", Path: "/some/path", Backend: "https://www2.example.org"}}, nil)
+
+	time.Sleep(30 * time.Millisecond)
+	if b := backendFor(rt, "/some/path"); b != "https://www1.example.org" {
+		t.Error("failed to delay the update", b)
+		return
+	}
+
+	if !waitForUpdate(t, updates) {
+		return
+	}
+
+	if b := backendFor(rt, "/some/path"); b != "https://www2.example.org" {
+		t.Error("failed to apply the delayed update", b)
+	}
+}
+
+func TestReorderedUpdates(t *testing.T) {
+	dc := New([]*eskip.Route{{Id: "route1", Path: "/some/path", Backend: "https://www1.example.org"}})
+	rt, updates, closeRouting := newRouting(dc)
+	defer closeRouting()
+
+	if !waitForUpdate(t, updates) {
+		return
+	}
+
+	dc.QueueUpdate([]*eskip.Route{{Id: "route1", Path: "/some/path", Backend: "https://www2.example.org"}}, nil)
+	dc.QueueUpdate([]*eskip.Route{{Id: "route1", Path: "/some/path", Backend: "https://www3.example.org"}}, nil)
+	dc.ReleaseQueued(1, 0)
+
+	// the routing may merge the updates, so waiting until the last
+	// write gets applied
+	timeout := time.After(3 * time.Second)
+	for {
+		if b := backendFor(rt, "/some/path"); b == "https://www2.example.org" {
+			return
+		}
+
+		select {
+		case <-updates:
+		case <-timeout:
+			t.Error("failed to converge to the last write", backendFor(rt, "/some/path"))
+			return
+		}
+	}
+}
@@ -29,10 +29,24 @@ import (
 	"github.com/zalando/skipper/innkeeper"
 	"github.com/zalando/skipper/logging"
 	"github.com/zalando/skipper/metrics"
+	"github.com/zalando/skipper/predicates/clientcn"
+	"github.com/zalando/skipper/predicates/contenttype"
 	"github.com/zalando/skipper/predicates/cookie"
+	"github.com/zalando/skipper/predicates/expression"
+	"github.com/zalando/skipper/predicates/featureflag"
+	"github.com/zalando/skipper/predicates/headerabsent"
+	"github.com/zalando/skipper/predicates/hostany"
 	"github.com/zalando/skipper/predicates/interval"
+	"github.com/zalando/skipper/predicates/jsonbody"
+	"github.com/zalando/skipper/predicates/jwt"
+	"github.com/zalando/skipper/predicates/methods"
+	"github.com/zalando/skipper/predicates/pathsegment"
+	"github.com/zalando/skipper/predicates/primitive"
 	"github.com/zalando/skipper/predicates/query"
+	"github.com/zalando/skipper/predicates/scheme"
 	"github.com/zalando/skipper/predicates/source"
+	"github.com/zalando/skipper/predicates/traffic"
+	"github.com/zalando/skipper/predicates/weight"
 	"github.com/zalando/skipper/proxy"
 	"github.com/zalando/skipper/routing"
 )
@@ -95,6 +109,29 @@ type Options struct {
 	// File containing static route definitions.
 	RoutesFile string
 
+	// When set, the routes file is watched for changes, and the
+	// routes are updated without restarting skipper.
+	WatchRoutesFile bool
+
+	// When set, the Source predicate takes the source address of the
+	// request from the X-Forwarded-For header, when it is present. Use
+	// it only behind a load balancer that sets the header.
+	TrustForwardedFor bool
+
+	// When set, the Scheme predicate takes the scheme of the request
+	// from the X-Forwarded-Proto header, when it is present. Use it
+	// only behind a load balancer that sets the header.
+	TrustForwardedProto bool
+
+	// The maximum number of bytes that the JSONBodyKV predicate reads
+	// from the request body. Defaults to jsonbody.DefaultMaxBodySize.
+	JSONBodyPredicateMaxSize int64
+
+	// Evaluates the feature flags of the FeatureFlag predicate, e.g. a
+	// client of a feature flag service. When not set, the predicate
+	// doesn't match any request.
+	FeatureFlagProvider featureflag.Provider
+
 	// Polling timeout of the routing data sources.
 	SourcePollTimeout time.Duration
 
@@ -117,6 +154,10 @@ type Options struct {
 	// lookup.
 	IgnoreTrailingSlash bool
 
+	// Flag indicating to ignore the case and the trailing dot of the
+	// host during matching the Host conditions.
+	IgnoreHostCase bool
+
 	// Priority routes that are matched against the requests before
 	// the standard routes from the data clients.
 	PriorityRoutes []proxy.PriorityRoute
@@ -191,13 +232,17 @@ func createDataClients(o Options, auth innkeeper.Authentication) ([]routing.Data
 	var clients []routing.DataClient
 
 	if o.RoutesFile != "" {
-		f, err := eskipfile.Open(o.RoutesFile)
-		if err != nil {
-			log.Error(err)
-			return nil, err
+		if o.WatchRoutesFile {
+			clients = append(clients, eskipfile.Watch(o.RoutesFile))
+		} else {
+			f, err := eskipfile.Open(o.RoutesFile)
+			if err != nil {
+				log.Error(err)
+				return nil, err
+			}
+
+			clients = append(clients, f)
 		}
-
-		clients = append(clients, f)
 	}
 
 	if o.InnkeeperUrl != "" {
@@ -337,6 +382,10 @@ func Run(o Options) error {
 		mo = routing.IgnoreTrailingSlash
 	}
 
+	if o.IgnoreHostCase {
+		mo |= routing.IgnoreHostCase
+	}
+
 	// ensure a non-zero poll timeout
 	if o.SourcePollTimeout <= 0 {
 		o.SourcePollTimeout = defaultSourcePollTimeout
@@ -350,12 +399,30 @@ func Run(o Options) error {
 
 	// include bundeled custom predicates
 	o.CustomPredicates = append(o.CustomPredicates,
-		source.New(),
+		source.NewWithOptions(source.Options{TrustForwardedFor: o.TrustForwardedFor}),
+		source.NewFromLast(),
 		interval.NewBetween(),
 		interval.NewBefore(),
 		interval.NewAfter(),
+		interval.NewBetweenTime(),
 		cookie.New(),
-		query.New())
+		query.New(),
+		methods.New(),
+		clientcn.New(),
+		weight.New(),
+		contenttype.New(),
+		jwt.New(),
+		hostany.New(),
+		primitive.NewTrue(),
+		primitive.NewFalse(),
+		scheme.NewWithOptions(scheme.Options{TrustForwardedProto: o.TrustForwardedProto}),
+		jsonbody.NewWithOptions(jsonbody.Options{MaxBodySize: o.JSONBodyPredicateMaxSize}),
+		traffic.New(),
+		headerabsent.New(),
+		pathsegment.NewCount(),
+		pathsegment.NewBetween(),
+		expression.New(),
+		featureflag.NewWithOptions(featureflag.Options{Provider: o.FeatureFlagProvider}))
 
 	// create a routing engine
 	routing := routing.New(routing.Options{